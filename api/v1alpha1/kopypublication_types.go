@@ -0,0 +1,88 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PublishedObject identifies an object in the namespace of the publication
+type PublishedObject struct {
+	// Kind of the published object
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind"`
+
+	// Name of the published object
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// KopyPublicationSpec defines the desired state of KopyPublication
+type KopyPublicationSpec struct {
+	// Objects are the Secrets and ConfigMaps in the namespace of the publication that are published
+	// +kubebuilder:validation:MinItems=1
+	Objects []PublishedObject `json:"objects"`
+
+	// NamespaceSelector selects the namespaces that receive copies of the objects, the same way the sync
+	// annotation does. When omitted the objects are only available to KopySubscriptions.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Tenants are the namespaces allowed to subscribe to the objects with a KopySubscription.
+	// When empty any namespace may subscribe.
+	// +optional
+	Tenants []string `json:"tenants,omitempty"`
+
+	// Clusters are the names of remote clusters the objects are published to. They are recorded in the
+	// kopy.kot-labs.com/publish-clusters annotation for tooling that syncs across clusters.
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+}
+
+// PublishedObjectStatus reports the state of a single published object
+type PublishedObjectStatus struct {
+	PublishedObject `json:",inline"`
+
+	// Published is true when the publication manages the kopy annotations of the object
+	Published bool `json:"published"`
+
+	// Message explains why the object could not be published
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// KopyPublicationStatus defines the observed state of KopyPublication
+type KopyPublicationStatus struct {
+	// Objects reports the state of every object in the spec
+	// +optional
+	Objects []PublishedObjectStatus `json:"objects,omitempty"`
+
+	// Conditions represent the latest available observations of the publication
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// KopyPublication declares which objects in its namespace are published and to whom
+type KopyPublication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KopyPublicationSpec   `json:"spec,omitempty"`
+	Status KopyPublicationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KopyPublicationList contains a list of KopyPublication
+type KopyPublicationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KopyPublication `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KopyPublication{}, &KopyPublicationList{})
+}
@@ -0,0 +1,120 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TargetState is the state of the copy of a source in a target namespace
+// +kubebuilder:validation:Enum=Synced;Failed;Pending
+type TargetState string
+
+const (
+	// TargetSynced is a target holding a copy of the current data of the source
+	TargetSynced TargetState = "Synced"
+	// TargetFailed is a target whose copy couldn't be written during the last sync
+	TargetFailed TargetState = "Failed"
+	// TargetPending is a selected target that wasn't synced yet, e.g. because it is held back by the propagation
+	// rate limit, an earlier target group or a change waiting to be confirmed
+	TargetPending TargetState = "Pending"
+)
+
+// ReportedSource identifies the source of a KopyReport in the namespace of the report
+type ReportedSource struct {
+	// APIVersion of the source object
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the source object
+	Kind string `json:"kind"`
+
+	// Name of the source object
+	Name string `json:"name"`
+}
+
+// ReportedTarget is the state of the copy of the source in a target namespace
+type ReportedTarget struct {
+	// Namespace is the target namespace
+	Namespace string `json:"namespace"`
+
+	// State of the copy in the namespace
+	State TargetState `json:"state"`
+
+	// LastSyncTime is when the copy was last written successfully
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Error is the error of the last failed sync to the namespace
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// KopyReportSpec defines the source a KopyReport reports on
+type KopyReportSpec struct {
+	// Source is the object in the namespace of the report whose copies are reported
+	Source ReportedSource `json:"source"`
+}
+
+// KopyReportStatus is the propagation state of a source
+type KopyReportStatus struct {
+	// Targets is the number of namespaces selected for the source
+	// +optional
+	Targets int32 `json:"targets,omitempty"`
+
+	// Synced is the number of namespaces holding a copy of the current data of the source
+	// +optional
+	Synced int32 `json:"synced,omitempty"`
+
+	// Failed is the number of namespaces whose copy couldn't be written during the last sync
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// LastSyncTime is when the source was last synced
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Namespaces is the state of every target namespace, sorted by namespace
+	// +optional
+	Namespaces []ReportedTarget `json:"namespaces,omitempty"`
+
+	// Suppressed are the warnings the source suppresses with the kopy.kot-labs.com/suppress annotation, they aren't
+	// recorded as events
+	// +optional
+	Suppressed []string `json:"suppressed,omitempty"`
+
+	// Conditions represent the latest available observations of the source. Propagated is true once every target
+	// holds a copy of the current data.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.source.kind`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.name`
+// +kubebuilder:printcolumn:name="Targets",type=integer,JSONPath=`.status.targets`
+// +kubebuilder:printcolumn:name="Synced",type=integer,JSONPath=`.status.synced`
+// +kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+// +kubebuilder:printcolumn:name="Propagated",type=string,JSONPath=`.status.conditions[?(@.type=="Propagated")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// KopyReport is written by kopy next to every source it syncs and reports whether the source is fully propagated:
+// its target namespaces, when each of them was last synced and the error of the ones that failed
+type KopyReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KopyReportSpec   `json:"spec,omitempty"`
+	Status KopyReportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KopyReportList contains a list of KopyReport
+type KopyReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KopyReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KopyReport{}, &KopyReportList{})
+}
@@ -0,0 +1,57 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KopySourceQuotaSpec defines the desired state of KopySourceQuota
+type KopySourceQuotaSpec struct {
+	// MaxSources is how many Secrets and ConfigMaps in the namespace of the quota may be synced by kopy. Sources
+	// beyond the limit, by creation time, are rejected and not copied.
+	// +kubebuilder:validation:Minimum=0
+	MaxSources int32 `json:"maxSources"`
+}
+
+// KopySourceQuotaStatus defines the observed state of KopySourceQuota
+type KopySourceQuotaStatus struct {
+	// Used is the number of sources in the namespace
+	// +optional
+	Used int32 `json:"used"`
+
+	// Rejected are the sources over the limit as kind/name, e.g. secret/db-password
+	// +optional
+	Rejected []string `json:"rejected,omitempty"`
+
+	// Conditions represent the latest available observations of the quota
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Max",type=integer,JSONPath=`.spec.maxSources`
+// +kubebuilder:printcolumn:name="Used",type=integer,JSONPath=`.status.used`
+// +kubebuilder:printcolumn:name="Exceeded",type=string,JSONPath=`.status.conditions[?(@.type=="Exceeded")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// KopySourceQuota limits how many sources kopy syncs from its namespace
+type KopySourceQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KopySourceQuotaSpec   `json:"spec,omitempty"`
+	Status KopySourceQuotaStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KopySourceQuotaList contains a list of KopySourceQuota
+type KopySourceQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KopySourceQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KopySourceQuota{}, &KopySourceQuotaList{})
+}
@@ -0,0 +1,77 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SourceReference identifies a published source object
+type SourceReference struct {
+	// Kind of the source object
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind"`
+
+	// Namespace of the source object
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// Name of the source object
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// KopySubscriptionSpec defines the desired state of KopySubscription
+type KopySubscriptionSpec struct {
+	// Sources are the published sources that should be copied into the namespace of the subscription
+	// +kubebuilder:validation:MinItems=1
+	Sources []SourceReference `json:"sources"`
+}
+
+// SubscribedSourceStatus reports the sync state of a single subscribed source
+type SubscribedSourceStatus struct {
+	SourceReference `json:",inline"`
+
+	// Synced is true when the copy of the source is present in the namespace of the subscription
+	Synced bool `json:"synced"`
+
+	// Message explains why the source could not be synced
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// KopySubscriptionStatus defines the observed state of KopySubscription
+type KopySubscriptionStatus struct {
+	// Sources reports the sync state of every source in the spec
+	// +optional
+	Sources []SubscribedSourceStatus `json:"sources,omitempty"`
+
+	// Conditions represent the latest available observations of the subscription
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// KopySubscription is created by a tenant in their own namespace to receive copies of published sources
+type KopySubscription struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KopySubscriptionSpec   `json:"spec,omitempty"`
+	Status KopySubscriptionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KopySubscriptionList contains a list of KopySubscription
+type KopySubscriptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KopySubscription `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KopySubscription{}, &KopySubscriptionList{})
+}
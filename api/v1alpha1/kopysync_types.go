@@ -0,0 +1,98 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SyncedObject identifies the source object in the namespace of a KopySync
+type SyncedObject struct {
+	// Kind of the source object
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind"`
+
+	// Name of the source object
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// KopySyncSpec defines the desired state of KopySync
+type KopySyncSpec struct {
+	// Source is the Secret or ConfigMap in the namespace of the KopySync that is synced
+	Source SyncedObject `json:"source"`
+
+	// NamespaceSelector selects the namespaces that receive copies of the source, the same way the sync annotation
+	// does. It must not be empty.
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+
+	// LocalCopy is the name of a copy kept in the namespace of the source
+	// +optional
+	LocalCopy string `json:"localCopy,omitempty"`
+
+	// ExcludeFromBackup adds the backup exclusion labels kopy is configured with to the copies
+	// +optional
+	ExcludeFromBackup bool `json:"excludeFromBackup,omitempty"`
+
+	// SyncWindow restricts the propagation of changes to change windows, e.g. "Mon-Fri 09:00-17:00 Europe/Berlin".
+	// Several windows are separated by ";".
+	// +optional
+	SyncWindow string `json:"syncWindow,omitempty"`
+
+	// MaxTargetsPerMinute limits how many namespaces a change is rolled out to per minute
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxTargetsPerMinute int32 `json:"maxTargetsPerMinute,omitempty"`
+
+	// PostSyncHooks are the names of the post-sync hooks that run once every copy carries the current data
+	// +optional
+	PostSyncHooks []string `json:"postSyncHooks,omitempty"`
+}
+
+// KopySyncStatus defines the observed state of KopySync
+type KopySyncStatus struct {
+	// ObservedGeneration is the generation of the KopySync the status was written for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Copies is the number of namespaces holding a copy of the current data of the source
+	// +optional
+	Copies int32 `json:"copies,omitempty"`
+
+	// Namespaces are the namespaces holding a copy of the current data of the source
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Conditions represent the latest available observations of the sync
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.source.kind`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.name`
+// +kubebuilder:printcolumn:name="Copies",type=integer,JSONPath=`.status.copies`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// KopySync declares that a source object in its namespace is synced to the namespaces matching a selector, in place
+// of the kopy annotations on the source
+type KopySync struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KopySyncSpec   `json:"spec,omitempty"`
+	Status KopySyncStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KopySyncList contains a list of KopySync
+type KopySyncList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KopySync `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KopySync{}, &KopySyncList{})
+}
@@ -0,0 +1,69 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KopyTokenSpec defines the desired state of KopyToken
+type KopyTokenSpec struct {
+	// ServiceAccountName is the service account in the namespace of the KopyToken that tokens are requested for
+	// +kubebuilder:validation:MinLength=1
+	ServiceAccountName string `json:"serviceAccountName"`
+
+	// Audiences are the intended audiences of the tokens. Defaults to the audiences of the API server.
+	// +optional
+	Audiences []string `json:"audiences,omitempty"`
+
+	// ExpirationSeconds is the requested lifetime of each token. Tokens are renewed once 80% of it has passed.
+	// +kubebuilder:validation:Minimum=600
+	// +kubebuilder:default=3600
+	// +optional
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+
+	// NamespaceSelector selects the namespaces that receive a token Secret
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+
+	// SecretName is the name of the Secret holding the token in each selected namespace.
+	// Defaults to the name of the KopyToken.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// KopyTokenStatus defines the observed state of KopyToken
+type KopyTokenStatus struct {
+	// Namespaces that currently hold a token Secret
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Conditions represent the latest available observations of the token distribution
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Service Account",type=string,JSONPath=`.spec.serviceAccountName`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// KopyToken distributes short-lived, audience-bound tokens of a service account as Secrets to selected namespaces
+type KopyToken struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KopyTokenSpec   `json:"spec,omitempty"`
+	Status KopyTokenStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KopyTokenList contains a list of KopyToken
+type KopyTokenList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KopyToken `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KopyToken{}, &KopyTokenList{})
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/flynshue/kopy/internal/cli"
+)
+
+func main() {
+	flag.Usage = cli.Usage
+	flag.Parse()
+	err := cli.Run(ctrl.SetupSignalHandler(), flag.Args())
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
@@ -1,29 +1,42 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	"go.uber.org/zap/zapcore"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	toolscache "k8s.io/client-go/tools/cache"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+	"github.com/flynshue/kopy/internal/api"
 	"github.com/flynshue/kopy/internal/controller"
+	"github.com/flynshue/kopy/internal/kopycrypto"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -37,6 +50,8 @@ var Version = "unreleased"
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
+	utilruntime.Must(syncv1alpha1.AddToScheme(scheme))
+
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -46,17 +61,237 @@ func main() {
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
 	var enableLeaderElection bool
+	var warmStandby bool
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
 	var printVersion bool
+	var apiAddr string
+	var adminAPIAddr string
+	var adminAPICertDir string
+	var adminAPIInsecure bool
+	var backupExclusionLabels string
+	var namespaces string
+	var excludedNamespaces string
+	var deniedCopyNames string
+	var syncDeadline time.Duration
+	var legacyDomains string
+	var queueShedThreshold int
+	var queueShedDelay time.Duration
+	var memoryHighWatermark float64
+	var memoryLowWatermark float64
+	var maxConcurrentReconciles int
+	var copyConcurrency int
+	var hnc bool
+	var inventoryConfigMap string
+	var signingKey string
+	var transformWebhooks string
+	var postSyncHooks string
+	var eventSink string
+	var namespaceDeletionProtection bool
+	var copyRefreshInterval time.Duration
+	var pruneGracePeriod time.Duration
+	var cascadeDelete bool
+	var cascadeGracePeriod time.Duration
+	var cascadeVerify bool
+	var tombstoneNamespace string
+	var tombstoneRetention time.Duration
+	var startupSyncDeadline time.Duration
+	var orphanGCInterval time.Duration
+	var namespaceInfo bool
+	var namespaceInfoContact string
+	var orphanGCDelete bool
+	var confirmThreshold int
+	var pinnedTargets string
+	var copyNameSuffix string
+	var secretMetadataOnly bool
+	var syncRBAC bool
+	var rbacSourceNamespaces string
+	var syncGVKs []schema.GroupVersionKind
+	var vclusterMode controller.VClusterMode
+	var costLabels map[string]string
+	var propagationBackend controller.PropagationBackend
+	var remoteClusterNamespace string
+	var reports bool
+	var selectorLogSampleRate int
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
+	flag.StringVar(&apiAddr, "api-bind-address", "0", "The address the kopy REST API binds to. "+
+		"Use :8082 to serve the API, or leave as 0 to disable the API server.")
+	flag.StringVar(&adminAPIAddr, "admin-api-bind-address", "0", "The address the gRPC admin API binds to. "+
+		"Use :9443 to serve it, or leave as 0 to disable it. Callers are authorized with TokenReviews and "+
+		"SubjectAccessReviews.")
+	flag.StringVar(&adminAPICertDir, "admin-api-cert-dir", "",
+		"The directory with the tls.crt and tls.key the admin API is served with. Required unless --admin-api-insecure "+
+			"is set.")
+	flag.BoolVar(&adminAPIInsecure, "admin-api-insecure", false,
+		"Serve the admin API without TLS when --admin-api-cert-dir is empty. Callers send their bearer tokens in "+
+			"cleartext, only use it for local development.")
+	flag.StringVar(&backupExclusionLabels, "backup-exclusion-labels", "velero.io/exclude-from-backup=true",
+		"Comma separated key=value labels added to copies of sources annotated with "+
+			"kopy.kot-labs.com/exclude-from-backup=true.")
+	flag.StringVar(&namespaces, "namespaces", "",
+		"Comma separated list of namespaces to restrict kopy to. When set, kopy only needs Role permissions in "+
+			"these namespaces and does not watch namespace label changes.")
+	flag.StringVar(&excludedNamespaces, "excluded-namespaces", "",
+		"Comma separated list of namespaces that never receive copies, even when their labels match a sync selector.")
+	flag.StringVar(&deniedCopyNames, "denied-copy-names", strings.Join(controller.DefaultDeniedCopyNames, ","),
+		"Comma separated names of objects kopy never overwrites with a copy, given as a name glob optionally prefixed "+
+			"by the kind name, e.g. secret/default-token-*. Use none to deny no name.")
+	flag.DurationVar(&syncDeadline, "sync-deadline", 5*time.Minute,
+		"How long a selected namespace may lack the copy of a source before a SyncStuck event is emitted on the "+
+			"source. Use 0 to disable stuck sync detection.")
+	flag.StringVar(&legacyDomains, "legacy-domains", "",
+		"Comma separated list of label domains used by previous kopy installations. Copies labeled under these "+
+			"domains are adopted instead of being reported as conflicts.")
+	flag.IntVar(&queueShedThreshold, "queue-shed-threshold", 0,
+		"Workqueue depth of the Secret and ConfigMap controllers above which new requests are delayed to protect the "+
+			"API server during event storms. Use 0 to disable load shedding.")
+	flag.DurationVar(&queueShedDelay, "queue-shed-delay", 30*time.Second,
+		"How long requests are delayed when the workqueue is over --queue-shed-threshold.")
+	flag.Float64Var(&memoryHighWatermark, "memory-high-watermark", 0.9,
+		"Fraction of the memory limit of the container, or GOMEMLIMIT, above which kopy switches to degraded mode: "+
+			"copies are written one at a time, sources are refreshed less often and the garbage collector runs more "+
+			"often. 0 disables degraded mode.")
+	flag.Float64Var(&memoryLowWatermark, "memory-low-watermark", 0.75,
+		"Fraction of the memory limit below which kopy leaves degraded mode.")
+	flag.IntVar(&copyConcurrency, "copy-concurrency", 10,
+		"Number of copies of a source written in parallel, for sources without target groups. Propagation rate "+
+			"limits still apply.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"How many Secrets and ConfigMaps are reconciled in parallel. Reconciles of the same source and its copies "+
+			"are always serialized.")
+	flag.BoolVar(&hnc, "hnc", false,
+		"Treat subnamespaces of selected namespaces, as created by the Hierarchical Namespace Controller, as targets.")
+	flag.StringVar(&inventoryConfigMap, "inventory-configmap", "",
+		"namespace/name of a ConfigMap to publish the identities of kopy managed objects to, e.g. for Gatekeeper "+
+			"constraint templates. Leave empty to disable.")
+	flag.BoolVar(&namespaceInfo, "namespace-info", false,
+		"Write a "+controller.NamespaceInfoName+" ConfigMap into every namespace with copies that lists them, "+
+			"their sources and how to request changes.")
+	flag.StringVar(&namespaceInfoContact, "namespace-info-contact", controller.DefaultNamespaceInfoContact,
+		"How tenants request changes to copies, e.g. a ticket queue or chat channel, as shown in the "+
+			controller.NamespaceInfoName+" ConfigMaps.")
+	flag.StringVar(&signingKey, "signing-key", "",
+		"Path to a PEM encoded ECDSA private key used to sign copies. Copies can be verified with "+
+			"\"kopy verify\". Leave empty to disable signing.")
+	flag.StringVar(&transformWebhooks, "transform-webhooks", "",
+		"Path to a YAML file listing webhooks that may mutate or skip copies before they are written. "+
+			"Leave empty to disable transform webhooks.")
+	flag.StringVar(&postSyncHooks, "post-sync-hooks", "",
+		"Path to a YAML file listing hooks that sources annotated with kopy.kot-labs.com/post-sync-hooks run once all "+
+			"their copies are current, e.g. to patch an Argo Rollout. Leave empty to disable post-sync hooks.")
+	flag.StringVar(&eventSink, "event-sink", "",
+		"An http(s) URL or nats://<host>:<port>/<subject> to publish CloudEvents to when copies are updated, fail to "+
+			"sync or are removed with their source. Leave empty to disable events.")
+	flag.BoolVar(&namespaceDeletionProtection, "namespace-deletion-protection", false,
+		"Serve a validating webhook that denies deleting namespaces holding sources with copies in other namespaces "+
+			"unless the namespace is annotated with kopy.kot-labs.com/confirm-delete=true. Requires the webhook "+
+			"server certificates.")
+	flag.DurationVar(&copyRefreshInterval, "copy-refresh-interval", 0,
+		"Resync every source after this interval so the last sync time of its copies stays fresh for workloads that "+
+			"check it with pkg/freshness. 0 disables the refresh.")
+	flag.BoolVar(&cascadeDelete, "cascade-delete", false,
+		"Delete the copies of a deleted source instead of leaving them in their namespaces without the kopy finalizer. "+
+			"The kopy.kot-labs.com/deletion-policy annotation of a source takes precedence.")
+	flag.DurationVar(&cascadeGracePeriod, "cascade-grace-period", 0,
+		"When copies are deleted with their source, annotate the copies of a deleted source with kopy.kot-labs.com/pending-deletion and "+
+			"delete them after the grace period, unless the source is recreated. 0 deletes them right away.")
+	flag.BoolVar(&cascadeVerify, "cascade-verify", false,
+		"When copies are deleted right away with their source, keep the finalizer of the source until every deleted copy "+
+			"is gone, e.g. while another finalizer holds a copy, and report the namespaces still holding one with a "+
+			"CascadePending event on the source.")
+	flag.StringVar(&tombstoneNamespace, "tombstone-namespace", "",
+		"When copies are deleted with their source, keep a tombstone Secret of every deleted copy in the namespace so kopy restore can "+
+			"recreate it. Empty keeps no tombstones.")
+	flag.DurationVar(&tombstoneRetention, "tombstone-retention", controller.DefaultTombstoneRetention,
+		"How long the tombstones of deleted copies are kept.")
+	flag.DurationVar(&startupSyncDeadline, "startup-sync-deadline", controller.DefaultStartupSyncDeadline,
+		"At startup, resync the sources whose copies missed changes while kopy was down and report the ones that "+
+			"aren't caught up within the deadline. 0 disables the startup sync.")
+	flag.DurationVar(&orphanGCInterval, "orphan-gc-interval", controller.DefaultOrphanGCInterval,
+		"How often to look for copies whose source no longer exists, e.g. because it was deleted while kopy was down, "+
+			"and remove their finalizer. 0 disables the orphan collector.")
+	flag.BoolVar(&orphanGCDelete, "orphan-gc-delete", false,
+		"Delete the copies the orphan collector finds instead of only removing their finalizer.")
+	flag.DurationVar(&pruneGracePeriod, "prune-grace-period", 0,
+		"Defer deleting a copy from a namespace that is no longer selected while pods in the namespace use it, for at "+
+			"most this long. Requires list and watch permissions on pods. 0 prunes copies right away.")
+	flag.IntVar(&confirmThreshold, "confirm-threshold", 0,
+		"Hold back changes to a source that would update more existing copies than this until they are confirmed with "+
+			"the kopy.kot-labs.com/confirm annotation or kopy confirm. 0 never asks for confirmation.")
+	flag.StringVar(&pinnedTargets, "pinned-targets", "",
+		"Path to a YAML file with rules that copy sources to fixed namespaces regardless of namespace labels. "+
+			"Leave empty to disable pinned targets.")
+	flag.BoolVar(&secretMetadataOnly, "secret-metadata-only", false,
+		"Cache only the metadata of Secrets and read Secrets from the API server when they are reconciled. Reduces "+
+			"memory in clusters with many large Secrets at the cost of more API requests.")
+	flag.BoolVar(&syncRBAC, "sync-rbac", false,
+		"Sync Roles and RoleBindings that carry the sync annotation. kopy needs the permissions of kopy rbac "+
+			"--enabled-features=role,rolebinding and can only copy Roles granting permissions it holds itself.")
+	flag.StringVar(&rbacSourceNamespaces, "rbac-source-namespaces", "",
+		"Comma separated namespaces Roles and RoleBindings are synced from with --sync-rbac. Annotated Roles and "+
+			"RoleBindings in other namespaces are refused, limit it to namespaces only administrators can write to.")
+	flag.Func("vcluster-namespaces",
+		"How to treat namespaces managed by a virtual cluster (labeled vcluster.loft.sh/managed-by): sync them like any "+
+			"other namespace, skip them, or translate the names of copies like vcluster names the objects it syncs. "+
+			"Unless sync, objects synced by a virtual cluster are never treated as sources. (default sync)",
+		func(s string) error {
+			mode, err := controller.ParseVClusterMode(s)
+			vclusterMode = mode
+			return err
+		})
+	flag.Func("cost-labels",
+		"Comma separated namespace labels, e.g. team,cost-center, whose values are stamped on the copies in the "+
+			"namespace so cost allocation tools attribute them to the namespace's owner. <copy label>=<namespace label> "+
+			"stamps a value under another key. Requires reading namespaces, so it can't be combined with --namespaces.",
+		func(s string) error {
+			parsed, err := controller.ParseCostLabels(s)
+			costLabels = parsed
+			return err
+		})
+	flag.Func("propagation-backend",
+		"How copies reach the remote clusters listed in the kopy.kot-labs.com/publish-clusters annotation of their "+
+			"source: direct leaves remote clusters to other tooling, karmada generates a Karmada PropagationPolicy for "+
+			"every copy when kopy runs against a Karmada control plane. (default direct)",
+		func(s string) error {
+			backend, err := controller.ParsePropagationBackend(s)
+			propagationBackend = backend
+			return err
+		})
+	flag.StringVar(&remoteClusterNamespace, "remote-cluster-namespace", "",
+		"Namespace with a Secret per remote cluster, named after the cluster and holding its kubeconfig under the "+
+			"kubeconfig key. Sources are pushed to the clusters listed in their kopy.kot-labs.com/publish-clusters "+
+			"annotation. Empty disables remote clusters.")
+	flag.IntVar(&selectorLogSampleRate, "selector-log-sample-rate", 100,
+		"Log how every n-th sync parsed its selector and which namespaces it matched at debug level (--zap-log-level=debug). "+
+			"0 disables it. Sources annotated with kopy.kot-labs.com/trace are always logged.")
+	flag.BoolVar(&reports, "reports", false,
+		"Write a KopyReport next to every source with its target namespaces, when each of them was last synced "+
+			"and the error of the ones that failed.")
+	flag.Func("sync-gvk",
+		"A kind to sync besides Secrets, ConfigMaps, ServiceAccounts, ResourceQuotas, LimitRanges, NetworkPolicies, "+
+			"PodDisruptionBudgets, Roles and RoleBindings, given as group/version,Kind, e.g. cert-manager.io/v1,Certificate. "+
+			"Repeat the flag for more kinds. kopy needs the same permissions on the kind as on Secrets.",
+		func(s string) error {
+			gvk, err := controller.ParseGVK(s)
+			if err != nil {
+				return err
+			}
+			syncGVKs = append(syncGVKs, gvk)
+			return nil
+		})
+	flag.StringVar(&copyNameSuffix, "copy-name-suffix", "",
+		"Suffix appended to the name of every copy, e.g. -kopy, so copies never collide with tenant objects of the "+
+			"same name. Existing copies are renamed when the suffix changes.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&warmStandby, "warm-standby", false,
+		"With leader election, keep the caches of replicas that aren't the leader warm so they take over without "+
+			"listing every watched object first and serve the read-only api from a synced cache.")
 	flag.BoolVar(&secureMetrics, "metrics-secure", true,
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.StringVar(&webhookCertPath, "webhook-cert-path", "", "The directory that contains the webhook certificate.")
@@ -177,8 +412,122 @@ func main() {
 		})
 	}
 
+	kopyOptions := controller.Options{
+		SyncDeadline:            syncDeadline,
+		QueueShedThreshold:      queueShedThreshold,
+		QueueShedDelay:          queueShedDelay,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		CopyConcurrency:         copyConcurrency,
+		HNC:                     hnc,
+		CopyNameSuffix:          copyNameSuffix,
+		RefreshInterval:         copyRefreshInterval,
+		SecretMetadataOnly:      secretMetadataOnly,
+		PruneGracePeriod:        pruneGracePeriod,
+		CascadeDelete:           cascadeDelete,
+		CascadeGracePeriod:      cascadeGracePeriod,
+		CascadeVerify:           cascadeVerify,
+		TombstoneNamespace:      tombstoneNamespace,
+		TombstoneRetention:      tombstoneRetention,
+		ConfirmThreshold:        confirmThreshold,
+		VClusterMode:            vclusterMode,
+		CostLabels:              costLabels,
+		PropagationBackend:      propagationBackend,
+		Reports:                 reports,
+		SelectorLogSampleRate:   selectorLogSampleRate,
+	}
+	if namespaces != "" {
+		kopyOptions.Namespaces = strings.Split(namespaces, ",")
+	}
+	if excludedNamespaces != "" {
+		kopyOptions.ExcludedNamespaces = strings.Split(excludedNamespaces, ",")
+	}
+	if rbacSourceNamespaces != "" {
+		kopyOptions.RBACSourceNamespaces = strings.Split(rbacSourceNamespaces, ",")
+	}
+	if syncRBAC && rbacSourceNamespaces == "" {
+		setupLog.Info("no RBAC source namespaces, every Role and RoleBinding will be refused")
+	}
+	switch deniedCopyNames {
+	case "none", "":
+		kopyOptions.DeniedCopyNames = []string{}
+	default:
+		kopyOptions.DeniedCopyNames = strings.Split(deniedCopyNames, ",")
+	}
+	// kinds are registered before the pinned targets and other configuration referring to them are loaded
+	for _, gvk := range syncGVKs {
+		controller.RegisterSyncKind(gvk)
+	}
+	if legacyDomains != "" {
+		kopyOptions.LegacyDomains = strings.Split(legacyDomains, ",")
+	}
+	if signingKey != "" {
+		key, err := kopycrypto.LoadPrivateKey(signingKey)
+		if err != nil {
+			setupLog.Error(err, "unable to load signing key")
+			os.Exit(1)
+		}
+		kopyOptions.SigningKey = key
+	}
+	if transformWebhooks != "" {
+		webhooks, err := controller.LoadTransformWebhooks(transformWebhooks)
+		if err != nil {
+			setupLog.Error(err, "unable to load transform webhooks")
+			os.Exit(1)
+		}
+		kopyOptions.TransformWebhooks = webhooks
+	}
+	if postSyncHooks != "" {
+		hooks, err := controller.LoadPostSyncHooks(postSyncHooks)
+		if err != nil {
+			setupLog.Error(err, "unable to load post-sync hooks")
+			os.Exit(1)
+		}
+		kopyOptions.PostSyncHooks = hooks
+	}
+	if pinnedTargets != "" {
+		rules, err := controller.LoadPinnedTargets(pinnedTargets)
+		if err != nil {
+			setupLog.Error(err, "unable to load pinned targets")
+			os.Exit(1)
+		}
+		kopyOptions.PinnedTargets = rules
+	}
+	if eventSink != "" {
+		sink, err := controller.NewEventSink(eventSink)
+		if err != nil {
+			setupLog.Error(err, "unable to configure event sink")
+			os.Exit(1)
+		}
+		kopyOptions.EventSink = sink
+	}
+	cacheOptions := cache.Options{}
+	clientOptions := client.Options{}
+	if memoryHighWatermark > 0 && !kopyOptions.SecretMetadataOnly {
+		// Secrets entering the cache while kopy is degraded are cached without their data
+		cacheOptions.ByObject = map[client.Object]cache.ByObject{
+			&corev1.Secret{}: {Transform: controller.LeanSecretTransform},
+		}
+	}
+	if kopyOptions.NamespaceScoped() {
+		if len(kopyOptions.CostLabels) > 0 {
+			setupLog.Error(nil, "cost labels are read from namespaces, which kopy can't read in namespace scoped mode",
+				"cost-labels", kopyOptions.CostLabels)
+			os.Exit(1)
+		}
+		setupLog.Info("restricting kopy to namespaces", "namespaces", kopyOptions.Namespaces)
+		cacheOptions.DefaultNamespaces = map[string]cache.Config{}
+		for _, ns := range kopyOptions.Namespaces {
+			cacheOptions.DefaultNamespaces[ns] = cache.Config{}
+		}
+		// namespaces are cluster scoped and can't be cached without cluster wide list/watch permissions
+		clientOptions.Cache = &client.CacheOptions{DisableFor: []client.Object{&corev1.Namespace{}}}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		NewClient:              kopyOptions.NewClient,
+		Cache:                  cacheOptions,
+		Client:                 clientOptions,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
@@ -201,22 +550,350 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controller.ConfigMapReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "ConfigMap")
+	exclusionLabels, err := labels.ConvertSelectorToLabelsMap(backupExclusionLabels)
+	if err != nil {
+		setupLog.Error(err, "invalid backup exclusion labels", "backup-exclusion-labels", backupExclusionLabels)
 		os.Exit(1)
 	}
-	if err = (&controller.SecretReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Secret")
-		os.Exit(1)
+	kopyOptions.BackupExclusionLabels = exclusionLabels
+	kopyOptions.NamespaceReader = mgr.GetClient()
+	if remoteClusterNamespace != "" {
+		kopyOptions.RemoteClusters = &controller.RemoteClusters{
+			Reader:    mgr.GetAPIReader(),
+			Namespace: remoteClusterNamespace,
+			Scheme:    mgr.GetScheme(),
+		}
+	}
+
+	// controllers that lack permissions are disabled instead of crash looping on forbidden list and watch calls
+	checked := map[string]bool{}
+	enabled := func(name string) bool {
+		if ok, done := checked[name]; done {
+			return ok
+		}
+		checked[name] = true
+		missing, err := controller.MissingPermissions(context.Background(), mgr.GetClient(), name, kopyOptions.Namespaces)
+		if err != nil {
+			setupLog.Error(err, "unable to check permissions, starting controller anyway", "controller", name)
+			return true
+		}
+		if len(missing) > 0 {
+			setupLog.Info("disabling controller that lacks permissions", "controller", name, "missing", missing)
+			controller.DisableController(name, missing)
+			checked[name] = false
+			return false
+		}
+		return true
+	}
+	// sources are checked against the quotas of their namespace only when the quota controller can run
+	kopyOptions.SourceQuotas = enabled("kopysourcequota")
+	if enabled("configmap") {
+		if err = (&controller.ConfigMapReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Options: kopyOptions,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ConfigMap")
+			os.Exit(1)
+		}
+	}
+	if enabled("secret") {
+		if err = (&controller.SecretReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Options: kopyOptions,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Secret")
+			os.Exit(1)
+		}
+	}
+	// syncKinds are the kind names of the copies the enabled controllers write
+	syncKinds := []string{}
+	for _, name := range controller.BuiltinKinds() {
+		if (name == "role" || name == "rolebinding") && !syncRBAC {
+			continue
+		}
+		if enabled(name) {
+			syncKinds = append(syncKinds, name)
+		}
+	}
+	syncKinds = append(syncKinds, controller.RegisteredSyncKinds()...)
+	controller.EnableKinds(syncKinds)
+	for _, name := range syncKinds {
+		// Secrets and ConfigMaps have reconcilers of their own
+		if name == "secret" || name == "configmap" {
+			continue
+		}
+		if err = (&controller.KindReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Options: kopyOptions,
+			Kind:    name,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", name)
+			os.Exit(1)
+		}
+	}
+	if enabled("kopysubscription") {
+		if err = (&controller.KopySubscriptionReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Options: kopyOptions,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "KopySubscription")
+			os.Exit(1)
+		}
+	}
+	if enabled("kopypublication") {
+		if err = (&controller.KopyPublicationReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Options: kopyOptions,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "KopyPublication")
+			os.Exit(1)
+		}
+	}
+	if enabled("kopysync") {
+		if err = (&controller.KopySyncReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Options: kopyOptions,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "KopySync")
+			os.Exit(1)
+		}
+	}
+	if enabled("kopytoken") {
+		if err = (&controller.KopyTokenReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Options: kopyOptions,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "KopyToken")
+			os.Exit(1)
+		}
+	}
+	if enabled("kopysourcequota") {
+		if err = (&controller.KopySourceQuotaReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Options: kopyOptions,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "KopySourceQuota")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
+	if namespaceDeletionProtection && enabled("secret") && enabled("configmap") {
+		if err = (&controller.NamespaceDeletionValidator{
+			Client:     mgr.GetClient(),
+			Namespaces: kopyOptions.Namespaces,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Namespace")
+			os.Exit(1)
+		}
+	}
+
+	if apiAddr != "0" {
+		if err := mgr.Add(&api.Server{Client: mgr.GetClient(), BindAddress: apiAddr}); err != nil {
+			setupLog.Error(err, "unable to add api server to manager")
+			os.Exit(1)
+		}
+	}
+
+	if adminAPIAddr != "0" {
+		if adminAPICertDir == "" && !adminAPIInsecure {
+			setupLog.Error(nil, "the admin api requires --admin-api-cert-dir, or --admin-api-insecure to serve it without TLS")
+			os.Exit(1)
+		}
+		if err := mgr.Add(&api.AdminServer{
+			Client: mgr.GetClient(), BindAddress: adminAPIAddr, CertDir: adminAPICertDir, Insecure: adminAPIInsecure,
+			Options: kopyOptions,
+		}); err != nil {
+			setupLog.Error(err, "unable to add admin api server to manager")
+			os.Exit(1)
+		}
+	}
+
+	if warmStandby && enableLeaderElection {
+		warmer := &controller.StandbyCacheWarmer{Cache: mgr.GetCache(), Options: kopyOptions}
+		for _, name := range controller.Features() {
+			if checked[name] {
+				warmer.Controllers = append(warmer.Controllers, name)
+			}
+		}
+		if apiAddr != "0" {
+			warmer.Objects = append(warmer.Objects, &corev1.Pod{})
+		}
+		if err := mgr.Add(warmer); err != nil {
+			setupLog.Error(err, "unable to add standby cache warmer to manager")
+			os.Exit(1)
+		}
+	}
+
+	if inventoryConfigMap != "" && enabled("secret") && enabled("configmap") {
+		namespace, name, ok := strings.Cut(inventoryConfigMap, "/")
+		if !ok {
+			setupLog.Error(nil, "inventory configmap must be namespace/name", "inventory-configmap", inventoryConfigMap)
+			os.Exit(1)
+		}
+		publisher := &controller.InventoryPublisher{
+			Client:   mgr.GetClient(),
+			Key:      types.NamespacedName{Namespace: namespace, Name: name},
+			Interval: time.Minute,
+		}
+		if err := mgr.Add(publisher); err != nil {
+			setupLog.Error(err, "unable to add inventory publisher to manager")
+			os.Exit(1)
+		}
+	}
+
+	if startupSyncDeadline > 0 && enabled("secret") && enabled("configmap") {
+		startupSync := &controller.StartupSync{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor("kopy-startup-sync"),
+			Deadline: startupSyncDeadline,
+			Interval: 5 * time.Second,
+		}
+		if err := mgr.Add(startupSync); err != nil {
+			setupLog.Error(err, "unable to add startup sync to manager")
+			os.Exit(1)
+		}
+	}
+
+	// sources can opt into deleting their copies with the deletion policy annotation, so tombstones expire regardless
+	// of --cascade-delete
+	if tombstoneNamespace != "" && (enabled("secret") || enabled("configmap")) {
+		collector := &controller.TombstoneCollector{
+			Client:    mgr.GetClient(),
+			Namespace: tombstoneNamespace,
+			Interval:  time.Minute,
+		}
+		if err := mgr.Add(collector); err != nil {
+			setupLog.Error(err, "unable to add tombstone collector to manager")
+			os.Exit(1)
+		}
+	}
+
+	if orphanGCInterval > 0 {
+		collector := &controller.OrphanCollector{
+			Client:   mgr.GetClient(),
+			Reader:   mgr.GetAPIReader(),
+			Kinds:    syncKinds,
+			Delete:   orphanGCDelete,
+			MinAge:   time.Minute,
+			Interval: orphanGCInterval,
+		}
+		if err := mgr.Add(collector); err != nil {
+			setupLog.Error(err, "unable to add orphan collector to manager")
+			os.Exit(1)
+		}
+	}
+
+	if namespaceInfo && enabled("configmap") {
+		publisher := &controller.NamespaceInfoPublisher{
+			Client:   mgr.GetClient(),
+			Kinds:    syncKinds,
+			Contact:  namespaceInfoContact,
+			Interval: time.Minute,
+		}
+		if err := mgr.Add(publisher); err != nil {
+			setupLog.Error(err, "unable to add namespace info publisher to manager")
+			os.Exit(1)
+		}
+	}
+
+	features := []string{}
+	for _, name := range controller.Features() {
+		switch name {
+		case "namespace-deletion-protection":
+			if namespaceDeletionProtection && checked["secret"] && checked["configmap"] {
+				features = append(features, name)
+			}
+		case "inventory":
+			if inventoryConfigMap != "" && checked["secret"] && checked["configmap"] {
+				features = append(features, name)
+			}
+		case "namespace-info":
+			if namespaceInfo && checked["configmap"] {
+				features = append(features, name)
+			}
+		case "prune-grace-period":
+			if pruneGracePeriod > 0 && (checked["secret"] || checked["configmap"] || checked["serviceaccount"]) {
+				features = append(features, name)
+			}
+		case "admin-api":
+			if adminAPIAddr != "0" {
+				features = append(features, name)
+			}
+		case "remote-clusters":
+			if remoteClusterNamespace != "" {
+				features = append(features, name)
+			}
+		case "reports":
+			if reports {
+				features = append(features, name)
+			}
+		case "karmada":
+			if propagationBackend == controller.PropagationKarmada {
+				features = append(features, name)
+			}
+		case "post-sync-hooks":
+			if postSyncHooks != "" && (checked["secret"] || checked["configmap"]) {
+				features = append(features, name)
+			}
+		case "leader-election":
+			if enableLeaderElection {
+				features = append(features, name)
+			}
+		default:
+			if checked[name] {
+				features = append(features, name)
+			}
+		}
+	}
+	if roles, err := controller.GenerateRBAC(features, controller.RBACOptions{
+		Name: "kopy-manager-role", Namespaces: kopyOptions.Namespaces,
+	}); err == nil {
+		setupLog.Info("RBAC needed by the enabled features, print it with kopy rbac --enabled-features="+
+			strings.Join(features, ","), "features", features, "roles", roles)
+	}
+
+	if err := mgr.AddMetricsServerExtraHandler(controller.DebugStatePath, controller.DebugStateHandler(mgr.GetClient())); err != nil {
+		setupLog.Error(err, "unable to add debug state handler to the metrics server")
+		os.Exit(1)
+	}
+	if err := mgr.Add(&controller.CacheSyncRecorder{Cache: mgr.GetCache()}); err != nil {
+		setupLog.Error(err, "unable to add cache sync recorder to manager")
+		os.Exit(1)
+	}
+	if memoryHighWatermark > 0 {
+		guard := &controller.MemoryGuard{
+			HighWatermark:      memoryHighWatermark,
+			LowWatermark:       memoryLowWatermark,
+			Interval:           10 * time.Second,
+			SecretMetadataOnly: secretMetadataOnly,
+		}
+		// the informer of a namespace scoped cache has a store per namespace, its Secrets are only stripped as they
+		// change
+		if !secretMetadataOnly && enabled("secret") {
+			informer, err := mgr.GetCache().GetInformer(context.Background(), &corev1.Secret{})
+			if err != nil {
+				setupLog.Error(err, "unable to get secret informer")
+				os.Exit(1)
+			}
+			if secrets, ok := informer.(toolscache.SharedIndexInformer); ok {
+				guard.Secrets, guard.Reader = secrets.GetStore(), mgr.GetAPIReader()
+			}
+		}
+		if err := mgr.Add(guard); err != nil {
+			setupLog.Error(err, "unable to add memory guard to manager")
+			os.Exit(1)
+		}
+	}
+
 	if metricsCertWatcher != nil {
 		setupLog.Info("Adding metrics certificate watcher to manager")
 		if err := mgr.Add(metricsCertWatcher); err != nil {
@@ -242,7 +919,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	setupLog.Info("starting manager")
+	setupLog.Info("starting manager", "version", Version, "fips", kopycrypto.FIPS)
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
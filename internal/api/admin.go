@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/flynshue/kopy/internal/controller"
+	"github.com/flynshue/kopy/pkg/adminapi"
+)
+
+var _ manager.Runnable = &AdminServer{}
+var _ manager.LeaderElectionRunnable = &AdminServer{}
+var _ adminapi.AdminServer = &adminService{}
+
+// AdminServer is a manager.Runnable that serves the gRPC admin service. Every call is authenticated with a
+// TokenReview of the bearer token of the caller and authorized with a SubjectAccessReview of the verb of the method
+// on the sources resource of the admin API group in the namespace of the source.
+type AdminServer struct {
+	client.Client
+	BindAddress string
+	// CertDir holds the tls.crt and tls.key the service is served with
+	CertDir string
+	// Insecure serves the service without TLS when CertDir is empty. The bearer tokens of the callers are then sent in
+	// cleartext, so it is only meant for local development.
+	Insecure bool
+	Options  controller.Options
+}
+
+// Start runs the grpc server until ctx is cancelled
+func (s *AdminServer) Start(ctx context.Context) error {
+	log := ctrllog.Log.WithName("admin-api")
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(s.authorize)}
+	if s.CertDir == "" && !s.Insecure {
+		return errors.New("the admin api requires a cert dir, callers would send their bearer tokens in cleartext")
+	}
+	if s.CertDir != "" {
+		watcher, err := certwatcher.New(filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := watcher.Start(ctx); err != nil {
+				log.Error(err, "certificate watcher failed")
+			}
+		}()
+		opts = append(opts, grpc.Creds(credentials.NewTLS(&tls.Config{
+			GetCertificate: watcher.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		})))
+	}
+	ln, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return err
+	}
+	srv := grpc.NewServer(opts...)
+	adminapi.RegisterAdminServer(srv, &adminService{AdminServer: s})
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+	log.Info("serving kopy admin api", "address", ln.Addr().String(), "tls", s.CertDir != "")
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false so every replica serves the admin api, the calls only annotate objects
+func (s *AdminServer) NeedLeaderElection() bool {
+	return false
+}
+
+// authorize rejects calls whose caller can't be authenticated or isn't allowed the verb of the method on the
+// namespace of the source
+func (s *AdminServer) authorize(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	token := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		token, _ = strings.CutPrefix(md.Get("authorization")[0], "Bearer ")
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := s.Create(ctx, review); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to review token: %v", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+	}
+	user := review.Status.User
+	ref, ok := req.(interface{ GetSource() adminapi.SourceRef })
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "%s takes no source", info.FullMethod)
+	}
+	src := ref.GetSource()
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	access := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+		User: user.Username, UID: user.UID, Groups: user.Groups, Extra: extra,
+		ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Group:     adminapi.Group,
+			Resource:  adminapi.Resource,
+			Verb:      adminapi.Verb(info.FullMethod),
+			Namespace: src.Namespace,
+			Name:      src.Name,
+		},
+	}}
+	if err := s.Create(ctx, access); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to review access: %v", err)
+	}
+	if !access.Status.Allowed {
+		return nil, status.Errorf(codes.PermissionDenied, "%s may not %s sources in namespace %s", user.Username,
+			adminapi.Verb(info.FullMethod), src.Namespace)
+	}
+	ctrllog.Log.WithName("admin-api").Info("admin call", "method", info.FullMethod, "user", user.Username,
+		"kind", src.Kind, "namespace", src.Namespace, "name", src.Name)
+	return handler(ctx, req)
+}
+
+// adminService implements the methods of the admin service with the admin helpers of the controller package
+type adminService struct {
+	*AdminServer
+}
+
+func (s *adminService) Resync(ctx context.Context, req *adminapi.ResyncRequest) (*adminapi.ResyncResponse, error) {
+	request, err := controller.RequestSourceResync(ctx, s.Client, req.Kind, sourceKey(req.SourceRef))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &adminapi.ResyncResponse{Request: request}, nil
+}
+
+func (s *adminService) Pause(ctx context.Context, req *adminapi.PauseRequest) (*adminapi.PauseResponse, error) {
+	if req.Reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "a reason is required to pause a source")
+	}
+	if err := controller.QuarantineSource(ctx, s.Client, req.Kind, sourceKey(req.SourceRef), req.Reason); err != nil {
+		return nil, grpcError(err)
+	}
+	return &adminapi.PauseResponse{}, nil
+}
+
+func (s *adminService) Resume(ctx context.Context, req *adminapi.ResumeRequest) (*adminapi.ResumeResponse, error) {
+	if err := controller.LiftQuarantine(ctx, s.Client, req.Kind, sourceKey(req.SourceRef)); err != nil {
+		return nil, grpcError(err)
+	}
+	return &adminapi.ResumeResponse{}, nil
+}
+
+func (s *adminService) Approve(ctx context.Context, req *adminapi.ApproveRequest) (*adminapi.ApproveResponse, error) {
+	revision, copies, err := controller.ConfirmChange(ctx, s.Client, req.Kind, sourceKey(req.SourceRef))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &adminapi.ApproveResponse{Revision: revision, Copies: copies}, nil
+}
+
+func (s *adminService) Explain(ctx context.Context, req *adminapi.ExplainRequest) (*adminapi.ExplainResponse, error) {
+	if req.TargetNamespace == "" {
+		return nil, status.Error(codes.InvalidArgument, "a target namespace is required")
+	}
+	e, err := controller.Explain(ctx, s.Client, req.Kind, sourceKey(req.SourceRef), req.TargetNamespace, s.Options)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &adminapi.ExplainResponse{Receives: e.Receives, Copy: e.Copy, Checks: make([]adminapi.Check, 0, len(e.Checks))}
+	for _, c := range e.Checks {
+		resp.Checks = append(resp.Checks, adminapi.Check{Name: c.Name, Result: c.Result, Detail: c.Detail})
+	}
+	return resp, nil
+}
+
+func sourceKey(src adminapi.SourceRef) types.NamespacedName {
+	return types.NamespacedName{Namespace: src.Namespace, Name: src.Name}
+}
+
+// grpcError maps the errors of the admin helpers to grpc status codes, so clients can tell the errors caused by the
+// request from the failures of the API server that are worth retrying
+func grpcError(err error) error {
+	return status.Error(grpcCode(err), err.Error())
+}
+
+func grpcCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, controller.ErrInvalidRequest):
+		return codes.InvalidArgument
+	case errors.Is(err, controller.ErrFailedPrecondition):
+		return codes.FailedPrecondition
+	case apierrors.IsNotFound(err):
+		return codes.NotFound
+	case apierrors.IsAlreadyExists(err):
+		return codes.AlreadyExists
+	case apierrors.IsConflict(err):
+		return codes.Aborted
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return codes.InvalidArgument
+	case apierrors.IsForbidden(err):
+		return codes.PermissionDenied
+	case apierrors.IsUnauthorized(err):
+		return codes.Unauthenticated
+	case apierrors.IsTooManyRequests(err):
+		return codes.ResourceExhausted
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), apierrors.IsServiceUnavailable(err),
+		errors.Is(err, context.DeadlineExceeded):
+		return codes.Unavailable
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+	return codes.Internal
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/flynshue/kopy/internal/controller"
+	"github.com/flynshue/kopy/pkg/adminapi"
+)
+
+var _ = Describe("Admin API\n", func() {
+	const (
+		token     = "token-of-alice"
+		namespace = "test-src-admin-ns-00"
+	)
+	var (
+		server *AdminServer
+		// access is the last reviewed access, allowed decides whether it is granted
+		access  *authorizationv1.SubjectAccessReview
+		allowed bool
+		called  bool
+	)
+	info := &grpc.UnaryServerInfo{FullMethod: "/" + adminapi.ServiceName + "/Resync"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return &adminapi.ResyncResponse{}, nil
+	}
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+	request := &adminapi.ResyncRequest{SourceRef: adminapi.SourceRef{Kind: "secret", Namespace: namespace, Name: "db"}}
+
+	BeforeEach(func() {
+		access, allowed, called = nil, false, false
+		// the API server answers reviews in the response to their creation without storing them
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				switch review := obj.(type) {
+				case *authenticationv1.TokenReview:
+					if review.Spec.Token == token {
+						review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{
+							Username: "alice", Groups: []string{"platform"},
+							Extra: map[string]authenticationv1.ExtraValue{"scopes": {"admin"}},
+						}}
+					}
+					return nil
+				case *authorizationv1.SubjectAccessReview:
+					access = review
+					review.Status.Allowed = allowed
+					return nil
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).Build()
+		server = &AdminServer{Client: c}
+	})
+
+	DescribeTable("Authorizing calls",
+		func(ctx context.Context, req any, allow bool, code codes.Code) {
+			allowed = allow
+			_, err := server.authorize(ctx, req, info, handler)
+			Expect(status.Code(err)).Should(Equal(code))
+			Expect(called).Should(Equal(code == codes.OK))
+		},
+		Entry("missing token", context.Background(), request, true, codes.Unauthenticated),
+		Entry("empty bearer token", withToken(""), request, true, codes.Unauthenticated),
+		Entry("unauthenticated token", withToken("token-of-mallory"), request, true, codes.Unauthenticated),
+		Entry("denied access", withToken(token), request, false, codes.PermissionDenied),
+		Entry("allowed access", withToken(token), request, true, codes.OK),
+		Entry("method without a source", withToken(token), struct{}{}, true, codes.InvalidArgument),
+	)
+
+	It("Should review the verb of the method on the namespace of the source as the caller", func() {
+		allowed = true
+		_, err := server.authorize(withToken(token), request, info, handler)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(access).ShouldNot(BeNil())
+		Expect(access.Spec.User).Should(Equal("alice"))
+		Expect(access.Spec.Groups).Should(Equal([]string{"platform"}))
+		Expect(access.Spec.Extra).Should(HaveKeyWithValue("scopes", authorizationv1.ExtraValue{"admin"}))
+		Expect(*access.Spec.ResourceAttributes).Should(Equal(authorizationv1.ResourceAttributes{
+			Group: adminapi.Group, Resource: adminapi.Resource, Verb: "resync", Namespace: namespace, Name: "db",
+		}))
+	})
+
+	It("Should fail calls whose reviews can't be created as internal errors", func() {
+		server.Client = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(context.Context, client.WithWatch, client.Object, ...client.CreateOption) error {
+				return errors.New("connection refused")
+			},
+		}).Build()
+		_, err := server.authorize(withToken(token), request, info, handler)
+		Expect(status.Code(err)).Should(Equal(codes.Internal))
+		Expect(called).Should(BeFalse())
+	})
+
+	secrets := schema.GroupResource{Resource: "secrets"}
+	DescribeTable("Mapping errors to grpc codes",
+		func(err error, code codes.Code) {
+			Expect(grpcCode(err)).Should(Equal(code))
+			Expect(status.Code(grpcError(err))).Should(Equal(code))
+		},
+		Entry("invalid request", controller.ErrInvalidRequest, codes.InvalidArgument),
+		Entry("wrapped invalid request", fmt.Errorf("kind widget: %w", controller.ErrInvalidRequest), codes.InvalidArgument),
+		Entry("failed precondition", controller.ErrFailedPrecondition, codes.FailedPrecondition),
+		Entry("not found", apierrors.NewNotFound(secrets, "db"), codes.NotFound),
+		Entry("already exists", apierrors.NewAlreadyExists(secrets, "db"), codes.AlreadyExists),
+		Entry("conflict", apierrors.NewConflict(secrets, "db", errors.New("modified")), codes.Aborted),
+		Entry("invalid object", apierrors.NewInvalid(corev1.SchemeGroupVersion.WithKind("Secret").GroupKind(), "db", nil), codes.InvalidArgument),
+		Entry("bad request", apierrors.NewBadRequest("bad"), codes.InvalidArgument),
+		Entry("forbidden", apierrors.NewForbidden(secrets, "db", errors.New("denied")), codes.PermissionDenied),
+		Entry("unauthorized", apierrors.NewUnauthorized("expired"), codes.Unauthenticated),
+		Entry("too many requests", apierrors.NewTooManyRequests("slow down", 1), codes.ResourceExhausted),
+		Entry("timeout", apierrors.NewTimeoutError("timeout", 1), codes.Unavailable),
+		Entry("server timeout", apierrors.NewServerTimeout(secrets, "get", 1), codes.Unavailable),
+		Entry("service unavailable", apierrors.NewServiceUnavailable("down"), codes.Unavailable),
+		Entry("deadline exceeded", context.DeadlineExceeded, codes.Unavailable),
+		Entry("canceled", context.Canceled, codes.Canceled),
+		Entry("other error", errors.New("boom"), codes.Internal),
+	)
+})
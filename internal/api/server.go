@@ -0,0 +1,161 @@
+// Package api serves the read-only kopy REST API used by the kopy CLI and automation, and the gRPC admin service
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+var _ manager.Runnable = &Server{}
+var _ manager.LeaderElectionRunnable = &Server{}
+
+// Server is a manager.Runnable that serves the kopy REST API from the manager cache
+type Server struct {
+	client.Client
+	BindAddress string
+}
+
+// Start runs the http server until ctx is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	log := ctrllog.Log.WithName("api")
+	ln, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	log.Info("serving kopy api", "address", ln.Addr().String())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false so every replica serves the api
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Handler returns the http.Handler with all api routes registered
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/origin/{kind}/{namespace}/{name}", s.origin)
+	mux.HandleFunc("GET /api/v1/inventory", s.inventory)
+	mux.HandleFunc("GET /api/v1/inventory/copies", s.inventoryCopies)
+	mux.HandleFunc("GET /api/v1/usage", s.usage)
+	mux.HandleFunc("GET /api/v1/whatif/namespaces/{namespace}", s.whatIf)
+	return mux
+}
+
+func (s *Server) origin(w http.ResponseWriter, r *http.Request) {
+	key := types.NamespacedName{Namespace: r.PathValue("namespace"), Name: r.PathValue("name")}
+	origin, err := controller.LookupOrigin(r.Context(), s.Client, r.PathValue("kind"), key)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, origin)
+}
+
+func (s *Server) inventory(w http.ResponseWriter, r *http.Request) {
+	inv, err := controller.BuildInventory(r.Context(), s.Client)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, inv)
+}
+
+// inventoryCopies lists every managed copy as JSON, or as CSV with ?format=csv
+func (s *Server) inventoryCopies(w http.ResponseWriter, r *http.Request) {
+	copies, err := controller.ListInventory(r.Context(), s.Client)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	// the REST API is unauthenticated, the unsalted hash of the data of a Secret would allow dictionary attacks on
+	// its values
+	for i := range copies {
+		if copies[i].Kind == "secret" {
+			copies[i].Hash = ""
+		}
+	}
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		writeJSON(w, http.StatusOK, copies)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		_ = controller.WriteInventoryCSV(w, copies)
+	default:
+		writeJSON(w, http.StatusBadRequest,
+			map[string]string{"error": fmt.Sprintf("unknown format %q, expected json or csv", format)})
+	}
+}
+
+func (s *Server) usage(w http.ResponseWriter, r *http.Request) {
+	blastRadius := controller.DefaultBlastRadius
+	if v := r.URL.Query().Get("blastRadius"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest,
+				map[string]string{"error": fmt.Sprintf("invalid blastRadius %q: %v", v, err)})
+			return
+		}
+		blastRadius = n
+	}
+	usage, err := controller.AnalyzeUsage(r.Context(), s.Client, blastRadius)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// whatIf previews the copies a change of the labels of a namespace would add or prune. Every label query
+// parameter is a change in the syntax of kubectl label, e.g. ?label=env=prod&label=team-
+func (s *Server) whatIf(w http.ResponseWriter, r *http.Request) {
+	changes, err := controller.PreviewLabelChange(r.Context(), s.Client, r.PathValue("namespace"), r.URL.Query()["label"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err with 400 if the request can't succeed as sent, 404 if the object doesn't exist and 500 if the
+// API server failed
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, controller.ErrInvalidRequest), errors.Is(err, controller.ErrFailedPrecondition):
+		code = http.StatusBadRequest
+	case apierrors.IsNotFound(err):
+		code = http.StatusNotFound
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
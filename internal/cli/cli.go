@@ -0,0 +1,94 @@
+// Package cli implements the kopy command line tool used to inspect and manage kopy sources and copies
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Command is a kopy subcommand
+type Command struct {
+	Name  string
+	Usage string
+	Short string
+	Run   func(ctx context.Context, args []string) error
+}
+
+var (
+	commands           = map[string]*Command{}
+	out      io.Writer = os.Stdout
+	in       io.Reader = os.Stdin
+)
+
+func register(cmd *Command) {
+	commands[cmd.Name] = cmd
+}
+
+// Run dispatches args to the matching subcommand
+func Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		Usage()
+		return fmt.Errorf("missing command")
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		Usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd.Run(ctx, args[1:])
+}
+
+// Usage prints the list of available subcommands
+func Usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: kopy [flags] <command> [args]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(flag.CommandLine.Output(), 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", commands[name].Usage, commands[name].Short)
+	}
+	w.Flush()
+	fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func newFlagSet(cmd *Command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: kopy %s\n\n%s\n", cmd.Usage, cmd.Short)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// newClient creates a client for the cluster in the current kubeconfig context
+func newClient() (client.Client, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: clientgoscheme.Scheme})
+}
+
+// parseNamespacedName parses a "namespace/name" reference
+func parseNamespacedName(ref string) (types.NamespacedName, error) {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("invalid reference %q, expected <namespace>/<name>", ref)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
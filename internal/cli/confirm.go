@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "confirm",
+		Usage: "confirm <kind> <namespace>/<name>",
+		Short: "Confirm a change to a source that kopy holds back because it updates many copies",
+		Run:   runConfirm,
+	})
+}
+
+func runConfirm(ctx context.Context, args []string) error {
+	cmd := commands["confirm"]
+	fs := newFlagSet(cmd)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	key, err := parseNamespacedName(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	revision, copies, err := controller.ConfirmChange(ctx, c, fs.Arg(0), key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "confirmed revision %s of %s %s, kopy updates %d copies\n", revision, fs.Arg(0), key, copies)
+	return nil
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/util/retry"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "convert",
+		Usage: "convert [--format v1|v2] [--dry-run] <kind> <namespace>/<name>",
+		Short: "Rewrite the sync annotation of a source in another annotation format",
+		Run:   runConvert,
+	})
+}
+
+func runConvert(ctx context.Context, args []string) error {
+	cmd := commands["convert"]
+	fs := newFlagSet(cmd)
+	format := fs.String("format", string(controller.SyncFormatV2), "Sync annotation format to convert to")
+	dryRun := fs.Bool("dry-run", false, "Only print the converted annotation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	key, err := parseNamespacedName(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	var before, after string
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		src, err := controller.NewObjectForKind(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if err := c.Get(ctx, key, src); err != nil {
+			return err
+		}
+		var ok bool
+		if before, ok = controller.SyncSelector(src); !ok {
+			return fmt.Errorf("%s %s has no sync annotation", fs.Arg(0), key)
+		}
+		if after, err = controller.ConvertSyncSelector(before, controller.SyncFormat(*format)); err != nil {
+			return err
+		}
+		if *dryRun || after == before {
+			return nil
+		}
+		controller.SetSyncSelector(src, after)
+		return c.Update(ctx, src)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Selector:\t%q -> %q\n", before, after)
+	if *dryRun {
+		fmt.Fprintln(out, "\n(dry run, source was not modified)")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "diff",
+		Usage: "diff --namespace <target> <kind> <namespace>/<name>",
+		Short: "Print a redacted key-level diff between a source and its copy in the target namespace",
+		Run:   runDiff,
+	})
+}
+
+func runDiff(ctx context.Context, args []string) error {
+	cmd := commands["diff"]
+	fs := newFlagSet(cmd)
+	target := fs.String("namespace", "", "Namespace that contains the copy")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 || *target == "" {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	key, err := parseNamespacedName(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	diffs, err := controller.DiffCopy(ctx, c, fs.Arg(0), key, *target)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "--- %s (source)\n+++ %s/%s (copy)\n", key, *target, key.Name)
+	changed := 0
+	for _, d := range diffs {
+		switch d.Status {
+		case controller.KeyMissing:
+			fmt.Fprintf(out, "- %s\t(%d bytes, missing from copy)\n", d.Key, d.SourceSize)
+		case controller.KeyExtra:
+			fmt.Fprintf(out, "+ %s\t(%d bytes, not in source)\n", d.Key, d.CopySize)
+		case controller.KeyChanged:
+			fmt.Fprintf(out, "~ %s\t(%d bytes -> %d bytes)\n", d.Key, d.SourceSize, d.CopySize)
+		default:
+			fmt.Fprintf(out, "  %s\n", d.Key)
+			continue
+		}
+		changed++
+	}
+	if changed == 0 {
+		fmt.Fprintln(out, "copy is identical to source")
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "explain",
+		Usage: "explain [--kind <kind>] --source <namespace>/<name> --namespace <target> [--scoped-namespaces ns1,ns2] [--pinned-targets <file>] [-o table|json|yaml]",
+		Short: "Explain why a namespace does or doesn't receive a copy of a source",
+		Run:   runExplain,
+	})
+}
+
+func runExplain(ctx context.Context, args []string) error {
+	cmd := commands["explain"]
+	fs := newFlagSet(cmd)
+	kind := fs.String("kind", "secret", "Kind of the source, secret or configmap")
+	source := fs.String("source", "", "The source as <namespace>/<name>")
+	target := fs.String("namespace", "", "Namespace that should receive the copy")
+	scoped := fs.String("scoped-namespaces", "", "Explain the controller's namespace scoped mode for these namespaces")
+	pinned := fs.String("pinned-targets", "", "The pinned targets file the controller runs with")
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *source == "" || *target == "" {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	key, err := parseNamespacedName(*source)
+	if err != nil {
+		return err
+	}
+	opts := controller.Options{}
+	if *scoped != "" {
+		opts.Namespaces = strings.Split(*scoped, ",")
+	}
+	if *pinned != "" {
+		if opts.PinnedTargets, err = controller.LoadPinnedTargets(*pinned); err != nil {
+			return err
+		}
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	e, err := controller.Explain(ctx, c, *kind, key, *target, opts)
+	if err != nil {
+		return err
+	}
+	return printOutput(out, *format, "ExplanationList", []*controller.Explanation{e}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Source:\t%s %s\n", e.Kind, e.Source)
+		fmt.Fprintf(w, "Namespace:\t%s\n\n", e.Namespace)
+		fmt.Fprintln(w, "CHECK\tRESULT\tDETAIL")
+		for _, check := range e.Checks {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", check.Name, check.Result, check.Detail)
+		}
+		verdict := "does not receive a copy"
+		if e.Receives {
+			verdict = "receives a copy"
+		}
+		fmt.Fprintf(w, "\nResult:\t%s %s\n", e.Namespace, verdict)
+		fmt.Fprintf(w, "Copy:\t%s\n", e.Copy)
+	})
+}
@@ -0,0 +1,408 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+// defaultProtectedNamespaces are offered as protected namespaces when they exist in the cluster
+var defaultProtectedNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+func init() {
+	register(&Command{
+		Name:  "init",
+		Usage: "init [--domain <domain>] [--kind <kind>] [--source <namespace>/<name>] [--protected <namespaces>] [--label <key>=<value>] [--namespaces <namespaces>] [--apply]",
+		Short: "Interactively generate the sync annotation for a source and the label for its target namespaces",
+		Run:   runInit,
+	})
+}
+
+// initAnswers are the answers to the questions of kopy init
+type initAnswers struct {
+	kind       string
+	src        client.Object
+	protected  []string
+	labelKey   string
+	labelValue string
+	namespaces []string
+}
+
+func runInit(ctx context.Context, args []string) error {
+	cmd := commands["init"]
+	fs := newFlagSet(cmd)
+	domain := fs.String("domain", "", "Label domain of the copies in the cluster, another domain than "+controller.Domain+" has to be adopted with --legacy-domains")
+	kind := fs.String("kind", "", "Kind of the source, secret or configmap")
+	source := fs.String("source", "", "The source as <namespace>/<name>")
+	protected := fs.String("protected", "", "Comma separated namespaces that never receive a copy, \"none\" for none")
+	label := fs.String("label", "", "The <key>=<value> label that selects the target namespaces")
+	namespaces := fs.String("namespaces", "", "Comma separated namespaces to label as targets, \"none\" to label none")
+	apply := fs.Bool("apply", false, "Apply the annotation and labels without asking")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	p := &prompter{r: bufio.NewReader(in)}
+	a := &initAnswers{}
+	if err := askDomain(ctx, c, p, *domain); err != nil {
+		return err
+	}
+	if err := askSource(ctx, c, p, a, *kind, *source); err != nil {
+		return err
+	}
+	if err := askProtected(ctx, c, p, a, *protected); err != nil {
+		return err
+	}
+	if err := askLabel(p, a, *label); err != nil {
+		return err
+	}
+	if err := askNamespaces(ctx, c, p, a, *namespaces); err != nil {
+		return err
+	}
+	selector, err := a.selector()
+	if err != nil {
+		return err
+	}
+	matching, err := controller.MatchingNamespaces(ctx, c, a.src.GetNamespace(), selector)
+	if err != nil {
+		return err
+	}
+	targets := sets.New(matching...).Insert(a.namespaces...)
+	fmt.Fprintf(out, "\nSelector:\t%q\n", selector)
+	fmt.Fprintf(out, "Commands:\n")
+	fmt.Fprintf(out, "  kubectl annotate %s -n %s %s %s --overwrite\n", a.kind, a.src.GetNamespace(), a.src.GetName(),
+		shellQuote(controller.SyncAnnotation+"="+selector))
+	if len(a.namespaces) > 0 {
+		fmt.Fprintf(out, "  kubectl label namespace %s %s=%s --overwrite\n", strings.Join(a.namespaces, " "), a.labelKey, a.labelValue)
+	}
+	fmt.Fprintf(out, "Namespaces:\n")
+	for _, name := range sets.List(targets) {
+		fmt.Fprintf(out, "    %s\n", name)
+	}
+	if !*apply {
+		answer, err := p.ask("Apply now? (y/N)", "n", func(v string) error {
+			if !slices.Contains([]string{"y", "yes", "n", "no"}, strings.ToLower(v)) {
+				return fmt.Errorf("answer y or n")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(strings.ToLower(answer), "y") {
+			return nil
+		}
+	}
+	return a.apply(ctx, c, selector)
+}
+
+// askDomain asks for the label domain of the copies already in the cluster. Only the current domain is written,
+// copies under another domain are adopted once the manager runs with --legacy-domains.
+func askDomain(ctx context.Context, c client.Client, p *prompter, answer string) error {
+	count := 0
+	validate := func(v string) error {
+		if errs := validation.IsDNS1123Subdomain(v); len(errs) > 0 {
+			return fmt.Errorf("invalid domain %q: %s", v, strings.Join(errs, ", "))
+		}
+		if v == controller.Domain {
+			return nil
+		}
+		var err error
+		if count, err = controller.CountDomainCopies(ctx, c, v); err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("no copies labeled under %s found, kopy always writes %s", v, controller.Domain)
+		}
+		return nil
+	}
+	domain, err := p.answer(answer, "Annotation domain", controller.Domain, validate)
+	if err != nil {
+		return err
+	}
+	if domain != controller.Domain {
+		fmt.Fprintf(out, "  %d copies are labeled under %s, start the manager with --legacy-domains=%s to adopt them\n",
+			count, domain, domain)
+	}
+	return nil
+}
+
+// askSource asks for the kind and namespace/name of the source, which has to exist
+func askSource(ctx context.Context, c client.Client, p *prompter, a *initAnswers, kind, source string) error {
+	kind, err := p.answer(kind, "Kind of the source (secret|configmap)", "secret", func(v string) error {
+		_, err := controller.NewObjectForKind(v)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	a.kind = kind
+	_, err = p.answer(source, "Source (<namespace>/<name>)", "", func(v string) error {
+		key, err := parseNamespacedName(v)
+		if err != nil {
+			return err
+		}
+		src, _ := controller.NewObjectForKind(kind)
+		if err := c.Get(ctx, key, src); err != nil {
+			return err
+		}
+		a.src = src
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if current, ok := controller.SyncSelector(a.src); ok {
+		fmt.Fprintf(out, "  the source is already synced with %q, the selector will be replaced\n", current)
+	}
+	return nil
+}
+
+// askProtected asks for the namespaces that never receive a copy, which have to exist
+func askProtected(ctx context.Context, c client.Client, p *prompter, a *initAnswers, answer string) error {
+	existing, err := namespaceSet(ctx, c)
+	if err != nil {
+		return err
+	}
+	defaults := []string{}
+	for _, name := range defaultProtectedNamespaces {
+		if existing.Has(name) {
+			defaults = append(defaults, name)
+		}
+	}
+	v, err := p.answer(answer, "Namespaces that never receive a copy (comma separated, none for none)",
+		strings.Join(defaults, ","), func(v string) error { return existingNamespaces(existing, splitList(v)) })
+	if err != nil {
+		return err
+	}
+	a.protected = splitList(v)
+	return nil
+}
+
+// askLabel asks for the label that selects the target namespaces
+func askLabel(p *prompter, a *initAnswers, answer string) error {
+	def := ""
+	if errs := validation.IsQualifiedName("kopy/" + a.src.GetName()); len(errs) == 0 {
+		def = "kopy/" + a.src.GetName() + "=true"
+	}
+	_, err := p.answer(answer, "Label of the target namespaces (<key>=<value>)", def, func(v string) error {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("invalid label %q, expected <key>=<value>", v)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid label value %q: %s", value, strings.Join(errs, ", "))
+		}
+		if key == corev1.LabelMetadataName {
+			return fmt.Errorf("%s is set by Kubernetes, choose another label", key)
+		}
+		a.labelKey, a.labelValue = key, value
+		return nil
+	})
+	return err
+}
+
+// askNamespaces asks for the namespaces to label as targets, which have to exist and mustn't be protected
+func askNamespaces(ctx context.Context, c client.Client, p *prompter, a *initAnswers, answer string) error {
+	existing, err := namespaceSet(ctx, c)
+	if err != nil {
+		return err
+	}
+	labeled := &corev1.NamespaceList{}
+	if err := c.List(ctx, labeled, client.MatchingLabels{a.labelKey: a.labelValue}); err != nil {
+		return err
+	}
+	already := []string{}
+	for _, ns := range labeled.Items {
+		already = append(already, ns.Name)
+	}
+	if len(already) > 0 {
+		fmt.Fprintf(out, "  already labeled: %s\n", strings.Join(already, ", "))
+	}
+	v, err := p.answer(answer, "Namespaces to label (comma separated, none for none)", "", func(v string) error {
+		names := splitList(v)
+		if len(names) == 0 && len(already) == 0 && v != "none" {
+			return fmt.Errorf("no namespace is labeled %s=%s yet, name at least one or answer none", a.labelKey, a.labelValue)
+		}
+		if err := existingNamespaces(existing, names); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if name == a.src.GetNamespace() {
+				return fmt.Errorf("%s is the namespace of the source", name)
+			}
+			if slices.Contains(a.protected, name) {
+				return fmt.Errorf("%s is protected", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	a.namespaces = splitList(v)
+	return nil
+}
+
+// selector returns the sync annotation value that selects the labeled namespaces except the protected ones
+func (a *initAnswers) selector() (string, error) {
+	reqs := []labels.Requirement{}
+	r, err := labels.NewRequirement(a.labelKey, selection.Equals, []string{a.labelValue})
+	if err != nil {
+		return "", err
+	}
+	reqs = append(reqs, *r)
+	if len(a.protected) > 0 {
+		r, err := labels.NewRequirement(corev1.LabelMetadataName, selection.NotIn, a.protected)
+		if err != nil {
+			return "", err
+		}
+		reqs = append(reqs, *r)
+	}
+	selector := labels.NewSelector().Add(reqs...).String()
+	if _, err := controller.ParseSyncSelector(selector); err != nil {
+		return "", err
+	}
+	return selector, nil
+}
+
+// apply labels the target namespaces and then annotates the source, so the source is only synced once its
+// targets are in place
+func (a *initAnswers) apply(ctx context.Context, c client.Client, selector string) error {
+	for _, name := range a.namespaces {
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+			return err
+		}
+		patch := client.MergeFrom(ns.DeepCopy())
+		if ns.Labels == nil {
+			ns.Labels = map[string]string{}
+		}
+		ns.Labels[a.labelKey] = a.labelValue
+		if err := c.Patch(ctx, ns, patch); err != nil {
+			return fmt.Errorf("unable to label namespace %s: %w", name, err)
+		}
+		fmt.Fprintf(out, "labeled namespace %s\n", name)
+	}
+	key := client.ObjectKeyFromObject(a.src)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.Get(ctx, key, a.src); err != nil {
+			return err
+		}
+		controller.SetSyncSelector(a.src, selector)
+		return c.Update(ctx, a.src)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to annotate %s: %w", key, err)
+	}
+	fmt.Fprintf(out, "annotated %s\n", key)
+	return nil
+}
+
+// prompter asks questions on the terminal and reads the answers
+type prompter struct {
+	r *bufio.Reader
+}
+
+// answer validates given if it is set, otherwise it asks question
+func (p *prompter) answer(given, question, def string, validate func(string) error) (string, error) {
+	if given == "" {
+		return p.ask(question, def, validate)
+	}
+	if err := validate(given); err != nil {
+		return "", err
+	}
+	return given, nil
+}
+
+// ask asks question until the answer passes validate. An empty answer takes def.
+func (p *prompter) ask(question, def string, validate func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", question)
+		}
+		line, err := p.r.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			if errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("no answer to %q", question)
+			}
+			return "", err
+		}
+		v := strings.TrimSpace(line)
+		if v == "" {
+			v = def
+		}
+		if err := validate(v); err != nil {
+			fmt.Fprintf(out, "  %v\n", err)
+			continue
+		}
+		return v, nil
+	}
+}
+
+// namespaceSet returns the names of the namespaces in the cluster
+func namespaceSet(ctx context.Context, c client.Client) (sets.Set[string], error) {
+	list := &corev1.NamespaceList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, err
+	}
+	names := sets.New[string]()
+	for _, ns := range list.Items {
+		names.Insert(ns.Name)
+	}
+	return names, nil
+}
+
+// existingNamespaces returns an error naming the namespaces of names that don't exist
+func existingNamespaces(existing sets.Set[string], names []string) error {
+	missing := sets.New(names...).Difference(existing)
+	if missing.Len() > 0 {
+		return fmt.Errorf("namespaces not found: %s", strings.Join(sets.List(missing), ", "))
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, "none" is the empty list
+func splitList(v string) []string {
+	names := []string{}
+	if v == "none" {
+		return names
+	}
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// shellQuote quotes v for a POSIX shell
+func shellQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
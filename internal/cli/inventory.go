@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "inventory",
+		Usage: "inventory [-o table|json|yaml|csv]",
+		Short: "Export every copy managed by kopy with its source, size, last sync time and hash",
+		Run:   runInventory,
+	})
+}
+
+func runInventory(ctx context.Context, args []string) error {
+	cmd := commands["inventory"]
+	fs := newFlagSet(cmd)
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	copies, err := controller.ListInventory(ctx, c)
+	if err != nil {
+		return err
+	}
+	if *format == "csv" {
+		return controller.WriteInventoryCSV(out, copies)
+	}
+	return printOutput(out, *format, "InventoryCopyList", copies, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "KIND\tCOPY\tSOURCE\tSIZE\tLAST SYNC\tHASH")
+		for _, cp := range copies {
+			lastSync := "-"
+			if cp.LastSync != nil {
+				lastSync = cp.LastSync.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s/%s\t%s\t%d\t%s\t%s\n", cp.Kind, cp.Namespace, cp.Name, cp.Source, cp.Size, lastSync, cp.Hash)
+		}
+	})
+}
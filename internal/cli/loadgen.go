@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name: "loadgen",
+		Usage: "loadgen [--namespaces <n>] [--sources <m>] [--size <bytes>] [--kind secret|configmap] [--prefix <prefix>] " +
+			"[--metrics-url <url>] [--keep] [--cleanup] [--force]",
+		Short: "Create namespaces and sources in a kind cluster and report how fast kopy converges and how many API calls it makes",
+		Run:   runLoadgen,
+	})
+}
+
+func runLoadgen(ctx context.Context, args []string) error {
+	cmd := commands["loadgen"]
+	fs := newFlagSet(cmd)
+	opts := controller.LoadgenOptions{}
+	fs.IntVar(&opts.Namespaces, "namespaces", 50, "Number of target namespaces")
+	fs.IntVar(&opts.Sources, "sources", 10, "Number of sources, each is copied into every target namespace")
+	fs.IntVar(&opts.Size, "size", 1024, "Bytes of data of every source")
+	fs.StringVar(&opts.Kind, "kind", "secret", "Kind of the sources, secret or configmap")
+	fs.StringVar(&opts.Prefix, "prefix", "kopy-loadgen", "Prefix of the names of the namespaces and sources")
+	fs.DurationVar(&opts.Timeout, "timeout", 10*time.Minute, "How long the copies may take to converge after the sources were created or updated")
+	fs.DurationVar(&opts.Interval, "interval", 500*time.Millisecond, "How often the copies are checked, it bounds the precision of the latencies")
+	metricsURL := fs.String("metrics-url", "",
+		"Metrics endpoint of the controller, e.g. https://localhost:8443/metrics through kubectl port-forward, to report "+
+			"the API requests kopy made during the run")
+	metricsToken := fs.String("metrics-token", "", "Bearer token for --metrics-url, e.g. from kubectl create token")
+	keep := fs.Bool("keep", false, "Keep the namespaces and sources after the run")
+	cleanup := fs.Bool("cleanup", false, "Only delete the namespaces and sources of a previous run with --keep")
+	force := fs.Bool("force", false, "Run against a cluster whose kubeconfig context isn't a kind cluster")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	if !*force {
+		if err := requireKindContext(); err != nil {
+			return err
+		}
+	}
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	// the load generator shouldn't be throttled by the client, its requests are counted instead
+	cfg.QPS, cfg.Burst = 1000, 2000
+	requests := &requestCounter{requests: map[string]int{}}
+	cfg.Wrap(requests.wrap)
+	c, err := client.New(cfg, client.Options{Scheme: clientgoscheme.Scheme})
+	if err != nil {
+		return err
+	}
+	if *cleanup {
+		return controller.CleanupLoadgen(ctx, c, opts.Prefix)
+	}
+	if !*keep {
+		defer func() {
+			// the run may have been interrupted, so the cleanup gets a context of its own
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Minute)
+			defer cancel()
+			if err := controller.CleanupLoadgen(ctx, c, opts.Prefix); err != nil {
+				fmt.Fprintf(out, "unable to clean up, run kopy loadgen --cleanup: %v\n", err)
+			}
+		}()
+	}
+
+	var before map[string]float64
+	if *metricsURL != "" {
+		if before, err = scrapeRequests(ctx, *metricsURL, *metricsToken); err != nil {
+			return err
+		}
+	}
+	last := time.Now()
+	opts.Progress = func(phase string, converged, copies int) {
+		if time.Since(last) >= 5*time.Second || converged == copies {
+			last = time.Now()
+			fmt.Fprintf(out, "%s: %d/%d copies converged\n", phase, converged, copies)
+		}
+	}
+	fmt.Fprintf(out, "creating %d namespaces and %d %ss of %d bytes\n", opts.Namespaces, opts.Sources, opts.Kind, opts.Size)
+	phases, runErr := controller.Loadgen(ctx, c, opts)
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PHASE\tCONVERGED\tDURATION\tP50\tP90\tP99")
+	for _, p := range phases {
+		fmt.Fprintf(w, "%s\t%d/%d\t%s\t%s\t%s\t%s\n", p.Name, p.Converged, p.Copies, p.Duration.Round(time.Millisecond),
+			p.P50.Round(time.Millisecond), p.P90.Round(time.Millisecond), p.P99.Round(time.Millisecond))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "load generator requests: %s\n", formatRequests(requests.snapshot()))
+	if *metricsURL != "" {
+		after, err := scrapeRequests(ctx, *metricsURL, *metricsToken)
+		if err != nil {
+			return err
+		}
+		kopy := map[string]int{}
+		for method, n := range after {
+			kopy[method] = int(n - before[method])
+		}
+		fmt.Fprintf(out, "kopy requests: %s\n", formatRequests(kopy))
+	}
+	return runErr
+}
+
+// requireKindContext returns an error unless the current kubeconfig context is a kind cluster, whose contexts are
+// named kind-<cluster>
+func requireKindContext() error {
+	raw, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{}).RawConfig()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(raw.CurrentContext, "kind-") {
+		return fmt.Errorf("context %q isn't a kind cluster, the load generator creates and deletes namespaces, "+
+			"use --force to run it anyway", raw.CurrentContext)
+	}
+	return nil
+}
+
+// requestCounter counts the requests of a client by method
+type requestCounter struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (r *requestCounter) wrap(rt http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		r.mu.Lock()
+		r.requests[req.Method]++
+		r.mu.Unlock()
+		return rt.RoundTrip(req)
+	})
+}
+
+func (r *requestCounter) snapshot() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.requests)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// scrapeRequests reads the API requests the controller made so far from its metrics endpoint. The endpoint serves a
+// self-signed certificate by default, which is accepted since the load generator only runs against test clusters.
+func scrapeRequests(ctx context.Context, url, token string) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	httpClient := &http.Client{Timeout: 10 * time.Second, Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // test clusters only
+	}}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scrape %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to scrape %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return controller.LoadgenRequests(string(body)), nil
+}
+
+// formatRequests formats request counts as the total followed by the count of each method
+func formatRequests(requests map[string]int) string {
+	total := 0
+	methods := slices.Sorted(maps.Keys(requests))
+	parts := make([]string, 0, len(methods))
+	for _, method := range methods {
+		total += requests[method]
+		parts = append(parts, fmt.Sprintf("%s %d", method, requests[method]))
+	}
+	return fmt.Sprintf("%d (%s)", total, strings.Join(parts, ", "))
+}
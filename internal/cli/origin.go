@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "origin",
+		Usage: "origin <kind> <namespace>/<name>",
+		Short: "Resolve a copy back to its source and report whether the copy is current",
+		Run:   runOrigin,
+	})
+}
+
+func runOrigin(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: kopy origin <kind> <namespace>/<name>")
+	}
+	key, err := parseNamespacedName(args[1])
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	origin, err := controller.LookupOrigin(ctx, c, args[0], key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Copy:\t\t%s/%s\n", origin.Namespace, origin.Name)
+	fmt.Fprintf(out, "Source:\t\t%s/%s\n", origin.SourceNamespace, origin.SourceName)
+	if !origin.SourceExists {
+		fmt.Fprintln(out, "Status:\t\tsource not found")
+		return nil
+	}
+	if origin.Current {
+		fmt.Fprintln(out, "Status:\t\tcurrent")
+		return nil
+	}
+	fmt.Fprintln(out, "Status:\t\tstale")
+	return nil
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+)
+
+// outputAPIVersion versions the schema of the json and yaml output of the kopy CLI. Fields are only ever added
+// within a version, so automation can rely on the fields it reads.
+const outputAPIVersion = "cli.kopy.kot-labs.com/v1"
+
+// outputList is the envelope of json and yaml output
+type outputList struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Items      any    `json:"items"`
+}
+
+// outputFlag registers the -o and --output flags on fs
+func outputFlag(fs *flag.FlagSet) *string {
+	format := new(string)
+	fs.StringVar(format, "o", "table", "Output format: table, json or yaml")
+	fs.StringVar(format, "output", "table", "Output format: table, json or yaml")
+	return format
+}
+
+// printOutput writes items as a kind list in format, table writes the table format
+func printOutput(w io.Writer, format, kind string, items any, table func(w *tabwriter.Writer)) error {
+	list := outputList{APIVersion: outputAPIVersion, Kind: kind, Items: items}
+	switch format {
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	case "yaml":
+		b, err := yaml.Marshal(list)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	return fmt.Errorf("unknown output format %q, expected table, json or yaml", format)
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "quarantine",
+		Usage: "quarantine [--reason <reason>] [--lift] <kind> <namespace>/<name>",
+		Short: "Freeze the copies of a source suspected to be compromised, or lift the quarantine",
+		Run:   runQuarantine,
+	})
+}
+
+func runQuarantine(ctx context.Context, args []string) error {
+	cmd := commands["quarantine"]
+	fs := newFlagSet(cmd)
+	reason := fs.String("reason", "suspected compromise", "Why the source is quarantined, recorded on the source and its copies")
+	lift := fs.Bool("lift", false, "Lift the quarantine so kopy syncs the copies again")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	key, err := parseNamespacedName(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if *lift {
+		if err := controller.LiftQuarantine(ctx, c, fs.Arg(0), key); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "lifted the quarantine of %s %s, kopy syncs its copies again\n", fs.Arg(0), key)
+		return nil
+	}
+	if err := controller.QuarantineSource(ctx, c, fs.Arg(0), key, *reason); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "quarantined %s %s, kopy keeps its copies as they are\n", fs.Arg(0), key)
+	return nil
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "rbac",
+		Usage: "rbac [--enabled-features f1,f2] [--namespaces ns1,ns2] [--name <role>] [--leader-election-namespace <namespace>]",
+		Short: "Print the least privilege ClusterRole and Roles the enabled features need",
+		Run:   runRBAC,
+	})
+}
+
+func runRBAC(_ context.Context, args []string) error {
+	cmd := commands["rbac"]
+	fs := newFlagSet(cmd)
+	features := fs.String("enabled-features", strings.Join(controller.DefaultFeatures, ","),
+		"Features to generate RBAC for, any of "+strings.Join(controller.Features(), ", "))
+	namespaces := fs.String("namespaces", "", "Namespaces the controller is restricted to")
+	name := fs.String("name", "kopy-manager-role", "Name of the ClusterRole and Roles")
+	electionNamespace := fs.String("leader-election-namespace", "kopy-system", "Namespace the controller runs in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	opts := controller.RBACOptions{Name: *name, LeaderElectionNamespace: *electionNamespace}
+	if *namespaces != "" {
+		opts.Namespaces = strings.Split(*namespaces, ",")
+	}
+	objects, err := controller.GenerateRBAC(strings.Split(*features, ","), opts)
+	if err != nil {
+		return err
+	}
+	for i, o := range objects {
+		b, err := yaml.Marshal(o)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(out, "---")
+		}
+		if _, err := out.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
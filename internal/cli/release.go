@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name: "release-all",
+		Usage: "release-all [--scale-down <namespace>/<deployment>] [--origin-labels] [--qps <n>] [--kinds <kinds>] " +
+			"[--sync-gvk <group/version,Kind>] [--dry-run]",
+		Short: "Strip the kopy finalizers, and optionally the origin labels, from every source and copy to uninstall kopy",
+		Run:   runReleaseAll,
+	})
+}
+
+func runReleaseAll(ctx context.Context, args []string) error {
+	cmd := commands["release-all"]
+	fs := newFlagSet(cmd)
+	scaleDown := fs.String("scale-down", "",
+		"Deployment of the controller to scale to zero replicas first, so it doesn't add the finalizers back")
+	scaleDownTimeout := fs.Duration("scale-down-timeout", 2*time.Minute, "How long to wait for the controller pods to stop")
+	originLabels := fs.Bool("origin-labels", false,
+		"Also strip the origin labels from copies, so a later kopy installation neither updates nor prunes them")
+	qps := fs.Float64("qps", 20, "Maximum updates per second, 0 doesn't limit them")
+	kinds := fs.String("kinds", "", "Comma separated kinds to release, every built-in kind and --sync-gvk kind if empty")
+	fs.Func("sync-gvk", "A kind kopy syncs besides the built-in ones, given as group/version,Kind. Repeat the flag for more kinds.",
+		func(s string) error {
+			gvk, err := controller.ParseGVK(s)
+			if err != nil {
+				return err
+			}
+			controller.RegisterSyncKind(gvk)
+			return nil
+		})
+	dryRun := fs.Bool("dry-run", false, "Only list the objects that would be released")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if *scaleDown != "" && !*dryRun {
+		if err := scaleDownDeployment(ctx, c, *scaleDown, *scaleDownTimeout); err != nil {
+			return err
+		}
+	}
+	opts := controller.ReleaseOptions{OriginLabels: *originLabels, QPS: float32(*qps), DryRun: *dryRun}
+	if *kinds != "" {
+		opts.Kinds = strings.Split(*kinds, ",")
+	}
+	verb := "released"
+	if *dryRun {
+		verb = "would release"
+	}
+	count := 0
+	opts.Progress = func(r controller.ReleasedObject) {
+		count++
+		stripped := append(append([]string{}, r.Finalizers...), r.Labels...)
+		fmt.Fprintf(out, "[%d] %s %s %s/%s: %s\n", count, verb, r.Kind, r.Namespace, r.Name, strings.Join(stripped, ", "))
+	}
+	released, err := controller.ReleaseAll(ctx, c, opts)
+	fmt.Fprintf(out, "%s %d objects\n", verb, len(released))
+	return err
+}
+
+// scaleDownDeployment scales the deployment ref to zero replicas and waits for its pods to stop
+func scaleDownDeployment(ctx context.Context, c client.Client, ref string, timeout time.Duration) error {
+	key, err := parseNamespacedName(ref)
+	if err != nil {
+		return err
+	}
+	deployment := &appsv1.Deployment{}
+	if err := c.Get(ctx, key, deployment); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	patch := client.MergeFrom(deployment.DeepCopy())
+	deployment.Spec.Replicas = ptr.To[int32](0)
+	if err := c.Patch(ctx, deployment, patch); err != nil {
+		return fmt.Errorf("unable to scale down %s: %w", key, err)
+	}
+	fmt.Fprintf(out, "scaled down %s, waiting for its pods to stop\n", key)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err = wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		if err := c.Get(ctx, key, deployment); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return deployment.Status.Replicas == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("pods of %s didn't stop: %w", key, err)
+	}
+	return nil
+}
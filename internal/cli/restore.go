@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "restore",
+		Usage: "restore [--tombstone-namespace <namespace>] [-o table|json|yaml] [<kind> <namespace>/<name>]",
+		Short: "List the copies deleted with their source or restore one from its tombstone",
+		Run:   runRestore,
+	})
+}
+
+func runRestore(ctx context.Context, args []string) error {
+	cmd := commands["restore"]
+	fs := newFlagSet(cmd)
+	namespace := fs.String("tombstone-namespace", "kopy-system", "Namespace kopy keeps the tombstones of deleted copies in")
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 && fs.NArg() != 2 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		tombstones, err := controller.ListTombstones(ctx, c, *namespace)
+		if err != nil {
+			return err
+		}
+		return printOutput(out, *format, "TombstoneList", tombstones, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, "KIND\tCOPY\tSOURCE\tDELETED\tEXPIRES")
+			for _, t := range tombstones {
+				fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\t%s\n", t.Kind, t.Namespace, t.Name, t.Source,
+					t.DeletedAt.Format(time.RFC3339), t.ExpiresAt.Format(time.RFC3339))
+			}
+		})
+	}
+	key, err := parseNamespacedName(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	t, err := controller.RestoreCopy(ctx, c, *namespace, fs.Arg(0), key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "restored %s %s/%s deleted with its source %s at %s\n", t.Kind, t.Namespace, t.Name, t.Source,
+		t.DeletedAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "resync",
+		Usage: "resync --namespace <namespace> | resync [--wait <timeout>] <kind> <namespace>/<name>",
+		Short: "Force every source that selects the namespace, or a single source, to verify and repair its copies",
+		Run:   runResync,
+	})
+}
+
+func runResync(ctx context.Context, args []string) error {
+	cmd := commands["resync"]
+	fs := newFlagSet(cmd)
+	namespace := fs.String("namespace", "", "Namespace to rebuild copies in")
+	wait := fs.Duration("wait", 0, "How long to wait for the controller to resync the source, 0 doesn't wait")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*namespace == "") == (fs.NArg() == 0) || (*namespace == "" && fs.NArg() != 2) {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if *namespace == "" {
+		return resyncSource(ctx, c, fs.Arg(0), fs.Arg(1), *wait)
+	}
+	sources, err := controller.RequestNamespaceResync(ctx, c, *namespace)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "requested resync of namespace %s from %d sources\n", *namespace, len(sources))
+	for _, s := range sources {
+		fmt.Fprintf(out, "  %s %s/%s\n", s.Kind, s.Namespace, s.Name)
+	}
+	return nil
+}
+
+// resyncSource requests a resync of a single source and optionally waits for the controller to handle it
+func resyncSource(ctx context.Context, c client.Client, kind, ref string, timeout time.Duration) error {
+	key, err := parseNamespacedName(ref)
+	if err != nil {
+		return err
+	}
+	request, err := controller.RequestSourceResync(ctx, c, kind, key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "requested resync of %s %s at %s\n", kind, key, request)
+	if timeout == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err = wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		return controller.ResyncObserved(ctx, c, kind, key, request)
+	})
+	if err != nil {
+		return fmt.Errorf("resync of %s %s was not observed: %w", kind, key, err)
+	}
+	fmt.Fprintf(out, "resync of %s %s completed\n", kind, key)
+	return nil
+}
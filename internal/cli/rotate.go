@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "rotate",
+		Usage: "rotate [--promote] <kind> <namespace>/<name>",
+		Short: "Stage the next version of a source next to it, or promote the staged version",
+		Run:   runRotate,
+	})
+}
+
+func runRotate(ctx context.Context, args []string) error {
+	cmd := commands["rotate"]
+	fs := newFlagSet(cmd)
+	promote := fs.Bool("promote", false, "Swap the data of the source with the data of its next version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	key, err := parseNamespacedName(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if *promote {
+		if err := controller.PromoteNext(ctx, c, fs.Arg(0), key); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "promoted the next version of %s %s, its copies keep the previous data until it is deleted\n", fs.Arg(0), key)
+		return nil
+	}
+	if err := controller.StageNext(ctx, c, fs.Arg(0), key); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "staged %s %s-next, replace its data and promote it with kopy rotate --promote\n", fs.Arg(0), key)
+	return nil
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "simulate",
+		Usage: "simulate --from-snapshot <dump.yaml> [--namespaces ns1,ns2]",
+		Short: "Print the writes kopy would make to a cluster snapshot without connecting to a cluster",
+		Run:   runSimulate,
+	})
+}
+
+func runSimulate(ctx context.Context, args []string) error {
+	cmd := commands["simulate"]
+	fs := newFlagSet(cmd)
+	snapshot := fs.String("from-snapshot", "", "YAML dump of namespaces and the objects kopy syncs, "+
+		"e.g. from kubectl get namespaces,secrets,configmaps,serviceaccounts -A -o yaml")
+	namespaces := fs.String("namespaces", "", "Simulate the controller's namespace scoped mode for these namespaces")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *snapshot == "" {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	f, err := os.Open(*snapshot)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	objects, err := controller.LoadSnapshot(f)
+	if err != nil {
+		return fmt.Errorf("unable to load snapshot %s: %w", *snapshot, err)
+	}
+	opts := controller.Options{}
+	if *namespaces != "" {
+		opts.Namespaces = strings.Split(*namespaces, ",")
+	}
+	result, err := controller.Simulate(ctx, objects, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Loaded %d objects from %s\n", len(objects), *snapshot)
+	if len(result.Actions) == 0 {
+		fmt.Fprintln(out, "kopy would make no changes")
+	}
+	for _, a := range result.Actions {
+		fmt.Fprintf(out, "%s\t%s\t%s/%s\n", a.Verb, a.Kind, a.Namespace, a.Name)
+	}
+	failed := make([]string, 0, len(result.Errors))
+	for key := range result.Errors {
+		failed = append(failed, key)
+	}
+	sort.Strings(failed)
+	for _, key := range failed {
+		fmt.Fprintf(out, "error\t%s: %v\n", key, result.Errors[key])
+	}
+	if !result.Converged {
+		fmt.Fprintln(out, "\nkopy was still making changes after the last round, the snapshot may cause a sync loop")
+	}
+	return nil
+}
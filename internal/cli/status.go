@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "status",
+		Usage: "status [-o table|json|yaml]",
+		Short: "Summarize how many copies of every source are synced, out of date or missing",
+		Run:   runStatus,
+	})
+	register(&Command{
+		Name:  "copies",
+		Usage: "copies [-o table|json|yaml] <kind> <namespace>/<name>",
+		Short: "List the copies of a source in its target namespaces and whether they are synced",
+		Run:   runCopies,
+	})
+}
+
+func runStatus(ctx context.Context, args []string) error {
+	cmd := commands["status"]
+	fs := newFlagSet(cmd)
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	statuses, err := controller.SyncStatus(ctx, c)
+	if err != nil {
+		return err
+	}
+	return printOutput(out, *format, "SourceStatusList", statuses, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "KIND\tSOURCE\tTARGETS\tSYNCED\tOUT OF DATE\tMISSING")
+		for _, s := range statuses {
+			fmt.Fprintf(w, "%s\t%s/%s\t%d\t%d\t%d\t%d\n", s.Kind, s.Namespace, s.Name, s.Targets, s.Synced, s.OutOfDate, s.Missing)
+		}
+	})
+}
+
+func runCopies(ctx context.Context, args []string) error {
+	cmd := commands["copies"]
+	fs := newFlagSet(cmd)
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	key, err := parseNamespacedName(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	status, err := controller.CopiesOf(ctx, c, fs.Arg(0), key)
+	if err != nil {
+		return err
+	}
+	return printOutput(out, *format, "CopyStatusList", status.Copies, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "NAMESPACE\tNAME\tSTATE")
+		for _, cp := range status.Copies {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", cp.Namespace, cp.Name, cp.State)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "support-bundle",
+		Usage: "support-bundle [--namespace <ns>] [--metrics-service <name:port>] [--since <duration>] [-f <file>]",
+		Short: "Collect controller logs, metrics, debug state and redacted object metadata into a tarball for an issue",
+		Run:   runSupportBundle,
+	})
+}
+
+func runSupportBundle(ctx context.Context, args []string) error {
+	cmd := commands["support-bundle"]
+	fs := newFlagSet(cmd)
+	namespace := fs.String("namespace", "kopy", "Namespace kopy runs in")
+	metricsService := fs.String("metrics-service", "kopy-controller-manager-metrics-service:8443",
+		"Service and port of the metrics server that also serves the debug state")
+	since := fs.Duration("since", time.Hour, "How far back to collect controller logs")
+	file := fs.String("f", "kopy-support-bundle-"+time.Now().UTC().Format("20060102T150405Z")+".tar.gz", "File the bundle is written to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	service, port, ok := strings.Cut(*metricsService, ":")
+	if !ok {
+		return fmt.Errorf("invalid metrics service %q, expected <name>:<port>", *metricsService)
+	}
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: clientgoscheme.Scheme})
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	// every file that can be collected goes into the bundle, what failed is listed in errors.txt instead
+	files := map[string][]byte{}
+	failures := []string{}
+	collect := func(name string, get func() ([]byte, error)) {
+		b, err := get()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		files[name] = b
+	}
+	asJSON := func(get func() (any, error)) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			v, err := get()
+			if err != nil {
+				return nil, err
+			}
+			return json.MarshalIndent(v, "", "  ")
+		}
+	}
+	services := clientset.CoreV1().Services(*namespace)
+	collect("metrics.txt", func() ([]byte, error) {
+		return services.ProxyGet("https", service, port, "/metrics", nil).DoRaw(ctx)
+	})
+	collect("debug-state.json", func() ([]byte, error) {
+		return services.ProxyGet("https", service, port, controller.DebugStatePath, nil).DoRaw(ctx)
+	})
+	collect("objects.json", asJSON(func() (any, error) { return controller.SupportObjects(ctx, c) }))
+	collect("stale-sources.json", asJSON(func() (any, error) { return controller.StaleSources(ctx, c) }))
+
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(*namespace), client.MatchingLabels{"control-plane": "controller-manager"}); err != nil {
+		failures = append(failures, fmt.Sprintf("logs: %v", err))
+	}
+	sinceSeconds := int64(since.Seconds())
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			opts := &corev1.PodLogOptions{Container: container.Name, SinceSeconds: &sinceSeconds, Timestamps: true}
+			collect("logs/"+pod.Name+"/"+container.Name+".log", func() ([]byte, error) {
+				return clientset.CoreV1().Pods(*namespace).GetLogs(pod.Name, opts).DoRaw(ctx)
+			})
+		}
+	}
+	if len(pods.Items) == 0 {
+		failures = append(failures, fmt.Sprintf("logs: no kopy controller pods found in namespace %s", *namespace))
+	}
+	if len(failures) > 0 {
+		files["errors.txt"] = []byte(strings.Join(failures, "\n") + "\n")
+	}
+
+	f, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if err := writeTarball(f, files); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "wrote %s with %d files", *file, len(files))
+	if len(failures) > 0 {
+		fmt.Fprintf(out, ", %d could not be collected, see errors.txt", len(failures))
+	}
+	fmt.Fprintln(out)
+	return nil
+}
+
+// writeTarball writes files as a gzip compressed tarball, ordered by name
+func writeTarball(w io.Writer, files map[string][]byte) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	modTime := time.Now()
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(files[name])), ModTime: modTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
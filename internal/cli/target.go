@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "target",
+		Usage: "target add|remove [--dry-run] <kind> <namespace>/<name> ns/<namespace>|<selector>",
+		Short: "Add or remove a target namespace or selector requirement on a source's sync annotation",
+		Run:   runTarget,
+	})
+}
+
+func runTarget(ctx context.Context, args []string) error {
+	cmd := commands["target"]
+	if len(args) == 0 || (args[0] != "add" && args[0] != "remove") {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	edit := controller.AddSyncTarget
+	if args[0] == "remove" {
+		edit = controller.RemoveSyncTarget
+	}
+	fs := newFlagSet(cmd)
+	dryRun := fs.Bool("dry-run", false, "Only print the resulting selector and namespace changes")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 3 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	key, err := parseNamespacedName(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	var before, after string
+	var synced bool
+	// the update carries the resourceVersion of the object that was read, so a concurrent edit results in a
+	// conflict and the edit is recomputed from the latest version of the source
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		src, err := controller.NewObjectForKind(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if err := c.Get(ctx, key, src); err != nil {
+			return err
+		}
+		before, synced = controller.SyncSelector(src)
+		after, err = edit(before, fs.Arg(2))
+		if err != nil {
+			return err
+		}
+		if *dryRun {
+			return nil
+		}
+		controller.SetSyncSelector(src, after)
+		return c.Update(ctx, src)
+	})
+	if err != nil {
+		return err
+	}
+	var oldTargets []string
+	if synced {
+		if oldTargets, err = controller.MatchingNamespaces(ctx, c, key.Namespace, before); err != nil {
+			return err
+		}
+	}
+	newTargets, err := controller.MatchingNamespaces(ctx, c, key.Namespace, after)
+	if err != nil {
+		return err
+	}
+	printTargetPreview(before, after, oldTargets, newTargets)
+	if *dryRun {
+		fmt.Fprintln(out, "\n(dry run, source was not modified)")
+	}
+	return nil
+}
+
+func printTargetPreview(before, after string, oldTargets, newTargets []string) {
+	oldSet, newSet := sets.New(oldTargets...), sets.New(newTargets...)
+	fmt.Fprintf(out, "Selector:\t%q -> %q\n", before, after)
+	fmt.Fprintln(out, "Namespaces:")
+	names := sets.List(oldSet.Union(newSet))
+	for _, name := range names {
+		switch {
+		case !oldSet.Has(name):
+			fmt.Fprintf(out, "  + %s\n", name)
+		case !newSet.Has(name):
+			fmt.Fprintf(out, "  - %s\n", name)
+		default:
+			fmt.Fprintf(out, "    %s\n", name)
+		}
+	}
+	if !slices.Equal(sets.List(oldSet), sets.List(newSet)) {
+		return
+	}
+	fmt.Fprintln(out, "  (no changes to the set of target namespaces)")
+}
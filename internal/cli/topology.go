@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "export",
+		Usage: "export [-f file]",
+		Short: "Export all sources, their selectors and target namespaces as YAML",
+		Run:   runExport,
+	})
+	register(&Command{
+		Name:  "import",
+		Usage: "import [--dry-run] -f file",
+		Short: "Apply the sync annotations and policies from an exported topology to the sources in the cluster",
+		Run:   runImport,
+	})
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs := newFlagSet(commands["export"])
+	file := fs.String("f", "", "Write the topology to file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	topology, err := controller.ExportTopology(ctx, c)
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(topology)
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		_, err = out.Write(b)
+		return err
+	}
+	return os.WriteFile(*file, b, 0o644)
+}
+
+func runImport(ctx context.Context, args []string) error {
+	cmd := commands["import"]
+	fs := newFlagSet(cmd)
+	file := fs.String("f", "", "Topology file produced by kopy export")
+	dryRun := fs.Bool("dry-run", false, "Only print the sources that would be updated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	b, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	topology := &controller.Topology{}
+	if err := yaml.UnmarshalStrict(b, topology); err != nil {
+		return fmt.Errorf("unable to parse topology file %s: %w", *file, err)
+	}
+	for _, s := range topology.Sources {
+		if _, err := controller.ParseSyncSelector(s.Selector); err != nil {
+			return fmt.Errorf("%s %s/%s: %w", s.Kind, s.Namespace, s.Name, err)
+		}
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	// a preview is reported as such so it can't be mistaken for an import
+	action := "applied"
+	if *dryRun {
+		action = "would apply"
+	}
+	failed := 0
+	for _, s := range topology.Sources {
+		if err := importSource(ctx, c, s, *dryRun); err != nil {
+			fmt.Fprintf(out, "failed\t%s %s/%s: %v\n", s.Kind, s.Namespace, s.Name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(out, "%s\t%s %s/%s (%s)\n", action, s.Kind, s.Namespace, s.Name, s.Selector)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sources could not be imported", failed, len(topology.Sources))
+	}
+	return nil
+}
+
+func importSource(ctx context.Context, c client.Client, s controller.SourceTopology, dryRun bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		o, err := controller.NewObjectForKind(s.Kind)
+		if err != nil {
+			return err
+		}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: s.Name}, o); err != nil {
+			return err
+		}
+		if dryRun {
+			return nil
+		}
+		controller.ApplySourceTopology(o, s)
+		return c.Update(ctx, o)
+	})
+}
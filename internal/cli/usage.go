@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "usage",
+		Usage: "usage [--blast-radius <workloads>] [--unused] [-o table|json|yaml]",
+		Short: "Report the workloads using the copies of every source, unused copies and high blast radius sources",
+		Run:   runUsage,
+	})
+}
+
+func runUsage(ctx context.Context, args []string) error {
+	cmd := commands["usage"]
+	fs := newFlagSet(cmd)
+	blastRadius := fs.Int("blast-radius", controller.DefaultBlastRadius,
+		"Number of workloads using the copies of a source from which on it is reported as high blast radius")
+	unused := fs.Bool("unused", false, "Only list sources with copies no pod uses")
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	usage, err := controller.AnalyzeUsage(ctx, c, *blastRadius)
+	if err != nil {
+		return err
+	}
+	if *unused {
+		filtered := []controller.SourceUsage{}
+		for _, u := range usage {
+			if len(u.Unused) > 0 {
+				filtered = append(filtered, u)
+			}
+		}
+		usage = filtered
+	}
+	return printOutput(out, *format, "SourceUsageList", usage, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "KIND\tSOURCE\tCOPIES\tWORKLOADS\tBLAST RADIUS\tUNUSED IN")
+		for _, u := range usage {
+			radius := "normal"
+			if u.HighBlastRadius {
+				radius = "high"
+			}
+			unusedIn := strings.Join(u.Unused, ",")
+			if unusedIn == "" {
+				unusedIn = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s/%s\t%d\t%d\t%s\t%s\n", u.Kind, u.Namespace, u.Name, len(u.Copies), u.Workloads, radius, unusedIn)
+		}
+	})
+}
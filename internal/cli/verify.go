@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flynshue/kopy/internal/controller"
+	"github.com/flynshue/kopy/internal/kopycrypto"
+)
+
+func init() {
+	register(&Command{
+		Name:  "verify",
+		Usage: "verify --key <public-key.pem> <kind> <namespace>/<name>",
+		Short: "Verify that a copy was signed by the kopy controller and hasn't been modified",
+		Run:   runVerify,
+	})
+}
+
+func runVerify(ctx context.Context, args []string) error {
+	cmd := commands["verify"]
+	fs := newFlagSet(cmd)
+	keyPath := fs.String("key", "", "PEM encoded public key matching the controller's --signing-key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 || *keyPath == "" {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	key, err := parseNamespacedName(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	pub, err := kopycrypto.LoadPublicKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := controller.VerifyCopyByKey(ctx, c, fs.Arg(0), key, pub); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Verified:\t%s\n", key)
+	return nil
+}
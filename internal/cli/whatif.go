@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/flynshue/kopy/internal/controller"
+)
+
+func init() {
+	register(&Command{
+		Name:  "whatif",
+		Usage: "whatif [-o table|json|yaml] <namespace> <key=value|key->...",
+		Short: "Preview the copies a namespace label change would add or prune, without changing the namespace",
+		Run:   runWhatIf,
+	})
+}
+
+func runWhatIf(ctx context.Context, args []string) error {
+	cmd := commands["whatif"]
+	fs := newFlagSet(cmd)
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: kopy %s", cmd.Usage)
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	changes, err := controller.PreviewLabelChange(ctx, c, fs.Arg(0), fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	return printOutput(out, *format, "CopyChangeList", changes, func(w *tabwriter.Writer) {
+		if len(changes) == 0 {
+			fmt.Fprintf(w, "no copies would be added to or pruned from namespace %s\n", fs.Arg(0))
+			return
+		}
+		fmt.Fprintln(w, "ACTION\tKIND\tSOURCE")
+		for _, change := range changes {
+			fmt.Fprintf(w, "%s\t%s\t%s/%s\n", change.Action, change.Kind, change.SourceNamespace, change.SourceName)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/internal/kopycrypto"
+)
+
+const (
+	// confirmKey is set on a source to the revision in its pending approval annotation to propagate a change that
+	// updates more copies than the confirmation threshold
+	confirmKey = kopyPrefix + "confirm"
+	// pendingApprovalKey is set by kopy on a source whose change is held back, to the revision of the data that needs
+	// to be confirmed and the number of copies it would update
+	pendingApprovalKey = kopyPrefix + "pending-approval"
+	// reasonPendingApproval is used for events on sources whose change is held back until it is confirmed
+	reasonPendingApproval = "PendingApproval"
+)
+
+// pendingApproval is the value of the pending approval annotation
+type pendingApproval struct {
+	Revision string
+	Copies   int
+}
+
+func (p pendingApproval) String() string {
+	return p.Revision + " " + strconv.Itoa(p.Copies)
+}
+
+// dataRevision returns the hash of the data of src that identifies the change to confirm. It is also the source-hash
+// of the copies, so every key and value is hashed as a field of its own and binary data can't produce the revision of
+// other data.
+func dataRevision(src client.Object) string {
+	data := objectData(src)
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := kopycrypto.NewHasher()
+	for _, k := range keys {
+		h.Field(k)
+		h.Field(string(data[k]))
+	}
+	return hex.EncodeToString(h.Sum())
+}
+
+// legacyRevisionLength is the length of the truncated data revisions of earlier kopy versions
+const legacyRevisionLength = 12
+
+// isLegacyRevision returns true if revision was computed by an earlier kopy version, which truncated it
+func isLegacyRevision(revision string) bool {
+	return len(revision) == legacyRevisionLength
+}
+
+// heldForApproval returns the target namespaces whose existing copies of src are out of date but may not be updated
+// yet, because the change updates more copies than the confirmation threshold and wasn't confirmed. The pending
+// approval annotation on src is kept in sync; it is removed once nothing is held back anymore. Missing copies are
+// never held back since creating them doesn't change what running workloads read.
+func (o Options) heldForApproval(ctx context.Context, c client.Client, recorder record.EventRecorder, src client.Object, namespaces []corev1.Namespace) (sets.Set[string], error) {
+	if o.ConfirmThreshold <= 0 {
+		return nil, nil
+	}
+	stale := sets.New[string]()
+	for _, ns := range namespaces {
+		cp, err := findCopy(ctx, c, src, ns.Name)
+		if err != nil {
+			continue
+		}
+		for _, d := range diffData(objectData(src), objectData(cp)) {
+			if d.Status != KeyUnchanged {
+				stale.Insert(ns.Name)
+				break
+			}
+		}
+	}
+	pending := pendingApproval{Revision: dataRevision(src), Copies: stale.Len()}
+	annotations := src.GetAnnotations()
+	if stale.Len() <= o.ConfirmThreshold || annotations[confirmKey] == pending.Revision {
+		if _, ok := annotations[pendingApprovalKey]; !ok {
+			return nil, nil
+		}
+		patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+		delete(annotations, pendingApprovalKey)
+		src.SetAnnotations(annotations)
+		return nil, c.Patch(ctx, src, patch)
+	}
+	if annotations[pendingApprovalKey] != pending.String() {
+		patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[pendingApprovalKey] = pending.String()
+		src.SetAnnotations(annotations)
+		if err := c.Patch(ctx, src, patch); err != nil {
+			return nil, err
+		}
+		recorder.Eventf(src, corev1.EventTypeWarning, reasonPendingApproval,
+			"Change would update %d copies, more than the confirmation threshold of %d; annotate the source with %s=%s to propagate it",
+			pending.Copies, o.ConfirmThreshold, confirmKey, pending.Revision)
+	}
+	return stale, nil
+}
+
+// ConfirmChange sets the confirm annotation of the source of kind to the revision of its pending change, so kopy
+// propagates it to every copy, and returns the pending change. It fails if the source has no pending change.
+func ConfirmChange(ctx context.Context, c client.Client, kind string, key types.NamespacedName) (string, int, error) {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := c.Get(ctx, key, src); err != nil {
+		return "", 0, err
+	}
+	value, ok := src.GetAnnotations()[pendingApprovalKey]
+	if !ok {
+		return "", 0, failedPrecondition("%s %s has no change pending approval", kind, key)
+	}
+	revision, n, _ := strings.Cut(value, " ")
+	copies, _ := strconv.Atoi(n)
+	if revision != dataRevision(src) {
+		return "", 0, failedPrecondition("%s %s changed since kopy held it back, wait for kopy to record the new change", kind, key)
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	annotations := src.GetAnnotations()
+	annotations[confirmKey] = revision
+	src.SetAnnotations(annotations)
+	return revision, copies, c.Patch(ctx, src, patch)
+}
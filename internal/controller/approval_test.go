@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Change confirmation\n", func() {
+	const sourceNamespace = "test-src-confirm-ns-00"
+	newSource := func(annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-confirm-00", Namespace: sourceNamespace, Annotations: annotations},
+			Data:       map[string][]byte{"password": []byte("rotated")},
+		}
+	}
+	newCopy := func(namespace string, password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-confirm-00", Namespace: namespace, Labels: map[string]string{
+				sourceLabelNamespace: sourceNamespace, sourceLabelName: "test-src-confirm-00",
+			}},
+			Data: map[string][]byte{"password": []byte(password)},
+		}
+	}
+	namespaces := []corev1.Namespace{}
+	for _, name := range []string{"test-dst-confirm-ns-00", "test-dst-confirm-ns-01", "test-dst-confirm-ns-02"} {
+		namespaces = append(namespaces, corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	newClient := func(src *corev1.Secret) client.Client {
+		return fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src,
+			newCopy("test-dst-confirm-ns-00", "old"), newCopy("test-dst-confirm-ns-01", "old"),
+		).Build()
+	}
+	opts := Options{ConfirmThreshold: 1}
+
+	It("Should give different data a different revision, even if it contains NUL bytes", func() {
+		a := &corev1.Secret{Data: map[string][]byte{"a": []byte("x\x00b\x00y")}}
+		b := &corev1.Secret{Data: map[string][]byte{"a": []byte("x"), "b": []byte("y")}}
+		Expect(dataRevision(a)).ShouldNot(Equal(dataRevision(b)))
+		Expect(dataRevision(a)).Should(HaveLen(64))
+	})
+
+	It("Should hold back changes that update more copies than the threshold until they are confirmed", func() {
+		src := newSource(nil)
+		c := newClient(src)
+		recorder := record.NewFakeRecorder(1)
+		held, err := opts.heldForApproval(context.Background(), c, recorder, src, namespaces)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(held.UnsortedList()).Should(ConsistOf("test-dst-confirm-ns-00", "test-dst-confirm-ns-01"))
+		Expect(src.Annotations).Should(HaveKeyWithValue(pendingApprovalKey, dataRevision(src)+" 2"))
+		Expect(<-recorder.Events).Should(ContainSubstring(reasonPendingApproval))
+
+		revision, copies, err := ConfirmChange(context.Background(), c, "secret", client.ObjectKeyFromObject(src))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(revision).Should(Equal(dataRevision(src)))
+		Expect(copies).Should(Equal(2))
+
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		held, err = opts.heldForApproval(context.Background(), c, recorder, src, namespaces)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(held).Should(BeEmpty())
+		Expect(src.Annotations).ShouldNot(HaveKey(pendingApprovalKey))
+	})
+	It("Should not hold back changes within the threshold or confirmed for another revision only", func() {
+		src := newSource(nil)
+		held, err := Options{ConfirmThreshold: 2}.heldForApproval(context.Background(), newClient(src), record.NewFakeRecorder(1), src, namespaces)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(held).Should(BeEmpty())
+
+		src = newSource(map[string]string{confirmKey: "0123456789ab"})
+		held, err = opts.heldForApproval(context.Background(), newClient(src), record.NewFakeRecorder(1), src, namespaces)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(held.Len()).Should(Equal(2))
+	})
+})
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"slices"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/internal/kopycrypto"
+)
+
+const (
+	// caBundleLabel marks a source as a contributor to the CA bundle named by its value. The certificates of all
+	// contributors are merged into one bundle that is distributed to the namespaces each contributor selects.
+	caBundleLabel = kopyPrefix + "ca-bundle"
+	// caBundleKeyKey is the key the bundle is written to, defaults to ca.crt
+	caBundleKeyKey = kopyPrefix + "ca-bundle-key"
+
+	defaultCABundleKey = "ca.crt"
+)
+
+// isCABundleSource returns true if src contributes to a CA bundle
+func isCABundleSource(src client.Object) bool {
+	return src.GetLabels()[caBundleLabel] != ""
+}
+
+// caBundleData merges the PEM certificates in every key of contributors into a single bundle. Certificates are
+// deduplicated and expired certificates are left out, the rest keep the order of the contributors and their keys.
+func caBundleData(contributors []client.Object) (map[string][]byte, error) {
+	key := ""
+	for _, src := range contributors {
+		if !isCABundleSource(src) {
+			return nil, fmt.Errorf("%w: %s is merged into a CA bundle without the %s label", errMergeConflict,
+				client.ObjectKeyFromObject(src), caBundleLabel)
+		}
+		k := src.GetAnnotations()[caBundleKeyKey]
+		if k == "" {
+			k = defaultCABundleKey
+		}
+		if key != "" && k != key {
+			return nil, fmt.Errorf("%w: %s writes the bundle to %s but %s writes it to %s", errMergeConflict,
+				client.ObjectKeyFromObject(src), k, client.ObjectKeyFromObject(contributors[0]), key)
+		}
+		key = k
+	}
+	var bundle bytes.Buffer
+	seen := map[[kopycrypto.Size]byte]bool{}
+	t := now()
+	for _, src := range contributors {
+		data := objectData(src)
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			rest := data[k]
+			for {
+				var block *pem.Block
+				block, rest = pem.Decode(rest)
+				if block == nil {
+					break
+				}
+				if block.Type != "CERTIFICATE" {
+					continue
+				}
+				cert, err := x509.ParseCertificate(block.Bytes)
+				if err != nil || t.After(cert.NotAfter) {
+					continue
+				}
+				sum := kopycrypto.Sum(cert.Raw)
+				if seen[sum] {
+					continue
+				}
+				seen[sum] = true
+				_ = pem.Encode(&bundle, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+			}
+		}
+	}
+	return map[string][]byte{key: bundle.Bytes()}, nil
+}
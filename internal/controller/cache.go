@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewClient is the client.NewClientFunc of the manager. Namespaces are read from the metadata-only informer that
+// the namespace watches use, so the cache never holds full Namespace objects. With SecretMetadataOnly Secrets and
+// Secret lists are read from the API server, so the cache never starts an informer holding the data of every Secret
+// in the cluster; the metadata of Secrets, used to map events to sources, is still read from the cache. Otherwise the
+// Secrets that LeanSecretTransform cached without their data are read from the API server.
+func (o Options) NewClient(config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+	c = &namespaceMetadataClient{Client: c}
+	live, err := client.New(config, client.Options{HTTPClient: options.HTTPClient, Scheme: options.Scheme, Mapper: options.Mapper})
+	if err != nil {
+		return nil, err
+	}
+	if !o.SecretMetadataOnly {
+		return &leanSecretClient{Client: c, live: live}, nil
+	}
+	return &liveSecretClient{Client: c, live: live}, nil
+}
+
+// liveSecretClient reads Secrets from the API server and everything else through the cache
+type liveSecretClient struct {
+	client.Client
+	live client.Reader
+}
+
+func (c *liveSecretClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if _, ok := obj.(*corev1.Secret); ok {
+		return c.live.Get(ctx, key, obj, opts...)
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *liveSecretClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if _, ok := list.(*corev1.SecretList); ok {
+		return c.live.List(ctx, list, opts...)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+// leanSecretClient reads Secrets through the cache, unless the cache holds them without their data
+type leanSecretClient struct {
+	client.Client
+	live client.Reader
+}
+
+func (c *leanSecretClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.Client.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+	s, ok := obj.(*corev1.Secret)
+	if !ok || !isLeanSecret(s) {
+		return nil
+	}
+	// a fresh object, decoding into s would keep the lean marker in its annotations
+	live := &corev1.Secret{}
+	if err := c.live.Get(ctx, key, live, opts...); err != nil {
+		return err
+	}
+	*s = *live
+	return nil
+}
+
+func (c *leanSecretClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	secrets, ok := list.(*corev1.SecretList)
+	if !ok {
+		return nil
+	}
+	// the lean Secrets are read one by one, the list options may select with indexes only the cache has
+	items := secrets.Items[:0]
+	for _, secret := range secrets.Items {
+		if isLeanSecret(&secret) {
+			live := &corev1.Secret{}
+			err := c.live.Get(ctx, client.ObjectKeyFromObject(&secret), live)
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			secret = *live
+		}
+		items = append(items, secret)
+	}
+	secrets.Items = items
+	return nil
+}
+
+// namespaceMetadataClient reads Namespaces as metadata. kopy only looks at the labels, annotations and deletion
+// timestamp of namespaces, the phase of a namespace that is being deleted is set to Terminating.
+type namespaceMetadataClient struct {
+	client.Client
+}
+
+func (c *namespaceMetadataClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return c.Client.Get(ctx, key, obj, opts...)
+	}
+	m := &metav1.PartialObjectMetadata{}
+	m.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+	if err := c.Client.Get(ctx, key, m, opts...); err != nil {
+		return err
+	}
+	*ns = namespaceFromMetadata(m)
+	return nil
+}
+
+func (c *namespaceMetadataClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	namespaces, ok := list.(*corev1.NamespaceList)
+	if !ok {
+		return c.Client.List(ctx, list, opts...)
+	}
+	m := &metav1.PartialObjectMetadataList{}
+	m.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("NamespaceList"))
+	if err := c.Client.List(ctx, m, opts...); err != nil {
+		return err
+	}
+	namespaces.ListMeta = m.ListMeta
+	namespaces.Items = make([]corev1.Namespace, 0, len(m.Items))
+	for i := range m.Items {
+		namespaces.Items = append(namespaces.Items, namespaceFromMetadata(&m.Items[i]))
+	}
+	return nil
+}
+
+// namespaceFromMetadata returns a Namespace with the metadata of m
+func namespaceFromMetadata(m *metav1.PartialObjectMetadata) corev1.Namespace {
+	ns := corev1.Namespace{ObjectMeta: *m.ObjectMeta.DeepCopy()}
+	ns.Status.Phase = corev1.NamespaceActive
+	if ns.DeletionTimestamp != nil {
+		ns.Status.Phase = corev1.NamespaceTerminating
+	}
+	return ns
+}
+
+// secretWatchOptions returns the options of watches on Secrets, which only cache their metadata when
+// SecretMetadataOnly is set
+func (o Options) secretWatchOptions() []builder.WatchesOption {
+	if o.SecretMetadataOnly {
+		return []builder.WatchesOption{builder.OnlyMetadata}
+	}
+	return nil
+}
+
+// secretSources returns the object the sync selector index of Secrets is registered for and the list sources are
+// looked up with, the metadata of Secrets when SecretMetadataOnly is set
+func (o Options) secretSources() (client.Object, client.ObjectList) {
+	if o.SecretMetadataOnly {
+		return &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}},
+			&metav1.PartialObjectMetadataList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "SecretList"}}
+	}
+	return &corev1.Secret{}, &corev1.SecretList{}
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Metadata only cache\n", func() {
+	key := types.NamespacedName{Namespace: "test-src-metadata-ns-00", Name: "test-src-metadata-00"}
+	It("Should read Secrets from the API server and other objects from the cache", func() {
+		// the cached client only has the metadata of the Secret, like a metadata informer
+		cached := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}},
+		).Build()
+		live := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}, Data: map[string][]byte{"k": []byte("v")}},
+		).Build()
+		c := &liveSecretClient{Client: cached, live: live}
+
+		s := &corev1.Secret{}
+		Expect(c.Get(context.Background(), key, s)).Should(Succeed())
+		Expect(s.Data).Should(HaveKey("k"))
+		secrets := &corev1.SecretList{}
+		Expect(c.List(context.Background(), secrets)).Should(Succeed())
+		Expect(secrets.Items).Should(HaveLen(1))
+		Expect(secrets.Items[0].Data).Should(HaveKey("k"))
+		Expect(c.Get(context.Background(), key, &corev1.ConfigMap{})).Should(Succeed())
+	})
+	It("Should read namespaces as metadata", func() {
+		deleted := metav1.Now()
+		c := &namespaceMetadataClient{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"env": "prod"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", DeletionTimestamp: &deleted, Finalizers: []string{"kubernetes"}}},
+		).Build()}
+		ns := &corev1.Namespace{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Name: "team-a"}, ns)).Should(Succeed())
+		Expect(ns.Labels).Should(HaveKeyWithValue("env", "prod"))
+		Expect(ns.Status.Phase).Should(Equal(corev1.NamespaceActive))
+		Expect(isNamespaceMarkedForDelete(context.Background(), c, "team-b")).Should(BeTrue())
+		list := &corev1.NamespaceList{}
+		Expect(c.List(context.Background(), list, client.MatchingLabels{"env": "prod"})).Should(Succeed())
+		Expect(list.Items).Should(HaveLen(1))
+		Expect(list.Items[0].Name).Should(Equal("team-a"))
+	})
+	It("Should map namespaces to pinned sources from Secret metadata", func() {
+		opts := Options{
+			SecretMetadataOnly: true,
+			PinnedTargets:      []PinnedTarget{{Kind: "secret", Source: key.String(), Namespaces: []string{"ingress"}}},
+		}
+		_, list := opts.secretSources()
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ingress"}}
+		Expect(opts.pinnedSourcesFor(list, ns)).Should(Equal([]reconcile.Request{{NamespacedName: key}}))
+		Expect(subscriptionKind(&metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}})).Should(Equal("Secret"))
+	})
+})
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// pendingDeletionKey is set by kopy on the copies of a deleted source to the time they are deleted at when
+	// they cascade
+	pendingDeletionKey = kopyPrefix + "pending-deletion"
+	// reasonPendingDeletion is used for events on copies that are deleted once the cascade grace period has passed
+	reasonPendingDeletion = "PendingDeletion"
+	// reasonCascadeDeleted is used for events on copies deleted because their source was deleted
+	reasonCascadeDeleted = "CascadeDeleted"
+	// reasonCascadePending is used for events on deleted sources whose deleted copies still exist with CascadeVerify
+	reasonCascadePending = "CascadePending"
+	// cascadeVerifyRequeueAfter is how often a deleted source checks again whether its deleted copies are gone
+	cascadeVerifyRequeueAfter = 5 * time.Second
+	// deletionPolicyKey is set on a source to DeletionPolicyDelete or DeletionPolicyOrphan to decide what happens to
+	// its copies when it is deleted, regardless of CascadeDelete
+	deletionPolicyKey = kopyPrefix + "deletion-policy"
+)
+
+const (
+	// DeletionPolicyDelete deletes the copies of a source along with it, honoring the cascade grace period
+	DeletionPolicyDelete = "Delete"
+	// DeletionPolicyOrphan releases the copies of a source to their namespaces when it is deleted
+	DeletionPolicyOrphan = "Orphan"
+)
+
+// errCascadePending is returned by cascadeSource while copies it deleted still exist with CascadeVerify
+var errCascadePending = errors.New("deleted copies still exist")
+
+// cascades returns true if the copies of src are deleted along with it. The deletion policy annotation of src takes
+// precedence over CascadeDelete, other values of it are ignored. The copies of a quarantined source are always kept.
+func (o Options) cascades(src client.Object) bool {
+	if isQuarantined(src) {
+		return false
+	}
+	policy := src.GetAnnotations()[deletionPolicyKey]
+	switch {
+	case strings.EqualFold(policy, DeletionPolicyDelete):
+		return true
+	case strings.EqualFold(policy, DeletionPolicyOrphan):
+		return false
+	}
+	return o.CascadeDelete
+}
+
+// cascadeSource deletes the copies of the deleted source src and removes the finalizer from src. With a
+// CascadeGracePeriod the copies are annotated as pending deletion instead and deleted by their own reconcile once the
+// grace period has passed, so recreating the source in the meantime keeps them. With CascadeVerify and no grace
+// period the finalizer is only removed once every deleted copy is gone, errCascadePending is returned until then.
+func (o Options) cascadeSource(ctx context.Context, c client.Client, recorder record.EventRecorder, src client.Object) error {
+	copies, err := newObjectListForKind(kindOf(src))
+	if err != nil {
+		return err
+	}
+	if err := c.List(ctx, copies, listOptions(src)); err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(copies)
+	if err != nil {
+		return err
+	}
+	log := ctrllog.FromContext(ctx).WithValues("controller", kindOf(src))
+	deleteAt := now().Add(o.CascadeGracePeriod).UTC().Format(time.RFC3339)
+	errs := []error{}
+	// remaining are the namespaces whose copy was deleted but is still there, e.g. held by the finalizer of another
+	// controller
+	remaining := []string{}
+	verify := o.CascadeVerify && o.CascadeGracePeriod == 0
+	for _, item := range items {
+		cp, ok := item.(client.Object)
+		if !ok || !isCopyOf(cp, src) {
+			continue
+		}
+		if !hasSyncFinalizer(cp) {
+			if verify && cp.GetDeletionTimestamp() != nil {
+				remaining = append(remaining, cp.GetNamespace())
+			}
+			continue
+		}
+		if o.CascadeGracePeriod == 0 {
+			log.Info("deleting copy of deleted source", "name", cp.GetName(), "namespace", cp.GetNamespace())
+			if err := o.recordTombstone(ctx, c, cp); err != nil {
+				errs = append(errs, fmt.Errorf("unable to record tombstone of copy in namespace %s: %w", cp.GetNamespace(), err))
+				continue
+			}
+			if err := pruneCopy(ctx, c, cp); err != nil {
+				errs = append(errs, fmt.Errorf("unable to delete copy in namespace %s: %w", cp.GetNamespace(), err))
+				continue
+			}
+			if !verify {
+				continue
+			}
+			err := c.Get(ctx, client.ObjectKeyFromObject(cp), cp)
+			if client.IgnoreNotFound(err) != nil {
+				errs = append(errs, fmt.Errorf("unable to verify the deletion of the copy in namespace %s: %w", cp.GetNamespace(), err))
+			} else if err == nil {
+				remaining = append(remaining, cp.GetNamespace())
+			}
+			continue
+		}
+		if _, ok := cp.GetAnnotations()[pendingDeletionKey]; ok {
+			continue
+		}
+		annotations := cp.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[pendingDeletionKey] = deleteAt
+		cp.SetAnnotations(annotations)
+		if err := c.Update(ctx, cp); err != nil {
+			errs = append(errs, fmt.Errorf("unable to mark copy in namespace %s for deletion: %w", cp.GetNamespace(), err))
+			continue
+		}
+		recorder.Eventf(cp, corev1.EventTypeWarning, reasonPendingDeletion,
+			"Source %s/%s was deleted, deleting copy at %s unless the source is recreated", src.GetNamespace(), src.GetName(), deleteAt)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if len(remaining) > 0 {
+		slices.Sort(remaining)
+		recorder.Eventf(src, corev1.EventTypeWarning, reasonCascadePending,
+			"Waiting for the deleted copies in namespaces %s to go away before releasing the source", strings.Join(remaining, ", "))
+		return fmt.Errorf("%w in namespaces %s", errCascadePending, strings.Join(remaining, ", "))
+	}
+	removeSyncFinalizer(src)
+	return c.Update(ctx, src)
+}
+
+// pendingDeletion returns true if cp is a copy pending deletion that must not be synced: it is deleted once the time
+// in its pending deletion annotation has passed, otherwise the result requeues it for then. Copies whose source was
+// recreated are synced from it again, which drops the annotation.
+func (o Options) pendingDeletion(ctx context.Context, c client.Client, recorder record.EventRecorder, cp client.Object) (ctrl.Result, bool, error) {
+	value, ok := cp.GetAnnotations()[pendingDeletionKey]
+	if !ok {
+		return ctrl.Result{}, false, nil
+	}
+	src, err := NewObjectForKind(kindOf(cp))
+	if err != nil {
+		return ctrl.Result{}, false, err
+	}
+	key := types.NamespacedName{Namespace: cp.GetLabels()[sourceLabelNamespace], Name: sourceNameOf(cp)}
+	err = c.Get(ctx, key, src)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, true, err
+	}
+	if err == nil && src.GetDeletionTimestamp() == nil && cp.GetDeletionTimestamp() == nil {
+		return ctrl.Result{}, false, nil
+	}
+	deleteAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		deleteAt = now()
+	}
+	// a copy pending deletion that is deleted by hand isn't restored
+	if wait := deleteAt.Sub(now()); wait > 0 && cp.GetDeletionTimestamp() == nil {
+		return ctrl.Result{RequeueAfter: wait}, true, nil
+	}
+	if err := o.recordTombstone(ctx, c, cp); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	if err := pruneCopy(ctx, c, cp); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	recorder.Eventf(cp, corev1.EventTypeNormal, reasonCascadeDeleted, "Deleted copy of deleted source %s", key)
+	return ctrl.Result{}, true, nil
+}
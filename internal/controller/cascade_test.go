@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Cascade delete\n", func() {
+	const sourceNamespace = "test-src-cascade-ns-00"
+	start := time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+	newSource := func() *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-cascade-00", Namespace: sourceNamespace, Finalizers: []string{syncFinalizer},
+			Annotations: map[string]string{syncKey: "env=prod"},
+		}}
+	}
+	newCopy := func(namespace string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-cascade-00", Namespace: namespace, Finalizers: []string{syncFinalizer},
+			Labels: map[string]string{sourceLabelNamespace: sourceNamespace, sourceLabelName: "test-src-cascade-00"},
+		}}
+	}
+	deleted := func(src *corev1.Secret) *corev1.Secret {
+		src.DeletionTimestamp = &metav1.Time{Time: start}
+		return src
+	}
+	BeforeEach(func() {
+		now = func() time.Time { return start }
+	})
+	AfterEach(func() {
+		now = time.Now
+	})
+
+	It("Should delete the copies of a deleted source right away without a grace period", func() {
+		src, cp := deleted(newSource()), newCopy("test-dst-cascade-ns-00")
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, cp).Build()
+		Expect(Options{CascadeDelete: true}.cascadeSource(context.Background(), c, record.NewFakeRecorder(1), src)).Should(Succeed())
+		err := c.Get(context.Background(), client.ObjectKeyFromObject(cp), cp)
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+	})
+
+	It("Should delete copies pending deletion after the grace period unless the source is recreated", func() {
+		src, kept, expired := deleted(newSource()), newCopy("test-dst-cascade-ns-00"), newCopy("test-dst-cascade-ns-01")
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, kept, expired).Build()
+		opts := Options{CascadeDelete: true, CascadeGracePeriod: time.Hour}
+		recorder := record.NewFakeRecorder(4)
+		Expect(opts.cascadeSource(context.Background(), c, recorder, src)).Should(Succeed())
+		Expect(<-recorder.Events).Should(ContainSubstring(reasonPendingDeletion))
+		for _, cp := range []*corev1.Secret{kept, expired} {
+			Expect(c.Get(context.Background(), client.ObjectKeyFromObject(cp), cp)).Should(Succeed())
+			Expect(cp.Annotations).Should(HaveKeyWithValue(pendingDeletionKey, start.Add(time.Hour).Format(time.RFC3339)))
+		}
+
+		result, pending, err := opts.pendingDeletion(context.Background(), c, recorder, expired)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(pending).Should(BeTrue())
+		Expect(result.RequeueAfter).Should(Equal(time.Hour))
+
+		now = func() time.Time { return start.Add(time.Hour) }
+		_, pending, err = opts.pendingDeletion(context.Background(), c, recorder, expired)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(pending).Should(BeTrue())
+		Expect(apierrors.IsNotFound(c.Get(context.Background(), client.ObjectKeyFromObject(expired), expired))).Should(BeTrue())
+
+		Expect(c.Create(context.Background(), newSource())).Should(Succeed())
+		_, pending, err = opts.pendingDeletion(context.Background(), c, recorder, kept)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(pending).Should(BeFalse())
+
+		// syncing the copy from the recreated source drops the annotation, which the apply of the copy leaves alone
+		Expect(clearTransientAnnotations(context.Background(), c, kept)).Should(Succeed())
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(kept), kept)).Should(Succeed())
+		Expect(kept.Annotations).ShouldNot(HaveKey(pendingDeletionKey))
+	})
+
+	It("Should keep the finalizer of a deleted source until its deleted copies are gone with CascadeVerify", func() {
+		ctx := context.Background()
+		src, cp := deleted(newSource()), newCopy("test-dst-cascade-ns-00")
+		cp.Finalizers = append(cp.Finalizers, "example.com/hold")
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, cp).Build()
+		opts := Options{CascadeDelete: true, CascadeVerify: true}
+		recorder := record.NewFakeRecorder(4)
+		err := opts.cascadeSource(ctx, c, recorder, src)
+		Expect(errors.Is(err, errCascadePending)).Should(BeTrue())
+		Expect(<-recorder.Events).Should(And(ContainSubstring(reasonCascadePending), ContainSubstring("test-dst-cascade-ns-00")))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(hasSyncFinalizer(src)).Should(BeTrue())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cp), cp)).Should(Succeed())
+		Expect(cp.DeletionTimestamp).ShouldNot(BeNil())
+
+		// a retry before the copy is gone keeps waiting
+		Expect(errors.Is(opts.cascadeSource(ctx, c, recorder, src), errCascadePending)).Should(BeTrue())
+
+		cp.Finalizers = nil
+		Expect(c.Update(ctx, cp)).Should(Succeed())
+		Expect(opts.cascadeSource(ctx, c, recorder, src)).Should(Succeed())
+		Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(src), src))).Should(BeTrue())
+	})
+
+	It("Should delete the copies of a deleted source whose deletion policy is Delete", func() {
+		ctx := context.Background()
+		src, cp := deleted(newSource()), newCopy("test-dst-cascade-ns-00")
+		src.Annotations[deletionPolicyKey] = DeletionPolicyDelete
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, cp).Build()
+		_, err := KopyReconcile(NewKopySecret(ctx, c, Options{}, record.NewFakeRecorder(4)),
+			ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(cp), cp))).Should(BeTrue())
+	})
+
+	DescribeTable("Deciding whether the copies of a deleted source are deleted",
+		func(cascadeDelete bool, annotations map[string]string, expected bool) {
+			src := newSource()
+			for k, v := range annotations {
+				src.Annotations[k] = v
+			}
+			Expect(Options{CascadeDelete: cascadeDelete}.cascades(src)).Should(Equal(expected))
+		},
+		Entry("cascade delete", true, nil, true),
+		Entry("release by default", false, nil, false),
+		Entry("delete policy", false, map[string]string{deletionPolicyKey: DeletionPolicyDelete}, true),
+		Entry("orphan policy", true, map[string]string{deletionPolicyKey: DeletionPolicyOrphan}, false),
+		Entry("lower case policy", false, map[string]string{deletionPolicyKey: "delete"}, true),
+		Entry("unknown policy", true, map[string]string{deletionPolicyKey: "Retain"}, true),
+		Entry("quarantined source", false, map[string]string{deletionPolicyKey: DeletionPolicyDelete, quarantineKey: "true"}, false),
+	)
+})
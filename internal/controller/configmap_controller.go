@@ -2,16 +2,16 @@ package controller
 
 import (
 	"context"
-	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
-	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -19,7 +19,11 @@ import (
 // ConfigMapReconciler reconciles a ConfigMap object
 type ConfigMapReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme  *runtime.Scheme
+	Options Options
+
+	recorder record.EventRecorder
+	tracker  *syncTracker
 }
 
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
@@ -37,39 +41,18 @@ type ConfigMapReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.4/pkg/reconcile
 func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	ks := NewKopyConfigMap(ctx, r.Client)
-	return KopyReconcile(ks, req)
+	ks := NewKopyConfigMap(ctx, r.Client, r.Options, r.recorder)
+	result, err := KopyReconcile(ks, req, r.tracker)
+	debugState.recordError("configmap", err)
+	return result, err
 }
 
+// watchNamespaces maps a namespace event to the source ConfigMaps whose sync selector matches the namespace
 func (r *ConfigMapReconciler) watchNamespaces(ctx context.Context, namespace client.Object) []reconcile.Request {
-	log := ctrllog.FromContext(ctx)
 	if isNamespaceMarkedForDelete(ctx, r.Client, namespace.GetName()) {
 		return nil
 	}
-	configMaps := &corev1.ConfigMapList{}
-	if err := r.List(ctx, configMaps); err != nil {
-		log.Info("unable to grab a list of configmaps")
-	}
-	req := make([]reconcile.Request, len(configMaps.Items))
-	for i, cm := range configMaps.Items {
-		v, ok := cm.Annotations[syncKey]
-		if !ok {
-			continue
-		}
-		syncLabel := strings.Split(v, "=")
-		labelKey := syncLabel[0]
-		labelValue := syncLabel[1]
-		nsLabels := namespace.GetLabels()
-		if nsLabels[labelKey] == labelValue {
-			req[i] = reconcile.Request{NamespacedName: types.NamespacedName{
-				Namespace: cm.GetNamespace(),
-				Name:      cm.GetName(),
-			}}
-			log.Info("need to add reconcile queue", "source.configMap", cm.GetName(), "source.Namespace", cm.GetNamespace(), "target.Namespace", namespace.GetName())
-		}
-
-	}
-	return req
+	return r.Options.sourcesSelecting(ctx, r.Client, &corev1.ConfigMapList{}, namespace)
 }
 
 var p = predicate.Funcs{
@@ -81,11 +64,26 @@ var p = predicate.Funcs{
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	r.recorder = mgr.GetEventRecorderFor("kopy-configmap-controller")
+	r.tracker = newSyncTracker(r.Options.SyncDeadline, r.recorder)
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.ConfigMap{}).
-		Watches(&corev1.Namespace{},
+		WithOptions(controller.Options{
+			NewQueue:                r.Options.newQueue(),
+			MaxConcurrentReconciles: r.Options.MaxConcurrentReconciles,
+		})
+	debugState.watch("configmap", "ConfigMap")
+	// namespaces can only be watched with cluster wide permissions
+	if !r.Options.NamespaceScoped() {
+		debugState.watch("configmap", "ConfigMap", "Namespace")
+		if err := setupSyncSelectorIndex(mgr, &corev1.ConfigMap{}); err != nil {
+			return err
+		}
+		// only labels, annotations and the deletion timestamp of namespaces are used
+		b = b.Watches(&corev1.Namespace{},
 			handler.EnqueueRequestsFromMapFunc(r.watchNamespaces),
-			// builder.WithPredicates(p),
-		).
-		Complete(r)
+			builder.OnlyMetadata,
+		)
+	}
+	return b.Complete(r)
 }
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"slices"
 	"time"
@@ -10,6 +11,8 @@ import (
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"sigs.k8s.io/yaml"
 )
@@ -26,8 +29,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 			}{
 				name: "test-config-00", namespace: "test-src-config-ns-00", configMap: &corev1.ConfigMap{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(func() bool {
 				err := tc.GetNamespace(src.namespace, &corev1.Namespace{})
 				return err == nil
@@ -91,8 +95,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 			}{
 				name: "test-config-01", namespace: "test-src-config-ns-01", configMap: &corev1.ConfigMap{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(func() bool {
 				err := tc.GetNamespace(src.namespace, &corev1.Namespace{})
 				return err == nil
@@ -137,8 +142,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 			}{
 				namespace: "test-src-config-ns-02", configMap: &corev1.ConfigMap{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			By("Creating new source configMap with 253 characters")
@@ -177,8 +183,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 				name: "test-config-03", namespace: "test-src-config-ns-03", configMap: &corev1.ConfigMap{},
 			}
 			tc = NewTestClient(context.Background())
-			_, err := tc.CreateNamespace(src.namespace, nil)
-			Expect(err).ToNot(HaveOccurred())
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			label := &syncLabel{key: testLabelKey, value: src.name}
@@ -197,9 +204,11 @@ var _ = Describe("ConfigMap Controller\n", func() {
 				{name: "target-tt-config-02", namespace: &corev1.Namespace{}, configMap: &corev1.ConfigMap{}},
 				{name: "target-tt-config-03", namespace: &corev1.Namespace{}, configMap: &corev1.ConfigMap{}},
 			}
-			for _, t := range testCases {
+			for i := range testCases {
+				t := &testCases[i]
 				t.namespace, err = tc.CreateNamespace(t.name, label)
 				Expect(err).ShouldNot(HaveOccurred())
+				t.name = t.namespace.Name
 				Eventually(tc.GetNamespace(t.name, t.namespace), timeout, interval).Should(Succeed())
 				Eventually(func() bool {
 					tc.GetConfigMap(src.name, t.name, t.configMap)
@@ -244,8 +253,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 				name: "test-src-config-04", namespace: "test-src-config-ns-04", configMap: &corev1.ConfigMap{},
 			}
 			tc = NewTestClient(context.Background())
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			label := &syncLabel{key: "kopy-sync", value: src.name}
@@ -294,8 +304,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 			}{
 				name: "test-src-config-07", namespace: "test-src-config-ns-07", configMap: &corev1.ConfigMap{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 			label := &syncLabel{key: testLabelKey, value: src.name}
 			data := map[string]string{"HOST": "https://test-kopy.io/duplicate"}
@@ -331,8 +342,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 			}{
 				name: "test-src-config-07", namespace: "test-src-config-dup-ns-07", configMap: &corev1.ConfigMap{},
 			}
-			_, err = tc.CreateNamespace(duplicate.namespace, nil)
+			duplicateNamespace, err := tc.CreateNamespace(duplicate.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			duplicate.namespace = duplicateNamespace.Name
 			Eventually(tc.GetNamespace(duplicate.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			duplicate.configMap, err = tc.CreateConfigMap(duplicate.name, duplicate.namespace, label, data)
@@ -370,8 +382,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 				name: "test-src-configmap-08", namespace: "test-src-configmap-ns-08", configmap: &corev1.ConfigMap{},
 			}
 			label := &syncLabel{key: testLabelKey, value: src.name}
-			_, err := tc.CreateNamespace(src.namespace, label)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, label)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			By("Creating new source configmap")
@@ -420,8 +433,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 				name: "test-src-configmap-09", namespace: "test-src-configmap-ns-09", configmap: &corev1.ConfigMap{},
 			}
 			label := &syncLabel{key: testLabelKey, value: src.name}
-			_, err := tc.CreateNamespace(src.namespace, label)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, label)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			By("Creating new source configmap")
@@ -468,6 +482,234 @@ var _ = Describe("ConfigMap Controller\n", func() {
 
 		})
 	})
+	Context("When the sync annotation value on the source changes", func() {
+		It("Should prune copies from namespaces that are no longer selected", func() {
+			By("Creating source namespace and configmap")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				configmap *corev1.ConfigMap
+			}{
+				name: "test-src-configmap-10", namespace: "test-src-configmap-ns-10", configmap: &corev1.ConfigMap{},
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			oldLabel := &syncLabel{key: testLabelKey, value: src.name}
+			newLabel := &syncLabel{key: testLabelKey, value: src.name + "-new"}
+			data := map[string]string{"HOST": "https://test-kopy.io/selector-change"}
+			src.configmap, err = tc.CreateConfigMap(src.name, src.namespace, oldLabel, data)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Creating target namespaces for the old and new selector")
+			oldNamespace, err := tc.CreateNamespace("test-target-configmap-ns-10-old", oldLabel)
+			Expect(err).ShouldNot(HaveOccurred())
+			newNamespace, err := tc.CreateNamespace("test-target-configmap-ns-10-new", newLabel)
+			Expect(err).ShouldNot(HaveOccurred())
+			Eventually(func() error {
+				return tc.GetConfigMap(src.name, oldNamespace.Name, &corev1.ConfigMap{})
+			}, timeout, interval).Should(Succeed())
+
+			By("Changing the sync annotation on the source")
+			Expect(tc.GetConfigMap(src.name, src.namespace, src.configmap)).ShouldNot(HaveOccurred())
+			src.configmap.Annotations[syncKey] = newLabel.key + "=" + newLabel.value
+			Expect(tc.UpdateConfigMap(src.configmap)).ShouldNot(HaveOccurred())
+
+			By("Verifying the copy was synced to the newly selected namespace")
+			Eventually(func() error {
+				return tc.GetConfigMap(src.name, newNamespace.Name, &corev1.ConfigMap{})
+			}, timeout, interval).Should(Succeed())
+
+			By("Verifying the copy was pruned from the previously selected namespace")
+			Eventually(func() bool {
+				err := tc.GetConfigMap(src.name, oldNamespace.Name, &corev1.ConfigMap{})
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+		})
+	})
+	Context("When a resync is requested for a namespace that lost its copies", func() {
+		It("Should recreate the copies in the namespace", func() {
+			By("Creating source namespace and configmap")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				configmap *corev1.ConfigMap
+			}{
+				name: "test-src-configmap-11", namespace: "test-src-configmap-ns-11", configmap: &corev1.ConfigMap{},
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			data := map[string]string{"HOST": "https://test-kopy.io/resync"}
+			src.configmap, err = tc.CreateConfigMap(src.name, src.namespace, label, data)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Creating target namespace and waiting for copy")
+			targetNamespace, err := tc.CreateNamespace("test-target-configmap-ns-11", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			copy := &corev1.ConfigMap{}
+			Eventually(func() error {
+				return tc.GetConfigMap(src.name, targetNamespace.Name, copy)
+			}, timeout, interval).Should(Succeed())
+
+			By("Dropping the copy the way a backup restore would")
+			copy.Finalizers = nil
+			Expect(tc.UpdateConfigMap(copy)).ShouldNot(HaveOccurred())
+			Expect(tc.DeleteConfigmap(copy)).ShouldNot(HaveOccurred())
+			Eventually(func() bool {
+				return apierrors.IsNotFound(tc.GetConfigMap(src.name, targetNamespace.Name, &corev1.ConfigMap{}))
+			}, timeout, interval).Should(BeTrue())
+
+			By("Requesting a resync of the target namespace")
+			sources, err := RequestNamespaceResync(tc.ctx, k8sClient, targetNamespace.Name)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sources).Should(ContainElement(SourceRef{Kind: "configmap", Namespace: src.namespace, Name: src.name}))
+
+			By("Verifying the copy was recreated")
+			Eventually(func() error {
+				return tc.GetConfigMap(src.name, targetNamespace.Name, &corev1.ConfigMap{})
+			}, timeout, interval).Should(Succeed())
+		})
+	})
+	Context("When a resync is requested for a source", func() {
+		It("Should rewrite the copies and mark the request as observed", func() {
+			By("Creating source namespace and configmap")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				configmap *corev1.ConfigMap
+			}{
+				name: "test-src-configmap-14", namespace: "test-src-configmap-ns-14", configmap: &corev1.ConfigMap{},
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			data := map[string]string{"HOST": "https://test-kopy.io/source-resync"}
+			src.configmap, err = tc.CreateConfigMap(src.name, src.namespace, label, data)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Creating target namespace and waiting for copy")
+			targetNamespace, err := tc.CreateNamespace("test-target-configmap-ns-14", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			Eventually(func() error {
+				return tc.GetConfigMap(src.name, targetNamespace.Name, &corev1.ConfigMap{})
+			}, timeout, interval).Should(Succeed())
+
+			By("Requesting a resync of the source")
+			key := types.NamespacedName{Namespace: src.namespace, Name: src.name}
+			request, err := RequestSourceResync(tc.ctx, k8sClient, "configmap", key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Verifying the request was observed")
+			Eventually(func() (bool, error) {
+				return ResyncObserved(tc.ctx, k8sClient, "configmap", key, request)
+			}, timeout, interval).Should(BeTrue())
+			copy := &corev1.ConfigMap{}
+			Expect(tc.GetConfigMap(src.name, targetNamespace.Name, copy)).Should(Succeed())
+			Expect(copy.Data).Should(Equal(data))
+		})
+	})
+	Context("When the target namespace has a copy labeled under a legacy domain", func() {
+		It("Should adopt the copy", func() {
+			By("Creating namespaces and a legacy copy in the target namespace")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				configmap *corev1.ConfigMap
+			}{
+				name: "test-src-configmap-12", namespace: "test-src-configmap-ns-12", configmap: &corev1.ConfigMap{},
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			targetNamespace, err := tc.CreateNamespace("test-target-configmap-ns-12", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			legacy := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       src.name,
+					Namespace:  targetNamespace.Name,
+					Labels:     map[string]string{testLegacyDomain + "/origin.namespace": src.namespace},
+					Finalizers: []string{testLegacyDomain + "/finalizer"},
+				},
+				Data: map[string]string{"HOST": "https://test-kopy.io/legacy"},
+			}
+			Expect(k8sClient.Create(tc.ctx, legacy)).ShouldNot(HaveOccurred())
+
+			By("Creating source configmap")
+			data := map[string]string{"HOST": "https://test-kopy.io/adopted"}
+			src.configmap, err = tc.CreateConfigMap(src.name, src.namespace, label, data)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Verifying the copy was adopted")
+			Eventually(func() bool {
+				copy := &corev1.ConfigMap{}
+				if err := tc.GetConfigMap(src.name, targetNamespace.Name, copy); err != nil {
+					return false
+				}
+				_, legacyLabel := copy.Labels[testLegacyDomain+"/origin.namespace"]
+				return copy.Labels[sourceLabelNamespace] == src.namespace && !legacyLabel &&
+					slices.Equal(copy.Finalizers, []string{syncFinalizer}) && reflect.DeepEqual(copy.Data, data)
+			}, timeout, interval).Should(BeTrue())
+		})
+	})
+	Context("When source configmap contains binaryData", func() {
+		It("Should copy the binaryData to the target namespace", func() {
+			By("Creating source namespace and configmap with binaryData")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				configmap *corev1.ConfigMap
+			}{
+				name: "test-src-configmap-13", namespace: "test-src-configmap-ns-13", configmap: &corev1.ConfigMap{},
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			src.configmap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        src.name,
+					Namespace:   src.namespace,
+					Annotations: map[string]string{syncKey: fmt.Sprintf("%s=%s", label.key, label.value)},
+				},
+				Data:       map[string]string{"keystore.type": "pkcs12"},
+				BinaryData: map[string][]byte{"keystore.p12": {0x30, 0x82, 0x00, 0xff}},
+			}
+			Expect(k8sClient.Create(tc.ctx, src.configmap)).ShouldNot(HaveOccurred())
+
+			By("Creating target namespace and checking the copy")
+			targetNamespace, err := tc.CreateNamespace("test-target-configmap-ns-13", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			Eventually(func() map[string][]byte {
+				copy := &corev1.ConfigMap{}
+				tc.GetConfigMap(src.name, targetNamespace.Name, copy)
+				return copy.BinaryData
+			}, timeout, interval).Should(Equal(src.configmap.BinaryData))
+
+			By("Updating the binaryData on the source configmap")
+			truststore := map[string][]byte{"truststore.p12": {0x30, 0x82, 0x01, 0x0a}}
+			Eventually(func() error {
+				if err := tc.GetConfigMap(src.name, src.namespace, src.configmap); err != nil {
+					return err
+				}
+				src.configmap.BinaryData = truststore
+				return tc.UpdateConfigMap(src.configmap)
+			}, timeout, interval).Should(Succeed())
+			Eventually(func() map[string][]byte {
+				copy := &corev1.ConfigMap{}
+				tc.GetConfigMap(src.name, targetNamespace.Name, copy)
+				return copy.BinaryData
+			}, timeout, interval).Should(Equal(truststore))
+		})
+	})
 	if useKind {
 		Context("When namespace that contains copy is deleted", func() {
 			It("The namespace should be deleted properly", func() {
@@ -480,8 +722,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 				}{
 					name: "test-src-config-05", namespace: "test-src-config-ns-05", configMap: &corev1.ConfigMap{},
 				}
-				_, err := tc.CreateNamespace(src.namespace, nil)
+				srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 				Expect(err).ShouldNot(HaveOccurred())
+				src.namespace = srcNamespace.Name
 				Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 				label := &syncLabel{key: testLabelKey, value: src.name}
 				data := map[string]string{"HOST": "https://test-kopy.io/"}
@@ -539,8 +782,9 @@ var _ = Describe("ConfigMap Controller\n", func() {
 					name: "test-config-06", namespace: "test-src-config-ns-06", configMap: &corev1.ConfigMap{},
 				}
 				tc = NewTestClient(context.Background())
-				_, err := tc.CreateNamespace(src.namespace, nil)
-				Expect(err).ToNot(HaveOccurred())
+				srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				src.namespace = srcNamespace.Name
 				Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 				label := &syncLabel{key: testLabelKey, value: src.name}
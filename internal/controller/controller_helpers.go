@@ -3,19 +3,21 @@ package controller
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/pkg/freshness"
 )
 
 func isNamespaceMarkedForDelete(ctx context.Context, c client.Client, namespace string) bool {
 	ns := &corev1.Namespace{}
-	if err := c.Get(ctx, types.NamespacedName{Name: namespace, Namespace: namespace}, ns); err != nil {
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
 		if apierrors.IsNotFound(err) {
 			return true
 		}
@@ -26,37 +28,127 @@ func isNamespaceMarkedForDelete(ctx context.Context, c client.Client, namespace
 	return false
 }
 
+// sourceLookupError classifies a source that can't be found as errSourceNotCached so the caller can retry
+func sourceLookupError(err error) error {
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%w: %w", errSourceNotCached, err)
+	}
+	return err
+}
+
 func namespaceContainsSyncLabel(o client.Object, namespace client.Object) bool {
-	annotations := o.GetAnnotations()
-	v, ok := annotations[syncKey]
+	v, ok := SyncSelector(o)
 	if !ok {
 		return false
 	}
-	label := strings.Split(v, "=")
-	key := label[0]
-	value := label[1]
-	return namespace.GetLabels()[key] == value
+	ls, err := ParseSyncSelector(v)
+	if err != nil {
+		return false
+	}
+	return ls.Matches(labels.Set(namespace.GetLabels()))
 }
 
-func getSyncNamespaces(ctx context.Context, c client.Client, req ctrl.Request, selector labels.Selector) ([]corev1.Namespace, error) {
+// getSyncNamespaces lists the namespaces selected by selector that a source in req.Namespace is synced to
+func getSyncNamespaces(ctx context.Context, c client.Client, req ctrl.Request, selector labels.Selector, excluded []string) ([]corev1.Namespace, error) {
 	namespaceList := &corev1.NamespaceList{}
 	opts := &client.ListOptions{LabelSelector: selector}
 	if err := c.List(ctx, namespaceList, opts); err != nil {
 		return nil, fmt.Errorf("unable to list namespaces")
 	}
-	namespaces := make([]corev1.Namespace, 0, len(namespaceList.Items))
-	for _, ns := range namespaceList.Items {
-		if ns.Name == req.Namespace {
+	return filterSyncNamespaces(namespaceList.Items, req.Namespace, selector, excluded), nil
+}
+
+// getScopedSyncNamespaces is the namespace scoped variant of getSyncNamespaces; it gets each of the allowed namespaces
+// individually because listing namespaces requires cluster wide permissions
+func getScopedSyncNamespaces(ctx context.Context, c client.Client, req ctrl.Request, selector labels.Selector, allowed, excluded []string) ([]corev1.Namespace, error) {
+	namespaces := make([]corev1.Namespace, 0, len(allowed))
+	for _, name := range allowed {
+		ns := corev1.Namespace{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, &ns); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("unable to get namespace %s: %w", name, err)
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return filterSyncNamespaces(namespaces, req.Namespace, selector, excluded), nil
+}
+
+// filterSyncNamespaces returns the namespaces a source in the namespace source is synced to: the namespace of the
+// source itself, terminating namespaces and the excluded namespaces are skipped. Selectors that can't be sent to the
+// API server, such as labels.Nothing, serialize to an empty string and list every namespace, so the selector is
+// checked again here.
+func filterSyncNamespaces(namespaces []corev1.Namespace, source string, selector labels.Selector, excluded []string) []corev1.Namespace {
+	deny := sets.New(excluded...)
+	filtered := make([]corev1.Namespace, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if ns.Name == source || deny.Has(ns.Name) || ns.DeletionTimestamp != nil {
 			continue
 		}
-		if ns.DeletionTimestamp == nil {
-			namespaces = append(namespaces, ns)
+		if selector.Matches(labels.Set(ns.Labels)) {
+			filtered = append(filtered, ns)
 		}
 	}
-	return namespaces, nil
+	return filtered
 }
 
 func listOptions(o client.Object) *client.ListOptions {
 	set := labels.Set(map[string]string{sourceLabelNamespace: o.GetNamespace()})
 	return &client.ListOptions{LabelSelector: set.AsSelector()}
 }
+
+// namespaceNames returns the set of names from the list of namespaces
+func namespaceNames(namespaces []corev1.Namespace) sets.Set[string] {
+	names := sets.New[string]()
+	for _, ns := range namespaces {
+		names.Insert(ns.Name)
+	}
+	return names
+}
+
+// isCopyOf returns true if cp was synced from src
+func isCopyOf(cp, src client.Object) bool {
+	return cp.GetLabels()[sourceLabelNamespace] == src.GetNamespace() && sourceNameOf(cp) == src.GetName()
+}
+
+// sourceNameOf returns the name of the source of cp; copies created before the origin name label fall back to their
+// own name
+func sourceNameOf(cp client.Object) string {
+	if name, ok := cp.GetLabels()[sourceLabelName]; ok {
+		return name
+	}
+	return cp.GetName()
+}
+
+// pruneCopy removes the kopy finalizer from the copy and deletes it from the cluster
+func pruneCopy(ctx context.Context, c client.Client, cp client.Object) error {
+	if removeSyncFinalizer(cp) {
+		if err := c.Update(ctx, cp); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+	}
+	return client.IgnoreNotFound(c.Delete(ctx, cp))
+}
+
+// preserveCopyMetadata carries kopy metadata that isn't derived from the source over from the existing copy
+func preserveCopyMetadata(existing, cp client.Object) {
+	if name, ok := existing.GetLabels()[subscriptionLabel]; ok {
+		labels := cp.GetLabels()
+		labels[subscriptionLabel] = name
+		cp.SetLabels(labels)
+	}
+	// the last sync time and source resourceVersion of a current copy are kept for a while so rewriting the copy
+	// doesn't trigger another reconcile of it every time, e.g. after a change to the metadata of the source
+	if t, err := freshness.LastSyncTime(existing.GetAnnotations()); err == nil && now().Sub(t) < lastSyncRefresh && copyIsCurrent(existing, cp) {
+		annotations := cp.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[freshness.LastSyncTimeAnnotation] = existing.GetAnnotations()[freshness.LastSyncTimeAnnotation]
+		if v, ok := existing.GetAnnotations()[sourceResourceVersionKey]; ok {
+			annotations[sourceResourceVersionKey] = v
+		}
+		cp.SetAnnotations(annotations)
+	}
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Sync namespaces\n", func() {
+	newNamespace := func(name string, terminating bool) corev1.Namespace {
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"env": "prod"}}}
+		if terminating {
+			deleted := metav1.Now()
+			ns.DeletionTimestamp = &deleted
+			ns.Finalizers = []string{"kubernetes"}
+		}
+		return ns
+	}
+	prod := labels.SelectorFromSet(labels.Set{"env": "prod"})
+
+	DescribeTable("Filtering the target namespaces of a source in test-src",
+		func(namespaces []corev1.Namespace, selector labels.Selector, excluded []string, expected []string) {
+			filtered := filterSyncNamespaces(namespaces, "test-src", selector, excluded)
+			Expect(namespaceNames(filtered).UnsortedList()).Should(ConsistOf(expected))
+			for _, ns := range filtered {
+				Expect(ns.Name).ShouldNot(BeEmpty())
+			}
+		},
+		Entry("keeps matching namespaces",
+			[]corev1.Namespace{newNamespace("test-a", false), newNamespace("test-b", false)}, prod, nil,
+			[]string{"test-a", "test-b"}),
+		Entry("skips the namespace of the source",
+			[]corev1.Namespace{newNamespace("test-src", false), newNamespace("test-a", false)}, prod, nil,
+			[]string{"test-a"}),
+		Entry("skips terminating namespaces",
+			[]corev1.Namespace{newNamespace("test-a", true), newNamespace("test-b", false)}, prod, nil,
+			[]string{"test-b"}),
+		Entry("skips excluded namespaces",
+			[]corev1.Namespace{newNamespace("test-a", false), newNamespace("test-b", false)}, prod, []string{"test-a"},
+			[]string{"test-b"}),
+		Entry("checks the selector again",
+			[]corev1.Namespace{newNamespace("test-a", false)}, labels.Nothing(), nil,
+			[]string{}),
+		Entry("returns no namespaces when every namespace is skipped",
+			[]corev1.Namespace{newNamespace("test-src", false), newNamespace("test-a", true), newNamespace("test-b", false)},
+			prod, []string{"test-b"}, []string{}),
+	)
+
+	It("Should filter listed and scoped namespaces the same way", func() {
+		objs := []client.Object{}
+		for _, ns := range []corev1.Namespace{
+			newNamespace("test-src", false), newNamespace("test-a", false), newNamespace("test-b", true),
+			newNamespace("test-c", false), newNamespace("test-d", false),
+		} {
+			objs = append(objs, ns.DeepCopy())
+		}
+		c := fake.NewClientBuilder().WithObjects(objs...).Build()
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "test-src", Name: "test-secret"}}
+		excluded := []string{"test-c"}
+
+		listed, err := getSyncNamespaces(context.Background(), c, req, prod, excluded)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(namespaceNames(listed).UnsortedList()).Should(ConsistOf("test-a", "test-d"))
+
+		allowed := []string{"test-src", "test-a", "test-b", "test-c", "test-d", "test-missing"}
+		scoped, err := getScopedSyncNamespaces(context.Background(), c, req, prod, allowed, excluded)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(namespaceNames(scoped)).Should(Equal(namespaceNames(listed)))
+	})
+})
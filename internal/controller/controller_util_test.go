@@ -9,19 +9,39 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/flynshue/kopy/pkg/testenv"
 )
 
 const (
 	testLabelKey   = "app"
 	testLabelValue = "myTestApp"
-	timeout        = time.Second * 10
-	interval       = time.Millisecond * 250
+	// testLegacyDomain is the label domain of a previous kopy installation
+	testLegacyDomain = "kopy.example.com"
+	timeout          = time.Second * 10
+	interval         = time.Millisecond * 250
 )
 
 var (
-	tc testClient
+	tc          testClient
+	testOptions = Options{
+		BackupExclusionLabels: map[string]string{"velero.io/exclude-from-backup": "true"},
+		SyncDeadline:          time.Second * 2,
+		LegacyDomains:         []string{testLegacyDomain},
+		HNC:                   true,
+		// reconcile in parallel so specs exercise the source locks
+		MaxConcurrentReconciles: 4,
+	}
 )
 
+// newFakeClientBuilder returns a builder of fake clients that emulate server-side apply for the specs that write
+// copies
+func newFakeClientBuilder() *fake.ClientBuilder {
+	return fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{Patch: fakeApply})
+}
+
 type syncLabel struct {
 	key   string
 	value string
@@ -41,21 +61,14 @@ func (tc testClient) GetNamespace(name string, ns *corev1.Namespace) error {
 	return k8sClient.Get(tc.ctx, types.NamespacedName{Name: name}, ns)
 }
 
-// CreateNamespace will namespace object in cluster using name and will use label as kopy sync label
-// Returns corev1.Namespace object
+// CreateNamespace creates a uniquely named namespace based on name and will use label as kopy sync label.
+// The namespace is deleted when the spec finishes, use the name of the returned corev1.Namespace object
 func (tc testClient) CreateNamespace(name string, label *syncLabel) (*corev1.Namespace, error) {
-	ns := &corev1.Namespace{
-		ObjectMeta: v1.ObjectMeta{
-			Name: name,
-		},
-	}
+	var labels map[string]string
 	if label != nil {
-		ns.Labels = map[string]string{label.key: label.value}
-	}
-	if err := k8sClient.Create(context.Background(), ns); err != nil {
-		return nil, err
+		labels = map[string]string{label.key: label.value}
 	}
-	return ns, nil
+	return testenv.CreateNamespace(tc.ctx, k8sClient, name, labels)
 }
 
 // GetConfigMap retrieves ConfigMap object and stores it cm
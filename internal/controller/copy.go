@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/flynshue/kopy/pkg/freshness"
+)
+
+const (
+	// reasonCopyReleased is used for events on copies that are no longer managed by kopy
+	reasonCopyReleased = "CopyReleased"
+	// reasonSyncDisabled is used for events on sources whose sync annotation was removed
+	reasonSyncDisabled = "SyncDisabled"
+	// reasonCopyMutated is used for events when the API server returned a copy that differs from what kopy wrote
+	reasonCopyMutated = "CopyMutated"
+	// reasonCopySynced is used for events on target namespaces when a copy was created or updated
+	reasonCopySynced = "CopySynced"
+	// reasonSynced is used for events on sources when a copy was created or updated
+	reasonSynced = "Synced"
+	// reasonSyncFailed is used for events on sources when a copy couldn't be written
+	reasonSyncFailed = "SyncFailed"
+	// reasonCopyDeleted is used for events on sources when a copy was pruned from a namespace that is no longer selected
+	reasonCopyDeleted = "CopyDeleted"
+	// reasonFinalizerRemoved is used for events on sources when the finalizer of a copy was removed to release it
+	reasonFinalizerRemoved = "FinalizerRemoved"
+)
+
+// newCopy builds the copy of src for the target namespace. The payload of src is carried over by the copySpec of its
+// kind, so copies of every kind are constructed the same way. Options.prepareCopy finishes the copy before it is
+// written.
+func newCopy(src client.Object, namespace string, opts Options) (client.Object, error) {
+	k, ok := kindForObject(src)
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind %T", src)
+	}
+	cp, _ := k.copyOf(src)
+	cp.SetName(opts.copyNameFor(src, namespace))
+	cp.SetNamespace(namespace)
+	cp.SetLabels(opts.copyLabels(src.GetAnnotations(), src.GetNamespace(), src.GetName()))
+	cp.SetAnnotations(map[string]string{
+		freshness.LastSyncTimeAnnotation: now().UTC().Format(time.RFC3339),
+		sourceHashKey:                    dataRevision(src),
+		sourceResourceVersionKey:         src.GetResourceVersion(),
+	})
+	addSyncFinalizer(cp)
+	return cp, nil
+}
+
+// writeCopy applies cp to the cluster, creating the copy or taking over the object that already exists in its place.
+// A copy that the API server returns with different data or metadata than kopy submitted, usually because of a
+// mutating webhook in the target namespace, is reported with a CopyMutated event on src and on the copy. Other field
+// managers whose fields conflict with the copy are recorded for the KopyReport of src. recorder may be nil.
+func writeCopy(ctx context.Context, c client.Client, recorder record.EventRecorder, src, cp client.Object) error {
+	kind := kindOf(cp)
+	existing := cp.DeepCopyObject().(client.Object)
+	// rewriting a copy without changing its payload, e.g. to refresh it, isn't reported on the namespace
+	changed := true
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cp), existing); err == nil {
+		preserveCopyMetadata(existing, cp)
+		if copyNeedsReplace(cp, existing) {
+			submitted := cp.DeepCopyObject().(client.Object)
+			if err := replaceCopy(ctx, c, existing, cp); err != nil {
+				return fmt.Errorf("unable to replace %s: %w", kind, err)
+			}
+			reportCopyMutations(ctx, recorder, src, submitted, cp)
+			reportSync(ctx, c, recorder, src, cp)
+			return nil
+		}
+		changed = !copyIsCurrent(cp, existing)
+		if err := clearTransientAnnotations(ctx, c, existing); err != nil {
+			return fmt.Errorf("unable to copy %s: %w", kind, err)
+		}
+	} else {
+		existing = nil
+	}
+	submitted := cp.DeepCopyObject().(client.Object)
+	if err := applyCopy(ctx, c, src, existing, cp); err != nil {
+		if existing == nil {
+			return fmt.Errorf("error copying %s %s in namespace: %s: %w", kind, cp.GetName(), cp.GetNamespace(), err)
+		}
+		return fmt.Errorf("unable to copy %s: %w", kind, err)
+	}
+	reportCopyMutations(ctx, recorder, src, submitted, cp)
+	if changed {
+		reportSync(ctx, c, recorder, src, cp)
+	}
+	return nil
+}
+
+// transientCopyAnnotations are set on copies outside of applying them, e.g. while a copy is pending deletion, and are
+// dropped once the copy is synced again
+var transientCopyAnnotations = []string{pendingDeletionKey, pruneDeferredKey, quarantineKey}
+
+// clearTransientAnnotations removes the transient annotations from the existing copy. An apply leaves them alone as
+// kopy doesn't apply them.
+func clearTransientAnnotations(ctx context.Context, c client.Client, existing client.Object) error {
+	patch := client.MergeFrom(existing.DeepCopyObject().(client.Object))
+	annotations := existing.GetAnnotations()
+	cleared := false
+	for _, k := range transientCopyAnnotations {
+		if _, ok := annotations[k]; ok {
+			delete(annotations, k)
+			cleared = true
+		}
+	}
+	if !cleared {
+		return nil
+	}
+	existing.SetAnnotations(annotations)
+	return c.Patch(ctx, existing, patch)
+}
+
+// copyNeedsReplace returns true if existing can't be updated to cp because a field that is immutable once the object
+// is created differs, i.e. the role reference of a RoleBinding
+func copyNeedsReplace(cp, existing client.Object) bool {
+	c, ok := cp.(*rbacv1.RoleBinding)
+	e, eok := existing.(*rbacv1.RoleBinding)
+	return ok && eok && c.RoleRef != e.RoleRef
+}
+
+// replaceCopy deletes existing and creates cp in its place. The kopy finalizer is removed first so the copy is gone
+// right away, the subjects of a RoleBinding lose their access until the replacement is created.
+func replaceCopy(ctx context.Context, c client.Client, existing, cp client.Object) error {
+	if removeSyncFinalizer(existing) {
+		if err := c.Update(ctx, existing); err != nil {
+			return err
+		}
+	}
+	uid := existing.GetUID()
+	if err := c.Delete(ctx, existing, client.Preconditions{UID: &uid}); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	cp.SetResourceVersion("")
+	return c.Create(ctx, cp)
+}
+
+// reportSync records an event on src and on the namespace of the copy cp that was created or updated from src, so
+// describing the source shows where and why it was copied, and the owners of the namespace see sync activity without
+// access to the namespace of the source. The namespace event is stored in the namespace itself rather than in the
+// default namespace. recorder may be nil.
+func reportSync(ctx context.Context, c client.Client, recorder record.EventRecorder, src, cp client.Object) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(src, corev1.EventTypeNormal, reasonSynced, "Synced copy %s to namespace %s %s", cp.GetName(),
+		cp.GetNamespace(), selectionReason(src, cp.GetNamespace()))
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: cp.GetNamespace()}, ns); err != nil {
+		ctrllog.FromContext(ctx).Error(err, "unable to get the namespace of the copy", "namespace", cp.GetNamespace())
+		return
+	}
+	ns.Namespace = ns.Name
+	recorder.Eventf(ns, corev1.EventTypeNormal, reasonCopySynced, "kopy synced %s %s from namespace %s",
+		kindOf(src), cp.GetName(), src.GetNamespace())
+}
+
+// selectionReason describes why src is copied to namespace for the events of its copies
+func selectionReason(src client.Object, namespace string) string {
+	if namespace == src.GetNamespace() {
+		return "as its local copy"
+	}
+	if v, ok := src.GetAnnotations()[syncKey]; ok {
+		return fmt.Sprintf("selected by %s=%q", syncKey, v)
+	}
+	return "as a pinned target"
+}
+
+// copyMutations returns the fields of the copy written that differ from the copy submitted, e.g. data.password or
+// labels.team
+func copyMutations(submitted, written client.Object) []string {
+	mutations := []string{}
+	for _, d := range diffData(objectData(submitted), objectData(written)) {
+		if d.Status != KeyUnchanged {
+			mutations = append(mutations, "data."+d.Key)
+		}
+	}
+	mutations = append(mutations, mapMutations("labels", submitted.GetLabels(), written.GetLabels())...)
+	mutations = append(mutations, mapMutations("annotations", submitted.GetAnnotations(), written.GetAnnotations())...)
+	if s, ok := submitted.(*corev1.Secret); ok && s.Type != "" && s.Type != written.(*corev1.Secret).Type {
+		mutations = append(mutations, "type")
+	}
+	if hasSyncFinalizer(submitted) && !hasSyncFinalizer(written) {
+		mutations = append(mutations, "finalizers")
+	}
+	return mutations
+}
+
+func mapMutations(field string, submitted, written map[string]string) []string {
+	mutations := []string{}
+	for k, v := range submitted {
+		if wv, ok := written[k]; !ok || wv != v {
+			mutations = append(mutations, field+"."+k)
+		}
+	}
+	for k := range written {
+		if _, ok := submitted[k]; !ok {
+			mutations = append(mutations, field+"."+k)
+		}
+	}
+	sort.Strings(mutations)
+	return mutations
+}
+
+// reportCopyMutations records the fields the API server changed on a copy of src after it was written
+func reportCopyMutations(ctx context.Context, recorder record.EventRecorder, src, submitted, written client.Object) {
+	mutations := copyMutations(submitted, written)
+	if len(mutations) == 0 {
+		return
+	}
+	copyMutationsTotal.WithLabelValues(kindOf(written)).Inc()
+	ctrllog.FromContext(ctx).Info("copy was changed by the API server, likely by a mutating webhook",
+		"name", written.GetName(), "namespace", written.GetNamespace(), "fields", mutations)
+	if recorder == nil {
+		return
+	}
+	fields := strings.Join(mutations, ", ")
+	recorder.Eventf(src, corev1.EventTypeWarning, reasonCopyMutated,
+		"copy in namespace %s was changed when it was written: %s", written.GetNamespace(), fields)
+	recorder.Eventf(written, corev1.EventTypeWarning, reasonCopyMutated,
+		"copy of %s/%s was changed when it was written: %s", src.GetNamespace(), src.GetName(), fields)
+}
+
+// releaseSource removes the kopy finalizer and origin labels from the copies of src, leaving them behind as
+// unmanaged objects, before removing the finalizer from src. It is used when src is deleted or its sync annotation
+// is removed, and records events so users can see why the finalizers were stripped. copies is an empty list of the
+// kind of src. recorder may be nil.
+func releaseSource(ctx context.Context, c client.Client, recorder record.EventRecorder, src client.Object, copies client.ObjectList) error {
+	if err := c.List(ctx, copies, listOptions(src)); err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(copies)
+	if err != nil {
+		return err
+	}
+	why := "the sync annotation was removed from the source"
+	if src.GetDeletionTimestamp() != nil {
+		why = "the source was deleted"
+	}
+	log := ctrllog.FromContext(ctx).WithValues("controller", kindOf(src))
+	errs := make([]error, 0, len(items))
+	released := 0
+	for _, item := range items {
+		cp, ok := item.(client.Object)
+		if !ok || !isCopyOf(cp, src) || !hasSyncFinalizer(cp) {
+			continue
+		}
+		log.Info("need to remove finalizer from copy", "name", cp.GetName(), "namespace", cp.GetNamespace())
+		removeSyncFinalizer(cp)
+		labels := cp.GetLabels()
+		delete(labels, sourceLabelNamespace)
+		delete(labels, sourceLabelName)
+		cp.SetLabels(labels)
+		log.Info("remove labels from copy", "name", cp.GetName(), "namespace", cp.GetNamespace())
+		if err := c.Update(ctx, cp); err != nil {
+			log.Info("unable to remove finalizer from copy in namespace " + cp.GetNamespace())
+			errs = append(errs, fmt.Errorf("unable to remove finalizer from copy in namespace %s", cp.GetNamespace()))
+			continue
+		}
+		released++
+		if recorder != nil {
+			recorder.Eventf(cp, corev1.EventTypeNormal, reasonCopyReleased,
+				"kopy finalizer removed because %s %s/%s", why, src.GetNamespace(), src.GetName())
+			if src.GetDeletionTimestamp() == nil {
+				recorder.Eventf(src, corev1.EventTypeNormal, reasonFinalizerRemoved,
+					"Removed the finalizer of copy %s in namespace %s because %s", cp.GetName(), cp.GetNamespace(), why)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if recorder != nil && src.GetDeletionTimestamp() == nil {
+		recorder.Eventf(src, corev1.EventTypeNormal, reasonSyncDisabled,
+			"released %d copies because %s", released, why)
+	}
+	log.Info("removing finalizer from source", "name", src.GetName())
+	removeSyncFinalizer(src)
+	return c.Update(ctx, src)
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Namespace sync events\n", func() {
+	const target = "test-dst-nsevent-ns-00"
+	src := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-src-nsevent-00", Namespace: "test-src-nsevent-ns-00"},
+		Data:       map[string][]byte{"password": []byte("test-src-nsevent-00")},
+	}
+	It("Should record an event on the target namespace when a copy is created or changed", func() {
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).
+			WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target}}).Build()
+		recorder := record.NewFakeRecorder(4)
+		write := func() {
+			cp, err := newCopy(src, target, Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(writeCopy(context.Background(), c, recorder, src, cp)).Should(Succeed())
+		}
+
+		write()
+		Expect(recorder.Events).Should(Receive(Equal(
+			"Normal Synced Synced copy test-src-nsevent-00 to namespace test-dst-nsevent-ns-00 as a pinned target")))
+		Expect(recorder.Events).Should(Receive(Equal(
+			"Normal CopySynced kopy synced secret test-src-nsevent-00 from namespace test-src-nsevent-ns-00")))
+
+		// rewriting an unchanged copy isn't reported
+		write()
+		Expect(recorder.Events).ShouldNot(Receive())
+
+		src.Data = map[string][]byte{"password": []byte("rotated")}
+		write()
+		Expect(recorder.Events).Should(Receive(ContainSubstring(reasonSynced)))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(reasonCopySynced)))
+	})
+})
+
+var _ = Describe("Source sync events\n", func() {
+	const (
+		namespace = "test-src-srcevent-ns-00"
+		target    = "test-dst-srcevent-ns-00"
+	)
+	var (
+		ctx      context.Context
+		c        client.Client
+		src      *corev1.Secret
+		recorder *record.FakeRecorder
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		src = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-srcevent-00", Namespace: namespace,
+				Annotations: map[string]string{syncKey: "team=payments"},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		recorder = record.NewFakeRecorder(20)
+	})
+	build := func(funcs interceptor.Funcs) {
+		// copies are applied through funcs
+		c = interceptor.NewClient(fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithInterceptorFuncs(funcs).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).Build(), interceptor.Funcs{Patch: fakeApply})
+	}
+	reconcile := func() {
+		_, _ = KopyReconcile(NewKopySecret(ctx, c, Options{}, recorder), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+	}
+	events := func() []string {
+		result := []string{}
+		for len(recorder.Events) > 0 {
+			result = append(result, <-recorder.Events)
+		}
+		return result
+	}
+
+	It("Should record where and why a source was copied and when a copy is deleted or released", func() {
+		build(interceptor.Funcs{})
+		reconcile()
+		Expect(events()).Should(ContainElement(
+			`Normal Synced Synced copy test-src-srcevent-00 to namespace test-dst-srcevent-ns-00 selected by ` +
+				syncKey + `="team=payments"`))
+
+		ns := &corev1.Namespace{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: target}, ns)).Should(Succeed())
+		ns.Labels = nil
+		Expect(c.Update(ctx, ns)).Should(Succeed())
+		reconcile()
+		Expect(events()).Should(ContainElement(
+			"Normal CopyDeleted Deleted copy test-src-srcevent-00 from namespace test-dst-srcevent-ns-00, the namespace is no longer selected"))
+
+		ns.Labels = map[string]string{"team": "payments"}
+		Expect(c.Update(ctx, ns)).Should(Succeed())
+		reconcile()
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		delete(src.Annotations, syncKey)
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(events()).Should(ContainElement(And(
+			HavePrefix("Normal FinalizerRemoved Removed the finalizer of copy test-src-srcevent-00 in namespace test-dst-srcevent-ns-00"),
+			ContainSubstring("sync annotation was removed"),
+		)))
+	})
+
+	It("Should record copies that couldn't be written", func() {
+		build(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.CreateOption) error {
+				if o.GetNamespace() == target {
+					return errors.New("admission denied")
+				}
+				return c.Create(ctx, o, opts...)
+			},
+		})
+		reconcile()
+		Expect(events()).Should(ContainElement(And(
+			HavePrefix("Warning SyncFailed Failed to sync copy to namespace test-dst-srcevent-ns-00"),
+			ContainSubstring("admission denied"),
+		)))
+	})
+})
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Mutated copies\n", func() {
+	src := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-src-mutated-00", Namespace: "test-src-mutated-ns-00"},
+		Data:       map[string][]byte{"password": []byte("test-src-mutated-00")},
+	}
+	newCopyFor := func() client.Object {
+		cp, err := newCopy(src, "test-target-mutated-ns-00", Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		return cp
+	}
+	DescribeTable("Finding the fields that were changed",
+		func(mutate func(cp *corev1.Secret), expected []string) {
+			submitted := newCopyFor()
+			written := submitted.DeepCopyObject().(*corev1.Secret)
+			mutate(written)
+			Expect(copyMutations(submitted, written)).Should(Equal(expected))
+		},
+		Entry("unchanged", func(cp *corev1.Secret) {}, []string{}),
+		Entry("changed data", func(cp *corev1.Secret) { cp.Data["password"] = []byte("injected") }, []string{"data.password"}),
+		Entry("added label", func(cp *corev1.Secret) { cp.Labels["team"] = "a" }, []string{"labels.team"}),
+		Entry("removed finalizer", func(cp *corev1.Secret) { cp.Finalizers = nil }, []string{"finalizers"}),
+	)
+	It("Should report copies changed by a mutating webhook", func() {
+		c := interceptor.NewClient(fake.NewClientBuilder().
+			WithScheme(clientgoscheme.Scheme).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.CreateOption) error {
+					o.SetAnnotations(map[string]string{"sidecar.example.com/injected": "true"})
+					return c.Create(ctx, o, opts...)
+				},
+			}).
+			Build(), interceptor.Funcs{Patch: fakeApply})
+		recorder := record.NewFakeRecorder(3)
+		Expect(writeCopy(context.Background(), c, recorder, src, newCopyFor())).Should(Succeed())
+		Expect(recorder.Events).Should(HaveLen(3))
+		Expect(<-recorder.Events).Should(ContainSubstring("annotations.sidecar.example.com/injected"))
+	})
+})
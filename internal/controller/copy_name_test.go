@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testCopyNameSnapshot = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-src-copyname-ns-00
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-copyname-ns-00
+    labels:
+      env: prod
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-copyname-ns-01
+    labels:
+      env: prod
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: test-src-copyname-00
+    namespace: test-src-copyname-ns-00
+    annotations:
+      kopy.kot-labs.com/sync: env=prod
+    finalizers:
+    - kopy.kot-labs.com/finalizer
+  data:
+    password: dGVzdC1zcmMtY29weW5hbWUtMDA=
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: test-src-copyname-00
+    namespace: test-target-copyname-ns-00
+  data:
+    password: dGVuYW50
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: test-src-copyname-00
+    namespace: test-target-copyname-ns-01
+    labels:
+      kopy.kot-labs.com/origin.namespace: test-src-copyname-ns-00
+      kopy.kot-labs.com/origin.name: test-src-copyname-00
+    finalizers:
+    - kopy.kot-labs.com/finalizer
+  data:
+    password: dGVzdC1zcmMtY29weW5hbWUtMDA=
+`
+
+var _ = Describe("Copy name suffix\n", func() {
+	Context("When copies are created with a name suffix", func() {
+		It("Should leave tenant objects alone and rename existing copies", func() {
+			objects, err := LoadSnapshot(strings.NewReader(testCopyNameSnapshot))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			result, err := Simulate(context.Background(), objects, Options{CopyNameSuffix: "-kopy"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.Errors).Should(BeEmpty())
+			Expect(result.Actions).Should(ContainElements(
+				SimulatedAction{Verb: "create", Kind: "secret", Namespace: "test-target-copyname-ns-00", Name: "test-src-copyname-00-kopy"},
+				SimulatedAction{Verb: "create", Kind: "secret", Namespace: "test-target-copyname-ns-01", Name: "test-src-copyname-00-kopy"},
+				SimulatedAction{Verb: "delete", Kind: "secret", Namespace: "test-target-copyname-ns-01", Name: "test-src-copyname-00"},
+			))
+			Expect(result.Actions).ShouldNot(ContainElement(
+				SimulatedAction{Verb: "update", Kind: "secret", Namespace: "test-target-copyname-ns-00", Name: "test-src-copyname-00"},
+			))
+		})
+	})
+})
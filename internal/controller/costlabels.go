@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ParseCostLabels parses the value of the --cost-labels flag, a comma separated list of namespace labels whose values
+// are stamped on the copies in the namespace. An entry <copy label>=<namespace label> stamps the value under another
+// key. It returns the namespace label of every copy label.
+func ParseCostLabels(v string) (map[string]string, error) {
+	costLabels := map[string]string{}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, namespaceLabel, ok := strings.Cut(entry, "=")
+		if !ok {
+			namespaceLabel = key
+		}
+		key, namespaceLabel = strings.TrimSpace(key), strings.TrimSpace(namespaceLabel)
+		for _, k := range []string{key, namespaceLabel} {
+			if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid cost label %q: %s", k, strings.Join(errs, ", "))
+			}
+		}
+		if strings.HasPrefix(key, kopyPrefix) {
+			return nil, fmt.Errorf("invalid cost label %q: labels with the prefix %s are reserved for kopy", key, kopyPrefix)
+		}
+		costLabels[key] = namespaceLabel
+	}
+	return costLabels, nil
+}
+
+// costLabels returns the cost attribution labels of the copies in ns, the namespace labels of Options.CostLabels it
+// has under their copy label
+func (o Options) costLabels(ns *corev1.Namespace) map[string]string {
+	labels := map[string]string{}
+	for key, namespaceLabel := range o.CostLabels {
+		if v, ok := ns.Labels[namespaceLabel]; ok {
+			labels[key] = v
+		}
+	}
+	return labels
+}
+
+// costLabelsOf reads namespace and returns the cost attribution labels of the copies in it
+func (o Options) costLabelsOf(ctx context.Context, c client.Reader, namespace string) (map[string]string, error) {
+	if len(o.CostLabels) == 0 {
+		return nil, nil
+	}
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return nil, fmt.Errorf("unable to read the cost labels of namespace %s: %w", namespace, err)
+	}
+	return o.costLabels(ns), nil
+}
+
+// stampCostLabels adds the cost attribution labels to cp. The labels kopy sets to track the copy are never replaced.
+func stampCostLabels(cp client.Object, costLabels map[string]string) {
+	if len(costLabels) == 0 {
+		return
+	}
+	labels := cp.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range costLabels {
+		if _, ok := labels[k]; !ok {
+			labels[k] = v
+		}
+	}
+	cp.SetLabels(labels)
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Cost labels\n", func() {
+	const (
+		namespace = "test-src-cost-ns-00"
+		target    = "test-dst-cost-ns-00"
+	)
+
+	It("Should stamp copies with the cost labels of their namespace", func() {
+		ctx := context.Background()
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-cost-00", Namespace: namespace,
+				Annotations: map[string]string{syncKey: "team=payments"},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{
+			"team": "payments", "finance.example.com/cost-center": "cc-42",
+		}}}
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src, ns, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+		).Build()
+		costLabels, err := ParseCostLabels("team, cost-center=finance.example.com/cost-center, owner")
+		Expect(err).ShouldNot(HaveOccurred())
+		reconcile := func() *corev1.Secret {
+			_, err := KopyReconcile(NewKopySecret(ctx, c, Options{CostLabels: costLabels}, record.NewFakeRecorder(10)),
+				ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			cp := &corev1.Secret{}
+			Expect(c.Get(ctx, types.NamespacedName{Namespace: target, Name: src.Name}, cp)).Should(Succeed())
+			return cp
+		}
+
+		cp := reconcile()
+		Expect(cp.Labels).Should(HaveKeyWithValue("team", "payments"))
+		Expect(cp.Labels).Should(HaveKeyWithValue("cost-center", "cc-42"))
+		Expect(cp.Labels).ShouldNot(HaveKey("owner"))
+		Expect(cp.Labels).Should(HaveKeyWithValue(sourceLabelNamespace, namespace))
+
+		// a cost label removed from the namespace is removed from the copy with the next sync
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(ns), ns)).Should(Succeed())
+		delete(ns.Labels, "finance.example.com/cost-center")
+		Expect(c.Update(ctx, ns)).Should(Succeed())
+		cp = reconcile()
+		Expect(cp.Labels).Should(HaveKeyWithValue("team", "payments"))
+		Expect(cp.Labels).ShouldNot(HaveKey("cost-center"))
+	})
+
+	DescribeTable("Parsing cost labels",
+		func(v string, expected map[string]string, valid bool) {
+			costLabels, err := ParseCostLabels(v)
+			if !valid {
+				Expect(err).Should(HaveOccurred())
+				return
+			}
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(costLabels).Should(Equal(expected))
+		},
+		Entry("empty", "", map[string]string{}, true),
+		Entry("namespace labels", "team,cost-center", map[string]string{"team": "team", "cost-center": "cost-center"}, true),
+		Entry("renamed label", "billing/team=team", map[string]string{"billing/team": "team"}, true),
+		Entry("invalid label", "team=", nil, false),
+		Entry("kopy label", kopyPrefix+"team=team", nil, false),
+	)
+})
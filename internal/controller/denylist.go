@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultDeniedCopyNames are the names of objects that Kubernetes or a distribution manages in every namespace. A
+// copy of the same name would overwrite them, e.g. the CA bundle every pod mounts or the default service account.
+var DefaultDeniedCopyNames = []string{
+	"configmap/kube-root-ca.crt",
+	"configmap/openshift-service-ca.crt",
+	"secret/default-token-*",
+	"serviceaccount/default",
+	"role/system:*",
+	"rolebinding/system:*",
+}
+
+// errCopyDenied is returned when the name of a copy is on the deny list
+var errCopyDenied = errors.New("copy name denied")
+
+// deniedCopyNames returns the deny list of copy names, the defaults unless Options.DeniedCopyNames is set
+func (o Options) deniedCopyNames() []string {
+	if o.DeniedCopyNames == nil {
+		return DefaultDeniedCopyNames
+	}
+	return o.DeniedCopyNames
+}
+
+// copyDenied returns the pattern of the deny list that the copy of kind named name matches. Patterns are a glob
+// for the name, optionally prefixed by the kind name and a slash, e.g. secret/default-token-* or kube-root-ca.crt.
+func (o Options) copyDenied(kind, name string) (string, bool) {
+	for _, pattern := range o.deniedCopyNames() {
+		namePattern := pattern
+		if k, n, ok := strings.Cut(pattern, "/"); ok {
+			if !strings.EqualFold(k, kind) {
+				continue
+			}
+			namePattern = n
+		}
+		if matched, _ := path.Match(namePattern, name); matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// checkCopyDenied returns errCopyDenied if the copy of kind named name is on the deny list
+func (o Options) checkCopyDenied(kind, name, namespace string) error {
+	if pattern, ok := o.copyDenied(kind, name); ok {
+		return fmt.Errorf("%w: %s %s/%s matches %q and is never overwritten", errCopyDenied, kind, namespace, name, pattern)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Denied copy names\n", func() {
+	DescribeTable("Matching the deny list",
+		func(opts Options, kind, name string, denied bool) {
+			_, ok := opts.copyDenied(kind, name)
+			Expect(ok).Should(Equal(denied))
+		},
+		Entry("root CA bundle", Options{}, "configmap", "kube-root-ca.crt", true),
+		Entry("root CA bundle of another kind", Options{}, "secret", "kube-root-ca.crt", false),
+		Entry("service account token", Options{}, "secret", "default-token-x7k2p", true),
+		Entry("default service account", Options{}, "serviceaccount", "default", true),
+		Entry("system role binding", Options{}, "rolebinding", "system:image-pullers", true),
+		Entry("tenant config", Options{}, "configmap", "app-config", false),
+		Entry("pattern without kind", Options{DeniedCopyNames: []string{"legacy-*"}}, "secret", "legacy-db", true),
+		Entry("overridden defaults", Options{DeniedCopyNames: []string{"legacy-*"}}, "configmap", "kube-root-ca.crt", false),
+		Entry("empty deny list", Options{DeniedCopyNames: []string{}}, "serviceaccount", "default", false),
+	)
+
+	It("Should never overwrite a denied object in the target namespace", func() {
+		ctx := context.Background()
+		const (
+			namespace = "test-src-deny-ns-00"
+			target    = "test-dst-deny-ns-00"
+		)
+		src := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: namespace,
+				Annotations: map[string]string{syncKey: "team=payments"}},
+			Data: map[string]string{"ca.crt": "forged"},
+		}
+		system := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: target},
+			Data:       map[string]string{"ca.crt": "cluster CA"},
+		}
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, system).Build()
+
+		err := NewKopyConfigMap(ctx, c, Options{}, nil).SyncSource(src.Name, namespace, target)
+		Expect(err).Should(MatchError(errCopyDenied))
+		Expect(isPermanentSyncError(err)).Should(BeTrue())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(system), system)).Should(Succeed())
+		Expect(system.Data).Should(HaveKeyWithValue("ca.crt", "cluster CA"))
+
+		By("Writing it once the deny list is emptied")
+		Expect(NewKopyConfigMap(ctx, c, Options{DeniedCopyNames: []string{}}, nil).SyncSource(src.Name, namespace, target)).Should(Succeed())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(system), system)).Should(Succeed())
+		Expect(system.Data).Should(HaveKeyWithValue("ca.crt", "forged"))
+	})
+})
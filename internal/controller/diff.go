@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// KeyDiff describes how a single data key differs between a source and its copy. Values are never included so
+// the diff is safe to print for Secrets.
+type KeyDiff struct {
+	Key        string `json:"key"`
+	Status     string `json:"status"`
+	SourceSize int    `json:"sourceSize"`
+	CopySize   int    `json:"copySize"`
+}
+
+const (
+	KeyUnchanged = "unchanged"
+	KeyChanged   = "changed"
+	KeyMissing   = "missing"
+	KeyExtra     = "extra"
+)
+
+// DiffCopy compares the data keys of the source object with the copy of it in targetNamespace
+func DiffCopy(ctx context.Context, c client.Client, kind string, source types.NamespacedName, targetNamespace string) ([]KeyDiff, error) {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Get(ctx, source, src); err != nil {
+		return nil, err
+	}
+	cp, err := findCopy(ctx, c, src, targetNamespace)
+	if err != nil {
+		return nil, err
+	}
+	return diffData(objectData(src), objectData(cp)), nil
+}
+
+// findCopy returns the copy of src in targetNamespace. Copies are looked up by their origin labels first because
+// their name has a suffix when the controller runs with a copy name suffix.
+func findCopy(ctx context.Context, c client.Client, src client.Object, targetNamespace string) (client.Object, error) {
+	kind := kindOf(src)
+	list, err := newObjectListForKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	err = c.List(ctx, list, client.InNamespace(targetNamespace), client.MatchingLabels{
+		sourceLabelNamespace: src.GetNamespace(),
+		sourceLabelName:      src.GetName(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) > 0 {
+		return items[0].(client.Object), nil
+	}
+	cp, _ := NewObjectForKind(kind)
+	if err := c.Get(ctx, types.NamespacedName{Namespace: targetNamespace, Name: src.GetName()}, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func diffData(src, cp map[string][]byte) []KeyDiff {
+	diffs := make([]KeyDiff, 0, len(src)+len(cp))
+	for k, v := range src {
+		d := KeyDiff{Key: k, SourceSize: len(v), Status: KeyMissing}
+		if cv, ok := cp[k]; ok {
+			d.CopySize = len(cv)
+			d.Status = KeyChanged
+			if bytes.Equal(v, cv) {
+				d.Status = KeyUnchanged
+			}
+		}
+		diffs = append(diffs, d)
+	}
+	for k, v := range cp {
+		if _, ok := src[k]; !ok {
+			diffs = append(diffs, KeyDiff{Key: k, CopySize: len(v), Status: KeyExtra})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Key < diffs[j].Key })
+	return diffs
+}
+
+// objectData returns the data payload of a Secret or ConfigMap keyed by data key. The payload of other kinds is keyed
+// by their top level fields, e.g. spec, imagePullSecrets or rules, with the JSON encoding of the field as value.
+func objectData(o client.Object) map[string][]byte {
+	if k, ok := kindForObject(o); ok {
+		if data, ok := k.dataOf(o); ok && data != nil {
+			return data
+		}
+	}
+	return map[string][]byte{}
+}
@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Results of a single check in an Explanation
+const (
+	ExplainPass = "pass"
+	ExplainFail = "fail"
+	ExplainInfo = "info"
+)
+
+// ExplainCheck is one of the decisions kopy makes about copying a source to a namespace
+type ExplainCheck struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Detail string `json:"detail"`
+}
+
+// Explanation describes why a namespace does or doesn't receive a copy of a source
+type Explanation struct {
+	Kind      string         `json:"kind"`
+	Source    string         `json:"source"`
+	Namespace string         `json:"namespace"`
+	Checks    []ExplainCheck `json:"checks"`
+	Receives  bool           `json:"receives"`
+	// Copy is the state of the copy in the namespace, e.g. missing, current or stale
+	Copy string `json:"copy"`
+}
+
+func (e *Explanation) check(name string, passed bool, format string, args ...any) bool {
+	result := ExplainPass
+	if !passed {
+		result = ExplainFail
+	}
+	e.Checks = append(e.Checks, ExplainCheck{Name: name, Result: result, Detail: fmt.Sprintf(format, args...)})
+	return passed
+}
+
+func (e *Explanation) info(name, format string, args ...any) {
+	e.Checks = append(e.Checks, ExplainCheck{Name: name, Result: ExplainInfo, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Explain runs the checks that decide whether the source of kind identified by source is copied to namespace and
+// reports the result of each of them, as the controller configured with opts would decide
+func Explain(ctx context.Context, c client.Client, kind string, source types.NamespacedName, namespace string, opts Options) (*Explanation, error) {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Get(ctx, source, src); err != nil {
+		return nil, err
+	}
+	e := &Explanation{Kind: kindOf(src), Source: source.String(), Namespace: namespace}
+	deleting := src.GetDeletionTimestamp() != nil
+	detail := "exists"
+	if deleting {
+		detail = "is being deleted, its copies are removed"
+	}
+	receives := e.check("source", !deleting, "%s", detail)
+
+	pinned := slices.Contains(opts.pinnedNamespaces(src), namespace)
+	localName, local := opts.localCopyName(src)
+	local = local && namespace == source.Namespace
+	v, annotated := SyncSelector(src)
+	switch {
+	case annotated:
+		e.check("sync annotation", true, "%s=%q", syncKey, v)
+	case pinned:
+		e.info("sync annotation", "%s is not set, the source is only copied to its pinned namespaces", syncKey)
+	default:
+		receives = e.check("sync annotation", false, "%s is not set, the source isn't synced", syncKey) && receives
+	}
+	var ls labels.Selector
+	if annotated {
+		format, _ := DetectSyncFormat(v)
+		if ls, err = ParseSyncSelector(v); err != nil {
+			e.check("selector", false, "%v", err)
+		} else {
+			e.check("selector", true, "%s format, parsed as %q", format, ls.String())
+		}
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		e.check("namespace", false, "not found")
+		e.Copy = "missing"
+		return e, nil
+	}
+	switch {
+	case ns.DeletionTimestamp != nil:
+		receives = e.check("namespace", false, "is terminating, copies aren't written to terminating namespaces") && receives
+	case namespace == source.Namespace && !local:
+		receives = e.check("namespace", false, "is the namespace of the source") && receives
+	default:
+		e.check("namespace", true, "exists")
+	}
+	if vcluster, ok := ns.Labels[vclusterManagedByLabel]; ok {
+		switch opts.VClusterMode {
+		case VClusterSkip:
+			receives = e.check("vcluster", false, "managed by virtual cluster %s, which kopy skips", vcluster) && receives
+		case VClusterTranslate:
+			e.info("vcluster", "managed by virtual cluster %s, the copy name is translated", vcluster)
+		}
+	}
+	if opts.NamespaceScoped() && !slices.Contains(opts.Namespaces, namespace) {
+		receives = e.check("scope", false, "kopy is scoped to %s", strings.Join(opts.Namespaces, ", ")) && receives
+	}
+
+	nsLabels := labels.Set(ns.Labels)
+	if len(nsLabels) == 0 {
+		e.info("labels", "none")
+	} else {
+		e.info("labels", "%s", nsLabels.String())
+	}
+	selected := false
+	if ls != nil {
+		reqs, _ := ls.Requirements()
+		for _, r := range reqs {
+			e.check("requirement", r.Matches(nsLabels), "%s: %s", r.String(), explainRequirement(r, nsLabels))
+		}
+		selected = ls.Matches(nsLabels)
+	}
+	if selected && hasTopology(src) {
+		if mismatch := topologyMismatch(src, ns); mismatch != "" {
+			selected = e.check("topology", false, "%s", mismatch)
+		} else {
+			e.check("topology", true, "the namespace is in the regions and zones of the source")
+		}
+	}
+	if len(opts.PinnedTargets) > 0 {
+		if pinned {
+			e.check("pinned", true, "the source is pinned to %s", namespace)
+		} else {
+			e.info("pinned", "the source isn't pinned to %s", namespace)
+		}
+	}
+	switch {
+	case local:
+		e.check("selected", true, "the source keeps a local copy named %s in its own namespace", localName)
+	case selected:
+		e.check("selected", true, "the selector matches the labels of the namespace")
+	case pinned:
+		e.check("selected", true, "the namespace is pinned")
+	default:
+		receives = e.check("selected", false, "the namespace isn't selected") && receives
+	}
+	e.Receives = receives
+
+	if delay, err := syncWindowDelay(src, now()); err != nil {
+		e.check("sync window", false, "%v", err)
+	} else if delay > 0 {
+		e.info("sync window", "closed, changes propagate in %s", delay.Round(time.Minute))
+	}
+	name := opts.copyNameFor(src, namespace)
+	if target, ok := mergeTarget(src); ok {
+		e.info("merge", "the keys of the source are merged into %s with the other sources of that name", target)
+		name = target
+	}
+	if pattern, ok := opts.copyDenied(kindOf(src), name); ok {
+		e.Receives = e.check("deny list", false, "the copy name matches %q, kopy never overwrites it", pattern) && e.Receives
+	}
+	cp, _ := NewObjectForKind(kind)
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cp); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		e.Copy = "missing"
+		return e, nil
+	}
+	e.Copy = explainCopy(opts, src, cp)
+	return e, nil
+}
+
+// explainRequirement describes the namespace label a requirement of the sync selector looked at
+func explainRequirement(r labels.Requirement, nsLabels labels.Set) string {
+	v, ok := nsLabels[r.Key()]
+	found := fmt.Sprintf("found %s=%s", r.Key(), v)
+	if !ok {
+		found = fmt.Sprintf("%s is not set", r.Key())
+	}
+	if r.Matches(nsLabels) {
+		return found
+	}
+	switch r.Operator() {
+	case selection.NotIn, selection.NotEquals, selection.DoesNotExist:
+		return "denied, " + found
+	}
+	return found
+}
+
+// explainCopy describes the object in the place of the copy of src
+func explainCopy(opts Options, src, cp client.Object) string {
+	if _, ok := mergeTarget(src); ok {
+		if cp.GetLabels()[mergedLabel] != "true" {
+			return "conflict, an object kopy didn't merge has the name of the merged object"
+		}
+		return "merged from " + cp.GetAnnotations()[mergedFromKey]
+	}
+	if isHNCPropagated(cp) {
+		return "propagated by HNC, kopy leaves it alone"
+	}
+	origin, _, ok := opts.copyOrigin(cp)
+	switch {
+	case !ok:
+		return "exists without kopy labels and is overwritten"
+	case origin != src.GetNamespace():
+		return fmt.Sprintf("conflict, the copy belongs to a source in namespace %s", origin)
+	case copyIsCurrent(src, cp):
+		return "current"
+	}
+	return "stale"
+}
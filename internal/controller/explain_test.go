@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Explain\n", func() {
+	const target = "test-dst-explain-ns-00"
+	source := types.NamespacedName{Namespace: "test-src-explain-ns-00", Name: "test-src-explain-00"}
+	newClient := func(selector string, nsLabels map[string]string, objects ...client.Object) client.Client {
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: source.Namespace}}
+		if selector != "" {
+			SetSyncSelector(src, selector)
+		}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: nsLabels}}
+		return fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(append(objects, src, ns)...).Build()
+	}
+	results := func(e *Explanation) map[string]string {
+		r := map[string]string{}
+		for _, check := range e.Checks {
+			r[check.Name] = check.Result
+		}
+		return r
+	}
+	It("Should explain a selected namespace", func() {
+		c := newClient("env=prod", map[string]string{"env": "prod"})
+		e, err := Explain(context.Background(), c, "secret", source, target, Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(e.Receives).Should(BeTrue())
+		Expect(e.Copy).Should(Equal("missing"))
+		Expect(results(e)).Should(HaveKeyWithValue("requirement", ExplainPass))
+	})
+	It("Should name the requirement that denies a namespace", func() {
+		c := newClient("env=prod,kubernetes.io/metadata.name notin ("+target+")", map[string]string{
+			"env": "prod", corev1.LabelMetadataName: target,
+		})
+		e, err := Explain(context.Background(), c, "secret", source, target, Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(e.Receives).Should(BeFalse())
+		Expect(e.Checks).Should(ContainElement(And(
+			HaveField("Result", ExplainFail),
+			HaveField("Detail", ContainSubstring("denied, found "+corev1.LabelMetadataName+"="+target)),
+		)))
+	})
+	It("Should report a selector that doesn't parse", func() {
+		c := newClient("app in (", map[string]string{"app": "web"})
+		e, err := Explain(context.Background(), c, "secret", source, target, Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(e.Receives).Should(BeFalse())
+		Expect(results(e)).Should(HaveKeyWithValue("selector", ExplainFail))
+	})
+	It("Should explain pinned namespaces and the state of the copy", func() {
+		cp := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: target, Labels: map[string]string{
+			sourceLabelNamespace: "test-src-explain-ns-01",
+		}}}
+		c := newClient("", nil, cp)
+		opts := Options{PinnedTargets: []PinnedTarget{{Kind: "secret", Source: source.String(), Namespaces: []string{target}}}}
+		e, err := Explain(context.Background(), c, "secret", source, target, opts)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(e.Receives).Should(BeTrue())
+		Expect(results(e)).Should(HaveKeyWithValue("pinned", ExplainPass))
+		Expect(e.Copy).Should(ContainSubstring("conflict"))
+	})
+})
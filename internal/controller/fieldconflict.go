@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// fieldOwner is the field manager kopy applies copies as
+	fieldOwner = "kopy"
+	// conditionFieldManagerConflict is the condition of a KopyReport that is true while the fields of other field
+	// managers conflict with the copies of the source
+	conditionFieldManagerConflict = "FieldManagerConflict"
+)
+
+// fieldConflicts holds the field managers whose fields conflicted with the copies kopy applied, so they can be reported on
+// the KopyReport of the source. Like debugState it is shared by the controllers of a manager.
+var fieldConflicts = newFieldConflictTracker()
+
+// fieldConflictTracker records the other field managers of copies by source and target namespace
+type fieldConflictTracker struct {
+	mu       sync.Mutex
+	managers map[string]map[string][]string
+}
+
+func newFieldConflictTracker() *fieldConflictTracker {
+	return &fieldConflictTracker{managers: map[string]map[string][]string{}}
+}
+
+// fieldConflictKey identifies src in the tracker
+func fieldConflictKey(src client.Object) string {
+	return kindOf(src) + "/" + client.ObjectKeyFromObject(src).String()
+}
+
+// record sets the managers that changed the copy of src in namespace, none clears the conflict of the namespace
+func (t *fieldConflictTracker) record(src client.Object, namespace string, managers []string) {
+	key := fieldConflictKey(src)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(managers) == 0 {
+		delete(t.managers[key], namespace)
+		if len(t.managers[key]) == 0 {
+			delete(t.managers, key)
+		}
+		return
+	}
+	if t.managers[key] == nil {
+		t.managers[key] = map[string][]string{}
+	}
+	t.managers[key][namespace] = managers
+}
+
+// conflicts returns the managers that changed the copies of src by target namespace
+func (t *fieldConflictTracker) conflicts(src client.Object) map[string][]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conflicts := map[string][]string{}
+	for namespace, managers := range t.managers[fieldConflictKey(src)] {
+		conflicts[namespace] = managers
+	}
+	return conflicts
+}
+
+// forget drops the conflicts of src once it is no longer synced
+func (t *fieldConflictTracker) forget(src client.Object) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.managers, fieldConflictKey(src))
+}
+
+// applyCopy writes cp with a server-side apply as kopy. An apply that conflicts with fields another field manager
+// changed since kopy applied the copy is retried with the ownership of the fields forced, after the managers named by
+// the conflict are recorded for the KopyReport of src. existing is the copy in the cluster or nil. Copies kopy wrote
+// before it applied them are managed by the field managers kopy updated them as, their conflicts are taken over
+// without reporting them.
+func applyCopy(ctx context.Context, c client.Client, src, existing, cp client.Object) error {
+	gvk, err := apiutil.GVKForObject(cp, c.Scheme())
+	if err != nil {
+		return err
+	}
+	legacy := isLegacyCopy(existing, src)
+	if existing != nil && !appliedByKopy(existing) {
+		if err := takeOverFields(ctx, c, existing); err != nil {
+			return err
+		}
+	}
+	cp.GetObjectKind().SetGroupVersionKind(gvk)
+	cp.SetResourceVersion("")
+	cp.SetManagedFields(nil)
+	err = c.Patch(ctx, cp, client.Apply, client.FieldOwner(fieldOwner))
+	managers := conflictingManagers(err)
+	if len(managers) == 0 {
+		if err == nil {
+			fieldConflicts.record(src, cp.GetNamespace(), nil)
+		}
+		return err
+	}
+	if legacy {
+		fieldConflicts.record(src, cp.GetNamespace(), nil)
+	} else {
+		for _, manager := range managers {
+			fieldManagerConflicts.WithLabelValues(kindOf(src), manager).Inc()
+		}
+		fieldConflicts.record(src, cp.GetNamespace(), managers)
+	}
+	return c.Patch(ctx, cp, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership)
+}
+
+// conflictingManagers returns the sorted field managers named by the causes of the conflict err, an apply that
+// failed with any other error has none
+func conflictingManagers(err error) []string {
+	var status apierrors.APIStatus
+	if !apierrors.IsConflict(err) || !errors.As(err, &status) || status.Status().Details == nil {
+		return nil
+	}
+	managers := sets.New[string]()
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		// the message is `conflict with "<manager>"`, followed by the API version of managers that updated the field
+		quoted, err := strconv.QuotedPrefix(strings.TrimPrefix(cause.Message, "conflict with "))
+		if err != nil {
+			continue
+		}
+		if manager, err := strconv.Unquote(quoted); err == nil {
+			managers.Insert(manager)
+		}
+	}
+	return sets.List(managers)
+}
+
+// isLegacyCopy returns true if existing is a copy of src, going by its origin labels, that kopy never applied
+func isLegacyCopy(existing, src client.Object) bool {
+	return existing != nil && isCopyOf(existing, src) && !appliedByKopy(existing)
+}
+
+// appliedByKopy returns true if kopy applied obj before
+func appliedByKopy(obj client.Object) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == fieldOwner && entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
+
+// takeOverFields moves the fields of existing that were written with updates, by kopy before it applied copies or by
+// the owner of an object SyncSource overwrites, to the apply manager of kopy. An apply only removes the fields it
+// leaves out if no other manager owns them, so e.g. a key removed from the source would stay in the copy otherwise.
+func takeOverFields(ctx context.Context, c client.Client, existing client.Object) error {
+	managers := sets.New[string]()
+	for _, entry := range existing.GetManagedFields() {
+		if entry.Operation == metav1.ManagedFieldsOperationUpdate && entry.Subresource == "" {
+			managers.Insert(entry.Manager)
+		}
+	}
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(existing, managers, fieldOwner)
+	if err != nil || patch == nil {
+		return err
+	}
+	return c.Patch(ctx, existing, client.RawPatch(types.JSONPatchType, patch))
+}
+
+// fieldConflictCondition returns the FieldManagerConflict condition of a KopyReport for the conflicts by namespace
+func fieldConflictCondition(conflicts map[string][]string) metav1.Condition {
+	if len(conflicts) == 0 {
+		return metav1.Condition{Type: conditionFieldManagerConflict, Status: metav1.ConditionFalse, Reason: "NoConflicts",
+			Message: "no other field manager changed the copies"}
+	}
+	namespaces := make([]string, 0, len(conflicts))
+	for namespace := range conflicts {
+		namespaces = append(namespaces, namespace)
+	}
+	slices.Sort(namespaces)
+	parts := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		parts = append(parts, fmt.Sprintf("%s: %s", namespace, strings.Join(conflicts[namespace], ", ")))
+	}
+	return metav1.Condition{Type: conditionFieldManagerConflict, Status: metav1.ConditionTrue, Reason: "ConflictingManagers",
+		Message: "other field managers changed the copies, " + strings.Join(parts, "; ")}
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Field manager conflicts\n", func() {
+	const target = "test-dst-fieldconflict-ns-00"
+	src := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-src-fieldconflict-00", Namespace: "test-src-fieldconflict-ns-00"},
+		Data:       map[string][]byte{"password": []byte("test-src-fieldconflict-00")},
+	}
+	managed := func(manager string, operation metav1.ManagedFieldsOperationType, fields string) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{Manager: manager, Operation: operation, FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(fields)}}
+	}
+	conflict := func(messages ...string) error {
+		causes := []metav1.StatusCause{}
+		for _, message := range messages {
+			causes = append(causes, metav1.StatusCause{Type: metav1.CauseTypeFieldManagerConflict, Message: message,
+				Field: ".data.password"})
+		}
+		return apierrors.NewApplyConflict(causes, "Apply failed")
+	}
+	AfterEach(func() { fieldConflicts.forget(src) })
+
+	It("Should read the managers from the causes of an apply conflict", func() {
+		Expect(conflictingManagers(conflict(
+			`conflict with "vault-injector" using v1 at 2026-10-18T04:05:06Z`,
+			`conflict with "argocd"`,
+			`conflict with "vault-injector" using v1`,
+		))).Should(Equal([]string{"argocd", "vault-injector"}))
+		Expect(conflictingManagers(apierrors.NewConflict(corev1.Resource("secrets"), src.Name, errors.New("stale")))).Should(BeEmpty())
+		Expect(conflictingManagers(nil)).Should(BeEmpty())
+	})
+
+	It("Should record the managers that changed a copy kopy applied until it is current again", func() {
+		ctx := context.Background()
+		existing, err := newCopy(src, target, Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		tampered := existing.(*corev1.Secret)
+		tampered.Data = map[string][]byte{"password": []byte("injected")}
+		tampered.ManagedFields = []metav1.ManagedFieldsEntry{
+			managed(fieldOwner, metav1.ManagedFieldsOperationApply, `{"f:metadata":{"f:labels":{}}}`),
+			managed("vault-injector", metav1.ManagedFieldsOperationUpdate, `{"f:data":{"f:password":{}}}`),
+		}
+		// the apply conflicts with the password while it differs from the password of the source
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(tampered).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				applied := &client.PatchOptions{}
+				applied.ApplyOptions(opts)
+				current := &corev1.Secret{}
+				if patch.Type() == types.ApplyPatchType && applied.Force == nil &&
+					c.Get(ctx, client.ObjectKeyFromObject(obj), current) == nil &&
+					string(current.Data["password"]) != string(obj.(*corev1.Secret).Data["password"]) {
+					return conflict(`conflict with "vault-injector" using v1`)
+				}
+				return fakeApply(ctx, c, obj, patch, opts...)
+			},
+		}).Build()
+		write := func() {
+			cp, err := newCopy(src, target, Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(writeCopy(ctx, c, nil, src, cp)).Should(Succeed())
+		}
+
+		write()
+		Expect(fieldConflicts.conflicts(src)).Should(Equal(map[string][]string{target: {"vault-injector"}}))
+		condition := fieldConflictCondition(fieldConflicts.conflicts(src))
+		Expect(condition.Status).Should(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).Should(ContainSubstring(target + ": vault-injector"))
+
+		By("Clearing the conflict once the copy wasn't changed by others")
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(tampered), tampered)).Should(Succeed())
+		Expect(tampered.Data).Should(Equal(src.Data))
+		write()
+		Expect(fieldConflicts.conflicts(src)).Should(BeEmpty())
+		Expect(fieldConflictCondition(fieldConflicts.conflicts(src)).Status).Should(Equal(metav1.ConditionFalse))
+
+		By("Taking over a copy kopy updated before it applied copies without reporting a conflict")
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(tampered), tampered)).Should(Succeed())
+		tampered.Data = map[string][]byte{"password": []byte("stale")}
+		tampered.ManagedFields = []metav1.ManagedFieldsEntry{
+			managed("manager", metav1.ManagedFieldsOperationUpdate, `{"f:data":{"f:password":{}}}`),
+		}
+		Expect(c.Update(ctx, tampered)).Should(Succeed())
+		write()
+		Expect(fieldConflicts.conflicts(src)).Should(BeEmpty())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(tampered), tampered)).Should(Succeed())
+		Expect(tampered.Data).Should(Equal(src.Data))
+	})
+	It("Should remove the keys removed from the source of a copy kopy updated before it applied copies", func() {
+		ctx := context.Background()
+		legacy := src.DeepCopy()
+		legacy.Data = map[string][]byte{"password": []byte("test-src-fieldconflict-00"), "username": []byte("admin")}
+		existing, err := newCopy(legacy, target, Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		existing.SetManagedFields([]metav1.ManagedFieldsEntry{
+			managed("manager", metav1.ManagedFieldsOperationUpdate, `{"f:data":{"f:password":{},"f:username":{}}}`),
+		})
+		// like the API server an apply keeps the keys it leaves out while another field manager owns them
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(existing).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				current := &corev1.Secret{}
+				if patch.Type() != types.ApplyPatchType || c.Get(ctx, client.ObjectKeyFromObject(obj), current) != nil {
+					return fakeApply(ctx, c, obj, patch, opts...)
+				}
+				applied := obj.(*corev1.Secret)
+				owned := map[string]any{}
+				for key := range applied.Data {
+					owned["f:"+key] = map[string]any{}
+				}
+				fields, err := json.Marshal(map[string]any{"f:data": owned})
+				Expect(err).ShouldNot(HaveOccurred())
+				managedFields := []metav1.ManagedFieldsEntry{managed(fieldOwner, metav1.ManagedFieldsOperationApply, string(fields))}
+				for _, entry := range current.ManagedFields {
+					if entry.Manager == fieldOwner && entry.Operation == metav1.ManagedFieldsOperationApply {
+						continue
+					}
+					managedFields = append(managedFields, entry)
+					other := map[string]map[string]any{}
+					Expect(json.Unmarshal(entry.FieldsV1.Raw, &other)).Should(Succeed())
+					for key := range other["f:data"] {
+						key = strings.TrimPrefix(key, "f:")
+						if _, ok := applied.Data[key]; !ok {
+							applied.Data[key] = current.Data[key]
+						}
+					}
+				}
+				if err := fakeApply(ctx, c, applied, patch, opts...); err != nil {
+					return err
+				}
+				applied.ManagedFields = managedFields
+				return c.Update(ctx, applied)
+			},
+		}).Build()
+
+		cp, err := newCopy(src, target, Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(writeCopy(ctx, c, nil, src, cp)).Should(Succeed())
+		copied := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(existing), copied)).Should(Succeed())
+		Expect(copied.Data).Should(Equal(src.Data))
+		Expect(copied.ManagedFields).Should(ConsistOf(HaveField("Manager", fieldOwner)))
+		Expect(fieldConflicts.conflicts(src)).Should(BeEmpty())
+	})
+})
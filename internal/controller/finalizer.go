@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// syncFinalizerVersionPrefix prefixes the versions of the sync finalizer after the first, e.g.
+// kopy.kot-labs.com/finalizer-v2. A release that changes how copies are cleaned up sets a new version, while every
+// release clears all versions. During a rolling upgrade or a rollback an object may carry a version the running
+// kopy didn't set, which is then handled like its own and replaced by syncFinalizer on the next write.
+const syncFinalizerVersionPrefix = syncFinalizer + "-v"
+
+// isSyncFinalizer returns true for syncFinalizer and any of its versions
+func isSyncFinalizer(f string) bool {
+	if f == syncFinalizer {
+		return true
+	}
+	version, ok := strings.CutPrefix(f, syncFinalizerVersionPrefix)
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(version)
+	return err == nil && n > 0
+}
+
+// hasSyncFinalizer returns true if o carries any version of the sync finalizer
+func hasSyncFinalizer(o client.Object) bool {
+	return slices.ContainsFunc(o.GetFinalizers(), isSyncFinalizer)
+}
+
+// addSyncFinalizer sets syncFinalizer on o in place of any other version and returns true if o changed
+func addSyncFinalizer(o client.Object) bool {
+	finalizers := o.GetFinalizers()
+	updated := slices.DeleteFunc(slices.Clone(finalizers), func(f string) bool {
+		return isSyncFinalizer(f) && f != syncFinalizer
+	})
+	if !slices.Contains(updated, syncFinalizer) {
+		updated = append(updated, syncFinalizer)
+	}
+	if slices.Equal(finalizers, updated) {
+		return false
+	}
+	o.SetFinalizers(updated)
+	return true
+}
+
+// removeSyncFinalizer removes every version of the sync finalizer from o and returns true if o changed
+func removeSyncFinalizer(o client.Object) bool {
+	finalizers := o.GetFinalizers()
+	updated := slices.DeleteFunc(slices.Clone(finalizers), isSyncFinalizer)
+	if len(updated) == len(finalizers) {
+		return false
+	}
+	o.SetFinalizers(updated)
+	return true
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Finalizer versions\n", func() {
+	DescribeTable("Recognizing the sync finalizer",
+		func(f string, expected bool) {
+			Expect(isSyncFinalizer(f)).Should(Equal(expected))
+		},
+		Entry("first version", syncFinalizer, true),
+		Entry("later version", syncFinalizer+"-v2", true),
+		Entry("version zero", syncFinalizer+"-v0", false),
+		Entry("malformed version", syncFinalizer+"-vnext", false),
+		Entry("remote cluster finalizer", remoteFinalizerPrefix+"eu-west", false),
+		Entry("other finalizer", "kubernetes", false),
+	)
+
+	It("Should replace other versions with its own and remove every version", func() {
+		cp := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"kubernetes", syncFinalizer + "-v2"}}}
+		Expect(hasSyncFinalizer(cp)).Should(BeTrue())
+		Expect(addSyncFinalizer(cp)).Should(BeTrue())
+		Expect(cp.Finalizers).Should(Equal([]string{"kubernetes", syncFinalizer}))
+		Expect(addSyncFinalizer(cp)).Should(BeFalse())
+
+		cp.Finalizers = append(cp.Finalizers, syncFinalizer+"-v3")
+		Expect(removeSyncFinalizer(cp)).Should(BeTrue())
+		Expect(cp.Finalizers).Should(Equal([]string{"kubernetes"}))
+		Expect(hasSyncFinalizer(cp)).Should(BeFalse())
+		Expect(removeSyncFinalizer(cp)).Should(BeFalse())
+	})
+})
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/pkg/testenv/fixtures"
+)
+
+// scenarioRounds is how often every object of a scenario is reconciled after a step, enough for sources that depend
+// on the copies of other sources such as merged bundles to converge
+const scenarioRounds = 3
+
+var _ = Describe("Scenario fixtures\n", func() {
+	scenarios, err := fixtures.LoadDir("testdata/scenarios")
+	It("Should load every scenario", func() {
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(scenarios).ShouldNot(BeEmpty())
+	})
+	for _, s := range scenarios {
+		It(s.Name, func(ctx context.Context) {
+			c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+			Expect(fixtures.Run(ctx, c, s, func(ctx context.Context) error {
+				return convergeScenario(ctx, c, s.Kinds())
+			})).Should(Succeed(), s.Path)
+		})
+	}
+})
+
+// convergeScenario reconciles every object of kinds like the controllers of a manager would
+func convergeScenario(ctx context.Context, c client.Client, kinds []string) error {
+	for range scenarioRounds {
+		for _, kind := range kinds {
+			list, err := newObjectListForKind(kind)
+			if err != nil {
+				return err
+			}
+			if err := c.List(ctx, list); err != nil {
+				return err
+			}
+			err = meta.EachListItem(list, func(obj runtime.Object) error {
+				k, err := newKopier(ctx, c, kind, Options{})
+				if err != nil {
+					return err
+				}
+				_, err = KopyReconcile(k, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj.(client.Object))}, nil)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
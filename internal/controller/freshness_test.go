@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flynshue/kopy/pkg/freshness"
+)
+
+var _ = Describe("Copy freshness\n", func() {
+	start := time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+	src := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-src-fresh-00", Namespace: "test-src-fresh-ns-00"},
+		Data:       map[string][]byte{"password": []byte("test-src-fresh-00")},
+	}
+	copyAt := func(t time.Time) *corev1.Secret {
+		now = func() time.Time { return t }
+		cp, err := newCopy(src, "test-dst-fresh-ns-00", Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		return cp.(*corev1.Secret)
+	}
+	AfterEach(func() {
+		now = time.Now
+	})
+	It("Should stamp copies with the time they were synced", func() {
+		cp := copyAt(start)
+		Expect(freshness.LastSyncTime(cp.Annotations)).Should(Equal(start))
+	})
+	It("Should keep the last sync time of a current copy for a while", func() {
+		existing := copyAt(start)
+		cp := copyAt(start.Add(30 * time.Second))
+		preserveCopyMetadata(existing, cp)
+		Expect(freshness.LastSyncTime(cp.Annotations)).Should(Equal(start))
+
+		cp = copyAt(start.Add(2 * time.Minute))
+		preserveCopyMetadata(existing, cp)
+		Expect(freshness.LastSyncTime(cp.Annotations)).Should(Equal(start.Add(2 * time.Minute)))
+
+		cp = copyAt(start.Add(30 * time.Second))
+		cp.Data = map[string][]byte{"password": []byte("rotated")}
+		preserveCopyMetadata(existing, cp)
+		Expect(freshness.LastSyncTime(cp.Annotations)).Should(Equal(start.Add(30 * time.Second)))
+	})
+	It("Should record the source resourceVersion a copy was synced from", func() {
+		DeferCleanup(func() { src.ResourceVersion = "" })
+		src.ResourceVersion = "41"
+		existing := copyAt(start)
+		Expect(existing.Annotations).Should(HaveKeyWithValue(sourceResourceVersionKey, "41"))
+
+		By("Keeping it while the copy is current")
+		src.ResourceVersion = "42"
+		cp := copyAt(start.Add(30 * time.Second))
+		preserveCopyMetadata(existing, cp)
+		Expect(cp.Annotations).Should(HaveKeyWithValue(sourceResourceVersionKey, "41"))
+
+		By("Updating it with the data of the copy")
+		cp = copyAt(start.Add(30 * time.Second))
+		cp.Data = map[string][]byte{"password": []byte("rotated")}
+		preserveCopyMetadata(existing, cp)
+		Expect(cp.Annotations).Should(HaveKeyWithValue(sourceResourceVersionKey, "42"))
+	})
+	It("Should requeue sources after the refresh interval", func() {
+		opts := Options{RefreshInterval: 10 * time.Minute}
+		Expect(opts.refresh(ctrl.Result{})).Should(Equal(ctrl.Result{RequeueAfter: 10 * time.Minute}))
+		Expect(opts.refresh(ctrl.Result{RequeueAfter: time.Minute})).Should(Equal(ctrl.Result{RequeueAfter: time.Minute}))
+		Expect(Options{}.refresh(ctrl.Result{})).Should(Equal(ctrl.Result{}))
+	})
+	It("Should fail readiness while a copy is stale", func() {
+		cp := copyAt(time.Now().Add(-time.Hour))
+		cp.ResourceVersion = ""
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(cp).Build()
+		key := types.NamespacedName{Namespace: cp.Namespace, Name: cp.Name}
+		check := func(maxAge time.Duration) int {
+			handler := freshness.Handler(func(ctx context.Context) error {
+				return freshness.CheckObject(ctx, c, key, &corev1.Secret{}, maxAge)
+			})
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			return rec.Code
+		}
+		Expect(check(30 * time.Minute)).Should(Equal(http.StatusServiceUnavailable))
+		Expect(check(2 * time.Hour)).Should(Equal(http.StatusOK))
+		Expect(freshness.Check(nil, time.Hour, time.Now())).Should(MatchError(freshness.ErrNotStamped))
+	})
+})
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// groupByKey names the namespace label, e.g. env, whose values group the target namespaces of a source. Groups
+	// are synced one after another and a group only starts once every copy of the groups before it was written.
+	groupByKey = kopyPrefix + "group-by"
+	// groupOrderKey lists the groups in the order they are synced, e.g. "dev,staging,prod". Groups that aren't
+	// listed follow in name order, namespaces without the label come last.
+	groupOrderKey = kopyPrefix + "group-order"
+	// groupConcurrencyKey sets how many copies of a group are written in parallel, e.g. "dev=10,prod=2". Groups
+	// that aren't listed are written one copy at a time.
+	groupConcurrencyKey = kopyPrefix + "group-concurrency"
+)
+
+// targetGroup is a set of target namespaces that share the value of the group-by label
+type targetGroup struct {
+	name        string
+	namespaces  []corev1.Namespace
+	concurrency int
+}
+
+// targetGroups splits namespaces into the groups configured on src, in the order they are synced. Without a
+// group-by annotation all namespaces form a single group that is written with up to concurrency copies in parallel.
+func targetGroups(src client.Object, namespaces []corev1.Namespace, concurrency int) ([]targetGroup, error) {
+	annotations := src.GetAnnotations()
+	label, ok := annotations[groupByKey]
+	if !ok {
+		return []targetGroup{{namespaces: namespaces, concurrency: max(concurrency, 1)}}, nil
+	}
+	groupConcurrency, err := parseGroupConcurrency(annotations[groupConcurrencyKey])
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]*targetGroup{}
+	for _, ns := range namespaces {
+		name := ns.Labels[label]
+		g, ok := byName[name]
+		if !ok {
+			g = &targetGroup{name: name, concurrency: max(groupConcurrency[name], 1)}
+			byName[name] = g
+		}
+		g.namespaces = append(g.namespaces, ns)
+	}
+	order := map[string]int{}
+	for i, name := range strings.Split(annotations[groupOrderKey], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			if _, ok := order[name]; !ok {
+				order[name] = i
+			}
+		}
+	}
+	groups := make([]targetGroup, 0, len(byName))
+	for _, g := range byName {
+		slices.SortFunc(g.namespaces, func(a, b corev1.Namespace) int { return strings.Compare(a.Name, b.Name) })
+		groups = append(groups, *g)
+	}
+	slices.SortFunc(groups, func(a, b targetGroup) int {
+		ia, aListed := order[a.name]
+		ib, bListed := order[b.name]
+		switch {
+		case aListed && bListed:
+			return ia - ib
+		case aListed != bListed:
+			// listed groups come first
+			if aListed {
+				return -1
+			}
+			return 1
+		case (a.name == "") != (b.name == ""):
+			// namespaces without the label come last
+			if a.name == "" {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	return groups, nil
+}
+
+// parseGroupConcurrency parses the value of the group concurrency annotation
+func parseGroupConcurrency(v string) (map[string]int, error) {
+	concurrency := map[string]int{}
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, n, ok := strings.Cut(pair, "=")
+		c, err := strconv.Atoi(strings.TrimSpace(n))
+		if !ok || err != nil || c < 1 {
+			return nil, fmt.Errorf("invalid %s %q: expected <group>=<positive number>", groupConcurrencyKey, pair)
+		}
+		concurrency[strings.TrimSpace(name)] = c
+	}
+	return concurrency, nil
+}
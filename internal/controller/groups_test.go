@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testGroupsSnapshot = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-src-groups-ns-00
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-groups-dev-00
+    labels:
+      env: dev
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-groups-dev-01
+    labels:
+      env: dev
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-groups-prod-00
+    labels:
+      env: prod
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: test-src-groups-00
+    namespace: test-src-groups-ns-00
+    annotations:
+      kopy.kot-labs.com/sync: env in (dev,prod)
+      kopy.kot-labs.com/group-by: env
+      kopy.kot-labs.com/group-order: dev,prod
+      kopy.kot-labs.com/group-concurrency: dev=2
+  data:
+    password: dGVzdC1zcmMtZ3JvdXBzLTAw
+`
+
+// testGroupsConflict is a copy of another source that blocks the copy of test-src-groups-00 in a dev namespace
+const testGroupsConflict = `
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: test-src-groups-00
+    namespace: test-target-groups-dev-01
+    labels:
+      kopy.kot-labs.com/origin.namespace: test-src-groups-ns-01
+      kopy.kot-labs.com/origin.name: test-src-groups-00
+    finalizers:
+    - kopy.kot-labs.com/finalizer
+  data:
+    password: b3RoZXI=
+`
+
+var _ = Describe("Target groups\n", func() {
+	namespace := func(name string, labels map[string]string) corev1.Namespace {
+		return corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	groupNames := func(groups []targetGroup) []string {
+		names := []string{}
+		for _, g := range groups {
+			names = append(names, g.name)
+		}
+		return names
+	}
+	It("Should order groups by the group order annotation", func() {
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			groupByKey:          "env",
+			groupOrderKey:       "dev, prod",
+			groupConcurrencyKey: "dev=3",
+		}}}
+		groups, err := targetGroups(src, []corev1.Namespace{
+			namespace("a", map[string]string{"env": "prod"}),
+			namespace("b", nil),
+			namespace("c", map[string]string{"env": "qa"}),
+			namespace("d", map[string]string{"env": "dev"}),
+		}, 10)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(groupNames(groups)).Should(Equal([]string{"dev", "prod", "qa", ""}))
+		Expect(groups[0].concurrency).Should(Equal(3))
+		Expect(groups[1].concurrency).Should(Equal(1))
+	})
+	It("Should sync ungrouped targets with the copy concurrency", func() {
+		groups, err := targetGroups(&corev1.Secret{}, []corev1.Namespace{namespace("a", nil)}, 10)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(groups).Should(HaveLen(1))
+		Expect(groups[0].concurrency).Should(Equal(10))
+	})
+	It("Should reject malformed group concurrency", func() {
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			groupByKey:          "env",
+			groupConcurrencyKey: "dev=0",
+		}}}
+		_, err := targetGroups(src, nil, 1)
+		Expect(err).Should(HaveOccurred())
+	})
+	Context("When a source groups its targets", func() {
+		created := func(target string) SimulatedAction {
+			return SimulatedAction{Verb: "create", Kind: "secret", Namespace: target, Name: "test-src-groups-00"}
+		}
+		It("Should sync earlier groups first", func() {
+			objects, err := LoadSnapshot(strings.NewReader(testGroupsSnapshot))
+			Expect(err).ShouldNot(HaveOccurred())
+			result, err := Simulate(context.Background(), objects, Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.Actions).Should(ContainElements(
+				created("test-target-groups-dev-00"), created("test-target-groups-dev-01"), created("test-target-groups-prod-00"),
+			))
+			Expect(result.Actions[len(result.Actions)-1]).Should(Equal(created("test-target-groups-prod-00")))
+		})
+		It("Should hold back later groups while a copy of an earlier group fails", func() {
+			objects, err := LoadSnapshot(strings.NewReader(testGroupsSnapshot + testGroupsConflict))
+			Expect(err).ShouldNot(HaveOccurred())
+			result, err := Simulate(context.Background(), objects, Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.Actions).Should(ContainElement(created("test-target-groups-dev-00")))
+			Expect(result.Actions).ShouldNot(ContainElement(created("test-target-groups-prod-00")))
+		})
+	})
+})
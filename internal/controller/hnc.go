@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Labels and annotations of the Hierarchical Namespace Controller (HNC), see
+// https://github.com/kubernetes-sigs/hierarchical-namespaces
+const (
+	// hncTreeDepthSuffix is the suffix of the labels HNC sets on a namespace for itself and each of its ancestors
+	hncTreeDepthSuffix = ".tree.hnc.x-k8s.io/depth"
+	// hncInheritedFrom is set by HNC on objects it propagated from an ancestor namespace
+	hncInheritedFrom = "hnc.x-k8s.io/inherited-from"
+	// hncPropagateNone stops an object from being propagated to any descendant
+	hncPropagateNone = "propagate.hnc.x-k8s.io/none"
+	// hncPropagateSelect only propagates an object to descendants matching a label selector
+	hncPropagateSelect = "propagate.hnc.x-k8s.io/select"
+	// hncPropagateTreeSelect only propagates an object to the listed descendants, "!" excludes a descendant
+	hncPropagateTreeSelect = "propagate.hnc.x-k8s.io/treeSelect"
+)
+
+// hncAncestors returns the names of the ancestors of ns from its HNC tree labels
+func hncAncestors(ns client.Object) []string {
+	ancestors := []string{}
+	for k, v := range ns.GetLabels() {
+		name, ok := strings.CutSuffix(k, hncTreeDepthSuffix)
+		if !ok {
+			continue
+		}
+		if depth, err := strconv.Atoi(v); err == nil && depth > 0 {
+			ancestors = append(ancestors, name)
+		}
+	}
+	return ancestors
+}
+
+// hncAllows returns true if the HNC exception annotations on src allow it to reach the subnamespace ns
+func hncAllows(src client.Object, ns client.Object) bool {
+	annotations := src.GetAnnotations()
+	if none, _ := strconv.ParseBool(annotations[hncPropagateNone]); none {
+		return false
+	}
+	if v, ok := annotations[hncPropagateSelect]; ok {
+		ls, err := labels.Parse(v)
+		if err != nil || !ls.Matches(labels.Set(ns.GetLabels())) {
+			return false
+		}
+	}
+	if v, ok := annotations[hncPropagateTreeSelect]; ok {
+		tree := sets.New(hncAncestors(ns)...).Insert(ns.GetName())
+		included := false
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if excluded, ok := strings.CutPrefix(name, "!"); ok {
+				if tree.Has(excluded) {
+					return false
+				}
+				continue
+			}
+			included = included || tree.Has(name)
+		}
+		return included
+	}
+	return true
+}
+
+// hncSubnamespaces returns the subnamespaces of the selected namespaces that src may be copied to. The source
+// namespace itself and namespaces that are already selected are skipped.
+func hncSubnamespaces(ctx context.Context, c client.Client, src client.Object, selected []corev1.Namespace) ([]corev1.Namespace, error) {
+	parents := namespaceNames(selected)
+	if parents.Len() == 0 {
+		return nil, nil
+	}
+	namespaceList := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaceList); err != nil {
+		return nil, err
+	}
+	subnamespaces := []corev1.Namespace{}
+	for _, ns := range namespaceList.Items {
+		if ns.Name == src.GetNamespace() || parents.Has(ns.Name) || ns.DeletionTimestamp != nil {
+			continue
+		}
+		for _, ancestor := range hncAncestors(&ns) {
+			if parents.Has(ancestor) && hncAllows(src, &ns) {
+				subnamespaces = append(subnamespaces, ns)
+				break
+			}
+		}
+	}
+	return subnamespaces, nil
+}
+
+// hncSourcesSelecting returns reconcile requests for the sources that select one of the ancestors of namespace,
+// so a new subnamespace receives the copies of its parent
+func hncSourcesSelecting(ctx context.Context, c client.Client, list client.ObjectList, namespace client.Object) []reconcile.Request {
+	req := []reconcile.Request{}
+	for _, name := range hncAncestors(namespace) {
+		ancestor := &corev1.Namespace{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, ancestor); err != nil {
+			continue
+		}
+		req = append(req, sourcesSelecting(ctx, c, list, ancestor)...)
+	}
+	return req
+}
+
+// isHNCPropagated returns true if o was propagated by HNC, kopy leaves those objects to HNC
+func isHNCPropagated(o client.Object) bool {
+	_, ok := o.GetLabels()[hncInheritedFrom]
+	return ok
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("HNC integration\n", func() {
+	subnamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "team-a-dev",
+			Labels: map[string]string{
+				"team-a" + hncTreeDepthSuffix:     "1",
+				"team-a-dev" + hncTreeDepthSuffix: "0",
+				"env":                             "dev",
+			},
+		},
+	}
+	It("Should read the ancestors from the tree labels", func() {
+		Expect(hncAncestors(subnamespace)).Should(ConsistOf("team-a"))
+	})
+	DescribeTable("Honoring HNC exception annotations",
+		func(annotations map[string]string, expected bool) {
+			src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+			Expect(hncAllows(src, subnamespace)).Should(Equal(expected))
+		},
+		Entry("no exceptions", nil, true),
+		Entry("none", map[string]string{hncPropagateNone: "true"}, false),
+		Entry("matching select", map[string]string{hncPropagateSelect: "env=dev"}, true),
+		Entry("not matching select", map[string]string{hncPropagateSelect: "env=prod"}, false),
+		Entry("treeSelect including the parent", map[string]string{hncPropagateTreeSelect: "team-a"}, true),
+		Entry("treeSelect excluding the subnamespace", map[string]string{hncPropagateTreeSelect: "team-a, !team-a-dev"}, false),
+		Entry("treeSelect of another tree", map[string]string{hncPropagateTreeSelect: "team-b"}, false),
+	)
+	Context("When a selected namespace has a subnamespace", func() {
+		It("Should copy the source to the subnamespace", func() {
+			By("Creating source namespace and secret")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				secret    *corev1.Secret
+			}{
+				name: "test-src-hnc-00", namespace: "test-src-hnc-ns-00", secret: &corev1.Secret{},
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			data := map[string][]byte{"password": []byte(src.name)}
+			src.secret, err = tc.CreateSecret(src.name, src.namespace, label, data, corev1.SecretTypeOpaque)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Creating the parent and its subnamespace")
+			parent, err := tc.CreateNamespace("test-target-hnc-ns-00", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			child, err := tc.CreateNamespace("test-target-hnc-ns-00-child", &syncLabel{key: parent.Name + hncTreeDepthSuffix, value: "1"})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Verifying both namespaces received a copy")
+			for _, ns := range []string{parent.Name, child.Name} {
+				Eventually(func() bool {
+					copy := &corev1.Secret{}
+					if err := tc.GetSecret(src.name, ns, copy); err != nil {
+						return false
+					}
+					return reflect.DeepEqual(copy.Data, data)
+				}, timeout, interval).Should(BeTrue())
+			}
+		})
+	})
+})
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// syncSelectorIndex indexes sources by the namespace labels their sync selector requires
+	syncSelectorIndex = kopyPrefix + "sync-selector"
+	// anyNamespace is indexed for selectors that can match namespaces without any particular label
+	anyNamespace = "*"
+)
+
+// indexSyncSelector returns the index values for the sync selector of a source object. A namespace has to match
+// every requirement of a selector, so only the first requirement that needs a namespace label is indexed as
+// "key=value" for each of its values or "key" when it only requires the label to exist. Selectors without such a
+// requirement are indexed as anyNamespace.
+func indexSyncSelector(o client.Object) []string {
+	v, ok := SyncSelector(o)
+	if !ok {
+		return nil
+	}
+	ls, err := ParseSyncSelector(v)
+	if err != nil {
+		return nil
+	}
+	reqs, _ := ls.Requirements()
+	for _, r := range reqs {
+		switch r.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			values := make([]string, 0, r.Values().Len())
+			for _, value := range r.Values().List() {
+				values = append(values, r.Key()+"="+value)
+			}
+			return values
+		case selection.Exists:
+			return []string{r.Key()}
+		}
+	}
+	return []string{anyNamespace}
+}
+
+// setupSyncSelectorIndex registers indexSyncSelector for the source kind of obj with the manager
+func setupSyncSelectorIndex(mgr ctrl.Manager, obj client.Object) error {
+	return mgr.GetFieldIndexer().IndexField(context.Background(), obj, syncSelectorIndex, indexSyncSelector)
+}
+
+// sourcesSelecting returns reconcile requests for the sources of the kind of list whose sync selector matches
+// namespace. Sources are looked up through syncSelectorIndex, so the work is proportional to the number of sources
+// that could match rather than to every object in the cluster.
+func sourcesSelecting(ctx context.Context, c client.Client, list client.ObjectList, namespace client.Object) []reconcile.Request {
+	log := ctrllog.FromContext(ctx)
+	keys := []string{anyNamespace}
+	for k, v := range namespace.GetLabels() {
+		keys = append(keys, k, k+"="+v)
+	}
+	seen := sets.New[types.NamespacedName]()
+	req := []reconcile.Request{}
+	for _, key := range keys {
+		sources := list.DeepCopyObject().(client.ObjectList)
+		if err := c.List(ctx, sources, client.MatchingFields{syncSelectorIndex: key}); err != nil {
+			log.Error(err, "unable to list sources from the sync selector index", "key", key)
+			continue
+		}
+		_ = meta.EachListItem(sources, func(obj runtime.Object) error {
+			o, ok := obj.(client.Object)
+			if !ok {
+				return nil
+			}
+			nn := client.ObjectKeyFromObject(o)
+			if seen.Has(nn) || !namespaceContainsSyncLabel(o, namespace) {
+				return nil
+			}
+			seen.Insert(nn)
+			req = append(req, reconcile.Request{NamespacedName: nn})
+			log.Info("need to add reconcile queue", "kind", kindOf(o), "sourceNamespace", nn.Namespace, "name", nn.Name, "targetNamespace", namespace.GetName())
+			return nil
+		})
+	}
+	return req
+}
+
+// sourcesOfCopiesIn returns reconcile requests for the sources of the copies of the kind of list in namespace. A
+// namespace whose labels no longer match the sync selector of a source isn't found by sourcesSelecting, so without
+// them the source wouldn't prune its copy until it is reconciled for another reason.
+func sourcesOfCopiesIn(ctx context.Context, c client.Client, list client.ObjectList, namespace client.Object) []reconcile.Request {
+	log := ctrllog.FromContext(ctx)
+	copies := list.DeepCopyObject().(client.ObjectList)
+	if err := c.List(ctx, copies, client.InNamespace(namespace.GetName()), client.HasLabels{sourceLabelNamespace}); err != nil {
+		log.Error(err, "unable to list copies in namespace", "namespace", namespace.GetName())
+		return nil
+	}
+	seen := sets.New[types.NamespacedName]()
+	req := []reconcile.Request{}
+	_ = meta.EachListItem(copies, func(obj runtime.Object) error {
+		cp, ok := obj.(client.Object)
+		if !ok {
+			return nil
+		}
+		nn := types.NamespacedName{Namespace: cp.GetLabels()[sourceLabelNamespace], Name: sourceNameOf(cp)}
+		if seen.Has(nn) {
+			return nil
+		}
+		seen.Insert(nn)
+		req = append(req, reconcile.Request{NamespacedName: nn})
+		return nil
+	})
+	return req
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Sync selector index\n", func() {
+	DescribeTable("Indexing a source",
+		func(selector string, expected []string) {
+			s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{syncKey: selector}}}
+			Expect(indexSyncSelector(s)).Should(Equal(expected))
+		},
+		Entry("equality requirement", "app=foo", []string{"app=foo"}),
+		Entry("set requirement", "kubernetes.io/metadata.name in (team-b,team-a)", []string{"kubernetes.io/metadata.name=team-a", "kubernetes.io/metadata.name=team-b"}),
+		Entry("exists requirement", "app", []string{"app"}),
+		Entry("negative requirement before positive requirement", "env!=prod,app=foo", []string{"app=foo"}),
+		Entry("only negative requirements", "env!=prod", []string{anyNamespace}),
+		Entry("malformed selector", "app in (", nil),
+	)
+	It("Should not index objects without the sync annotation", func() {
+		Expect(indexSyncSelector(&corev1.ConfigMap{})).Should(BeNil())
+	})
+	It("Should reconcile the sources of the copies in a namespace that no longer matches their selector", func() {
+		const target = "test-dst-index-ns-00"
+		selected := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-src-index-00", Namespace: "test-src-index-ns-00",
+			Annotations: map[string]string{syncKey: "team=payments"}}}
+		optedOut := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-src-index-01", Namespace: "test-src-index-ns-00",
+			Annotations: map[string]string{syncKey: "team=checkout"}}}
+		cp := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: optedOut.Name, Namespace: target,
+			Labels: map[string]string{sourceLabelNamespace: optedOut.Namespace, sourceLabelName: optedOut.Name}}}
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+			WithIndex(&corev1.ConfigMap{}, syncSelectorIndex, indexSyncSelector).
+			WithObjects(selected, optedOut, cp).Build()
+
+		req := Options{}.sourcesSelecting(context.Background(), c, &corev1.ConfigMapList{}, namespace)
+		Expect(req).Should(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: selected.Namespace, Name: selected.Name}},
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: optedOut.Namespace, Name: optedOut.Name}},
+		))
+	})
+})
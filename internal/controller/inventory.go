@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/flynshue/kopy/pkg/freshness"
+)
+
+const (
+	// inventorySecretsKey and inventoryConfigMapsKey are the keys of the inventory ConfigMap, the copies of the
+	// other kinds are published under the kind name, e.g. serviceaccount.json
+	inventorySecretsKey    = "secrets.json"
+	inventoryConfigMapsKey = "configmaps.json"
+)
+
+// Inventory maps the "namespace/name" of every copy managed by kopy to the "namespace/name" of its source
+type Inventory struct {
+	Secrets    map[string]string `json:"secrets"`
+	ConfigMaps map[string]string `json:"configMaps"`
+	// Kinds holds the copies of the other kinds by kind name, e.g. serviceaccount
+	Kinds map[string]map[string]string `json:"kinds,omitempty"`
+}
+
+// BuildInventory lists the copies managed by kopy
+func BuildInventory(ctx context.Context, c client.Client) (*Inventory, error) {
+	copies, err := ListInventory(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	inv := &Inventory{Secrets: map[string]string{}, ConfigMaps: map[string]string{}}
+	for _, cp := range copies {
+		key := cp.Namespace + "/" + cp.Name
+		switch cp.Kind {
+		case "secret":
+			inv.Secrets[key] = cp.Source
+		case "configmap":
+			inv.ConfigMaps[key] = cp.Source
+		default:
+			if inv.Kinds == nil {
+				inv.Kinds = map[string]map[string]string{}
+			}
+			if inv.Kinds[cp.Kind] == nil {
+				inv.Kinds[cp.Kind] = map[string]string{}
+			}
+			inv.Kinds[cp.Kind][key] = cp.Source
+		}
+	}
+	return inv, nil
+}
+
+// InventoryCopy describes a copy managed by kopy for compliance inventories and capacity planning
+type InventoryCopy struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Source is the namespace/name of the source of the copy
+	Source string `json:"source"`
+	// Size is the number of bytes of the data of the copy
+	Size int `json:"size"`
+	// LastSync is when kopy last wrote the copy, unset for copies written before kopy recorded it
+	LastSync *time.Time `json:"lastSync,omitempty"`
+	// Hash is the revision of the data of the copy, which equals the revision of its source while it is current. It
+	// is left out for Secrets by the REST API.
+	Hash string `json:"hash,omitempty"`
+}
+
+// ListInventory returns the copies of the enabled kinds managed by kopy ordered by kind, namespace and name
+func ListInventory(ctx context.Context, c client.Client) ([]InventoryCopy, error) {
+	opts := client.HasLabels{sourceLabelNamespace}
+	copies := []InventoryCopy{}
+	for _, kind := range enabledKinds() {
+		list, err := newObjectListForKind(kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.List(ctx, list, opts); err != nil {
+			return nil, err
+		}
+		if err := meta.EachListItem(list, func(obj runtime.Object) error {
+			cp, ok := obj.(client.Object)
+			if !ok {
+				return fmt.Errorf("unexpected object %T", obj)
+			}
+			size := 0
+			for _, v := range objectData(cp) {
+				size += len(v)
+			}
+			ic := InventoryCopy{
+				Kind: kindOf(cp), Namespace: cp.GetNamespace(), Name: cp.GetName(),
+				Source: inventorySource(cp), Size: size, Hash: dataRevision(cp),
+			}
+			if t, err := freshness.LastSyncTime(cp.GetAnnotations()); err == nil {
+				ic.LastSync = &t
+			}
+			copies = append(copies, ic)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(copies, func(i, j int) bool {
+		a, b := copies[i], copies[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return copies, nil
+}
+
+// WriteInventoryCSV writes copies as CSV with a header row
+func WriteInventoryCSV(w io.Writer, copies []InventoryCopy) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"kind", "namespace", "name", "source", "size", "last_sync", "hash"}); err != nil {
+		return err
+	}
+	for _, cp := range copies {
+		lastSync := ""
+		if cp.LastSync != nil {
+			lastSync = cp.LastSync.UTC().Format(time.RFC3339)
+		}
+		if err := cw.Write([]string{cp.Kind, cp.Namespace, cp.Name, cp.Source, strconv.Itoa(cp.Size), lastSync, cp.Hash}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func inventorySource(cp client.Object) string {
+	name, ok := cp.GetLabels()[sourceLabelName]
+	if !ok {
+		name = cp.GetName()
+	}
+	return cp.GetLabels()[sourceLabelNamespace] + "/" + name
+}
+
+var _ manager.Runnable = &InventoryPublisher{}
+var _ manager.LeaderElectionRunnable = &InventoryPublisher{}
+
+// InventoryPublisher periodically writes the Inventory into a well-known ConfigMap so policy engines such as
+// OPA Gatekeeper can sync it and reference the identities of kopy managed objects from constraint templates
+type InventoryPublisher struct {
+	client.Client
+	Key      types.NamespacedName
+	Interval time.Duration
+}
+
+// Start publishes the inventory every Interval until ctx is cancelled
+func (p *InventoryPublisher) Start(ctx context.Context) error {
+	log := ctrllog.Log.WithName("inventory")
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Publish(ctx); err != nil {
+			log.Error(err, "unable to publish inventory", "configmap", p.Key)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns true so only the leader writes the inventory
+func (p *InventoryPublisher) NeedLeaderElection() bool {
+	return true
+}
+
+// Publish writes the current inventory into the ConfigMap, creating it if needed
+func (p *InventoryPublisher) Publish(ctx context.Context) error {
+	inv, err := BuildInventory(ctx, p.Client)
+	if err != nil {
+		return err
+	}
+	secrets, err := json.Marshal(inv.Secrets)
+	if err != nil {
+		return err
+	}
+	configMaps, err := json.Marshal(inv.ConfigMaps)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{inventorySecretsKey: string(secrets), inventoryConfigMapsKey: string(configMaps)}
+	for kind, kindCopies := range inv.Kinds {
+		b, err := json.Marshal(kindCopies)
+		if err != nil {
+			return err
+		}
+		data[kind+".json"] = string(b)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := p.Get(ctx, p.Key, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: p.Key.Name, Namespace: p.Key.Namespace},
+			Data:       data,
+		}
+		return p.Create(ctx, cm)
+	}
+	cm.Data = data
+	if err := p.Update(ctx, cm); err != nil {
+		return fmt.Errorf("unable to update inventory: %w", err)
+	}
+	return nil
+}
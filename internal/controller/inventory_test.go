@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flynshue/kopy/pkg/freshness"
+)
+
+var _ = Describe("Inventory\n", func() {
+	Context("When a source secret is copied", func() {
+		It("Should list the copy with its source", func() {
+			By("Creating source and target namespaces")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+			}{
+				name: "test-src-inventory-00", namespace: "test-src-inventory-ns-00",
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			targetNamespace, err := tc.CreateNamespace("test-target-inventory-ns-00", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = tc.CreateSecret(src.name, src.namespace, label, map[string][]byte{"password": []byte(src.name)}, corev1.SecretTypeOpaque)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Verifying the inventory contains the copy")
+			Eventually(func() map[string]string {
+				inv, err := BuildInventory(tc.ctx, k8sClient)
+				if err != nil {
+					return nil
+				}
+				return inv.Secrets
+			}, timeout, interval).Should(HaveKeyWithValue(targetNamespace.Name+"/"+src.name, src.namespace+"/"+src.name))
+		})
+	})
+	It("Should export the size, last sync time and hash of every copy", func() {
+		synced := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		labels := map[string]string{sourceLabelNamespace: "test-src-inventory-ns-01", sourceLabelName: "test-src-inventory-01"}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-inventory-01", Namespace: "test-dst-inventory-ns-01", Labels: labels,
+				Annotations: map[string]string{freshness.LastSyncTimeAnnotation: synced.Format(time.RFC3339)}},
+			Data: map[string][]byte{"password": []byte("s3cr3t")},
+		}
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-inventory-01", Namespace: "test-dst-inventory-ns-00", Labels: labels},
+			Data:       map[string]string{"a": "1", "b": "22"},
+		}
+		unmanaged := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-unmanaged", Namespace: "test-dst-inventory-ns-00"}}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(secret, configMap, unmanaged).Build()
+
+		copies, err := ListInventory(context.Background(), c)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(copies).Should(HaveLen(2))
+		Expect(copies[0]).Should(And(
+			HaveField("Kind", "configmap"), HaveField("Size", 3), HaveField("LastSync", BeNil()),
+			HaveField("Source", "test-src-inventory-ns-01/test-src-inventory-01"),
+		))
+		Expect(copies[1]).Should(And(
+			HaveField("Kind", "secret"), HaveField("Size", 6), HaveField("Hash", dataRevision(secret)),
+		))
+		Expect(copies[1].LastSync).ShouldNot(BeNil())
+		Expect(copies[1].LastSync.Equal(synced)).Should(BeTrue())
+
+		var b bytes.Buffer
+		Expect(WriteInventoryCSV(&b, copies)).Should(Succeed())
+		Expect(b.String()).Should(Equal("kind,namespace,name,source,size,last_sync,hash\n" +
+			"configmap,test-dst-inventory-ns-00,test-src-inventory-01,test-src-inventory-ns-01/test-src-inventory-01,3,," + dataRevision(configMap) + "\n" +
+			"secret,test-dst-inventory-ns-01,test-src-inventory-01,test-src-inventory-ns-01/test-src-inventory-01,6,2024-01-01T12:00:00Z," + dataRevision(secret) + "\n"))
+	})
+	It("Should list the copies of the other enabled kinds by kind", func() {
+		labels := map[string]string{sourceLabelNamespace: "test-src-inventory-ns-02", sourceLabelName: "test-src-inventory-02"}
+		serviceAccount := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-inventory-02", Namespace: "test-dst-inventory-ns-02", Labels: labels},
+		}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(serviceAccount).Build()
+
+		copies, err := ListInventory(context.Background(), c)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(copies).Should(ConsistOf(HaveField("Kind", "serviceaccount")))
+		inv, err := BuildInventory(context.Background(), c)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(inv.Secrets).Should(BeEmpty())
+		Expect(inv.ConfigMaps).Should(BeEmpty())
+		Expect(inv.Kinds).Should(Equal(map[string]map[string]string{
+			"serviceaccount": {"test-dst-inventory-ns-02/test-src-inventory-02": "test-src-inventory-ns-02/test-src-inventory-02"},
+		}))
+	})
+})
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// PropagationBackend is how copies reach the clusters listed in the publish-clusters annotation of their source
+type PropagationBackend string
+
+const (
+	// PropagationDirect only writes copies to the cluster kopy runs in, remote clusters are left to other tooling
+	PropagationDirect PropagationBackend = ""
+	// PropagationKarmada writes copies to the Karmada control plane kopy runs against and generates a Karmada
+	// PropagationPolicy for every copy that places it on the clusters of its source
+	PropagationKarmada PropagationBackend = "karmada"
+)
+
+// karmadaPolicyGVK is the kind of the policies generated for the karmada propagation backend
+var karmadaPolicyGVK = schema.GroupVersionKind{Group: "policy.karmada.io", Version: "v1alpha1", Kind: "PropagationPolicy"}
+
+// ParsePropagationBackend parses the value of the --propagation-backend flag, direct or karmada
+func ParsePropagationBackend(v string) (PropagationBackend, error) {
+	switch v {
+	case "direct", "":
+		return PropagationDirect, nil
+	case string(PropagationKarmada):
+		return PropagationKarmada, nil
+	}
+	return "", fmt.Errorf("invalid propagation backend %q, expected direct or karmada", v)
+}
+
+// publishClusters returns the clusters listed in the publish-clusters annotation of src
+func publishClusters(src client.Object) []string {
+	clusters := []string{}
+	for _, cluster := range strings.Split(src.GetAnnotations()[publishClustersKey], ",") {
+		if cluster = strings.TrimSpace(cluster); cluster != "" {
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters
+}
+
+// propagationPolicyName returns the name of the PropagationPolicy of the copy named copyName
+func propagationPolicyName(copyName string) string {
+	return "kopy-" + copyName
+}
+
+// propagateCopy generates the PropagationPolicy that places the copy cp of src on the clusters of src when copies
+// are propagated with Karmada. The policy is owned by the copy so it is garbage collected with it, and deleted when
+// src no longer lists any clusters.
+func (o Options) propagateCopy(ctx context.Context, c client.Client, src, cp client.Object) error {
+	if o.PropagationBackend != PropagationKarmada {
+		return nil
+	}
+	policy := &unstructured.Unstructured{}
+	policy.SetGroupVersionKind(karmadaPolicyGVK)
+	policy.SetNamespace(cp.GetNamespace())
+	policy.SetName(propagationPolicyName(cp.GetName()))
+	clusters := publishClusters(src)
+	if len(clusters) == 0 {
+		if err := c.Delete(ctx, policy); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to delete propagation policy %s: %w", policy.GetName(), err)
+		}
+		return nil
+	}
+	gvk, err := apiutil.GVKForObject(cp, c.Scheme())
+	if err != nil {
+		return err
+	}
+	spec := map[string]any{
+		"resourceSelectors": []any{map[string]any{
+			"apiVersion": gvk.GroupVersion().String(),
+			"kind":       gvk.Kind,
+			"name":       cp.GetName(),
+		}},
+		"placement": map[string]any{
+			"clusterAffinity": map[string]any{"clusterNames": toAnySlice(clusters)},
+		},
+	}
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(karmadaPolicyGVK)
+	err = c.Get(ctx, client.ObjectKeyFromObject(policy), existing)
+	if err == nil {
+		if err := unstructured.SetNestedField(existing.Object, spec, "spec"); err != nil {
+			return err
+		}
+		if err := c.Update(ctx, existing); err != nil {
+			return fmt.Errorf("unable to update propagation policy %s: %w", policy.GetName(), err)
+		}
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	policy.SetLabels(o.copyLabels(src.GetAnnotations(), src.GetNamespace(), src.GetName()))
+	policy.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind, Name: cp.GetName(), UID: cp.GetUID(),
+	}})
+	policy.Object["spec"] = spec
+	if err := c.Create(ctx, policy); err != nil {
+		return fmt.Errorf("unable to create propagation policy %s: %w", policy.GetName(), err)
+	}
+	return nil
+}
+
+func toAnySlice(values []string) []any {
+	result := make([]any, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+	return result
+}
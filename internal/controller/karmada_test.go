@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Karmada propagation\n", func() {
+	const (
+		namespace = "test-src-karmada-ns-00"
+		target    = "test-dst-karmada-ns-00"
+	)
+	It("Should generate a propagation policy for every copy of a source published to clusters", func() {
+		ctx := context.Background()
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-karmada-00", Namespace: namespace,
+				Annotations: map[string]string{syncKey: "team=payments", publishClustersKey: "member1, member2"},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(karmadaPolicyGVK, meta.RESTScopeNamespace)
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithRESTMapper(mapper).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).Build()
+		reconcile := func() {
+			opts := Options{PropagationBackend: PropagationKarmada}
+			_, err := KopyReconcile(NewKopySecret(ctx, c, opts, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		policy := &unstructured.Unstructured{}
+		policy.SetGroupVersionKind(karmadaPolicyGVK)
+		policyKey := client.ObjectKey{Namespace: target, Name: propagationPolicyName(src.Name)}
+
+		reconcile()
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: target, Name: src.Name}, &corev1.Secret{})).Should(Succeed())
+		Expect(c.Get(ctx, policyKey, policy)).Should(Succeed())
+		Expect(policy.GetLabels()).Should(HaveKeyWithValue(sourceLabelNamespace, namespace))
+		Expect(policy.GetOwnerReferences()).Should(ConsistOf(HaveField("Name", src.Name)))
+		selectors, _, _ := unstructured.NestedSlice(policy.Object, "spec", "resourceSelectors")
+		Expect(selectors).Should(ConsistOf(map[string]any{"apiVersion": "v1", "kind": "Secret", "name": src.Name}))
+		clusters, _, _ := unstructured.NestedStringSlice(policy.Object, "spec", "placement", "clusterAffinity", "clusterNames")
+		Expect(clusters).Should(Equal([]string{"member1", "member2"}))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		delete(src.Annotations, publishClustersKey)
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(apierrors.IsNotFound(c.Get(ctx, policyKey, policy))).Should(BeTrue())
+	})
+
+	DescribeTable("Should parse propagation backends",
+		func(v string, expected PropagationBackend, valid bool) {
+			backend, err := ParsePropagationBackend(v)
+			if !valid {
+				Expect(err).Should(HaveOccurred())
+				return
+			}
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(backend).Should(Equal(expected))
+		},
+		Entry("direct", "direct", PropagationDirect, true),
+		Entry("karmada", "karmada", PropagationKarmada, true),
+		Entry("fleet", "fleet", PropagationDirect, false),
+	)
+})
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// KindReconciler reconciles the objects of a kind registered with registerKind or RegisterSyncKind. kopy needs the
+// same permissions on the kind as on Secrets, see permissionsOf.
+type KindReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Options Options
+	// Kind is the name of the kind reconciled, e.g. serviceaccount or certificate.cert-manager.io
+	Kind string
+
+	kind     syncKind
+	recorder record.EventRecorder
+	tracker  *syncTracker
+}
+
+// Reconcile syncs the source or copy of the kind of r identified by req
+func (r *KindReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ks := r.kind.newKopier(ctx, r.Client, r.Options, r.recorder)
+	result, err := KopyReconcile(ks, req, r.tracker)
+	debugState.recordError(r.kind.kindName(), err)
+	return result, err
+}
+
+// watchNamespaces maps a namespace event to the sources of the kind of r whose sync selector matches the namespace
+func (r *KindReconciler) watchNamespaces(ctx context.Context, namespace client.Object) []reconcile.Request {
+	if isNamespaceMarkedForDelete(ctx, r.Client, namespace.GetName()) {
+		return nil
+	}
+	return r.Options.sourcesSelecting(ctx, r.Client, r.kind.newSourceList(), namespace)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KindReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	k, ok := kindNamed(r.Kind)
+	if !ok {
+		return fmt.Errorf("kind %q isn't registered", r.Kind)
+	}
+	r.kind = k
+	name := k.kindName()
+	gvk, err := apiutil.GVKForObject(k.newSource(), mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	r.recorder = mgr.GetEventRecorderFor("kopy-" + name + "-controller")
+	r.tracker = newSyncTracker(r.Options.SyncDeadline, r.recorder)
+	b := ctrl.NewControllerManagedBy(mgr).
+		// controller names are used as metric labels
+		Named(strings.NewReplacer(".", "_", "-", "_").Replace(name)).
+		For(k.newSource()).
+		WithOptions(controller.Options{
+			NewQueue:                r.Options.newQueue(),
+			MaxConcurrentReconciles: r.Options.MaxConcurrentReconciles,
+		})
+	debugState.watch(name, gvk.Kind)
+	// namespaces can only be watched with cluster wide permissions
+	if !r.Options.NamespaceScoped() {
+		debugState.watch(name, gvk.Kind, "Namespace")
+		if err := setupSyncSelectorIndex(mgr, k.newSource()); err != nil {
+			return err
+		}
+		// only labels, annotations and the deletion timestamp of namespaces are used
+		b = b.Watches(&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.watchNamespaces),
+			builder.OnlyMetadata,
+		)
+	}
+	return b.Complete(r)
+}
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// syncKind is a kopyKind without its type parameter, so kinds can be looked up by name or by object
+type syncKind interface {
+	// kindName is the lower case name of the kind, e.g. networkpolicy
+	kindName() string
+	// qualifiedName is the kind name qualified by its API group like unstructuredKindName, e.g.
+	// networkpolicy.networking.k8s.io
+	qualifiedName() string
+	// apiResource returns the API group and resource of the kind, the resource is empty for kinds registered with
+	// RegisterSyncKind
+	apiResource() (group, resource string)
+	newSource() client.Object
+	newSourceList() client.ObjectList
+	newKopier(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) Kopier
+	// copyOf returns a new object with the payload of src, without metadata
+	copyOf(src client.Object) (client.Object, bool)
+	// current returns true if the payload of the copy cp matches the payload of src
+	current(src, cp client.Object) bool
+	// dataOf returns the payload of o keyed by data key or top level field, see objectData
+	dataOf(o client.Object) (map[string][]byte, bool)
+}
+
+func (k kopyKind[T]) kindName() string {
+	return k.name
+}
+
+func (k kopyKind[T]) qualifiedName() string {
+	if k.group == "" {
+		return k.name
+	}
+	return k.name + "." + k.group
+}
+
+func (k kopyKind[T]) apiResource() (string, string) {
+	return k.group, k.resource
+}
+
+func (k kopyKind[T]) newSource() client.Object {
+	return k.newObject()
+}
+
+func (k kopyKind[T]) newSourceList() client.ObjectList {
+	return k.newList()
+}
+
+func (k kopyKind[T]) newKopier(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) Kopier {
+	return newKopy(ctx, c, k, opts, recorder)
+}
+
+func (k kopyKind[T]) copyOf(src client.Object) (client.Object, bool) {
+	s, ok := src.(T)
+	if !ok {
+		return nil, false
+	}
+	cp := k.newObject()
+	k.copySpec(s, cp)
+	return cp, true
+}
+
+func (k kopyKind[T]) current(src, cp client.Object) bool {
+	s, ok := src.(T)
+	c, cok := cp.(T)
+	return ok && cok && k.isCurrent(s, c)
+}
+
+func (k kopyKind[T]) dataOf(o client.Object) (map[string][]byte, bool) {
+	obj, ok := o.(T)
+	if !ok {
+		return nil, false
+	}
+	return k.payload(obj), true
+}
+
+// kinds are the kinds kopy syncs: the built-in kinds register themselves with registerKind, the kinds given with
+// --sync-gvk are registered with RegisterSyncKind
+var kinds = &kindRegistry{byName: map[string]syncKind{}, byType: map[reflect.Type]syncKind{}, builtin: map[string]bool{}}
+
+// kindRegistry holds the kinds by their names and aliases and the built-in kinds by the types of their objects and
+// lists. The objects of registered kinds are all unstructured, so they are only found by name.
+type kindRegistry struct {
+	sync.RWMutex
+	byName  map[string]syncKind
+	byType  map[reflect.Type]syncKind
+	builtin map[string]bool
+	// enabled are the names of the kinds whose controllers run, nil until EnableKinds is called
+	enabled []string
+}
+
+// registerKind registers the built-in kind k, looked up by its name and aliases, and returns it
+func registerKind[T client.Object](k kopyKind[T], aliases ...string) kopyKind[T] {
+	kinds.Lock()
+	defer kinds.Unlock()
+	for _, name := range append([]string{k.name}, aliases...) {
+		kinds.byName[name] = k
+	}
+	kinds.byType[reflect.TypeOf(k.newObject())] = k
+	kinds.byType[reflect.TypeOf(k.newList())] = k
+	kinds.builtin[k.name] = true
+	return k
+}
+
+// kindNamed returns the kind named name, or one of its aliases, ignoring case
+func kindNamed(name string) (syncKind, bool) {
+	kinds.RLock()
+	defer kinds.RUnlock()
+	k, ok := kinds.byName[strings.ToLower(name)]
+	return k, ok
+}
+
+// kindForObject returns the kind of o
+func kindForObject(o client.Object) (syncKind, bool) {
+	if u, ok := o.(*unstructured.Unstructured); ok {
+		return kindNamed(unstructuredKindName(u.GroupVersionKind()))
+	}
+	kinds.RLock()
+	defer kinds.RUnlock()
+	k, ok := kinds.byType[reflect.TypeOf(o)]
+	return k, ok
+}
+
+// kindOfList returns the name of the kind of the objects in list, including lists of metadata and unstructured
+// objects
+func kindOfList(list client.ObjectList) string {
+	switch l := list.(type) {
+	case *unstructured.UnstructuredList:
+		gvk := l.GroupVersionKind()
+		return unstructuredKindName(gvk.GroupVersion().WithKind(strings.TrimSuffix(gvk.Kind, "List")))
+	case *metav1.PartialObjectMetadataList:
+		return strings.ToLower(strings.TrimSuffix(l.Kind, "List"))
+	}
+	kinds.RLock()
+	defer kinds.RUnlock()
+	if k, ok := kinds.byType[reflect.TypeOf(list)]; ok {
+		return k.kindName()
+	}
+	return ""
+}
+
+// builtinKind returns the built-in kind named exactly name
+func builtinKind(name string) (syncKind, bool) {
+	kinds.RLock()
+	defer kinds.RUnlock()
+	if !kinds.builtin[name] {
+		return nil, false
+	}
+	return kinds.byName[name], true
+}
+
+// BuiltinKinds returns the sorted names of the kinds kopy syncs without RegisterSyncKind
+func BuiltinKinds() []string {
+	kinds.RLock()
+	defer kinds.RUnlock()
+	return slices.Sorted(maps.Keys(kinds.builtin))
+}
+
+// EnableKinds sets the names of the kinds whose controllers run. The topology, inventory, usage and what-if reports,
+// namespace resyncs, the startup sync, namespace deletion protection and simulations cover the enabled kinds.
+func EnableKinds(names []string) {
+	kinds.Lock()
+	defer kinds.Unlock()
+	kinds.enabled = slices.Clone(names)
+}
+
+// enabledKinds returns the names of the enabled kinds. Until EnableKinds is called these are the kinds of a
+// default install, every built-in kind but the RBAC kinds which are only synced with --sync-rbac, and the kinds
+// registered with RegisterSyncKind.
+func enabledKinds() []string {
+	kinds.RLock()
+	enabled := slices.Clone(kinds.enabled)
+	kinds.RUnlock()
+	if enabled != nil {
+		return enabled
+	}
+	for _, name := range BuiltinKinds() {
+		if !rbacKinds[name] {
+			enabled = append(enabled, name)
+		}
+	}
+	return append(enabled, RegisteredSyncKinds()...)
+}
+
+// jsonPayload returns the payload fields of an object with the JSON encoding of each field as value
+func jsonPayload(fields map[string]any) map[string][]byte {
+	data := map[string][]byte{}
+	for name, v := range fields {
+		if b, err := json.Marshal(v); err == nil {
+			data[name] = b
+		}
+	}
+	return data
+}
@@ -2,12 +2,22 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 type Kopier interface {
@@ -16,8 +26,11 @@ type Kopier interface {
 	GetClient() client.Client
 	GetContext() context.Context
 	GetObject() client.Object
+	GetOptions() Options
+	GetRecorder() record.EventRecorder
 	LabelSelector() labels.Selector
 	MarkedForDeletion() bool
+	PruneCopies(namespaces []corev1.Namespace) error
 	SyncOptions() bool
 	SyncDeletedCopy() error
 	SyncSource(name, sourceNamespace, targetNamespace string) error
@@ -31,10 +44,35 @@ const (
 	sourceLabelName      = "kopy.kot-labs.com/origin.name"
 	sourceLabelNamespace = "kopy.kot-labs.com/origin.namespace"
 	syncFinalizer        = "kopy.kot-labs.com/finalizer"
+
+	// cacheLagRequeueAfter is how long to wait before retrying when a source isn't in the cache yet
+	cacheLagRequeueAfter = 5 * time.Second
+
+	// lastSyncRefresh is how old the last sync time of a current copy may get before it is refreshed when the copy
+	// is rewritten
+	lastSyncRefresh = time.Minute
+
+	// groupRetryAfter is how long to wait before retrying when later target groups are held back by an earlier
+	// group that isn't synced yet
+	groupRetryAfter = 30 * time.Second
 )
 
-// KopyReconcile runs the reconcile loop logic for Kopier interface
-func KopyReconcile(k Kopier, req ctrl.Request) (ctrl.Result, error) {
+// errSourceNotCached is returned when the source of a copy can't be found, which is usually transient because the
+// copy was observed before its source made it into the cache
+var errSourceNotCached = errors.New("source not found in cache")
+
+// newKopier returns the Kopier implementation for the kind name
+func newKopier(ctx context.Context, c client.Client, kind string, opts Options) (Kopier, error) {
+	k, ok := kindNamed(kind)
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind %q", kind)
+	}
+	return k.newKopier(ctx, c, opts, nil), nil
+}
+
+// KopyReconcile runs the reconcile loop logic for Kopier interface.
+// tracker reports copies that are stuck, it may be nil to disable stuck sync detection.
+func KopyReconcile(k Kopier, req ctrl.Request, tracker *syncTracker) (ctrl.Result, error) {
 	log := k.Logger().WithValues("name", req.Name, "namespace", req.Namespace)
 	// delete log statement later; using this to debugging reconcile
 	// log.Info("Event received")
@@ -44,19 +82,51 @@ func KopyReconcile(k Kopier, req ctrl.Request) (ctrl.Result, error) {
 	if err := k.Fetch(req); err != nil {
 		return ctrl.Result{}, err
 	}
-	if ctrlutil.ContainsFinalizer(k.GetObject(), syncFinalizer) {
+	// the object is fetched again if another reconcile of its source held the lock, so the reconcile starts from the
+	// state the previous reconcile of the source or one of its copies left behind
+	unlock, waited := sourceLocks.lock(sourceLockKey(k.GetObject(), req))
+	defer unlock()
+	if waited {
+		if err := k.Fetch(req); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if hasSyncFinalizer(k.GetObject()) {
 		log.Info("object contains kopy finalizer")
+		// copies of a deleted source wait for the cascade grace period instead of being synced
+		if result, pending, err := k.GetOptions().pendingDeletion(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject()); pending || err != nil {
+			return result, err
+		}
 		if k.MarkedForDeletion() {
 			log.Info("object marked for deletion")
 			if k.SyncOptions() {
-				if err := k.SourceDeletion(); err != nil {
+				tracker.Retain(k.GetObject(), nil)
+				fieldConflicts.forget(k.GetObject())
+				if err := refreshMergedObjects(k.GetContext(), k.GetClient(), k.GetObject(), nil); err != nil {
+					return ctrl.Result{Requeue: true}, err
+				}
+				if err := k.GetOptions().releaseRemoteClusters(k.GetContext(), k.GetClient(), k.GetObject()); err != nil {
 					return ctrl.Result{Requeue: true}, err
 				}
+				var err error
+				if k.GetOptions().cascades(k.GetObject()) {
+					err = k.GetOptions().cascadeSource(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject())
+				} else {
+					err = k.SourceDeletion()
+				}
+				if errors.Is(err, errCascadePending) {
+					log.Info("waiting for the deleted copies to go away", "reason", err.Error())
+					return ctrl.Result{RequeueAfter: cascadeVerifyRequeueAfter}, nil
+				}
+				if err != nil {
+					return ctrl.Result{Requeue: true}, err
+				}
+				publishEvent(k.GetContext(), k.GetOptions(), EventSourceDeleted, k.GetObject(), SyncEventData{})
 				return ctrl.Result{}, nil
 			}
 			if isNamespaceMarkedForDelete(k.GetContext(), k.GetClient(), req.Namespace) {
 				log.Info("namespace marked for deletion")
-				ctrlutil.RemoveFinalizer(k.GetObject(), syncFinalizer)
+				removeSyncFinalizer(k.GetObject())
 				if err := k.GetClient().Update(k.GetContext(), k.GetObject()); err != nil {
 					log.Error(err, "unable to remove the finalizer from object")
 					return ctrl.Result{}, err
@@ -66,6 +136,9 @@ func KopyReconcile(k Kopier, req ctrl.Request) (ctrl.Result, error) {
 			}
 			log.Info("Object is a copy that is marked for deletion; will trigger sync")
 			if err := k.SyncDeletedCopy(); err != nil {
+				if errors.Is(err, errSourceNotCached) {
+					return requeueForCacheLag(k, log), nil
+				}
 				log.Error(err, "unable to sync deleted object")
 				return ctrl.Result{}, err
 			}
@@ -74,7 +147,48 @@ func KopyReconcile(k Kopier, req ctrl.Request) (ctrl.Result, error) {
 		}
 		sourceNamespace, ok := k.GetObject().GetLabels()[sourceLabelNamespace]
 		if ok {
-			err := k.SyncSource(req.Name, sourceNamespace, req.Namespace)
+			sourceName := sourceNameOf(k.GetObject())
+			src, err := NewObjectForKind(kindOf(k.GetObject()))
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			err = k.GetClient().Get(k.GetContext(), types.NamespacedName{Namespace: sourceNamespace, Name: sourceName}, src)
+			if client.IgnoreNotFound(err) != nil {
+				return ctrl.Result{}, err
+			}
+			if err == nil && isQuarantined(src) {
+				log.Info("source is quarantined, not syncing copy", "sourceNamespace", sourceNamespace)
+				return ctrl.Result{}, nil
+			}
+			// copies named before the copy name suffix or the local copy name was changed are replaced by a copy with
+			// the current name
+			name := k.GetOptions().copyName(sourceName)
+			if err == nil {
+				name = k.GetOptions().copyNameFor(src, req.Namespace)
+			}
+			if name != req.Name {
+				log.Info("replacing copy named for a different copy name", "copyName", name)
+				if err := pruneCopy(k.GetContext(), k.GetClient(), k.GetObject()); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			// restoring a copy would propagate changes of the source as well, so it waits for the sync window too
+			if err == nil {
+				if k.GetOptions().refusedRBACSource(k.GetRecorder(), src) {
+					log.Info("source isn't in an RBAC source namespace, not syncing copy", "sourceNamespace", sourceNamespace)
+					return ctrl.Result{}, nil
+				}
+				if rejected, err := k.GetOptions().rejectedByQuota(k.GetContext(), k.GetClient(), k.GetRecorder(), src); rejected || err != nil {
+					return ctrl.Result{}, err
+				}
+				if result, wait, err := waitForSyncWindow(src, log); wait || err != nil {
+					return result, err
+				}
+			}
+			err = k.SyncSource(sourceName, sourceNamespace, req.Namespace)
+			if errors.Is(err, errSourceNotCached) {
+				return requeueForCacheLag(k, log), nil
+			}
 			if err != nil {
 				return ctrl.Result{}, err
 			}
@@ -82,23 +196,86 @@ func KopyReconcile(k Kopier, req ctrl.Request) (ctrl.Result, error) {
 			return ctrl.Result{}, nil
 		}
 		if k.SyncOptions() {
-			namespaces, err := getSyncNamespaces(k.GetContext(), k.GetClient(), req, k.LabelSelector())
+			if isQuarantined(k.GetObject()) {
+				log.Info("source is quarantined, not syncing its copies")
+				return ctrl.Result{}, quarantineCopies(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject())
+			}
+			if k.GetOptions().refusedRBACSource(k.GetRecorder(), k.GetObject()) {
+				log.Info("source isn't in an RBAC source namespace, not syncing")
+				return ctrl.Result{}, nil
+			}
+			if rejected, err := k.GetOptions().rejectedByQuota(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject()); rejected || err != nil {
+				if rejected {
+					log.Info("source exceeds the source quota of its namespace, not syncing")
+				}
+				return ctrl.Result{}, err
+			}
+			if result, wait, err := waitForSyncWindow(k.GetObject(), log); wait || err != nil {
+				return result, err
+			}
+			namespaces, err := k.GetOptions().syncNamespaces(k.GetContext(), k.GetClient(), k.GetObject(), k.LabelSelector())
 			if err != nil {
 				log.Error(err, "unable to grab list of namespaces with sync key", "syncKey", k.LabelSelector().String())
 				return ctrl.Result{}, err
 			}
-			for _, n := range namespaces {
-				if err := k.SyncSource(req.Name, req.Namespace, n.Name); err != nil {
-					log.Error(err, "unable to sync object", "sourceNamespace", req.Namespace, "targetNamespace", n.Name)
-					continue
+			if name, ok := mergeTarget(k.GetObject()); ok {
+				return syncMergedSource(k, name, namespaces, log)
+			}
+			namespaces, staleIn, err := applyMaxAge(k, namespaces, log)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			result, err := syncCopies(k, req, namespaces, tracker)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if staleIn > 0 && (result.RequeueAfter == 0 || staleIn < result.RequeueAfter) {
+				result.RequeueAfter = staleIn
+			}
+			if err := k.GetOptions().runPostSyncHooks(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject(), namespaces); err != nil {
+				log.Error(err, "unable to run post-sync hooks")
+				return ctrl.Result{}, err
+			}
+			if err := k.PruneCopies(namespaces); errors.Is(err, errPruneDeferred) {
+				log.Info("copies are still used by pods, checking them again later", "requeueAfter", pruneDeferredRequeueAfter)
+				if result.RequeueAfter == 0 || pruneDeferredRequeueAfter < result.RequeueAfter {
+					result.RequeueAfter = pruneDeferredRequeueAfter
 				}
-				log.Info("successfully synced", "sourceNamespace", req.Namespace, "targetNamespace", n.Name)
+			} else if err != nil {
+				log.Error(err, "unable to prune copies from namespaces that are no longer selected")
+				return ctrl.Result{}, err
 			}
-			return ctrl.Result{}, nil
+			// the source may have been merged into other objects before
+			if err := refreshMergedObjects(k.GetContext(), k.GetClient(), k.GetObject(), nil); err != nil {
+				log.Error(err, "unable to refresh objects the source was merged into")
+				return ctrl.Result{}, err
+			}
+			if result, err = syncRemoteCopies(k, result); err != nil {
+				log.Error(err, "unable to sync remote clusters")
+				return ctrl.Result{}, err
+			}
+			// every copy was rewritten above unless some are still held back, which answers a pending resync request
+			if err := observeResyncRequest(k.GetContext(), k.GetClient(), k.GetObject(), result); err != nil {
+				return ctrl.Result{}, err
+			}
+			return k.GetOptions().refresh(result), nil
 		}
 		// object has a finalizer but doesn't have a source label and doesn't have sync key annotation
 		// object was a source that had annotations removed and will need to remove finalizers from copies
 		log.Info("sync key annotations were removed from object")
+		tracker.Retain(k.GetObject(), nil)
+		if err := refreshMergedObjects(k.GetContext(), k.GetClient(), k.GetObject(), nil); err != nil {
+			log.Error(err, "unable to refresh objects the source was merged into")
+			return ctrl.Result{}, err
+		}
+		if err := k.GetOptions().releaseRemoteClusters(k.GetContext(), k.GetClient(), k.GetObject()); err != nil {
+			log.Error(err, "unable to release the copies in remote clusters")
+			return ctrl.Result{}, err
+		}
+		if err := k.GetOptions().deleteReport(k.GetContext(), k.GetClient(), k.GetObject()); err != nil {
+			log.Error(err, "unable to delete the report of the source")
+			return ctrl.Result{}, err
+		}
 		if err := k.SourceDeletion(); err != nil {
 			log.Error(err, "unable to remove finalizers")
 			return ctrl.Result{}, err
@@ -111,19 +288,279 @@ func KopyReconcile(k Kopier, req ctrl.Request) (ctrl.Result, error) {
 		if err := k.AddFinalizer(); err != nil {
 			return ctrl.Result{}, err
 		}
-		namespaces, err := getSyncNamespaces(k.GetContext(), k.GetClient(), req, k.LabelSelector())
+		if isQuarantined(k.GetObject()) {
+			log.Info("source is quarantined, not syncing its copies")
+			return ctrl.Result{}, quarantineCopies(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject())
+		}
+		if k.GetOptions().refusedRBACSource(k.GetRecorder(), k.GetObject()) {
+			log.Info("source isn't in an RBAC source namespace, not syncing")
+			return ctrl.Result{}, nil
+		}
+		if rejected, err := k.GetOptions().rejectedByQuota(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject()); rejected || err != nil {
+			if rejected {
+				log.Info("source exceeds the source quota of its namespace, not syncing")
+			}
+			return ctrl.Result{}, err
+		}
+		if result, wait, err := waitForSyncWindow(k.GetObject(), log); wait || err != nil {
+			return result, err
+		}
+		namespaces, err := k.GetOptions().syncNamespaces(k.GetContext(), k.GetClient(), k.GetObject(), k.LabelSelector())
 		if err != nil {
 			log.Error(err, "unable to grab list of namespaces with sync key", "syncKey", k.LabelSelector().String())
 			return ctrl.Result{}, err
 		}
-		for _, n := range namespaces {
-			if err := k.SyncSource(req.Name, req.Namespace, n.Name); err != nil {
-				log.Error(err, "unable to sync object", "sourceNamespace", req.Namespace, "targetNamespace", n.Name)
-			}
-			log.Info("successfully synced", "sourceNamespace", req.Namespace, "targetNamespace", n.Name)
+		if name, ok := mergeTarget(k.GetObject()); ok {
+			return syncMergedSource(k, name, namespaces, log)
 		}
-		return ctrl.Result{}, nil
+		namespaces, staleIn, err := applyMaxAge(k, namespaces, log)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		result, err := syncCopies(k, req, namespaces, tracker)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if staleIn > 0 && (result.RequeueAfter == 0 || staleIn < result.RequeueAfter) {
+			result.RequeueAfter = staleIn
+		}
+		if err := k.GetOptions().runPostSyncHooks(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject(), namespaces); err != nil {
+			log.Error(err, "unable to run post-sync hooks")
+			return ctrl.Result{}, err
+		}
+		if result, err = syncRemoteCopies(k, result); err != nil {
+			log.Error(err, "unable to sync remote clusters")
+			return ctrl.Result{}, err
+		}
+		if err := observeResyncRequest(k.GetContext(), k.GetClient(), k.GetObject(), result); err != nil {
+			return ctrl.Result{}, err
+		}
+		return k.GetOptions().refresh(result), nil
 	}
 
 	return ctrl.Result{}, nil
 }
+
+// syncMergedSource merges the source of k into the objects named name in namespaces instead of copying it
+func syncMergedSource(k Kopier, name string, namespaces []corev1.Namespace, log logr.Logger) (ctrl.Result, error) {
+	if err := syncMergeTargets(k, name, namespaces); err != nil {
+		log.Error(err, "unable to merge object", "mergeInto", name)
+		return ctrl.Result{}, err
+	}
+	if err := observeResyncRequest(k.GetContext(), k.GetClient(), k.GetObject(), ctrl.Result{}); err != nil {
+		return ctrl.Result{}, err
+	}
+	return k.GetOptions().refresh(ctrl.Result{}), nil
+}
+
+// syncCopies copies the source in req into namespaces and returns a result that requeues while copies are
+// failing but not yet reported as stuck, or while out of date copies are held back by the propagation rate limit or
+// by an earlier target group that isn't synced yet. Copies held back until a change is confirmed are skipped, the
+// confirmation updates the source which reconciles it again.
+func syncCopies(k Kopier, req ctrl.Request, namespaces []corev1.Namespace, tracker *syncTracker) (ctrl.Result, error) {
+	log := k.Logger().WithValues("name", req.Name, "namespace", req.Namespace)
+	t := now()
+	ro, err := newRollout(k.GetObject(), t)
+	if err != nil {
+		log.Error(err, "unable to read the propagation rate limit")
+		return ctrl.Result{}, err
+	}
+	groups, err := targetGroups(k.GetObject(), namespaces, k.GetOptions().CopyConcurrency)
+	if err != nil {
+		log.Error(err, "unable to group target namespaces")
+		return ctrl.Result{}, err
+	}
+	held, err := k.GetOptions().heldForApproval(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject(), namespaces)
+	if err != nil {
+		log.Error(err, "unable to check whether the change needs to be confirmed")
+		return ctrl.Result{}, err
+	}
+	if held.Len() > 0 {
+		log.Info("holding back change until it is confirmed", "copies", held.Len(), "pendingApproval", k.GetObject().GetAnnotations()[pendingApprovalKey])
+	}
+	var requeueAfter time.Duration
+	requeue := func(after time.Duration) {
+		if after > 0 && (requeueAfter == 0 || after < requeueAfter) {
+			requeueAfter = after
+		}
+	}
+	deferred := 0
+	outcome := &syncOutcome{errors: map[string]string{}}
+	for i, g := range groups {
+		if ro != nil {
+			// rate limited sources are rolled out in a stable order
+			slices.SortFunc(g.namespaces, func(a, b corev1.Namespace) int { return strings.Compare(a.Name, b.Name) })
+		}
+		targets := make([]string, 0, len(g.namespaces))
+		for _, n := range g.namespaces {
+			if held.Has(n.Name) {
+				continue
+			}
+			if ro != nil && needsPropagation(k.GetContext(), k.GetClient(), k.GetObject(), n.Name) && !ro.take() {
+				deferred++
+				continue
+			}
+			targets = append(targets, n.Name)
+		}
+		err := syncGroup(k, req, targets, g.concurrency, tracker, requeue, outcome)
+		if err != nil {
+			log.Error(err, "unable to sync object", "sourceNamespace", req.Namespace, "group", g.name)
+		}
+		if (err != nil || deferred > 0) && i < len(groups)-1 {
+			log.Info("holding back later target groups until the current group is synced", "group", g.name)
+			requeue(groupRetryAfter)
+			break
+		}
+	}
+	outcome.publish(k)
+	if err := k.GetOptions().writeReport(k.GetContext(), k.GetClient(), k.GetObject(), namespaces, outcome); err != nil {
+		// the report is informational, a failure to write it doesn't hold back the sync
+		log.Error(err, "unable to write the report of the source")
+	}
+	tracker.Retain(k.GetObject(), namespaceNames(namespaces))
+	if ro == nil {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	if err := ro.saveProgress(k.GetContext(), k.GetClient(), k.GetObject()); err != nil {
+		log.Error(err, "unable to save rollout progress")
+		return ctrl.Result{}, err
+	}
+	if deferred > 0 {
+		after := ro.retryAfter(t)
+		log.Info("propagation rate limit reached, requeueing", "deferred", deferred, "requeueAfter", after)
+		requeue(after)
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// syncGroup copies the source in req into the targets of a group with up to concurrency workers. It returns the
+// errors of the copies that failed joined by target namespace, requeue is called with the retry delay of failed
+// copies. The updated and failed copies are recorded in outcome.
+func syncGroup(k Kopier, req ctrl.Request, targets []string, concurrency int, tracker *syncTracker, requeue func(time.Duration), outcome *syncOutcome) error {
+	log := k.Logger().WithValues("name", req.Name, "namespace", req.Namespace)
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	// a fan-out in parallel holds every copy in memory at once, which a degraded controller can't afford
+	if memoryDegraded.Load() {
+		concurrency = 1
+	}
+	g := &errgroup.Group{}
+	g.SetLimit(concurrency)
+	for _, target := range targets {
+		g.Go(func() error {
+			// finding out of date copies costs a read per target, which is only worth it when events are published or
+			// reports are written
+			opts := k.GetOptions()
+			stale := (opts.EventSink != nil || opts.Reports) && needsPropagation(k.GetContext(), k.GetClient(), k.GetObject(), target)
+			if err := k.SyncSource(req.Name, req.Namespace, target); err != nil {
+				debugState.recordError(kindOf(k.GetObject()), err)
+				// a conflicting copy is reported to the source unless it suppresses conflicts
+				suppressed := errors.Is(err, errCopyConflict) && suppressedWarnings(k.GetObject()).Has(warningConflict)
+				if suppressed {
+					suppressedWarningsTotal.WithLabelValues(reasonSyncFailed).Inc()
+				}
+				if recorder := k.GetRecorder(); recorder != nil && !suppressed {
+					recorder.Eventf(k.GetObject(), corev1.EventTypeWarning, reasonSyncFailed,
+						"Failed to sync copy to namespace %s: %v", target, err)
+				}
+				after := tracker.Failed(k.GetObject(), target, err)
+				outcome.failed(target, err)
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, fmt.Errorf("%s: %w", target, err))
+				requeue(after)
+				// the other targets are synced regardless, so the error is only collected
+				return nil
+			}
+			if stale {
+				outcome.updated(target)
+			}
+			outcome.synced(target)
+			tracker.Synced(k.GetObject(), target)
+			log.Info("successfully synced", "sourceNamespace", req.Namespace, "targetNamespace", target)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return errors.Join(errs...)
+}
+
+// syncOutcome collects the target namespaces whose copies were updated or failed during a sync. current are the
+// targets that hold a copy of the current data afterwards, whether it was updated or not.
+type syncOutcome struct {
+	mu      sync.Mutex
+	targets []string
+	current []string
+	errors  map[string]string
+}
+
+func (o *syncOutcome) updated(target string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.targets = append(o.targets, target)
+}
+
+func (o *syncOutcome) synced(target string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.current = append(o.current, target)
+}
+
+func (o *syncOutcome) failed(target string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.errors[target] = err.Error()
+}
+
+// publish publishes a sync.failed event if any copy failed, or a sync.completed event if any copy was updated
+func (o *syncOutcome) publish(k Kopier) {
+	switch {
+	case len(o.errors) > 0:
+		targets := slices.Sorted(maps.Keys(o.errors))
+		publishEvent(k.GetContext(), k.GetOptions(), EventSyncFailed, k.GetObject(), SyncEventData{Targets: targets, Errors: o.errors})
+	case len(o.targets) > 0:
+		slices.Sort(o.targets)
+		publishEvent(k.GetContext(), k.GetOptions(), EventSyncCompleted, k.GetObject(), SyncEventData{Targets: o.targets})
+	}
+}
+
+// waitForSyncWindow returns true with a result that requeues when the next sync window of src opens if src may not
+// be propagated right now
+func waitForSyncWindow(src client.Object, log logr.Logger) (ctrl.Result, bool, error) {
+	delay, err := syncWindowDelay(src, now())
+	if err != nil {
+		log.Error(err, "unable to parse sync window", "syncWindow", src.GetAnnotations()[syncWindowKey])
+		return ctrl.Result{}, true, err
+	}
+	if delay == 0 {
+		return ctrl.Result{}, false, nil
+	}
+	log.Info("outside of sync window, requeueing", "syncWindow", src.GetAnnotations()[syncWindowKey], "requeueAfter", delay)
+	return ctrl.Result{RequeueAfter: delay}, true, nil
+}
+
+// applyMaxAge checks the source of k against its max age and narrows namespaces to the ones that already hold a copy
+// if the source is stale and its max age is enforced. It returns the time left until the source gets stale, so the
+// source is reconciled again to report it.
+func applyMaxAge(k Kopier, namespaces []corev1.Namespace, log logr.Logger) ([]corev1.Namespace, time.Duration, error) {
+	age, err := checkSourceAge(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject())
+	if err != nil {
+		log.Error(err, "unable to check the age of the source", "maxAge", k.GetObject().GetAnnotations()[maxAgeKey])
+		return nil, 0, err
+	}
+	if !age.enforced {
+		return namespaces, age.staleIn, nil
+	}
+	log.Info("source is older than its max age, not creating new copies", "maxAge", k.GetObject().GetAnnotations()[maxAgeKey])
+	namespaces, err = namespacesWithCopies(k.GetContext(), k.GetClient(), k.GetObject(), namespaces)
+	return namespaces, 0, err
+}
+
+// requeueForCacheLag records a cache lag retry for the kind of k and returns a result that retries shortly
+func requeueForCacheLag(k Kopier, log logr.Logger) ctrl.Result {
+	cacheLagRetries.WithLabelValues(kindOf(k.GetObject())).Inc()
+	log.Info("source not found in cache yet, requeueing", "requeueAfter", cacheLagRequeueAfter)
+	return ctrl.Result{RequeueAfter: cacheLagRequeueAfter}
+}
@@ -0,0 +1,299 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// kopyKind describes a kind of object that can be copied, it's all a new kind needs to implement Kopier with Kopy and
+// to be reconciled by a KindReconciler once it is registered with registerKind
+type kopyKind[T client.Object] struct {
+	// name is the lower case kind name used in kind arguments, logs, events and metrics, e.g. networkpolicy
+	name string
+	// group and resource are the API group and resource of the kind, used for the permissions of its controller
+	group, resource string
+	// newObject returns an empty object of the kind
+	newObject func() T
+	// newList returns an empty list of the kind
+	newList func() client.ObjectList
+	// copySpec sets the payload of the copy dst, everything kopy copies besides the metadata, to the payload of src
+	copySpec func(src, dst T)
+	// isCurrent returns true if the payload of the copy cp matches the payload of src
+	isCurrent func(src, cp T) bool
+	// payload returns the payload of o keyed by data key or top level field, see objectData
+	payload func(o T) map[string][]byte
+}
+
+// Kopy implements Kopier for the kind of T, e.g. *corev1.Secret
+type Kopy[T client.Object] struct {
+	context.Context
+	client.Client
+	// Object is the object of the reconcile request
+	Object   T
+	kind     kopyKind[T]
+	opts     Options
+	recorder record.EventRecorder
+}
+
+// newKopy creates a new instance of Kopy for kind, recorder is used to emit events and may be nil
+func newKopy[T client.Object](ctx context.Context, c client.Client, kind kopyKind[T], opts Options, recorder record.EventRecorder) *Kopy[T] {
+	return &Kopy[T]{Context: ctx, Client: c, Object: kind.newObject(), kind: kind, opts: opts, recorder: suppressWarnings(recorder)}
+}
+
+// AddFinalizer adds finalizer to the object and updates object in kubernetes cluster
+func (ks *Kopy[T]) AddFinalizer() error {
+	addSyncFinalizer(ks.Object)
+	if err := ks.Update(ks.Context, ks.Object); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Copy takes the source object and creates a copy in the provided target namespace
+func (ks *Kopy[T]) Copy(s T, namespace string) error {
+	if err := ks.opts.checkRBACSource(s); err != nil {
+		return err
+	}
+	cp, err := newCopy(s, namespace, ks.opts)
+	if err != nil {
+		return err
+	}
+	costLabels, err := ks.opts.costLabelsOf(ks.Context, ks.Client, namespace)
+	if err != nil {
+		return err
+	}
+	stampCostLabels(cp, costLabels)
+	if err := ks.opts.checkCopyDenied(kindOf(cp), cp.GetName(), namespace); err != nil {
+		return err
+	}
+	cp, err = ks.opts.prepareCopy(ks.Context, ks.Client, s, cp)
+	if errors.Is(err, errTransformSkipped) {
+		ks.Logger().Info("not writing copy", "namespace", namespace, "reason", err.Error())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeCopy(ks.Context, ks.Client, ks.recorder, s, cp); err != nil {
+		return err
+	}
+	return ks.opts.propagateCopy(ks.Context, ks.Client, s, cp)
+}
+
+// Fetch uses the event request to retrieve object from the cache
+func (ks *Kopy[T]) Fetch(req ctrl.Request) error {
+	if err := ks.Get(ks.Context, req.NamespacedName, ks.Object); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// GetClient returns Reconciler client.Client
+func (ks *Kopy[T]) GetClient() client.Client {
+	return ks.Client
+}
+
+// GetContext returns Reconciler context.Context
+func (ks *Kopy[T]) GetContext() context.Context {
+	return ks.Context
+}
+
+func (ks *Kopy[T]) GetObject() client.Object {
+	return ks.Object
+}
+
+// GetRecorder returns the EventRecorder of the Reconciler
+func (ks *Kopy[T]) GetRecorder() record.EventRecorder {
+	return ks.recorder
+}
+
+// GetOptions returns the Options the Reconciler was configured with
+func (ks *Kopy[T]) GetOptions() Options {
+	return ks.opts
+}
+
+// LabelSelector parses the sync annotations on the object to create a label selector
+func (ks *Kopy[T]) LabelSelector() labels.Selector {
+	annotations := ks.Object.GetAnnotations()
+	v, ok := annotations[syncKey]
+	if !ok {
+		// pinned sources without the sync annotation are only copied to the namespaces they are pinned to
+		return labels.Nothing()
+	}
+	ls, err := ParseSyncSelector(v)
+	if err != nil {
+		// a malformed annotation must never fall back to selecting every namespace
+		return labels.Nothing()
+	}
+	return ls
+}
+
+// MarkedForDeletion returns true if the object is marked for deletion and contains the kopy sync finalizer field
+func (ks *Kopy[T]) MarkedForDeletion() bool {
+	return ks.Object.GetDeletionTimestamp() != nil && hasSyncFinalizer(ks.Object)
+}
+
+// SyncDeletedCopy uses the labels on the receiver object to grab a copy of the original object
+// It will Remove the finalizer from the receiver object to allow kubernetes to delete object
+// It will verify the receiver object namespace still contains the sync labels first before syncing the object back into namespace
+func (ks *Kopy[T]) SyncDeletedCopy() error {
+	log := ks.Logger()
+	originNamespace := ks.Object.GetLabels()[sourceLabelNamespace]
+	origin := ks.kind.newObject()
+	if err := ks.Get(ks.Context, types.NamespacedName{Namespace: originNamespace, Name: sourceNameOf(ks.Object)}, origin); err != nil {
+		return sourceLookupError(err)
+	}
+	ns := &corev1.Namespace{}
+	if err := ks.Get(ks.Context, types.NamespacedName{Namespace: ks.Object.GetNamespace(), Name: ks.Object.GetNamespace()}, ns); err != nil {
+		return err
+	}
+	removeSyncFinalizer(ks.Object)
+	if err := ks.Update(ks.Context, ks.Object); err != nil {
+		return err
+	}
+	if isQuarantined(origin) {
+		log.Info("source is quarantined, not restoring the deleted copy")
+		return nil
+	}
+	age, err := checkSourceAge(ks.Context, ks.Client, ks.recorder, origin)
+	if err != nil {
+		return err
+	}
+	if age.enforced {
+		log.Info("source is older than its max age, not restoring the deleted copy")
+		return nil
+	}
+	if ks.opts.syncsTo(origin, ns) {
+		return ks.Copy(origin, ns.Name)
+	}
+	log.Info("Namespace missing sync labels")
+	return nil
+}
+
+// SyncOptions returns true if the object annotations contains the sync key to be managed by the controller. Objects
+// synced by a virtual cluster are left to it unless namespaces of virtual clusters are synced like any other.
+func (ks *Kopy[T]) SyncOptions() bool {
+	if ks.opts.VClusterMode != VClusterSync && isVClusterManaged(ks.Object) {
+		return false
+	}
+	annotations := ks.Object.GetAnnotations()
+	_, ok := annotations[syncKey]
+	return ok || ks.opts.isPinned(ks.Object)
+}
+
+func (ks *Kopy[T]) SyncSource(name, sourceNamespace, targetNamespace string) error {
+	source := ks.kind.newObject()
+	req := types.NamespacedName{Namespace: sourceNamespace, Name: name}
+	if err := ks.Client.Get(ks.Context, req, source); err != nil {
+		return sourceLookupError(err)
+	}
+	// Verify that there are no other sources
+	req.Namespace, req.Name = targetNamespace, ks.opts.copyNameFor(source, targetNamespace)
+	target := ks.kind.newObject()
+	err := ks.Client.Get(ks.Context, req, target)
+	// if the object doesn't exist in targetNamespace yet, copy
+	if apierrors.IsNotFound(err) {
+		return ks.Copy(source, targetNamespace)
+	}
+	// object exists in the targetNamespace, need to verify if it contains labels "kopy.kot-labs.com/origin.namespace"
+	if isHNCPropagated(target) {
+		ks.Logger().Info("skipping object propagated by HNC", "name", name, "namespace", targetNamespace)
+		return nil
+	}
+	origin, legacy, ok := ks.opts.copyOrigin(target)
+	// if "kopy.kot-labs.com/origin.namespace" doesn't exist on the target object, overwrite it
+	if !ok {
+		return ks.Copy(source, targetNamespace)
+	}
+	if origin != sourceNamespace {
+		return fmt.Errorf("%w: %s has a different source in namespace %s", errCopyConflict, name, origin)
+	}
+	// copies of the same source labeled under a legacy domain are adopted by rewriting their labels and finalizers
+	if legacy {
+		ks.Logger().Info("adopting copy labeled under a legacy domain", "name", name, "namespace", targetNamespace)
+	}
+	return ks.Copy(source, targetNamespace)
+}
+
+// PruneCopies deletes copies of the receiver object from namespaces that are no longer selected by the
+// sync annotations, e.g. after the selector on the source was changed
+func (ks *Kopy[T]) PruneCopies(namespaces []corev1.Namespace) error {
+	copies := ks.kind.newList()
+	if err := ks.List(ks.Context, copies, listOptions(ks.Object)); err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(copies)
+	if err != nil {
+		return err
+	}
+	log := ks.Logger()
+	targets := namespaceNames(namespaces)
+	errs := make([]error, 0, len(items))
+	deferred := false
+	for _, item := range items {
+		cp := item.(client.Object)
+		if !isCopyOf(cp, ks.Object) || targets.Has(cp.GetNamespace()) {
+			continue
+		}
+		// copies requested by a KopySubscription are managed by the subscription controller
+		if _, ok := cp.GetLabels()[subscriptionLabel]; ok {
+			continue
+		}
+		if isNamespaceMarkedForDelete(ks.Context, ks.Client, cp.GetNamespace()) {
+			continue
+		}
+		wait, err := ks.GetOptions().deferPrune(ks.Context, ks.Client, ks.recorder, cp)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to check the pods using the copy in namespace %s: %w", cp.GetNamespace(), err))
+			continue
+		}
+		if wait {
+			log.Info("deferring prune of copy that is still used by pods", "name", cp.GetName(), "namespace", cp.GetNamespace())
+			deferred = true
+			continue
+		}
+		log.Info("pruning copy from namespace that is no longer selected", "name", cp.GetName(), "namespace", cp.GetNamespace())
+		if err := pruneCopy(ks.Context, ks.Client, cp); err != nil {
+			errs = append(errs, fmt.Errorf("unable to prune copy in namespace %s: %w", cp.GetNamespace(), err))
+			continue
+		}
+		if ks.recorder != nil {
+			ks.recorder.Eventf(ks.Object, corev1.EventTypeNormal, reasonCopyDeleted,
+				"Deleted copy %s from namespace %s, the namespace is no longer selected", cp.GetName(), cp.GetNamespace())
+		}
+	}
+	if len(errs) == 0 && deferred {
+		return errPruneDeferred
+	}
+	return errors.Join(errs...)
+}
+
+// SourceDeletion will grab a list objects that are copies of the receiver object and remove the
+// finalizer from the copies before removing the finalizer from the receiver object
+func (ks *Kopy[T]) SourceDeletion() error {
+	return releaseSource(ks.Context, ks.Client, ks.recorder, ks.Object, ks.kind.newList())
+}
+
+func (ks *Kopy[T]) IsCopy() bool {
+	_, ok := ks.Object.GetLabels()[sourceLabelNamespace]
+	return ok && hasSyncFinalizer(ks.Object)
+}
+
+func (ks *Kopy[T]) Logger() logr.Logger {
+	return ctrllog.Log.WithValues("controller", ks.kind.name)
+}
@@ -2,203 +2,43 @@ package controller
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"reflect"
 
-	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/types"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
-	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var _ Kopier = &KopyConfigMap{}
 
-type KopyConfigMap struct {
-	context.Context
-	client.Client
-	*corev1.ConfigMap
-}
-
-// NewKopyConfigMap creates a new instance of KopyConfigMap
-func NewKopyConfigMap(ctx context.Context, c client.Client) *KopyConfigMap {
-	return &KopyConfigMap{Context: ctx, Client: c, ConfigMap: &corev1.ConfigMap{}}
-}
-
-// AddFinalizer adds finalizer to ConfigMap object and updates object in kubernetes cluster
-func (ks *KopyConfigMap) AddFinalizer() error {
-	ctrlutil.AddFinalizer(ks.ConfigMap, syncFinalizer)
-	if err := ks.Update(ks.Context, ks.ConfigMap); err != nil {
-		return err
-	}
-	return nil
-}
-
-// Copy takes the ConfigMap Object and creates a copy in the provided target namespace
-func (ks *KopyConfigMap) Copy(s *corev1.ConfigMap, namespace string) error {
-	copy := &corev1.ConfigMap{
-		Data: s.Data,
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      s.Name,
-			Namespace: namespace,
-			Labels: map[string]string{
-				sourceLabelNamespace: s.Namespace,
-			},
-		},
-	}
-	ctrlutil.AddFinalizer(copy, syncFinalizer)
-	if err := ks.Create(ks.Context, copy); err != nil {
-		if apierrors.IsAlreadyExists(err) {
-			if err := ks.Update(ks.Context, copy); err != nil {
-				return fmt.Errorf("unable to copy ConfigMap")
-			}
-			return nil
-		}
-		return fmt.Errorf("error copying ConfigMap %s in namespace: %s", copy.GetName(), copy.GetNamespace())
-	}
-	return nil
-}
-
-// Fetch uses the event request to retrieve object from the cache
-func (ks *KopyConfigMap) Fetch(req ctrl.Request) error {
-	if err := ks.Get(ks.Context, req.NamespacedName, ks.ConfigMap); err != nil {
-		if apierrors.IsNotFound(err) {
-			return nil
-		}
-		return err
-	}
-	return nil
-}
-
-// GetClient returns Reconciler client.Client
-func (ks *KopyConfigMap) GetClient() client.Client {
-	return ks.Client
-}
-
-// GetContext returns Reconciler context.Context
-func (ks *KopyConfigMap) GetContext() context.Context {
-	return ks.Context
-}
-
-func (ks *KopyConfigMap) GetObject() client.Object {
-	return ks.ConfigMap
-}
-
-// LabelSelector parses the sync annotations on ConfigMap to create a label selector
-func (ks *KopyConfigMap) LabelSelector() labels.Selector {
-	annotations := ks.ConfigMap.GetAnnotations()
-	v := annotations[syncKey]
-	ls, _ := labels.Parse(v)
-	return ls
-}
-
-// MarkedForDeletion returns true if the ConfigMap object is marked for deletion and contains the kopy sync finalizer field
-func (ks *KopyConfigMap) MarkedForDeletion() bool {
-	return ks.ConfigMap.DeletionTimestamp != nil && ctrlutil.ContainsFinalizer(ks.ConfigMap, syncFinalizer)
-}
-
-// SyncDeletedCopy uses the labels on the receiver ConfigMap object to grab a copy of the original ConfigMap
-// It will Remove the finalizer from the receiver ConfigMap object to allow kubernetes to delete object
-// It will verify the receiver ConfigMap Object namespace still contains the sync labels first before syncing the ConfigMap back into namespace
-func (ks *KopyConfigMap) SyncDeletedCopy() error {
-	log := ks.Logger()
-	originNamespace := ks.Labels[sourceLabelNamespace]
-	originConfigMap := &corev1.ConfigMap{}
-	if err := ks.Get(ks.Context, types.NamespacedName{Namespace: originNamespace, Name: ks.Name}, originConfigMap); err != nil {
-		return err
-	}
-	ns := &corev1.Namespace{}
-	if err := ks.Get(ks.Context, types.NamespacedName{Namespace: ks.Namespace, Name: ks.Namespace}, ns); err != nil {
-		return err
-	}
-	ctrlutil.RemoveFinalizer(ks.ConfigMap, syncFinalizer)
-	if err := ks.Update(ks.Context, ks.ConfigMap); err != nil {
-		return err
-	}
-	if namespaceContainsSyncLabel(originConfigMap, ns) {
-		return ks.Copy(originConfigMap, ns.Name)
-	}
-	log.Info("Namespace missing sync labels")
-	return nil
-}
-
-// SyncOptions returns true if the object annotations contains the sync key to be managed by the controller
-func (ks *KopyConfigMap) SyncOptions() bool {
-	annotations := ks.GetAnnotations()
-	_, ok := annotations[syncKey]
-	return ok
-}
-
-func (ks *KopyConfigMap) SyncSource(name, sourceNamespace, targetNamespace string) error {
-	sourceConfigMap := &corev1.ConfigMap{}
-	req := types.NamespacedName{Namespace: sourceNamespace, Name: name}
-	if err := ks.Client.Get(ks.Context, req, sourceConfigMap); err != nil {
-		return err
-	}
-	// Verify that there are no other sources
-	req.Namespace = targetNamespace
-	targetConfigMap := &corev1.ConfigMap{}
-	err := ks.Client.Get(ks.Context, req, targetConfigMap)
-	// if configmap doesn't exist in targetNamespace yet, copy
-	if apierrors.IsNotFound(err) {
-		return ks.Copy(sourceConfigMap, targetNamespace)
-	}
-	// configmap exists in the targetNamespace, need to verify if it contains labels "kopy.kot-labs.com/origin.namespace"
-	origin, ok := targetConfigMap.Labels[sourceLabelNamespace]
-	// if "kopy.kot-labs.com/origin.namespace" doesn't exist on the target configmap, overwrite it
-	if !ok {
-		return ks.Copy(sourceConfigMap, targetNamespace)
-	}
-	if origin != sourceNamespace {
-		return fmt.Errorf("%s has a different source in namespace %s", name, origin)
-	}
-	return ks.Copy(sourceConfigMap, targetNamespace)
-
-}
-
-// SourceDeletion will grab a list objects that are copies of the receiver ConfigMap object and remove the
-// finalizer from the copies before removing the finalizer from the receiver ConfigMap object
-func (ks *KopyConfigMap) SourceDeletion() error {
-	copies := &corev1.ConfigMapList{}
-	if err := ks.List(ks.Context, copies, listOptions(ks.ConfigMap)); err != nil {
-		return err
-	}
-	log := ks.Logger()
-	errs := make([]error, 0, len(copies.Items))
-	for _, cp := range copies.Items {
-		if cp.Name != ks.ConfigMap.Name {
-			continue
+// KopyConfigMap copies ConfigMaps
+type KopyConfigMap = Kopy[*corev1.ConfigMap]
+
+var configMapKind = registerKind(kopyKind[*corev1.ConfigMap]{
+	name:      "configmap",
+	group:     "",
+	resource:  "configmaps",
+	newObject: func() *corev1.ConfigMap { return &corev1.ConfigMap{} },
+	newList:   func() client.ObjectList { return &corev1.ConfigMapList{} },
+	copySpec: func(src, dst *corev1.ConfigMap) {
+		dst.Data, dst.BinaryData = src.Data, src.BinaryData
+	},
+	isCurrent: func(src, cp *corev1.ConfigMap) bool {
+		return reflect.DeepEqual(src.Data, cp.Data) && reflect.DeepEqual(src.BinaryData, cp.BinaryData)
+	},
+	payload: func(o *corev1.ConfigMap) map[string][]byte {
+		data := map[string][]byte{}
+		for k, v := range o.Data {
+			data[k] = []byte(v)
 		}
-		if ctrlutil.ContainsFinalizer(&cp, syncFinalizer) {
-			log.Info("need to remove finalizer from copy", "name", cp.Name, "namespace", cp.Namespace)
-			ctrlutil.RemoveFinalizer(&cp, syncFinalizer)
-			delete(cp.Labels, sourceLabelNamespace)
-			log.Info("remove labels from copy", "name", cp.Name, "namespace", cp.Namespace)
-			if err := ks.Update(ks.Context, &cp); err != nil {
-				log.Info("unable to remove finalizer from copy in namespace " + cp.Namespace)
-				errs = append(errs, fmt.Errorf("unable to remove finalizer from copy in namespace %s", cp.Namespace))
-			}
+		for k, v := range o.BinaryData {
+			data[k] = v
 		}
-	}
-	if len(errs) > 0 {
-		return errors.Join(errs...)
-	}
-	log.Info("removing finalizer from source", "name", ks.ConfigMap.Name)
-	ctrlutil.RemoveFinalizer(ks.ConfigMap, syncFinalizer)
-	return ks.Update(ks.Context, ks.ConfigMap)
-}
-
-func (ks *KopyConfigMap) IsCopy() bool {
-	_, ok := ks.ConfigMap.Labels[sourceLabelNamespace]
-	ctrlutil.ContainsFinalizer(ks.ConfigMap, syncFinalizer)
-	return ok && ctrlutil.ContainsFinalizer(ks.ConfigMap, syncFinalizer)
-}
+		return data
+	},
+}, "configmaps", "cm")
 
-func (ks *KopyConfigMap) Logger() logr.Logger {
-	return ctrllog.Log.WithValues("controller", "configMap")
+// NewKopyConfigMap creates a new instance of KopyConfigMap, recorder is used to emit events and may be nil
+func NewKopyConfigMap(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) *KopyConfigMap {
+	return newKopy(ctx, c, configMapKind, opts, recorder)
 }
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=limitranges,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=limitranges/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+var _ Kopier = &KopyLimitRange{}
+
+// KopyLimitRange copies the spec of LimitRanges
+type KopyLimitRange = Kopy[*corev1.LimitRange]
+
+var limitRangeKind = registerKind(kopyKind[*corev1.LimitRange]{
+	name:      "limitrange",
+	group:     "",
+	resource:  "limitranges",
+	newObject: func() *corev1.LimitRange { return &corev1.LimitRange{} },
+	newList:   func() client.ObjectList { return &corev1.LimitRangeList{} },
+	copySpec:  func(src, dst *corev1.LimitRange) { dst.Spec = src.Spec },
+	isCurrent: func(src, cp *corev1.LimitRange) bool { return reflect.DeepEqual(src.Spec, cp.Spec) },
+	payload: func(o *corev1.LimitRange) map[string][]byte {
+		return jsonPayload(map[string]any{"spec": o.Spec})
+	},
+}, "limitranges", "limits")
+
+// NewKopyLimitRange creates a new instance of KopyLimitRange, recorder is used to emit events and may be nil
+func NewKopyLimitRange(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) *KopyLimitRange {
+	return newKopy(ctx, c, limitRangeKind, opts, recorder)
+}
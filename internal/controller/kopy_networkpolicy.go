@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+var _ Kopier = &KopyNetworkPolicy{}
+
+// KopyNetworkPolicy copies the spec of NetworkPolicies
+type KopyNetworkPolicy = Kopy[*networkingv1.NetworkPolicy]
+
+var networkPolicyKind = registerKind(kopyKind[*networkingv1.NetworkPolicy]{
+	name:      "networkpolicy",
+	group:     "networking.k8s.io",
+	resource:  "networkpolicies",
+	newObject: func() *networkingv1.NetworkPolicy { return &networkingv1.NetworkPolicy{} },
+	newList:   func() client.ObjectList { return &networkingv1.NetworkPolicyList{} },
+	// the pod selector of the copy selects pods in the target namespace
+	copySpec:  func(src, dst *networkingv1.NetworkPolicy) { dst.Spec = src.Spec },
+	isCurrent: func(src, cp *networkingv1.NetworkPolicy) bool { return reflect.DeepEqual(src.Spec, cp.Spec) },
+	payload: func(o *networkingv1.NetworkPolicy) map[string][]byte {
+		return jsonPayload(map[string]any{"spec": o.Spec})
+	},
+}, "networkpolicies", "netpol")
+
+// NewKopyNetworkPolicy creates a new instance of KopyNetworkPolicy, recorder is used to emit events and may be nil
+func NewKopyNetworkPolicy(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) *KopyNetworkPolicy {
+	return newKopy(ctx, c, networkPolicyKind, opts, recorder)
+}
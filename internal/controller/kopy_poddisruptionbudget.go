@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+var _ Kopier = &KopyPodDisruptionBudget{}
+
+// KopyPodDisruptionBudget copies the spec of PodDisruptionBudgets
+type KopyPodDisruptionBudget = Kopy[*policyv1.PodDisruptionBudget]
+
+var podDisruptionBudgetKind = registerKind(kopyKind[*policyv1.PodDisruptionBudget]{
+	name:      "poddisruptionbudget",
+	group:     "policy",
+	resource:  "poddisruptionbudgets",
+	newObject: func() *policyv1.PodDisruptionBudget { return &policyv1.PodDisruptionBudget{} },
+	newList:   func() client.ObjectList { return &policyv1.PodDisruptionBudgetList{} },
+	// the selector of the copy selects pods in the target namespace, the status is computed for the copy
+	copySpec:  func(src, dst *policyv1.PodDisruptionBudget) { dst.Spec = src.Spec },
+	isCurrent: func(src, cp *policyv1.PodDisruptionBudget) bool { return reflect.DeepEqual(src.Spec, cp.Spec) },
+	payload: func(o *policyv1.PodDisruptionBudget) map[string][]byte {
+		return jsonPayload(map[string]any{"spec": o.Spec})
+	},
+}, "poddisruptionbudgets", "pdb")
+
+// NewKopyPodDisruptionBudget creates a new instance of KopyPodDisruptionBudget, recorder is used to emit events and may be nil
+func NewKopyPodDisruptionBudget(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) *KopyPodDisruptionBudget {
+	return newKopy(ctx, c, podDisruptionBudgetKind, opts, recorder)
+}
@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=resourcequotas,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=resourcequotas/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+var _ Kopier = &KopyResourceQuota{}
+
+// KopyResourceQuota copies the spec of ResourceQuotas
+type KopyResourceQuota = Kopy[*corev1.ResourceQuota]
+
+var resourceQuotaKind = registerKind(kopyKind[*corev1.ResourceQuota]{
+	name:      "resourcequota",
+	group:     "",
+	resource:  "resourcequotas",
+	newObject: func() *corev1.ResourceQuota { return &corev1.ResourceQuota{} },
+	newList:   func() client.ObjectList { return &corev1.ResourceQuotaList{} },
+	// the usage in the status is tracked by the quota controller of the target namespace
+	copySpec:  func(src, dst *corev1.ResourceQuota) { dst.Spec = src.Spec },
+	isCurrent: func(src, cp *corev1.ResourceQuota) bool { return reflect.DeepEqual(src.Spec, cp.Spec) },
+	payload: func(o *corev1.ResourceQuota) map[string][]byte {
+		return jsonPayload(map[string]any{"spec": o.Spec})
+	},
+}, "resourcequotas", "quota")
+
+// NewKopyResourceQuota creates a new instance of KopyResourceQuota, recorder is used to emit events and may be nil
+func NewKopyResourceQuota(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) *KopyResourceQuota {
+	return newKopy(ctx, c, resourceQuotaKind, opts, recorder)
+}
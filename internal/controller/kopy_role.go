@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The permissions on roles aren't part of the default role, the controller only starts when they are granted, see
+// the RBAC section of the README.
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+var _ Kopier = &KopyRole{}
+
+// KopyRole copies the rules of Roles
+type KopyRole = Kopy[*rbacv1.Role]
+
+var roleKind = registerKind(kopyKind[*rbacv1.Role]{
+	name:      "role",
+	group:     "rbac.authorization.k8s.io",
+	resource:  "roles",
+	newObject: func() *rbacv1.Role { return &rbacv1.Role{} },
+	newList:   func() client.ObjectList { return &rbacv1.RoleList{} },
+	copySpec:  func(src, dst *rbacv1.Role) { dst.Rules = src.Rules },
+	isCurrent: func(src, cp *rbacv1.Role) bool { return reflect.DeepEqual(src.Rules, cp.Rules) },
+	payload: func(o *rbacv1.Role) map[string][]byte {
+		return jsonPayload(map[string]any{"rules": o.Rules})
+	},
+}, "roles")
+
+// NewKopyRole creates a new instance of KopyRole, recorder is used to emit events and may be nil
+func NewKopyRole(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) *KopyRole {
+	return newKopy(ctx, c, roleKind, opts, recorder)
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The permissions on role bindings aren't part of the default role, the controller only starts when they are granted,
+// see the RBAC section of the README.
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+var _ Kopier = &KopyRoleBinding{}
+
+// KopyRoleBinding copies the subjects and role reference of RoleBindings
+type KopyRoleBinding = Kopy[*rbacv1.RoleBinding]
+
+var roleBindingKind = registerKind(kopyKind[*rbacv1.RoleBinding]{
+	name:      "rolebinding",
+	group:     "rbac.authorization.k8s.io",
+	resource:  "rolebindings",
+	newObject: func() *rbacv1.RoleBinding { return &rbacv1.RoleBinding{} },
+	newList:   func() client.ObjectList { return &rbacv1.RoleBindingList{} },
+	// a role reference of kind Role refers to the Role of the same name in the target namespace
+	copySpec: func(src, dst *rbacv1.RoleBinding) {
+		dst.Subjects, dst.RoleRef = src.Subjects, src.RoleRef
+	},
+	isCurrent: func(src, cp *rbacv1.RoleBinding) bool {
+		return reflect.DeepEqual(src.Subjects, cp.Subjects) && src.RoleRef == cp.RoleRef
+	},
+	payload: func(o *rbacv1.RoleBinding) map[string][]byte {
+		return jsonPayload(map[string]any{"subjects": o.Subjects, "roleRef": o.RoleRef})
+	},
+}, "rolebindings")
+
+// NewKopyRoleBinding creates a new instance of KopyRoleBinding, recorder is used to emit events and may be nil
+func NewKopyRoleBinding(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) *KopyRoleBinding {
+	return newKopy(ctx, c, roleBindingKind, opts, recorder)
+}
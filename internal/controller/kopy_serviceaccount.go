@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+var _ Kopier = &KopyServiceAccount{}
+
+// KopyServiceAccount copies ServiceAccounts along with their image pull secret references
+type KopyServiceAccount = Kopy[*corev1.ServiceAccount]
+
+var serviceAccountKind = registerKind(kopyKind[*corev1.ServiceAccount]{
+	name:      "serviceaccount",
+	group:     "",
+	resource:  "serviceaccounts",
+	newObject: func() *corev1.ServiceAccount { return &corev1.ServiceAccount{} },
+	newList:   func() client.ObjectList { return &corev1.ServiceAccountList{} },
+	// the image pull secrets are referenced by name in the target namespace, the token secrets of the source are
+	// bound to the service account in the source namespace and aren't carried over
+	copySpec: func(src, dst *corev1.ServiceAccount) {
+		dst.ImagePullSecrets, dst.AutomountServiceAccountToken = src.ImagePullSecrets, src.AutomountServiceAccountToken
+	},
+	isCurrent: func(src, cp *corev1.ServiceAccount) bool {
+		return reflect.DeepEqual(src.ImagePullSecrets, cp.ImagePullSecrets) &&
+			reflect.DeepEqual(src.AutomountServiceAccountToken, cp.AutomountServiceAccountToken)
+	},
+	payload: func(o *corev1.ServiceAccount) map[string][]byte {
+		fields := map[string]any{}
+		if len(o.ImagePullSecrets) > 0 {
+			fields["imagePullSecrets"] = o.ImagePullSecrets
+		}
+		if o.AutomountServiceAccountToken != nil {
+			fields["automountServiceAccountToken"] = o.AutomountServiceAccountToken
+		}
+		return jsonPayload(fields)
+	},
+}, "serviceaccounts", "sa")
+
+// NewKopyServiceAccount creates a new instance of KopyServiceAccount, recorder is used to emit events and may be nil
+func NewKopyServiceAccount(ctx context.Context, c client.Client, opts Options, recorder record.EventRecorder) *KopyServiceAccount {
+	return newKopy(ctx, c, serviceAccountKind, opts, recorder)
+}
@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+const (
+	// publicationKey is set on objects whose kopy annotations are managed by a KopyPublication
+	publicationKey = kopyPrefix + "publication"
+	// publishTenantsKey restricts which namespaces may subscribe to a published source
+	publishTenantsKey = kopyPrefix + "publish-tenants"
+	// publishClustersKey lists the remote clusters a source is published to, for tooling that syncs across clusters
+	publishClustersKey = kopyPrefix + "publish-clusters"
+)
+
+// KopyPublicationReconciler reconciles a KopyPublication object
+type KopyPublicationReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Options Options
+}
+
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopypublications,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopypublications/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopypublications/finalizers,verbs=update
+
+// Reconcile manages the kopy annotations of every object listed in the KopyPublication so the objects are synced
+// and published the same way as objects that were annotated by hand
+func (r *KopyPublicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	pub := &syncv1alpha1.KopyPublication{}
+	if err := r.Get(ctx, req.NamespacedName, pub); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if pub.DeletionTimestamp != nil {
+		if err := r.releaseObjects(ctx, pub, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		if removeSyncFinalizer(pub) {
+			return ctrl.Result{}, r.Update(ctx, pub)
+		}
+		return ctrl.Result{}, nil
+	}
+	if addSyncFinalizer(pub) {
+		if err := r.Update(ctx, pub); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	condition := metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Published", Message: "all objects are published"}
+	selector, err := publicationSelector(pub)
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "InvalidSelector"
+		condition.Message = err.Error()
+		meta.SetStatusCondition(&pub.Status.Conditions, condition)
+		return ctrl.Result{}, r.Status().Update(ctx, pub)
+	}
+
+	statuses := make([]syncv1alpha1.PublishedObjectStatus, 0, len(pub.Spec.Objects))
+	errs := make([]error, 0, len(pub.Spec.Objects))
+	for _, po := range pub.Spec.Objects {
+		status := syncv1alpha1.PublishedObjectStatus{PublishedObject: po}
+		if err := r.publishObject(ctx, pub, po, selector); err != nil {
+			status.Message = err.Error()
+			if condition.Status == metav1.ConditionTrue {
+				condition.Status = metav1.ConditionFalse
+				condition.Reason = "PublishFailed"
+				condition.Message = fmt.Sprintf("%s %s: %s", po.Kind, po.Name, err)
+			}
+			if !apierrors.IsNotFound(err) && !errors.Is(err, errNotSubscribable) {
+				errs = append(errs, err)
+			}
+		} else {
+			status.Published = true
+		}
+		statuses = append(statuses, status)
+	}
+	if err := r.releaseObjects(ctx, pub, pub.Spec.Objects); err != nil {
+		errs = append(errs, err)
+	}
+	pub.Status.Objects = statuses
+	meta.SetStatusCondition(&pub.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, pub); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		log.Error(errors.Join(errs...), "unable to publish objects")
+		return ctrl.Result{}, errors.Join(errs...)
+	}
+	return ctrl.Result{}, nil
+}
+
+// publishObject sets the kopy annotations described by pub on the object po
+func (r *KopyPublicationReconciler) publishObject(ctx context.Context, pub *syncv1alpha1.KopyPublication, po syncv1alpha1.PublishedObject, selector string) error {
+	o, err := NewObjectForKind(po.Kind)
+	if err != nil {
+		return err
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pub.Namespace, Name: po.Name}, o); err != nil {
+		return err
+	}
+	annotations := o.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if owner, ok := annotations[publicationKey]; ok && owner != pub.Name {
+		return fmt.Errorf("%w: object is already published by %s", errNotSubscribable, owner)
+	}
+	_, synced := annotations[syncKey]
+	_, published := annotations[publishKey]
+	if _, ok := annotations[publicationKey]; !ok && (synced || published) {
+		return fmt.Errorf("%w: object is already managed by its own kopy annotations", errNotSubscribable)
+	}
+	patch := client.MergeFrom(o.DeepCopyObject().(client.Object))
+	annotations[publicationKey] = pub.Name
+	annotations[publishKey] = "true"
+	delete(annotations, publishTenantsKey)
+	if len(pub.Spec.Tenants) > 0 {
+		annotations[publishTenantsKey] = strings.Join(pub.Spec.Tenants, ",")
+	}
+	delete(annotations, publishClustersKey)
+	if len(pub.Spec.Clusters) > 0 {
+		annotations[publishClustersKey] = strings.Join(pub.Spec.Clusters, ",")
+	}
+	delete(annotations, syncKey)
+	if selector != "" {
+		annotations[syncKey] = selector
+	}
+	o.SetAnnotations(annotations)
+	return r.Patch(ctx, o, patch)
+}
+
+// releaseObjects removes the kopy annotations from objects published by pub that are not in keep
+func (r *KopyPublicationReconciler) releaseObjects(ctx context.Context, pub *syncv1alpha1.KopyPublication, keep []syncv1alpha1.PublishedObject) error {
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.InNamespace(pub.Namespace)); err != nil {
+		return err
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.List(ctx, configMaps, client.InNamespace(pub.Namespace)); err != nil {
+		return err
+	}
+	objects := make([]client.Object, 0, len(secrets.Items)+len(configMaps.Items))
+	for i := range secrets.Items {
+		objects = append(objects, &secrets.Items[i])
+	}
+	for i := range configMaps.Items {
+		objects = append(objects, &configMaps.Items[i])
+	}
+	errs := make([]error, 0)
+	for _, o := range objects {
+		if o.GetAnnotations()[publicationKey] != pub.Name {
+			continue
+		}
+		if slices.Contains(keep, syncv1alpha1.PublishedObject{Kind: subscriptionKind(o), Name: o.GetName()}) {
+			continue
+		}
+		patch := client.MergeFrom(o.DeepCopyObject().(client.Object))
+		annotations := o.GetAnnotations()
+		for _, k := range []string{publicationKey, publishKey, publishTenantsKey, publishClustersKey, syncKey} {
+			delete(annotations, k)
+		}
+		o.SetAnnotations(annotations)
+		if err := r.Patch(ctx, o, patch); err != nil {
+			errs = append(errs, client.IgnoreNotFound(err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// watchPublishedObjects maps Secrets and ConfigMaps to the publications in their namespace that list them
+func (r *KopyPublicationReconciler) watchPublishedObjects(ctx context.Context, o client.Object) []reconcile.Request {
+	pubs := &syncv1alpha1.KopyPublicationList{}
+	if err := r.List(ctx, pubs, client.InNamespace(o.GetNamespace())); err != nil {
+		ctrllog.FromContext(ctx).Info("unable to grab a list of publications")
+		return nil
+	}
+	po := syncv1alpha1.PublishedObject{Kind: subscriptionKind(o), Name: o.GetName()}
+	req := make([]reconcile.Request, 0, len(pubs.Items))
+	for _, pub := range pubs.Items {
+		if slices.Contains(pub.Spec.Objects, po) || o.GetAnnotations()[publicationKey] == pub.Name {
+			req = append(req, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pub)})
+		}
+	}
+	return req
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KopyPublicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	debugState.watch("kopypublication", "KopyPublication", "Secret", "ConfigMap")
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&syncv1alpha1.KopyPublication{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.watchPublishedObjects), r.Options.secretWatchOptions()...).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.watchPublishedObjects)).
+		Complete(r)
+}
+
+// publicationSelector converts the namespace selector of pub into the sync annotation format
+func publicationSelector(pub *syncv1alpha1.KopyPublication) (string, error) {
+	if pub.Spec.NamespaceSelector == nil {
+		return "", nil
+	}
+	return namespaceSelectorAnnotation(pub.Spec.NamespaceSelector)
+}
+
+// namespaceSelectorAnnotation converts a namespace selector into the sync annotation format. Empty selectors are
+// rejected since they match every namespace.
+func namespaceSelectorAnnotation(selector *metav1.LabelSelector) (string, error) {
+	ls, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return "", err
+	}
+	if ls.Empty() {
+		return "", fmt.Errorf("namespaceSelector must not be empty, an empty selector matches every namespace")
+	}
+	return ls.String(), nil
+}
+
+// canSubscribe returns true if namespace is allowed to subscribe to the published source
+func canSubscribe(src client.Object, namespace string) bool {
+	if !isPublished(src) {
+		return false
+	}
+	tenants, ok := src.GetAnnotations()[publishTenantsKey]
+	return !ok || slices.Contains(strings.Split(tenants, ","), namespace)
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+var _ = Describe("KopyPublication Controller\n", func() {
+	Context("When a publication lists a configmap", func() {
+		It("Should manage the kopy annotations of the configmap", func() {
+			By("Creating the configmap without annotations")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+			}{
+				name: "test-src-publication-00", namespace: "test-src-publication-ns-00",
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			_, err = tc.CreateConfigMap(src.name, src.namespace, nil, map[string]string{"ca.crt": src.name})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Creating the publication")
+			pub := &syncv1alpha1.KopyPublication{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: src.namespace},
+				Spec: syncv1alpha1.KopyPublicationSpec{
+					Objects: []syncv1alpha1.PublishedObject{{Kind: "ConfigMap", Name: src.name}},
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{testLabelKey: testLabelValue},
+					},
+					Tenants: []string{"team-a", "team-b"},
+				},
+			}
+			Expect(k8sClient.Create(tc.ctx, pub)).ShouldNot(HaveOccurred())
+
+			By("Verifying the configmap was annotated by the publication")
+			Eventually(func() map[string]string {
+				cm := &corev1.ConfigMap{}
+				tc.GetConfigMap(src.name, src.namespace, cm)
+				return cm.Annotations
+			}, timeout, interval).Should(And(
+				HaveKeyWithValue(publicationKey, pub.Name),
+				HaveKeyWithValue(publishKey, "true"),
+				HaveKeyWithValue(publishTenantsKey, "team-a,team-b"),
+				HaveKeyWithValue(syncKey, testLabelKey+"="+testLabelValue),
+			))
+			Eventually(func() bool {
+				if err := k8sClient.Get(tc.ctx, types.NamespacedName{Namespace: pub.Namespace, Name: pub.Name}, pub); err != nil {
+					return false
+				}
+				return meta.IsStatusConditionTrue(pub.Status.Conditions, "Ready")
+			}, timeout, interval).Should(BeTrue())
+
+			By("Deleting the publication")
+			Expect(k8sClient.Delete(tc.ctx, pub)).ShouldNot(HaveOccurred())
+
+			By("Verifying the annotations were removed from the configmap")
+			Eventually(func() map[string]string {
+				cm := &corev1.ConfigMap{}
+				tc.GetConfigMap(src.name, src.namespace, cm)
+				return cm.Annotations
+			}, timeout, interval).ShouldNot(Or(HaveKey(publicationKey), HaveKey(publishKey), HaveKey(syncKey)))
+		})
+	})
+})
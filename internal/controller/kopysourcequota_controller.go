@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+const (
+	// rejectedKey is set by kopy on a source that exceeds the source quota of its namespace to the reason it is not
+	// synced
+	rejectedKey = kopyPrefix + "rejected"
+	// reasonQuotaExceeded is used for events on sources rejected by a source quota
+	reasonQuotaExceeded = "QuotaExceeded"
+)
+
+// KopySourceQuotaReconciler reconciles a KopySourceQuota object
+type KopySourceQuotaReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Options  Options
+	recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopysourcequotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopysourcequotas/status,verbs=get;update;patch
+
+// Reconcile counts the sources in the namespace of the KopySourceQuota, reports the sources over the limit in its
+// status and marks them as rejected. Sources that fit the quota again lose the rejected annotation, which triggers
+// their sync.
+func (r *KopySourceQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	quota := &syncv1alpha1.KopySourceQuota{}
+	if err := r.Get(ctx, req.NamespacedName, quota); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	usage, err := r.Options.sourceQuotaUsage(ctx, r.Client, req.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	errs := []error{}
+	for _, s := range usage.sources {
+		if err := applySourceQuota(ctx, r.Client, r.recorder, s.obj, usage.rejection(s.kind, s.obj)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	quota.Status.Used = int32(len(usage.sources))
+	quota.Status.Rejected = usage.rejectedNames()
+	condition := metav1.Condition{Type: "Exceeded", Status: metav1.ConditionFalse, Reason: "WithinQuota",
+		Message: fmt.Sprintf("%d of %d sources are used", len(usage.sources), usage.max)}
+	if len(quota.Status.Rejected) > 0 {
+		condition.Status, condition.Reason = metav1.ConditionTrue, "SourcesRejected"
+		condition.Message = fmt.Sprintf("%d sources exceed the quota of %d and are not synced: %s",
+			len(quota.Status.Rejected), usage.max, strings.Join(quota.Status.Rejected, ", "))
+	}
+	meta.SetStatusCondition(&quota.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, quota); err != nil {
+		errs = append(errs, err)
+	}
+	return ctrl.Result{}, errors.Join(errs...)
+}
+
+// quotaUsage are the sources of a namespace in the order they are admitted by the tightest source quota of the
+// namespace
+type quotaUsage struct {
+	quota   string
+	max     int
+	sources []quotaSource
+}
+
+// quotaSource is a source counted by a source quota. The kind is kept apart since Secrets may be listed as metadata
+// only.
+type quotaSource struct {
+	kind string
+	obj  client.Object
+}
+
+// rejection returns why the source of kind is rejected by the quota, or "" if it fits the quota
+func (u *quotaUsage) rejection(kind string, src client.Object) string {
+	if u.quota == "" {
+		return ""
+	}
+	for i, s := range u.sources {
+		if s.kind == kind && s.obj.GetName() == src.GetName() {
+			if i < u.max {
+				return ""
+			}
+			return fmt.Sprintf("source %d of namespace %s exceeds the source quota %s of %d sources",
+				i+1, src.GetNamespace(), u.quota, u.max)
+		}
+	}
+	return ""
+}
+
+// rejectedNames returns the sources over the limit as kind/name
+func (u *quotaUsage) rejectedNames() []string {
+	names := []string{}
+	for i := u.max; i < len(u.sources); i++ {
+		names = append(names, u.sources[i].kind+"/"+u.sources[i].obj.GetName())
+	}
+	return names
+}
+
+// sourceQuotaUsage returns the sources of namespace ordered by creation time and the tightest KopySourceQuota of the
+// namespace. The usage has no quota if the namespace has none.
+func (o Options) sourceQuotaUsage(ctx context.Context, c client.Client, namespace string) (*quotaUsage, error) {
+	quotas := &syncv1alpha1.KopySourceQuotaList{}
+	if err := c.List(ctx, quotas, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	usage := &quotaUsage{}
+	for _, q := range quotas.Items {
+		if usage.quota == "" || int(q.Spec.MaxSources) < usage.max ||
+			(int(q.Spec.MaxSources) == usage.max && q.Name < usage.quota) {
+			usage.quota, usage.max = q.Name, int(q.Spec.MaxSources)
+		}
+	}
+	if usage.quota == "" {
+		return usage, nil
+	}
+	_, secrets := o.secretSources()
+	lists := map[string]client.ObjectList{"secret": secrets, "configmap": &corev1.ConfigMapList{}}
+	for kind, list := range lists {
+		if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		if err := meta.EachListItem(list, func(obj runtime.Object) error {
+			if o, ok := obj.(client.Object); ok {
+				if _, ok := SyncSelector(o); ok {
+					usage.sources = append(usage.sources, quotaSource{kind: kind, obj: o})
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(usage.sources, func(i, j int) bool {
+		a, b := usage.sources[i], usage.sources[j]
+		ta, tb := a.obj.GetCreationTimestamp(), b.obj.GetCreationTimestamp()
+		if !ta.Equal(&tb) {
+			return ta.Before(&tb)
+		}
+		return a.kind+"/"+a.obj.GetName() < b.kind+"/"+b.obj.GetName()
+	})
+	return usage, nil
+}
+
+// rejectedByQuota returns true if src exceeds the source quota of its namespace and must not be synced. The rejected
+// annotation of src is kept in sync.
+func (o Options) rejectedByQuota(ctx context.Context, c client.Client, recorder record.EventRecorder, src client.Object) (bool, error) {
+	if !o.SourceQuotas {
+		return false, nil
+	}
+	usage, err := o.sourceQuotaUsage(ctx, c, src.GetNamespace())
+	if err != nil {
+		return false, err
+	}
+	reason := usage.rejection(kindOf(src), src)
+	return reason != "", applySourceQuota(ctx, c, recorder, src, reason)
+}
+
+// applySourceQuota sets the rejected annotation of src to reason, or removes it if reason is empty, and emits an
+// event when src is newly rejected
+func applySourceQuota(ctx context.Context, c client.Client, recorder record.EventRecorder, src client.Object, reason string) error {
+	annotations := src.GetAnnotations()
+	if annotations[rejectedKey] == reason {
+		return nil
+	}
+	if _, ok := annotations[rejectedKey]; !ok && reason == "" {
+		return nil
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	if reason == "" {
+		delete(annotations, rejectedKey)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[rejectedKey] = reason
+	}
+	src.SetAnnotations(annotations)
+	if err := c.Patch(ctx, src, patch); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if reason != "" {
+		recorder.Eventf(src, corev1.EventTypeWarning, reasonQuotaExceeded, "Not synced: %s", reason)
+	}
+	return nil
+}
+
+// watchQuotaSources maps a change to a source to the source quotas of its namespace
+func (r *KopySourceQuotaReconciler) watchQuotaSources(ctx context.Context, o client.Object) []reconcile.Request {
+	quotas := &syncv1alpha1.KopySourceQuotaList{}
+	if err := r.List(ctx, quotas, client.InNamespace(o.GetNamespace())); err != nil {
+		return nil
+	}
+	req := make([]reconcile.Request, 0, len(quotas.Items))
+	for _, q := range quotas.Items {
+		req = append(req, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&q)})
+	}
+	return req
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KopySourceQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = mgr.GetEventRecorderFor("kopy-sourcequota-controller")
+	debugState.watch("kopysourcequota", "KopySourceQuota", "Secret", "ConfigMap")
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&syncv1alpha1.KopySourceQuota{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.watchQuotaSources), r.Options.secretWatchOptions()...).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.watchQuotaSources)).
+		Complete(r)
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+var _ = Describe("KopySourceQuota Controller\n", func() {
+	const namespace = "test-src-quota-ns-00"
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSecret := func(name string, age time.Duration) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(created.Add(-age)),
+			Annotations: map[string]string{syncKey: "env=prod"},
+		}}
+	}
+	newConfigMap := func(name string, age time.Duration) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(created.Add(-age)),
+			Annotations: map[string]string{syncKey: "env=prod"},
+		}}
+	}
+	newQuota := func(name string, maxSources int32) *syncv1alpha1.KopySourceQuota {
+		return &syncv1alpha1.KopySourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       syncv1alpha1.KopySourceQuotaSpec{MaxSources: maxSources},
+		}
+	}
+	newClient := func(objs ...client.Object) client.Client {
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).Should(Succeed())
+		Expect(syncv1alpha1.AddToScheme(s)).Should(Succeed())
+		return fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).
+			WithStatusSubresource(&syncv1alpha1.KopySourceQuota{}).Build()
+	}
+
+	It("Should reject the newest sources beyond the tightest quota", func() {
+		oldest, older, newest := newSecret("test-src-quota-00", 3*time.Hour), newConfigMap("test-src-quota-01", 2*time.Hour), newSecret("test-src-quota-02", time.Hour)
+		unsynced := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-src-quota-03", Namespace: namespace}}
+		c := newClient(oldest, older, newest, unsynced, newQuota("test-quota-00", 5), newQuota("test-quota-01", 2))
+		opts := Options{SourceQuotas: true}
+		recorder := record.NewFakeRecorder(2)
+
+		rejected, err := opts.rejectedByQuota(context.Background(), c, recorder, oldest)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rejected).Should(BeFalse())
+		rejected, err = opts.rejectedByQuota(context.Background(), c, recorder, newest)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rejected).Should(BeTrue())
+		Expect(newest.Annotations).Should(HaveKeyWithValue(rejectedKey, ContainSubstring("test-quota-01")))
+		Expect(<-recorder.Events).Should(ContainSubstring(reasonQuotaExceeded))
+
+		rejected, err = Options{}.rejectedByQuota(context.Background(), c, recorder, newest)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rejected).Should(BeFalse())
+	})
+
+	It("Should report rejected sources in the status and admit them again once they fit", func() {
+		src := newSecret("test-src-quota-00", time.Hour)
+		src.Annotations[rejectedKey] = "source 2 of namespace test-src-quota-ns-00 exceeds the source quota"
+		quota := newQuota("test-quota-00", 1)
+		c := newClient(newConfigMap("test-src-quota-01", 2*time.Hour), src, quota)
+		r := &KopySourceQuotaReconciler{Client: c, Options: Options{SourceQuotas: true}, recorder: record.NewFakeRecorder(2)}
+
+		_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(quota)})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(quota), quota)).Should(Succeed())
+		Expect(quota.Status.Used).Should(BeEquivalentTo(2))
+		Expect(quota.Status.Rejected).Should(Equal([]string{"secret/test-src-quota-00"}))
+		Expect(meta.IsStatusConditionTrue(quota.Status.Conditions, "Exceeded")).Should(BeTrue())
+
+		quota.Spec.MaxSources = 2
+		Expect(c.Update(context.Background(), quota)).Should(Succeed())
+		_, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(quota)})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(quota), quota)).Should(Succeed())
+		Expect(quota.Status.Rejected).Should(BeEmpty())
+		Expect(meta.IsStatusConditionFalse(quota.Status.Conditions, "Exceeded")).Should(BeTrue())
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Annotations).ShouldNot(HaveKey(rejectedKey))
+	})
+})
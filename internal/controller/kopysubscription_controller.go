@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+const (
+	// publishKey is set to "true" on a source by the owners of the source namespace to make the source available
+	// to KopySubscriptions; the set of published sources is the catalog tenants can subscribe to
+	publishKey = kopyPrefix + "publish"
+	// subscriptionLabel is set on copies created for a KopySubscription and contains the name of the subscription
+	subscriptionLabel = kopyPrefix + "subscription"
+)
+
+// KopySubscriptionReconciler reconciles a KopySubscription object
+type KopySubscriptionReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Options Options
+}
+
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopysubscriptions,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopysubscriptions/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopysubscriptions/finalizers,verbs=update
+
+// Reconcile copies every published source listed in the KopySubscription into the namespace of the subscription
+// and prunes copies of sources that are no longer subscribed or published
+func (r *KopySubscriptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	sub := &syncv1alpha1.KopySubscription{}
+	if err := r.Get(ctx, req.NamespacedName, sub); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if sub.DeletionTimestamp != nil {
+		if err := r.pruneSubscribedCopies(ctx, sub, sets.New[syncv1alpha1.SourceReference]()); err != nil {
+			return ctrl.Result{}, err
+		}
+		if removeSyncFinalizer(sub) {
+			return ctrl.Result{}, r.Update(ctx, sub)
+		}
+		return ctrl.Result{}, nil
+	}
+	if addSyncFinalizer(sub) {
+		if err := r.Update(ctx, sub); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	keep := sets.New[syncv1alpha1.SourceReference]()
+	statuses := make([]syncv1alpha1.SubscribedSourceStatus, 0, len(sub.Spec.Sources))
+	errs := make([]error, 0, len(sub.Spec.Sources))
+	for _, ref := range sub.Spec.Sources {
+		status := syncv1alpha1.SubscribedSourceStatus{SourceReference: ref}
+		err := r.syncSubscribedSource(ctx, sub, ref)
+		switch {
+		case err == nil:
+			status.Synced = true
+			keep.Insert(ref)
+		case errors.Is(err, errNotSubscribable):
+			status.Message = err.Error()
+		default:
+			// keep the existing copy around on transient errors
+			status.Message = err.Error()
+			keep.Insert(ref)
+			errs = append(errs, err)
+		}
+		statuses = append(statuses, status)
+	}
+	if err := r.pruneSubscribedCopies(ctx, sub, keep); err != nil {
+		errs = append(errs, err)
+	}
+
+	sub.Status.Sources = statuses
+	condition := metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Synced", Message: "all sources are synced"}
+	for _, s := range statuses {
+		if !s.Synced {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "SyncFailed"
+			condition.Message = fmt.Sprintf("%s %s/%s: %s", s.Kind, s.Namespace, s.Name, s.Message)
+			break
+		}
+	}
+	meta.SetStatusCondition(&sub.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, sub); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		log.Error(errors.Join(errs...), "unable to sync subscription")
+		return ctrl.Result{}, errors.Join(errs...)
+	}
+	return ctrl.Result{}, nil
+}
+
+var errNotSubscribable = errors.New("source can't be subscribed to")
+
+// syncSubscribedSource copies the published source ref into the namespace of sub
+func (r *KopySubscriptionReconciler) syncSubscribedSource(ctx context.Context, sub *syncv1alpha1.KopySubscription, ref syncv1alpha1.SourceReference) error {
+	if ref.Namespace == sub.Namespace {
+		return fmt.Errorf("%w: source is in the namespace of the subscription", errNotSubscribable)
+	}
+	src, err := NewObjectForKind(ref.Kind)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errNotSubscribable, err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, src); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("%w: source not found", errNotSubscribable)
+		}
+		return err
+	}
+	if !isPublished(src) {
+		return fmt.Errorf("%w: source is not published", errNotSubscribable)
+	}
+	if !canSubscribe(src, sub.Namespace) {
+		return fmt.Errorf("%w: namespace %s is not an allowed tenant of the source", errNotSubscribable, sub.Namespace)
+	}
+	k, err := newKopier(ctx, r.Client, ref.Kind, r.Options)
+	if err != nil {
+		return err
+	}
+	if err := k.SyncSource(ref.Name, ref.Namespace, sub.Namespace); err != nil {
+		return err
+	}
+	cp, _ := NewObjectForKind(ref.Kind)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: sub.Namespace, Name: r.Options.copyName(ref.Name)}, cp); err != nil {
+		return err
+	}
+	if cp.GetLabels()[subscriptionLabel] == sub.Name {
+		return nil
+	}
+	patch := client.MergeFrom(cp.DeepCopyObject().(client.Object))
+	cp.GetLabels()[subscriptionLabel] = sub.Name
+	return r.Patch(ctx, cp, patch)
+}
+
+// pruneSubscribedCopies deletes copies created for sub whose source is not in keep
+func (r *KopySubscriptionReconciler) pruneSubscribedCopies(ctx context.Context, sub *syncv1alpha1.KopySubscription, keep sets.Set[syncv1alpha1.SourceReference]) error {
+	opts := []client.ListOption{client.InNamespace(sub.Namespace), client.MatchingLabels{subscriptionLabel: sub.Name}}
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, opts...); err != nil {
+		return err
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.List(ctx, configMaps, opts...); err != nil {
+		return err
+	}
+	copies := make([]client.Object, 0, len(secrets.Items)+len(configMaps.Items))
+	for i := range secrets.Items {
+		copies = append(copies, &secrets.Items[i])
+	}
+	for i := range configMaps.Items {
+		copies = append(copies, &configMaps.Items[i])
+	}
+	errs := make([]error, 0, len(copies))
+	for _, cp := range copies {
+		ref := syncv1alpha1.SourceReference{
+			Kind:      subscriptionKind(cp),
+			Namespace: cp.GetLabels()[sourceLabelNamespace],
+			Name:      sourceNameOf(cp),
+		}
+		if keep.Has(ref) {
+			continue
+		}
+		if err := pruneCopy(ctx, r.Client, cp); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// watchSubscribedObjects maps copies created for a subscription and published sources to the subscriptions using them
+func (r *KopySubscriptionReconciler) watchSubscribedObjects(ctx context.Context, o client.Object) []reconcile.Request {
+	if name, ok := o.GetLabels()[subscriptionLabel]; ok {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: name}}}
+	}
+	if !isPublished(o) {
+		return nil
+	}
+	subs := &syncv1alpha1.KopySubscriptionList{}
+	if err := r.List(ctx, subs); err != nil {
+		ctrllog.FromContext(ctx).Info("unable to grab a list of subscriptions")
+		return nil
+	}
+	ref := syncv1alpha1.SourceReference{Kind: subscriptionKind(o), Namespace: o.GetNamespace(), Name: o.GetName()}
+	req := make([]reconcile.Request, 0, len(subs.Items))
+	for _, sub := range subs.Items {
+		for _, s := range sub.Spec.Sources {
+			if s == ref {
+				req = append(req, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&sub)})
+				break
+			}
+		}
+	}
+	return req
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KopySubscriptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	debugState.watch("kopysubscription", "KopySubscription", "Secret", "ConfigMap")
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&syncv1alpha1.KopySubscription{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.watchSubscribedObjects), r.Options.secretWatchOptions()...).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.watchSubscribedObjects)).
+		Complete(r)
+}
+
+// isPublished returns true if the source is published for subscriptions
+func isPublished(o client.Object) bool {
+	published, _ := strconv.ParseBool(o.GetAnnotations()[publishKey])
+	return published
+}
+
+// subscriptionKind returns the kind name used by SourceReference for o
+func subscriptionKind(o client.Object) string {
+	switch o := o.(type) {
+	case *corev1.Secret:
+		return "Secret"
+	case *corev1.ConfigMap:
+		return "ConfigMap"
+	case *metav1.PartialObjectMetadata:
+		// watches that only cache the metadata of Secrets set the kind on every object
+		return o.Kind
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+var _ = Describe("KopySubscription Controller\n", func() {
+	Context("When a tenant subscribes to a published secret", func() {
+		It("Should copy the secret into the namespace of the subscription", func() {
+			By("Creating the published source secret")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				secret    *corev1.Secret
+			}{
+				name: "test-src-subscription-00", namespace: "test-src-subscription-ns-00", secret: &corev1.Secret{},
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			src.secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        src.name,
+					Namespace:   src.namespace,
+					Annotations: map[string]string{publishKey: "true"},
+				},
+				Data: map[string][]byte{"password": []byte(src.name)},
+			}
+			Expect(k8sClient.Create(tc.ctx, src.secret)).ShouldNot(HaveOccurred())
+
+			By("Creating the subscription in the tenant namespace")
+			tenant, err := tc.CreateNamespace("test-tenant-subscription-ns-00", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			sub := &syncv1alpha1.KopySubscription{
+				ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: tenant.Name},
+				Spec: syncv1alpha1.KopySubscriptionSpec{
+					Sources: []syncv1alpha1.SourceReference{
+						{Kind: "Secret", Namespace: src.namespace, Name: src.name},
+						{Kind: "Secret", Namespace: src.namespace, Name: "not-published"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(tc.ctx, sub)).ShouldNot(HaveOccurred())
+
+			By("Verifying the copy was created with the subscription label")
+			Eventually(func() map[string]string {
+				copy := &corev1.Secret{}
+				tc.GetSecret(src.name, tenant.Name, copy)
+				return copy.Labels
+			}, timeout, interval).Should(HaveKeyWithValue(subscriptionLabel, sub.Name))
+
+			By("Verifying the status reports the unpublished source")
+			Eventually(func() bool {
+				if err := k8sClient.Get(tc.ctx, types.NamespacedName{Namespace: tenant.Name, Name: sub.Name}, sub); err != nil {
+					return false
+				}
+				return meta.IsStatusConditionFalse(sub.Status.Conditions, "Ready") && len(sub.Status.Sources) == 2
+			}, timeout, interval).Should(BeTrue())
+
+			By("Deleting the subscription")
+			Expect(k8sClient.Delete(tc.ctx, sub)).ShouldNot(HaveOccurred())
+
+			By("Verifying the copy was pruned")
+			Eventually(func() bool {
+				return apierrors.IsNotFound(tc.GetSecret(src.name, tenant.Name, &corev1.Secret{}))
+			}, timeout, interval).Should(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+// kopySyncKey is set on sources whose kopy annotations are managed by a KopySync, to the name of the KopySync
+const kopySyncKey = kopyPrefix + "kopysync"
+
+// kopySyncIndex indexes sources by the KopySync that manages them, so a KopySync finds its sources without listing
+// every object of its namespace
+const kopySyncIndex = kopySyncKey
+
+// indexKopySync returns the name of the KopySync managing the source o
+func indexKopySync(o client.Object) []string {
+	if name, ok := o.GetAnnotations()[kopySyncKey]; ok {
+		return []string{name}
+	}
+	return nil
+}
+
+// kopySyncAnnotations are the annotations a KopySync manages on its source
+var kopySyncAnnotations = []string{
+	kopySyncKey, syncKey, localCopyKey, excludeFromBackupKey, syncWindowKey, maxTargetsPerMinuteKey, postSyncHooksKey,
+}
+
+// KopySyncReconciler reconciles a KopySync object
+type KopySyncReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Options Options
+}
+
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopysyncs,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopysyncs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopysyncs/finalizers,verbs=update
+
+// Reconcile manages the kopy annotations of the source of the KopySync, so the source is synced the same way as a
+// source that was annotated by hand, and reports the namespaces holding a current copy in the status
+func (r *KopySyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	ks := &syncv1alpha1.KopySync{}
+	if err := r.Get(ctx, req.NamespacedName, ks); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if ks.DeletionTimestamp != nil {
+		if err := r.releaseSources(ctx, ks, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		if removeSyncFinalizer(ks) {
+			return ctrl.Result{}, r.Update(ctx, ks)
+		}
+		return ctrl.Result{}, nil
+	}
+	if addSyncFinalizer(ks) {
+		if err := r.Update(ctx, ks); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	ks.Status.ObservedGeneration = ks.Generation
+	ks.Status.Copies, ks.Status.Namespaces = 0, nil
+	condition := metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Synced"}
+	errs := []error{}
+	src, err := r.manageSource(ctx, ks)
+	switch {
+	case err == nil:
+		namespaces, err := currentCopies(ctx, r.Client, src)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		ks.Status.Copies, ks.Status.Namespaces = int32(len(namespaces)), namespaces
+		condition.Message = fmt.Sprintf("%d copies carry the current data of the source", len(namespaces))
+	case apierrors.IsNotFound(err):
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, "SourceNotFound", err.Error()
+	case errors.Is(err, errInvalidKopySync):
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, "InvalidSpec", err.Error()
+	case errors.Is(err, errNotSubscribable):
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, "Conflict", err.Error()
+	default:
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, "SyncFailed", err.Error()
+		errs = append(errs, err)
+	}
+	if err := r.releaseSources(ctx, ks, &ks.Spec.Source); err != nil {
+		errs = append(errs, err)
+	}
+	meta.SetStatusCondition(&ks.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, ks); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		log.Error(errors.Join(errs...), "unable to sync source")
+		return ctrl.Result{}, errors.Join(errs...)
+	}
+	return ctrl.Result{}, nil
+}
+
+// errInvalidKopySync is returned for KopySyncs whose spec can't be turned into kopy annotations
+var errInvalidKopySync = errors.New("invalid KopySync")
+
+// kopySyncAnnotationValues returns the kopy annotations described by the spec of ks
+func kopySyncAnnotationValues(ks *syncv1alpha1.KopySync) (map[string]string, error) {
+	selector, err := namespaceSelectorAnnotation(&ks.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidKopySync, err)
+	}
+	values := map[string]string{kopySyncKey: ks.Name, syncKey: selector}
+	if ks.Spec.LocalCopy != "" {
+		values[localCopyKey] = ks.Spec.LocalCopy
+	}
+	if ks.Spec.ExcludeFromBackup {
+		values[excludeFromBackupKey] = "true"
+	}
+	if ks.Spec.SyncWindow != "" {
+		if _, err := parseSyncWindows(ks.Spec.SyncWindow); err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidKopySync, err)
+		}
+		values[syncWindowKey] = ks.Spec.SyncWindow
+	}
+	if ks.Spec.MaxTargetsPerMinute > 0 {
+		values[maxTargetsPerMinuteKey] = strconv.Itoa(int(ks.Spec.MaxTargetsPerMinute))
+	}
+	if len(ks.Spec.PostSyncHooks) > 0 {
+		values[postSyncHooksKey] = strings.Join(ks.Spec.PostSyncHooks, ",")
+	}
+	return values, nil
+}
+
+// manageSource sets the kopy annotations described by ks on its source and returns the source
+func (r *KopySyncReconciler) manageSource(ctx context.Context, ks *syncv1alpha1.KopySync) (client.Object, error) {
+	values, err := kopySyncAnnotationValues(ks)
+	if err != nil {
+		return nil, err
+	}
+	src, err := NewObjectForKind(ks.Spec.Source.Kind)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidKopySync, err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ks.Namespace, Name: ks.Spec.Source.Name}, src); err != nil {
+		return nil, err
+	}
+	annotations := src.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if owner, ok := annotations[kopySyncKey]; ok && owner != ks.Name {
+		return nil, fmt.Errorf("%w: source is already synced by KopySync %s", errNotSubscribable, owner)
+	}
+	if _, ok := annotations[kopySyncKey]; !ok {
+		if _, synced := annotations[syncKey]; synced {
+			return nil, fmt.Errorf("%w: source is already managed by its own kopy annotations", errNotSubscribable)
+		}
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	for _, k := range kopySyncAnnotations {
+		delete(annotations, k)
+	}
+	for k, v := range values {
+		annotations[k] = v
+	}
+	src.SetAnnotations(annotations)
+	return src, r.Patch(ctx, src, patch)
+}
+
+// releaseSources removes the kopy annotations from the sources managed by ks except keep. Without the sync annotation
+// the source releases its copies the same way as when the annotation is removed by hand.
+func (r *KopySyncReconciler) releaseSources(ctx context.Context, ks *syncv1alpha1.KopySync, keep *syncv1alpha1.SyncedObject) error {
+	errs := []error{}
+	// the index of Secrets is registered for their metadata when SecretMetadataOnly is set
+	_, secrets := r.Options.secretSources()
+	for _, list := range []client.ObjectList{secrets, &corev1.ConfigMapList{}} {
+		if err := r.List(ctx, list, client.InNamespace(ks.Namespace), client.MatchingFields{kopySyncIndex: ks.Name}); err != nil {
+			return err
+		}
+		_ = meta.EachListItem(list, func(obj runtime.Object) error {
+			o := obj.(client.Object)
+			if m, ok := o.(*metav1.PartialObjectMetadata); ok && m.Kind == "" {
+				// only Secrets are listed as metadata, patches of metadata need the kind
+				m.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+			}
+			if keep != nil && *keep == (syncv1alpha1.SyncedObject{Kind: subscriptionKind(o), Name: o.GetName()}) {
+				return nil
+			}
+			patch := client.MergeFrom(o.DeepCopyObject().(client.Object))
+			annotations := o.GetAnnotations()
+			for _, k := range kopySyncAnnotations {
+				delete(annotations, k)
+			}
+			o.SetAnnotations(annotations)
+			if err := r.Patch(ctx, o, patch); err != nil {
+				errs = append(errs, client.IgnoreNotFound(err))
+			}
+			return nil
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// currentCopies returns the sorted namespaces holding a copy of the current data of src
+func currentCopies(ctx context.Context, c client.Client, src client.Object) ([]string, error) {
+	copies, err := newObjectListForKind(kindOf(src))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.List(ctx, copies, listOptions(src)); err != nil {
+		return nil, err
+	}
+	revision := dataRevision(src)
+	namespaces := []string{}
+	_ = meta.EachListItem(copies, func(obj runtime.Object) error {
+		cp := obj.(client.Object)
+		if isCopyOf(cp, src) && cp.GetAnnotations()[sourceHashKey] == revision {
+			namespaces = append(namespaces, cp.GetNamespace())
+		}
+		return nil
+	})
+	slices.Sort(namespaces)
+	return namespaces, nil
+}
+
+// watchSyncedObjects maps sources, and the copies of sources, to the KopySyncs in the namespace of the source that
+// sync it
+func (r *KopySyncReconciler) watchSyncedObjects(ctx context.Context, o client.Object) []reconcile.Request {
+	namespace, name := o.GetNamespace(), o.GetName()
+	if origin, ok := o.GetLabels()[sourceLabelNamespace]; ok {
+		namespace, name = origin, sourceNameOf(o)
+	}
+	syncs := &syncv1alpha1.KopySyncList{}
+	if err := r.List(ctx, syncs, client.InNamespace(namespace)); err != nil {
+		ctrllog.FromContext(ctx).Info("unable to grab a list of KopySyncs")
+		return nil
+	}
+	source := syncv1alpha1.SyncedObject{Kind: subscriptionKind(o), Name: name}
+	req := make([]reconcile.Request, 0, len(syncs.Items))
+	for _, ks := range syncs.Items {
+		if ks.Spec.Source == source || o.GetAnnotations()[kopySyncKey] == ks.Name {
+			req = append(req, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&ks)})
+		}
+	}
+	return req
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KopySyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	debugState.watch("kopysync", "KopySync", "Secret", "ConfigMap")
+	secret, _ := r.Options.secretSources()
+	for _, obj := range []client.Object{secret, &corev1.ConfigMap{}} {
+		if err := mgr.GetFieldIndexer().IndexField(context.Background(), obj, kopySyncIndex, indexKopySync); err != nil {
+			return err
+		}
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&syncv1alpha1.KopySync{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.watchSyncedObjects), r.Options.secretWatchOptions()...).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.watchSyncedObjects)).
+		Complete(r)
+}
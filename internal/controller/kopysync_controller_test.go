@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+var _ = Describe("KopySync Controller\n", func() {
+	const (
+		namespace = "test-src-kopysync-ns-00"
+		target    = "test-dst-kopysync-ns-00"
+	)
+	newKopySync := func(name, source string) *syncv1alpha1.KopySync {
+		return &syncv1alpha1.KopySync{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: syncv1alpha1.KopySyncSpec{
+				Source:              syncv1alpha1.SyncedObject{Kind: "Secret", Name: source},
+				NamespaceSelector:   metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+				MaxTargetsPerMinute: 10,
+			},
+		}
+	}
+
+	It("Should sync the source, report its copies and release the source when deleted", func() {
+		ctx := context.Background()
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-kopysync-00", Namespace: namespace},
+			Data:       map[string][]byte{"password": []byte("hunter2")},
+		}
+		ks := newKopySync("test-kopysync-00", src.Name)
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).Should(Succeed())
+		Expect(syncv1alpha1.AddToScheme(s)).Should(Succeed())
+		c := newFakeClientBuilder().WithScheme(s).WithObjects(
+			src, ks,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"env": "prod"}}},
+		).WithStatusSubresource(&syncv1alpha1.KopySync{}).
+			WithIndex(&corev1.Secret{}, kopySyncIndex, indexKopySync).
+			WithIndex(&corev1.ConfigMap{}, kopySyncIndex, indexKopySync).Build()
+		r := &KopySyncReconciler{Client: c}
+		reconcile := func(ks *syncv1alpha1.KopySync) {
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ks)})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(ks), ks)).Should(Succeed())
+		}
+
+		By("Annotating the source")
+		reconcile(ks)
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Annotations).Should(And(
+			HaveKeyWithValue(kopySyncKey, ks.Name),
+			HaveKeyWithValue(syncKey, "env=prod"),
+			HaveKeyWithValue(maxTargetsPerMinuteKey, "10"),
+		))
+		Expect(ks.Status.Copies).Should(BeZero())
+
+		By("Reporting the copies once the source is synced")
+		_, err := KopyReconcile(NewKopySecret(ctx, c, Options{}, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		reconcile(ks)
+		Expect(ks.Status.Copies).Should(BeEquivalentTo(1))
+		Expect(ks.Status.Namespaces).Should(Equal([]string{target}))
+		Expect(meta.IsStatusConditionTrue(ks.Status.Conditions, "Ready")).Should(BeTrue())
+
+		By("Refusing a second KopySync of the same source")
+		other := newKopySync("test-kopysync-01", src.Name)
+		Expect(c.Create(ctx, other)).Should(Succeed())
+		reconcile(other)
+		Expect(meta.FindStatusCondition(other.Status.Conditions, "Ready")).Should(HaveField("Reason", "Conflict"))
+
+		By("Rejecting an empty namespace selector")
+		other.Spec.NamespaceSelector = metav1.LabelSelector{}
+		Expect(c.Update(ctx, other)).Should(Succeed())
+		reconcile(other)
+		Expect(meta.FindStatusCondition(other.Status.Conditions, "Ready")).Should(HaveField("Reason", "InvalidSpec"))
+
+		By("Releasing the source when the KopySync is deleted")
+		Expect(c.Delete(ctx, ks)).Should(Succeed())
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ks)})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Annotations).ShouldNot(Or(HaveKey(kopySyncKey), HaveKey(syncKey), HaveKey(maxTargetsPerMinuteKey)))
+	})
+
+	It("Should release sources through the metadata index when only the metadata of Secrets is cached", func() {
+		ctx := context.Background()
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-kopysync-01", Namespace: namespace,
+			Annotations: map[string]string{kopySyncKey: "test-kopysync-02", syncKey: "env=prod"},
+		}}
+		ks := newKopySync("test-kopysync-02", src.Name)
+		options := Options{SecretMetadataOnly: true}
+		secret, _ := options.secretSources()
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src).
+			WithIndex(secret, kopySyncIndex, indexKopySync).
+			WithIndex(&corev1.ConfigMap{}, kopySyncIndex, indexKopySync).Build()
+		r := &KopySyncReconciler{Client: c, Options: options}
+		Expect(r.releaseSources(ctx, ks, nil)).Should(Succeed())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Annotations).ShouldNot(Or(HaveKey(kopySyncKey), HaveKey(syncKey)))
+	})
+})
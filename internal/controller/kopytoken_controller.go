@@ -0,0 +1,297 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+const (
+	// tokenDistributionKey must be set to "true" on a ServiceAccount by its owners before KopyTokens may request
+	// tokens for it, so the permission to create a KopyToken doesn't grant the permissions of every service account
+	// in the namespace
+	tokenDistributionKey = kopyPrefix + "token-distribution"
+	// tokenNamespaceLabel and tokenNameLabel identify the KopyToken a token Secret was created for
+	tokenNamespaceLabel = kopyPrefix + "token.namespace"
+	tokenNameLabel      = kopyPrefix + "token.name"
+	// tokenExpirationKey records when the token in a Secret expires, in RFC 3339 format
+	tokenExpirationKey = kopyPrefix + "token-expiration"
+
+	// defaultTokenExpirationSeconds is used when a KopyToken doesn't set expirationSeconds
+	defaultTokenExpirationSeconds = 3600
+	// tokenRenewFraction is the fraction of a token's lifetime after which it is renewed
+	tokenRenewFraction = 0.8
+)
+
+// KopyTokenReconciler reconciles a KopyToken object
+type KopyTokenReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Options Options
+}
+
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopytokens,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopytokens/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopytokens/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts/token,verbs=create
+
+// Reconcile requests a token of the service account of the KopyToken for every selected namespace, stores it in a
+// Secret in that namespace and renews it before it expires. Secrets in namespaces that are no longer selected are
+// deleted.
+func (r *KopyTokenReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	tok := &syncv1alpha1.KopyToken{}
+	if err := r.Get(ctx, req.NamespacedName, tok); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if tok.DeletionTimestamp != nil {
+		if err := r.pruneTokenSecrets(ctx, tok, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		if removeSyncFinalizer(tok) {
+			return ctrl.Result{}, r.Update(ctx, tok)
+		}
+		return ctrl.Result{}, nil
+	}
+	if addSyncFinalizer(tok) {
+		if err := r.Update(ctx, tok); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	condition := metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Distributed", Message: "tokens are distributed"}
+	namespaces, err := r.tokenNamespaces(ctx, tok)
+	if err != nil {
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, "InvalidSelector", err.Error()
+		meta.SetStatusCondition(&tok.Status.Conditions, condition)
+		return ctrl.Result{}, r.Status().Update(ctx, tok)
+	}
+	if err := r.checkServiceAccount(ctx, tok); err != nil {
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, "ServiceAccountNotAllowed", err.Error()
+		meta.SetStatusCondition(&tok.Status.Conditions, condition)
+		return ctrl.Result{}, r.Status().Update(ctx, tok)
+	}
+
+	var renewAfter time.Duration
+	distributed := make([]string, 0, len(namespaces))
+	errs := make([]error, 0)
+	for _, ns := range namespaces {
+		next, err := r.distributeToken(ctx, tok, ns)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", ns, err))
+			continue
+		}
+		distributed = append(distributed, ns)
+		if renewAfter == 0 || next < renewAfter {
+			renewAfter = next
+		}
+	}
+	if err := r.pruneTokenSecrets(ctx, tok, namespaces); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, "DistributionFailed", errors.Join(errs...).Error()
+	}
+	tok.Status.Namespaces = distributed
+	meta.SetStatusCondition(&tok.Status.Conditions, condition)
+	if err := r.Status().Update(ctx, tok); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		log.Error(errors.Join(errs...), "unable to distribute tokens")
+		return ctrl.Result{}, errors.Join(errs...)
+	}
+	return ctrl.Result{RequeueAfter: renewAfter}, nil
+}
+
+// tokenNamespaces returns the sorted names of the namespaces selected by tok
+func (r *KopyTokenReconciler) tokenNamespaces(ctx context.Context, tok *syncv1alpha1.KopyToken) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&tok.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	if selector.Empty() {
+		return nil, fmt.Errorf("namespaceSelector must not be empty, an empty selector matches every namespace")
+	}
+	namespaces, err := r.Options.syncNamespaces(ctx, r.Client, tok, selector)
+	if err != nil {
+		return nil, err
+	}
+	names := namespaceNames(namespaces).UnsortedList()
+	slices.Sort(names)
+	return names, nil
+}
+
+// checkServiceAccount verifies that the owners of the service account of tok allowed token distribution
+func (r *KopyTokenReconciler) checkServiceAccount(ctx context.Context, tok *syncv1alpha1.KopyToken) error {
+	sa := &corev1.ServiceAccount{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: tok.Namespace, Name: tok.Spec.ServiceAccountName}, sa); err != nil {
+		return err
+	}
+	if allowed, _ := strconv.ParseBool(sa.Annotations[tokenDistributionKey]); !allowed {
+		return fmt.Errorf("service account %s is not annotated with %s=true", sa.Name, tokenDistributionKey)
+	}
+	return nil
+}
+
+// distributeToken makes sure the Secret of tok in namespace holds a token that isn't due for renewal and returns
+// how long until it is
+func (r *KopyTokenReconciler) distributeToken(ctx context.Context, tok *syncv1alpha1.KopyToken, namespace string) (time.Duration, error) {
+	lifetime := time.Duration(tokenExpirationSeconds(tok)) * time.Second
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: tokenSecretName(tok)}, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tokenSecretName(tok), Namespace: namespace}}
+	case err != nil:
+		return 0, err
+	case !isTokenSecretOf(secret, tok):
+		return 0, fmt.Errorf("%w: secret %s is not managed by this KopyToken", errCopyConflict, secret.Name)
+	default:
+		if expiration, err := time.Parse(time.RFC3339, secret.Annotations[tokenExpirationKey]); err == nil {
+			renewAt := expiration.Add(-time.Duration(float64(lifetime) * (1 - tokenRenewFraction)))
+			if until := time.Until(renewAt); until > 0 && len(secret.Data["token"]) > 0 {
+				return until, nil
+			}
+		}
+	}
+
+	expirationSeconds := tokenExpirationSeconds(tok)
+	tr := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{
+		Audiences:         tok.Spec.Audiences,
+		ExpirationSeconds: &expirationSeconds,
+	}}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: tok.Namespace, Name: tok.Spec.ServiceAccountName}}
+	if err := r.SubResource("token").Create(ctx, sa, tr); err != nil {
+		return 0, fmt.Errorf("unable to request token: %w", err)
+	}
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[tokenNamespaceLabel] = tok.Namespace
+	secret.Labels[tokenNameLabel] = tok.Name
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	expiration := tr.Status.ExpirationTimestamp.Time
+	secret.Annotations[tokenExpirationKey] = expiration.UTC().Format(time.RFC3339)
+	secret.Type = corev1.SecretTypeOpaque
+	secret.Data = map[string][]byte{"token": []byte(tr.Status.Token)}
+	if secret.ResourceVersion == "" {
+		err = r.Create(ctx, secret)
+	} else {
+		err = r.Update(ctx, secret)
+	}
+	if err != nil {
+		return 0, err
+	}
+	// the API server may shorten the requested lifetime, so renewal is based on the expiration it returned
+	return time.Duration(float64(time.Until(expiration)) * tokenRenewFraction), nil
+}
+
+// pruneTokenSecrets deletes the Secrets of tok in namespaces that are not in keep
+func (r *KopyTokenReconciler) pruneTokenSecrets(ctx context.Context, tok *syncv1alpha1.KopyToken, keep []string) error {
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.MatchingLabels{tokenNamespaceLabel: tok.Namespace, tokenNameLabel: tok.Name}); err != nil {
+		return err
+	}
+	errs := make([]error, 0)
+	for i := range secrets.Items {
+		if slices.Contains(keep, secrets.Items[i].Namespace) {
+			continue
+		}
+		if err := r.Delete(ctx, &secrets.Items[i]); client.IgnoreNotFound(err) != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// watchTokenSecrets maps token Secrets to the KopyToken that created them
+func (r *KopyTokenReconciler) watchTokenSecrets(ctx context.Context, o client.Object) []reconcile.Request {
+	name, ok := o.GetLabels()[tokenNameLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: o.GetLabels()[tokenNamespaceLabel], Name: name}}}
+}
+
+// watchTokenObjects maps namespaces and service accounts to the KopyTokens that use them
+func (r *KopyTokenReconciler) watchTokenObjects(ctx context.Context, o client.Object) []reconcile.Request {
+	toks := &syncv1alpha1.KopyTokenList{}
+	if err := r.List(ctx, toks); err != nil {
+		ctrllog.FromContext(ctx).Info("unable to grab a list of tokens")
+		return nil
+	}
+	req := make([]reconcile.Request, 0)
+	for _, tok := range toks.Items {
+		switch o := o.(type) {
+		case *metav1.PartialObjectMetadata:
+			// namespaces are watched as metadata
+			selector, err := metav1.LabelSelectorAsSelector(&tok.Spec.NamespaceSelector)
+			if err != nil {
+				continue
+			}
+			if !selector.Matches(labels.Set(o.Labels)) && !slices.Contains(tok.Status.Namespaces, o.Name) {
+				continue
+			}
+		case *corev1.ServiceAccount:
+			if o.Namespace != tok.Namespace || o.Name != tok.Spec.ServiceAccountName {
+				continue
+			}
+		}
+		req = append(req, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&tok)})
+	}
+	return req
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KopyTokenReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	debugState.watch("kopytoken", "KopyToken", "Secret", "Namespace", "ServiceAccount")
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&syncv1alpha1.KopyToken{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.watchTokenSecrets), r.Options.secretWatchOptions()...).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.watchTokenObjects), builder.OnlyMetadata).
+		Watches(&corev1.ServiceAccount{}, handler.EnqueueRequestsFromMapFunc(r.watchTokenObjects)).
+		Complete(r)
+}
+
+func tokenExpirationSeconds(tok *syncv1alpha1.KopyToken) int64 {
+	if tok.Spec.ExpirationSeconds != nil {
+		return *tok.Spec.ExpirationSeconds
+	}
+	return defaultTokenExpirationSeconds
+}
+
+func tokenSecretName(tok *syncv1alpha1.KopyToken) string {
+	if tok.Spec.SecretName != "" {
+		return tok.Spec.SecretName
+	}
+	return tok.Name
+}
+
+// isTokenSecretOf returns true if secret holds a token distributed by tok
+func isTokenSecretOf(secret *corev1.Secret, tok *syncv1alpha1.KopyToken) bool {
+	return secret.Labels[tokenNamespaceLabel] == tok.Namespace && secret.Labels[tokenNameLabel] == tok.Name
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+var _ = Describe("KopyToken Controller\n", func() {
+	Context("When a token is distributed to selected namespaces", func() {
+		It("Should store a short-lived token in each namespace", func() {
+			By("Creating the service account and target namespace")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+			}{
+				name: "test-src-token-00", namespace: "test-src-token-ns-00",
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			targetNamespace, err := tc.CreateNamespace("test-target-token-ns-00", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: src.name, Namespace: src.namespace}}
+			Expect(k8sClient.Create(tc.ctx, sa)).ShouldNot(HaveOccurred())
+
+			By("Creating the token")
+			tok := &syncv1alpha1.KopyToken{
+				ObjectMeta: metav1.ObjectMeta{Name: src.name, Namespace: src.namespace},
+				Spec: syncv1alpha1.KopyTokenSpec{
+					ServiceAccountName: sa.Name,
+					NamespaceSelector:  metav1.LabelSelector{MatchLabels: map[string]string{label.key: label.value}},
+				},
+			}
+			Expect(k8sClient.Create(tc.ctx, tok)).ShouldNot(HaveOccurred())
+
+			By("Verifying no token is distributed until the service account allows it")
+			Eventually(func() string {
+				if err := k8sClient.Get(tc.ctx, types.NamespacedName{Namespace: tok.Namespace, Name: tok.Name}, tok); err != nil {
+					return ""
+				}
+				if c := meta.FindStatusCondition(tok.Status.Conditions, "Ready"); c != nil {
+					return c.Reason
+				}
+				return ""
+			}, timeout, interval).Should(Equal("ServiceAccountNotAllowed"))
+
+			By("Allowing token distribution on the service account")
+			sa.Annotations = map[string]string{tokenDistributionKey: "true"}
+			Expect(k8sClient.Update(tc.ctx, sa)).ShouldNot(HaveOccurred())
+
+			By("Verifying the token secret was created in the target namespace")
+			secret := &corev1.Secret{}
+			Eventually(func() []byte {
+				if err := k8sClient.Get(tc.ctx, types.NamespacedName{Namespace: targetNamespace.Name, Name: tok.Name}, secret); err != nil {
+					return nil
+				}
+				return secret.Data["token"]
+			}, timeout, interval).ShouldNot(BeEmpty())
+			Expect(secret.Annotations).Should(HaveKey(tokenExpirationKey))
+			Expect(secret.Labels).Should(HaveKeyWithValue(tokenNameLabel, tok.Name))
+
+			By("Deleting the token")
+			Expect(k8sClient.Delete(tc.ctx, tok)).ShouldNot(HaveOccurred())
+
+			By("Verifying the token secret was removed")
+			Eventually(func() bool {
+				err := k8sClient.Get(tc.ctx, types.NamespacedName{Namespace: targetNamespace.Name, Name: tok.Name}, secret)
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,272 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// loadgenLabel labels the namespaces of a load generator run with its prefix, the sources select them with it
+	loadgenLabel = kopyPrefix + "loadgen"
+	// loadgenRevisionKey is the data key of the sources that Loadgen bumps to measure how fast updates converge
+	loadgenRevisionKey = "revision"
+	// loadgenPayloadKey is the data key that pads the sources to LoadgenOptions.Size
+	loadgenPayloadKey = "payload"
+)
+
+// LoadgenOptions configure Loadgen
+type LoadgenOptions struct {
+	// Prefix names the namespaces and sources of the run. The sources are created in <prefix>-src and copied into
+	// <prefix>-0 to <prefix>-<Namespaces-1>.
+	Prefix     string
+	Namespaces int
+	Sources    int
+	// Kind of the sources, secret or configmap
+	Kind string
+	// Size is the number of bytes of data of every source
+	Size int
+	// Timeout is how long each phase may take to converge
+	Timeout time.Duration
+	// Interval is how often the copies are checked for convergence, it bounds the precision of the latencies
+	Interval time.Duration
+	// Progress is called with the number of converged copies every Interval
+	Progress func(phase string, converged, copies int)
+}
+
+// LoadgenPhase is how long kopy took to converge after the sources were created or updated
+type LoadgenPhase struct {
+	Name   string `json:"name"`
+	Copies int    `json:"copies"`
+	// Converged is the number of copies that were current when the phase ended
+	Converged int `json:"converged"`
+	// Duration is the time from the first source write until every copy was current, or until the timeout
+	Duration time.Duration `json:"duration"`
+	// P50, P90 and P99 are percentiles of the time from the write of its source until a copy was current
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+}
+
+// Loadgen creates Namespaces target namespaces and Sources sources selecting all of them in a test cluster, waits for
+// kopy to copy them, then updates every source and waits for the copies to follow. It returns the convergence times
+// of both phases. The objects are left in place, remove them with CleanupLoadgen.
+func Loadgen(ctx context.Context, c client.Client, opts LoadgenOptions) ([]LoadgenPhase, error) {
+	if opts.Kind != "secret" && opts.Kind != "configmap" {
+		return nil, fmt.Errorf("unsupported kind %q, expected secret or configmap", opts.Kind)
+	}
+	sourceNamespace := opts.Prefix + "-src"
+	namespaces := []string{sourceNamespace}
+	for i := range opts.Namespaces {
+		namespaces = append(namespaces, fmt.Sprintf("%s-%d", opts.Prefix, i))
+	}
+	for i, name := range namespaces {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if i > 0 {
+			ns.Labels = map[string]string{loadgenLabel: opts.Prefix}
+		}
+		if err := c.Create(ctx, ns); client.IgnoreAlreadyExists(err) != nil {
+			return nil, fmt.Errorf("unable to create namespace %s: %w", name, err)
+		}
+	}
+
+	phases := []LoadgenPhase{}
+	for _, revision := range []string{"1", "2"} {
+		name := "create"
+		if revision != "1" {
+			name = "update"
+		}
+		written := map[string]time.Time{}
+		for i := range opts.Sources {
+			src := loadgenSource(opts, sourceNamespace, i, revision)
+			var err error
+			if revision == "1" {
+				err = c.Create(ctx, src)
+			} else {
+				err = updateLoadgenSource(ctx, c, src)
+			}
+			if err != nil {
+				return phases, fmt.Errorf("unable to write source %s/%s: %w", sourceNamespace, src.GetName(), err)
+			}
+			written[src.GetName()] = time.Now()
+		}
+		phase, err := waitForLoadgen(ctx, c, opts, name, sourceNamespace, revision, written)
+		phases = append(phases, phase)
+		if err != nil {
+			return phases, err
+		}
+	}
+	return phases, nil
+}
+
+// loadgenSource returns source i of the run at revision
+func loadgenSource(opts LoadgenOptions, namespace string, i int, revision string) client.Object {
+	objectMeta := metav1.ObjectMeta{
+		Name: fmt.Sprintf("%s-%d", opts.Prefix, i), Namespace: namespace,
+		Annotations: map[string]string{syncKey: loadgenLabel + "=" + opts.Prefix},
+	}
+	payload := strings.Repeat("x", max(opts.Size-len(revision), 0))
+	if opts.Kind == "secret" {
+		return &corev1.Secret{ObjectMeta: objectMeta,
+			Data: map[string][]byte{loadgenRevisionKey: []byte(revision), loadgenPayloadKey: []byte(payload)}}
+	}
+	return &corev1.ConfigMap{ObjectMeta: objectMeta,
+		Data: map[string]string{loadgenRevisionKey: revision, loadgenPayloadKey: payload}}
+}
+
+// updateLoadgenSource writes the data of src into the source in the cluster, keeping the finalizer and annotations kopy
+// set on it
+func updateLoadgenSource(ctx context.Context, c client.Client, src client.Object) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := src.DeepCopyObject().(client.Object)
+		if err := c.Get(ctx, client.ObjectKeyFromObject(src), current); err != nil {
+			return err
+		}
+		switch cur := current.(type) {
+		case *corev1.Secret:
+			cur.Data = src.(*corev1.Secret).Data
+		case *corev1.ConfigMap:
+			cur.Data = src.(*corev1.ConfigMap).Data
+		}
+		return c.Update(ctx, current)
+	})
+}
+
+// waitForLoadgen polls the copies of the sources in sourceNamespace until every target namespace has a copy of every
+// source at revision. written is when each source was written.
+func waitForLoadgen(ctx context.Context, c client.Client, opts LoadgenOptions, name, sourceNamespace, revision string,
+	written map[string]time.Time) (LoadgenPhase, error) {
+	phase := LoadgenPhase{Name: name, Copies: opts.Namespaces * opts.Sources}
+	start := time.Now()
+	for _, t := range written {
+		if t.Before(start) {
+			start = t
+		}
+	}
+	converged := map[string]time.Duration{}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	err := wait.PollUntilContextCancel(ctx, opts.Interval, true, func(ctx context.Context) (bool, error) {
+		list, _ := newObjectListForKind(opts.Kind)
+		if err := c.List(ctx, list, client.MatchingLabels{sourceLabelNamespace: sourceNamespace}); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		observed := time.Now()
+		if err := meta.EachListItem(list, func(obj runtime.Object) error {
+			cp, ok := obj.(client.Object)
+			if !ok {
+				return fmt.Errorf("unexpected object %T", obj)
+			}
+			key := cp.GetNamespace() + "/" + cp.GetName()
+			if _, ok := converged[key]; ok || string(objectData(cp)[loadgenRevisionKey]) != revision {
+				return nil
+			}
+			if t, ok := written[sourceNameOf(cp)]; ok {
+				converged[key] = observed.Sub(t)
+			}
+			return nil
+		}); err != nil {
+			return false, err
+		}
+		if opts.Progress != nil {
+			opts.Progress(name, len(converged), phase.Copies)
+		}
+		return len(converged) >= phase.Copies, nil
+	})
+	phase.Duration = time.Since(start)
+	phase.Converged = len(converged)
+	latencies := make([]time.Duration, 0, len(converged))
+	for _, d := range converged {
+		latencies = append(latencies, d)
+	}
+	slices.Sort(latencies)
+	phase.P50, phase.P90, phase.P99 = percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99)
+	if err != nil {
+		return phase, fmt.Errorf("%d of %d copies converged after the %s of the sources: %w", phase.Converged, phase.Copies, name, err)
+	}
+	return phase, nil
+}
+
+// percentile returns the p-th percentile of the sorted durations, 0 if there are none
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+// CleanupLoadgen deletes the namespaces of the load generator run named prefix. The sync finalizers of the sources
+// and copies are removed first, so the namespaces go away even if kopy isn't running anymore.
+func CleanupLoadgen(ctx context.Context, c client.Client, prefix string) error {
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, client.MatchingLabels{loadgenLabel: prefix}); err != nil {
+		return err
+	}
+	names := []string{prefix + "-src"}
+	for _, ns := range namespaces.Items {
+		names = append(names, ns.Name)
+	}
+	for _, name := range names {
+		for _, list := range []client.ObjectList{&corev1.SecretList{}, &corev1.ConfigMapList{}} {
+			if err := c.List(ctx, list, client.InNamespace(name)); err != nil {
+				return err
+			}
+			items, err := meta.ExtractList(list)
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				obj := item.(client.Object)
+				if removeSyncFinalizer(obj) {
+					if err := c.Update(ctx, obj); client.IgnoreNotFound(err) != nil {
+						return err
+					}
+				}
+			}
+		}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if err := c.Delete(ctx, ns); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to delete namespace %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// LoadgenRequests sums the requests per method in the rest_client_requests_total samples of a Prometheus text
+// exposition, e.g. the metrics endpoint of the controller
+func LoadgenRequests(metrics string) map[string]float64 {
+	requests := map[string]float64{}
+	for _, line := range strings.Split(metrics, "\n") {
+		labels, ok := strings.CutPrefix(line, "rest_client_requests_total{")
+		if !ok {
+			continue
+		}
+		labels, value, ok := strings.Cut(labels, "} ")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		method := "unknown"
+		for _, label := range strings.Split(labels, ",") {
+			if m, ok := strings.CutPrefix(label, `method="`); ok {
+				method = strings.TrimSuffix(m, `"`)
+			}
+		}
+		requests[method] += v
+	}
+	return requests
+}
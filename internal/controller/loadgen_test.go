@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Load generator\n", func() {
+	const prefix = "test-loadgen"
+
+	It("Should measure how fast the copies of created and updated sources converge", func() {
+		ctx := context.Background()
+		var c client.Client
+		// copy every source write into the target namespaces the way kopy would
+		sync := func(ctx context.Context, obj client.Object) error {
+			src, ok := obj.(*corev1.ConfigMap)
+			if !ok || src.Namespace != prefix+"-src" {
+				return nil
+			}
+			for _, target := range []string{prefix + "-0", prefix + "-1"} {
+				cp := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: src.Name, Namespace: target,
+					Labels: map[string]string{sourceLabelNamespace: src.Namespace, sourceLabelName: src.Name}}, Data: src.Data}
+				if err := c.Create(ctx, cp); apierrors.IsAlreadyExists(err) {
+					err = c.Update(ctx, cp)
+				} else if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		c = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if err := cl.Create(ctx, obj, opts...); err != nil {
+					return err
+				}
+				return sync(ctx, obj)
+			},
+			Update: func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if err := cl.Update(ctx, obj, opts...); err != nil {
+					return err
+				}
+				return sync(ctx, obj)
+			},
+		}).Build()
+
+		phases, err := Loadgen(ctx, c, LoadgenOptions{Prefix: prefix, Namespaces: 2, Sources: 3, Kind: "configmap",
+			Size: 64, Timeout: 5 * time.Second, Interval: 10 * time.Millisecond})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(phases).Should(HaveLen(2))
+		for i, name := range []string{"create", "update"} {
+			Expect(phases[i].Name).Should(Equal(name))
+			Expect(phases[i].Converged).Should(Equal(6))
+			Expect(phases[i].Copies).Should(Equal(6))
+		}
+		cp := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: prefix + "-1", Name: prefix + "-2"}, cp)).Should(Succeed())
+		Expect(cp.Data).Should(HaveKeyWithValue(loadgenRevisionKey, "2"))
+		Expect(cp.Data[loadgenPayloadKey]).Should(HaveLen(63))
+
+		By("Deleting the namespaces of the run")
+		Expect(CleanupLoadgen(ctx, c, prefix)).Should(Succeed())
+		namespaces := &corev1.NamespaceList{}
+		Expect(c.List(ctx, namespaces)).Should(Succeed())
+		Expect(namespaces.Items).Should(BeEmpty())
+	})
+
+	It("Should sum the API requests of the controller by method", func() {
+		metrics := `# HELP rest_client_requests_total Number of HTTP requests, partitioned by status code, method, and host.
+# TYPE rest_client_requests_total counter
+rest_client_requests_total{code="200",host="10.96.0.1:443",method="GET"} 12
+rest_client_requests_total{code="200",host="10.96.0.1:443",method="PUT"} 40
+rest_client_requests_total{code="409",host="10.96.0.1:443",method="PUT"} 2
+rest_client_requests_total_other{method="GET"} 7
+`
+		Expect(LoadgenRequests(metrics)).Should(Equal(map[string]float64{"GET": 12, "PUT": 42}))
+	})
+})
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// localCopyKey is set on a source to keep a copy in the namespace of the source too, e.g. when the source is managed
+// by gitops under its own name and a workload in the same namespace consumes the standardized copy name. Its value is
+// the name of the local copy, or "true" to name it like every other copy, which needs a copy name suffix.
+const localCopyKey = kopyPrefix + "local-copy"
+
+// localCopyName returns the name of the copy of src in its own namespace, false if src doesn't keep a local copy or
+// the local copy would have the name of src itself
+func (o Options) localCopyName(src client.Object) (string, bool) {
+	name, ok := src.GetAnnotations()[localCopyKey]
+	if !ok {
+		return "", false
+	}
+	if name == "true" {
+		name = o.copyName(src.GetName())
+	}
+	return name, name != "" && name != src.GetName()
+}
+
+// copyNameFor returns the name of the copy of src in namespace, translated if namespace is managed by a virtual
+// cluster
+func (o Options) copyNameFor(src client.Object, namespace string) string {
+	if name, ok := o.localCopyName(src); ok && namespace == src.GetNamespace() {
+		return name
+	}
+	if vcluster := o.vclusterOf(namespace); vcluster != "" {
+		return vclusterName(o.copyName(src.GetName()), src.GetNamespace(), vcluster)
+	}
+	return o.copyName(src.GetName())
+}
+
+// syncsTo returns true if src is copied to the namespace ns, either because ns is selected and within the topology of
+// src or because it is the namespace of a source that keeps a local copy
+func (o Options) syncsTo(src client.Object, ns client.Object) bool {
+	if o.VClusterMode == VClusterSkip && isVClusterManaged(ns) {
+		return false
+	}
+	if ns.GetName() == src.GetNamespace() {
+		_, ok := o.localCopyName(src)
+		return ok
+	}
+	return namespaceContainsSyncLabel(src, ns) && topologyMismatch(src, ns) == ""
+}
+
+// addLocalNamespace adds the namespace of src to namespaces if src keeps a local copy
+func (o Options) addLocalNamespace(ctx context.Context, c client.Client, src client.Object, namespaces []corev1.Namespace) ([]corev1.Namespace, error) {
+	if _, ok := o.localCopyName(src); !ok {
+		return namespaces, nil
+	}
+	ns := corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: src.GetNamespace()}, &ns); err != nil {
+		return nil, fmt.Errorf("unable to get namespace %s: %w", src.GetNamespace(), err)
+	}
+	if ns.DeletionTimestamp != nil {
+		return namespaces, nil
+	}
+	return append(namespaces, ns), nil
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Local copies\n", func() {
+	const (
+		namespace = "test-src-local-ns-00"
+		target    = "test-dst-local-ns-00"
+	)
+	newSource := func(annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-local-00", Namespace: namespace, Annotations: annotations},
+			Data:       map[string][]byte{"password": []byte("s3cr3t")},
+		}
+	}
+	DescribeTable("Naming the local copy",
+		func(value string, opts Options, expected string, ok bool) {
+			name, local := opts.localCopyName(newSource(map[string]string{localCopyKey: value}))
+			Expect(local).Should(Equal(ok))
+			if ok {
+				Expect(name).Should(Equal(expected))
+			}
+		},
+		Entry("a name", "app-config", Options{}, "app-config", true),
+		Entry("true with a copy name suffix", "true", Options{CopyNameSuffix: "-kopy"}, "test-src-local-00-kopy", true),
+		Entry("true without a copy name suffix", "true", Options{}, "", false),
+		Entry("the name of the source", "test-src-local-00", Options{}, "", false),
+		Entry("empty", "", Options{}, "", false),
+	)
+	It("Should not keep a local copy without the annotation", func() {
+		_, ok := Options{}.localCopyName(newSource(nil))
+		Expect(ok).Should(BeFalse())
+	})
+
+	It("Should keep a renamed copy in the namespace of the source until the annotation is removed", func() {
+		ctx := context.Background()
+		src := newSource(map[string]string{syncKey: "env=prod", localCopyKey: "app-config"})
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"env": "prod"}}},
+		).Build()
+		req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}
+		reconcile := func() {
+			_, err := KopyReconcile(NewKopySecret(ctx, c, Options{}, nil), req, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		reconcile()
+		local := &corev1.Secret{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "app-config"}, local)).Should(Succeed())
+		Expect(local.Data).Should(Equal(src.Data))
+		Expect(isCopyOf(local, src)).Should(BeTrue())
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: target, Name: src.Name}, &corev1.Secret{})).Should(Succeed())
+
+		// reconciling the local copy keeps it instead of replacing it with a copy named like the source
+		_, err := KopyReconcile(NewKopySecret(ctx, c, Options{}, nil), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(local)}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(local), local)).Should(Succeed())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Annotations).Should(HaveKey(syncKey))
+
+		delete(src.Annotations, localCopyKey)
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(local), local)).ShouldNot(Succeed())
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: target, Name: src.Name}, &corev1.Secret{})).Should(Succeed())
+	})
+})
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sourceLocks serializes the reconciles of a source and of its copies. The workqueue only keeps a single request
+// from being processed twice at the same time, but a copy is queued under its own name, so without the lock the
+// repair of a copy could interleave with the fan-out of its source computed from another snapshot of the sync
+// annotation and the namespace labels.
+var sourceLocks = newKeyedMutex()
+
+// keyedMutex is a set of mutexes created on demand for each key and removed once no reconcile holds or waits for them
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: map[string]*keyedLock{}}
+}
+
+// lock locks key and returns the func that unlocks it and whether the lock had to wait for another holder
+func (m *keyedMutex) lock(key string) (func(), bool) {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	waited := !l.TryLock()
+	if waited {
+		l.Lock()
+	}
+	return func() {
+		l.Unlock()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(m.locks, key)
+		}
+	}, waited
+}
+
+// sourceLockKey returns the key of the source of o, which is o itself unless it is a copy. Objects that weren't
+// found are locked under the name of the request.
+func sourceLockKey(o client.Object, req ctrl.Request) string {
+	key := req.NamespacedName
+	if namespace, ok := o.GetLabels()[sourceLabelNamespace]; ok {
+		key = types.NamespacedName{Namespace: namespace, Name: sourceNameOf(o)}
+	}
+	return kindOf(o) + "/" + key.String()
+}
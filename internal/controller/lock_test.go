@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Source locks\n", func() {
+	It("Should serialize reconciles of the same source only", func() {
+		m := newKeyedMutex()
+		var running, overlapped atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				unlock, _ := m.lock("secret/ns/a")
+				defer unlock()
+				if running.Add(1) > 1 {
+					overlapped.Add(1)
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+			}()
+		}
+		unlock, waited := m.lock("secret/ns/b")
+		unlock()
+		Expect(waited).Should(BeFalse())
+		wg.Wait()
+		Expect(overlapped.Load()).Should(BeZero())
+		Expect(m.locks).Should(BeEmpty())
+	})
+	It("Should lock copies under the key of their source", func() {
+		cp := &corev1.Secret{}
+		cp.Labels = map[string]string{sourceLabelNamespace: "platform", sourceLabelName: "tls"}
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "tls-kopy"}}
+		Expect(sourceLockKey(cp, req)).Should(Equal("secret/platform/tls"))
+		Expect(sourceLockKey(&corev1.Secret{}, req)).Should(Equal("secret/team-a/tls-kopy"))
+	})
+	It("Should converge when the selector and namespace labels change at the same time", func() {
+		tc = NewTestClient(context.Background())
+		const name = "test-src-lock-00"
+		srcNamespace, err := tc.CreateNamespace("test-src-lock-ns-00", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		blue, green := &syncLabel{key: "lock-color", value: "blue"}, &syncLabel{key: "lock-color", value: "green"}
+		src, err := tc.CreateSecret(name, srcNamespace.Name, blue, map[string][]byte{"k": []byte("v")}, corev1.SecretTypeOpaque)
+		Expect(err).ShouldNot(HaveOccurred())
+		namespaces := []string{}
+		for i := 0; i < 6; i++ {
+			label := blue
+			if i%2 == 1 {
+				label = green
+			}
+			ns, err := tc.CreateNamespace("test-dst-lock-ns-00", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			namespaces = append(namespaces, ns.Name)
+		}
+
+		By("Flipping the selector while the namespace labels flip")
+		selectors := []*syncLabel{green, blue, green}
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+			for _, label := range selectors {
+				Expect(retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					if err := tc.GetSecret(name, src.Namespace, src); err != nil {
+						return err
+					}
+					SetSyncSelector(src, label.key+"="+label.value)
+					return tc.UpdateSecret(src)
+				})).Should(Succeed())
+			}
+		}()
+		for _, n := range namespaces {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for range 3 {
+					Expect(retry.RetryOnConflict(retry.DefaultRetry, func() error {
+						ns := &corev1.Namespace{}
+						if err := tc.GetNamespace(n, ns); err != nil {
+							return err
+						}
+						if ns.Labels[blue.key] == blue.value {
+							ns.Labels[blue.key] = green.value
+						} else {
+							ns.Labels[blue.key] = blue.value
+						}
+						return k8sClient.Update(context.Background(), ns)
+					})).Should(Succeed())
+				}
+			}()
+		}
+		wg.Wait()
+
+		By("Checking that exactly the namespaces selected at the end have a copy")
+		final := selectors[len(selectors)-1]
+		Eventually(func(g Gomega) {
+			for _, n := range namespaces {
+				ns := &corev1.Namespace{}
+				g.Expect(tc.GetNamespace(n, ns)).Should(Succeed())
+				err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: n, Name: name}, &corev1.Secret{})
+				if ns.Labels[final.key] == final.value {
+					g.Expect(err).ShouldNot(HaveOccurred(), "copy missing from %s", n)
+				} else {
+					g.Expect(apierrors.IsNotFound(err)).Should(BeTrue(), "copy left in %s", n)
+				}
+			}
+		}, timeout, interval).Should(Succeed())
+		Expect(client.IgnoreNotFound(tc.DeleteSecret(src))).Should(Succeed())
+	})
+})
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flynshue/kopy/pkg/testenv"
+)
+
+var _ = Describe("Test matchers\n", func() {
+	src := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-src-matchers-00", Namespace: "test-src-matchers-ns-00"},
+		Data:       map[string]string{"level": "debug"},
+	}
+	It("Should match the copies kopy writes", func() {
+		cp, err := newCopy(src, "test-dst-matchers-ns-00", Options{CopyNameSuffix: "-kopy"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(cp).Should(And(testenv.BeSyncedFrom(src), testenv.HaveKopyFinalizer(), Not(testenv.BeOrphaned())))
+
+		cp.(*corev1.ConfigMap).Data = map[string]string{"level": "info"}
+		Expect(cp).ShouldNot(testenv.BeSyncedFrom(src))
+	})
+	It("Should match copies released by kopy", func() {
+		cp, err := newCopy(src, "test-dst-matchers-ns-00", Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		cp.SetFinalizers(nil)
+		cp.SetLabels(nil)
+		Expect(cp).Should(And(testenv.BeOrphaned(), Not(testenv.HaveKopyFinalizer())))
+	})
+})
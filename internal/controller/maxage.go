@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxAgeKey declares how old the data of a source may get before it counts as stale, e.g. "90d" for credentials
+	// that have to be rotated every 90 days. Go durations like "720h" are accepted as well.
+	maxAgeKey = kopyPrefix + "max-age"
+	// maxAgeEnforceKey is set to "true" on a source with a max age to stop creating copies in namespaces that don't
+	// have one yet, including copies that were deleted, once the source is stale
+	maxAgeEnforceKey = kopyPrefix + "max-age-enforce"
+	// dataChangedKey is set by kopy on sources with a max age to the time their data last changed
+	dataChangedKey = kopyPrefix + "data-changed-at"
+	// dataRevisionKey is set by kopy on sources with a max age to the revision of the data changed at dataChangedKey
+	dataRevisionKey = kopyPrefix + "data-revision"
+	// reasonSourceStale is used for events on sources whose data is older than their max age
+	reasonSourceStale = "SourceStale"
+)
+
+// sourceAge is the result of checking the age of a source against its max age
+type sourceAge struct {
+	// stale is true if the data of the source is older than its max age
+	stale bool
+	// enforced is true if no new copies may be created because the source is stale and its max age is enforced
+	enforced bool
+	// staleIn is the time left until the source gets stale, 0 for sources without a max age or that are stale
+	staleIn time.Duration
+}
+
+// parseMaxAge parses the value of the max age annotation, a number of days like "90d" or a Go duration
+func parseMaxAge(v string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid max age %q: %w", v, err)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(v); err != nil {
+			return 0, fmt.Errorf("invalid max age %q: %w", v, err)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid max age %q: must be positive", v)
+	}
+	return d, nil
+}
+
+// checkSourceAge checks the data of src against its max age. The time the data last changed is recorded on src
+// whenever its revision differs from the recorded one, sources seen for the first time count from their creation.
+// Stale sources get a SourceStale warning event. recorder may be nil.
+func checkSourceAge(ctx context.Context, c client.Client, recorder record.EventRecorder, src client.Object) (sourceAge, error) {
+	v, ok := src.GetAnnotations()[maxAgeKey]
+	if !ok {
+		return sourceAge{}, nil
+	}
+	maxAge, err := parseMaxAge(v)
+	if err != nil {
+		return sourceAge{}, err
+	}
+	changedAt, err := recordDataChange(ctx, c, src)
+	if err != nil {
+		return sourceAge{}, err
+	}
+	age := now().Sub(changedAt)
+	if age <= maxAge {
+		return sourceAge{staleIn: maxAge - age}, nil
+	}
+	result := sourceAge{stale: true, enforced: src.GetAnnotations()[maxAgeEnforceKey] == "true"}
+	if recorder != nil {
+		msg := fmt.Sprintf("Source data last changed %s ago, more than its max age %s", age.Truncate(time.Second), v)
+		if result.enforced {
+			msg += ", no new copies are created until it is rotated"
+		}
+		recorder.Event(src, corev1.EventTypeWarning, reasonSourceStale, msg)
+	}
+	return result, nil
+}
+
+// recordDataChange returns the time the data of src last changed and records it on src if the data changed since
+// it was recorded
+func recordDataChange(ctx context.Context, c client.Client, src client.Object) (time.Time, error) {
+	annotations := src.GetAnnotations()
+	revision := dataRevision(src)
+	recorded := annotations[dataRevisionKey]
+	changed, err := time.Parse(time.RFC3339, annotations[dataChangedKey])
+	if recorded == revision && err == nil {
+		return changed, nil
+	}
+	changedAt := now()
+	switch created := src.GetCreationTimestamp(); {
+	case isLegacyRevision(recorded) && err == nil:
+		// the truncated revision of an earlier kopy version is replaced without restarting the max age of the source
+		changedAt = changed
+	case recorded == "" && !created.IsZero():
+		changedAt = created.Time
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	annotations[dataRevisionKey] = revision
+	annotations[dataChangedKey] = changedAt.UTC().Format(time.RFC3339)
+	src.SetAnnotations(annotations)
+	if err := c.Patch(ctx, src, patch); err != nil {
+		return time.Time{}, err
+	}
+	return changedAt, nil
+}
+
+// namespacesWithCopies returns the namespaces of namespaces that already hold a copy of src
+func namespacesWithCopies(ctx context.Context, c client.Client, src client.Object, namespaces []corev1.Namespace) ([]corev1.Namespace, error) {
+	copies, err := newObjectListForKind(kindOf(src))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.List(ctx, copies, listOptions(src)); err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(copies)
+	if err != nil {
+		return nil, err
+	}
+	existing := sets.New[string]()
+	for _, item := range items {
+		if cp := item.(client.Object); isCopyOf(cp, src) {
+			existing.Insert(cp.GetNamespace())
+		}
+	}
+	result := []corev1.Namespace{}
+	for _, ns := range namespaces {
+		if existing.Has(ns.Name) {
+			result = append(result, ns)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Source max age\n", func() {
+	const (
+		namespace = "test-src-maxage-ns-00"
+		target    = "test-dst-maxage-ns-00"
+	)
+	start := time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+	BeforeEach(func() {
+		now = func() time.Time { return start }
+	})
+	AfterEach(func() {
+		now = time.Now
+	})
+
+	DescribeTable("Should parse max ages",
+		func(v string, expected time.Duration, valid bool) {
+			d, err := parseMaxAge(v)
+			if !valid {
+				Expect(err).Should(HaveOccurred())
+				return
+			}
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(d).Should(Equal(expected))
+		},
+		Entry("days", "90d", 90*24*time.Hour, true),
+		Entry("a duration", "36h", 36*time.Hour, true),
+		Entry("no days", "0d", time.Duration(0), false),
+		Entry("garbage", "quarterly", time.Duration(0), false),
+	)
+
+	It("Should stop creating copies of a stale source until its data changes", func() {
+		ctx := context.Background()
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-maxage-00", Namespace: namespace, CreationTimestamp: metav1.NewTime(start.Add(-100 * 24 * time.Hour)),
+				Annotations: map[string]string{syncKey: "team=payments", maxAgeKey: "90d", maxAgeEnforceKey: "true"},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).Build()
+		recorder := record.NewFakeRecorder(10)
+		reconcile := func() ctrl.Result {
+			result, err := KopyReconcile(NewKopySecret(ctx, c, Options{}, recorder), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			return result
+		}
+		copyKey := types.NamespacedName{Namespace: target, Name: src.Name}
+
+		reconcile()
+		Expect(apierrors.IsNotFound(c.Get(ctx, copyKey, &corev1.Secret{}))).Should(BeTrue())
+		Expect(recorder.Events).Should(Receive(ContainSubstring(reasonSourceStale)))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Annotations).Should(HaveKeyWithValue(dataChangedKey, start.Add(-100*24*time.Hour).Format(time.RFC3339)))
+
+		src.Data["password"] = []byte("correct horse battery staple")
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		result := reconcile()
+		Expect(c.Get(ctx, copyKey, &corev1.Secret{})).Should(Succeed())
+		Expect(result.RequeueAfter).Should(Equal(90 * 24 * time.Hour))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Annotations).Should(HaveKeyWithValue(dataChangedKey, start.Format(time.RFC3339)))
+	})
+
+	It("Should keep the time of the last data change recorded with a truncated revision", func() {
+		changed := start.Add(-10 * 24 * time.Hour).Format(time.RFC3339)
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-maxage-01", Namespace: namespace,
+				Annotations: map[string]string{dataRevisionKey: "0123456789ab", dataChangedKey: changed},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src).Build()
+		t, err := recordDataChange(context.Background(), c, src)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(t.Format(time.RFC3339)).Should(Equal(changed))
+		Expect(src.Annotations).Should(HaveKeyWithValue(dataRevisionKey, dataRevision(src)))
+	})
+})
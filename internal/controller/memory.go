@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// conditionDegraded is the debug state condition that is true while kopy runs in degraded mode
+	conditionDegraded = "Degraded"
+	// degradedRefreshFactor stretches the refresh interval of sources while kopy is degraded
+	degradedRefreshFactor = 4
+	// degradedGCPercent is the GC target while kopy is degraded, so the heap is collected long before it doubles
+	degradedGCPercent = 25
+	// leanSecretKey marks the Secrets that were cached without their data while kopy was degraded. It only exists in
+	// the cache, reads of such Secrets go to the API server.
+	leanSecretKey = kopyPrefix + "lean"
+)
+
+// memoryDegraded is true while the memory of kopy is close to its limit. Copies are then written one at a time,
+// sources are refreshed less often and Secrets are cached without their data, so a fan-out doesn't get the controller
+// OOM killed halfway through.
+var memoryDegraded atomic.Bool
+
+var _ manager.Runnable = &MemoryGuard{}
+var _ manager.LeaderElectionRunnable = &MemoryGuard{}
+
+// MemoryGuard switches kopy to degraded mode when the memory of the process passes HighWatermark of its limit and
+// back once it drops below LowWatermark. In degraded mode the garbage collector runs more often and returns freed
+// memory to the OS, copies are written one at a time, the refresh interval is stretched and Secrets entering the cache
+// are stripped of their data by LeanSecretTransform. The Secrets cached before are stripped when kopy enters degraded
+// mode and restored when it leaves it. The Degraded condition of the debug state and the kopy_memory_degraded metric
+// report the mode.
+type MemoryGuard struct {
+	// Limit is the memory limit in bytes, detected from the cgroup of the container or GOMEMLIMIT when 0
+	Limit uint64
+	// HighWatermark and LowWatermark are fractions of the limit, e.g. 0.9 and 0.75
+	HighWatermark float64
+	LowWatermark  float64
+	Interval      time.Duration
+	// SecretMetadataOnly is whether Secrets are cached without their data already, degraded mode then leaves the cache
+	// of Secrets alone
+	SecretMetadataOnly bool
+	// Secrets is the store of the Secret informer. The cached Secrets are stripped of their data when kopy enters
+	// degraded mode and read from Reader when it leaves it. When nil only the Secrets entering the cache while kopy is
+	// degraded are cached without their data.
+	Secrets toolscache.Store
+	// Reader reads the Secrets to restore from the API server
+	Reader client.Reader
+
+	// usage returns the memory used by the process, overridden by tests
+	usage        func() uint64
+	gcPercent    int
+	gcPercentSet bool
+}
+
+// Start checks the memory of the process every interval until ctx is cancelled. It returns right away if no limit is
+// set or detected.
+func (g *MemoryGuard) Start(ctx context.Context) error {
+	log := ctrllog.Log.WithName("memory-guard")
+	if g.Limit == 0 {
+		g.Limit = detectMemoryLimit()
+	}
+	if g.Limit == 0 {
+		log.Info("no memory limit detected, degraded mode is disabled")
+		return nil
+	}
+	log.Info("watching memory", "limit", g.Limit, "highWatermark", g.HighWatermark, "lowWatermark", g.LowWatermark)
+	ticker := time.NewTicker(g.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			g.check(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection returns false, every replica guards its own memory
+func (g *MemoryGuard) NeedLeaderElection() bool {
+	return false
+}
+
+// check enters or leaves degraded mode depending on the current memory usage
+func (g *MemoryGuard) check(ctx context.Context) {
+	usage := processMemory
+	if g.usage != nil {
+		usage = g.usage
+	}
+	used := usage()
+	ratio := float64(used) / float64(g.Limit)
+	log := ctrllog.FromContext(ctx).WithName("memory-guard")
+	switch {
+	case !memoryDegraded.Load() && ratio >= g.HighWatermark:
+		msg := fmt.Sprintf("memory use %d of %d bytes is above %.0f%% of the limit, copies are written one at a time "+
+			"and sources are refreshed less often", used, g.Limit, g.HighWatermark*100)
+		if !g.SecretMetadataOnly {
+			msg += ", Secrets are cached without their data and read from the API server"
+		}
+		log.Info("entering degraded mode", "used", used, "limit", g.Limit)
+		memoryDegraded.Store(true)
+		memoryDegradedGauge.Set(1)
+		if g.Secrets != nil && !g.SecretMetadataOnly {
+			log.Info("stripped cached secrets", "secrets", g.stripSecrets())
+		}
+		g.gcPercent, g.gcPercentSet = debug.SetGCPercent(degradedGCPercent), true
+		debug.FreeOSMemory()
+		debugState.setCondition(metav1.Condition{Type: conditionDegraded, Status: metav1.ConditionTrue,
+			Reason: "MemoryPressure", Message: msg})
+	case memoryDegraded.Load() && ratio < g.LowWatermark:
+		log.Info("leaving degraded mode", "used", used, "limit", g.Limit)
+		memoryDegraded.Store(false)
+		memoryDegradedGauge.Set(0)
+		if g.gcPercentSet {
+			debug.SetGCPercent(g.gcPercent)
+			g.gcPercentSet = false
+		}
+		if g.Secrets != nil && !g.SecretMetadataOnly {
+			restored, err := g.restoreSecrets(ctx)
+			if err != nil {
+				log.Error(err, "unable to restore cached secrets, the others are read from the API server until they change",
+					"restored", restored)
+			} else {
+				log.Info("restored cached secrets", "secrets", restored)
+			}
+		}
+		debugState.setCondition(metav1.Condition{Type: conditionDegraded, Status: metav1.ConditionFalse,
+			Reason: "MemoryRecovered", Message: fmt.Sprintf("memory use %d of %d bytes is below %.0f%% of the limit",
+				used, g.Limit, g.LowWatermark*100)})
+	}
+}
+
+// stripSecrets replaces the cached Secrets with data by lean Secrets and returns how many were replaced
+func (g *MemoryGuard) stripSecrets() int {
+	stripped := 0
+	for _, obj := range g.Secrets.List() {
+		s, ok := obj.(*corev1.Secret)
+		if !ok || isLeanSecret(s) || (len(s.Data) == 0 && len(s.StringData) == 0) {
+			continue
+		}
+		// cached objects are shared with their readers, so s is replaced instead of changed
+		lean := &corev1.Secret{TypeMeta: s.TypeMeta, ObjectMeta: *s.ObjectMeta.DeepCopy(), Immutable: s.Immutable, Type: s.Type}
+		markLean(lean)
+		if g.replaceCached(s, lean) {
+			stripped++
+		}
+	}
+	return stripped
+}
+
+// restoreSecrets replaces the lean Secrets in the cache by the Secrets read from the API server and returns how many
+// were replaced
+func (g *MemoryGuard) restoreSecrets(ctx context.Context) (int, error) {
+	restored := 0
+	for _, obj := range g.Secrets.List() {
+		s, ok := obj.(*corev1.Secret)
+		if !ok || !isLeanSecret(s) {
+			continue
+		}
+		live := &corev1.Secret{}
+		if err := g.Reader.Get(ctx, client.ObjectKeyFromObject(s), live); apierrors.IsNotFound(err) {
+			// the informer drops the Secret once it sees the deletion
+			continue
+		} else if err != nil {
+			return restored, err
+		}
+		if g.replaceCached(s, live) {
+			restored++
+		}
+	}
+	return restored, nil
+}
+
+// replaceCached replaces old with obj in the cache unless the informer replaced old in the meantime, which is newer
+// than obj and already went through LeanSecretTransform
+func (g *MemoryGuard) replaceCached(old, obj *corev1.Secret) bool {
+	cached, exists, err := g.Secrets.Get(old)
+	if err != nil || !exists || cached != any(old) {
+		return false
+	}
+	return g.Secrets.Update(obj) == nil
+}
+
+// LeanSecretTransform is the cache transform of Secrets. While kopy is degraded it drops the data of the Secrets that
+// enter the cache, e.g. the copies a fan-out writes, and marks them so the client reads them from the API server.
+// MemoryGuard strips the Secrets cached before.
+func LeanSecretTransform(obj any) (any, error) {
+	s, ok := obj.(*corev1.Secret)
+	if !ok || !memoryDegraded.Load() || (len(s.Data) == 0 && len(s.StringData) == 0) {
+		return obj, nil
+	}
+	s.Data, s.StringData = nil, nil
+	markLean(s)
+	return s, nil
+}
+
+// markLean marks s as cached without its data
+func markLean(s *corev1.Secret) {
+	annotations := s.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[leanSecretKey] = "true"
+	s.SetAnnotations(annotations)
+}
+
+// isLeanSecret returns true if s was cached without its data by LeanSecretTransform
+func isLeanSecret(s *corev1.Secret) bool {
+	_, ok := s.GetAnnotations()[leanSecretKey]
+	return ok
+}
+
+// processMemory returns the memory the Go runtime holds from the OS
+func processMemory() uint64 {
+	samples := []metrics.Sample{{Name: "/memory/classes/total:bytes"}, {Name: "/memory/classes/heap/released:bytes"}}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
+
+// detectMemoryLimit returns the memory limit of the cgroup of the process or GOMEMLIMIT, whichever is lower, and 0
+// if neither is set
+func detectMemoryLimit() uint64 {
+	var limit uint64
+	for _, path := range []string{"/sys/fs/cgroup/memory.max", "/sys/fs/cgroup/memory/memory.limit_in_bytes"} {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// cgroup v2 reports max and cgroup v1 a huge number when there is no limit
+		if v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64); err == nil && v < 1<<62 {
+			limit = v
+		}
+		break
+	}
+	if v := debug.SetMemoryLimit(-1); v != math.MaxInt64 && (limit == 0 || uint64(v) < limit) {
+		limit = uint64(v)
+	}
+	return limit
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Memory guard\n", func() {
+	AfterEach(func() {
+		memoryDegraded.Store(false)
+	})
+
+	It("Should enter degraded mode near the memory limit and leave it below the low watermark", func() {
+		ctx := context.Background()
+		var used uint64
+		g := &MemoryGuard{Limit: 1000, HighWatermark: 0.9, LowWatermark: 0.75, usage: func() uint64 { return used }}
+		opts := Options{RefreshInterval: time.Minute}
+		degraded := func() *metav1.Condition {
+			state, err := debugState.snapshot(ctx, fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build())
+			Expect(err).ShouldNot(HaveOccurred())
+			return meta.FindStatusCondition(state.Conditions, conditionDegraded)
+		}
+
+		used = 800
+		g.check(ctx)
+		Expect(memoryDegraded.Load()).Should(BeFalse())
+		Expect(opts.refresh(ctrl.Result{}).RequeueAfter).Should(Equal(time.Minute))
+
+		used = 950
+		g.check(ctx)
+		Expect(memoryDegraded.Load()).Should(BeTrue())
+		Expect(opts.refresh(ctrl.Result{}).RequeueAfter).Should(Equal(4 * time.Minute))
+		Expect(degraded()).Should(HaveField("Status", metav1.ConditionTrue))
+		Expect(degraded().Message).Should(ContainSubstring("Secrets are cached without their data"))
+
+		// degraded mode is only left below the low watermark, so it doesn't flap around the high one
+		used = 800
+		g.check(ctx)
+		Expect(memoryDegraded.Load()).Should(BeTrue())
+
+		used = 700
+		g.check(ctx)
+		Expect(memoryDegraded.Load()).Should(BeFalse())
+		Expect(degraded()).Should(HaveField("Reason", "MemoryRecovered"))
+	})
+
+	It("Should cache Secrets without their data while degraded and read them from the API server", func() {
+		ctx := context.Background()
+		newSecret := func() *corev1.Secret {
+			return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-src-lean-00", Namespace: "test-src-lean-ns-00"},
+				Data: map[string][]byte{"password": []byte("hunter2")}}
+		}
+		obj, err := LeanSecretTransform(newSecret())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(obj.(*corev1.Secret).Data).Should(HaveKey("password"))
+
+		memoryDegraded.Store(true)
+		obj, err = LeanSecretTransform(newSecret())
+		Expect(err).ShouldNot(HaveOccurred())
+		lean := obj.(*corev1.Secret)
+		Expect(lean.Data).Should(BeEmpty())
+		Expect(isLeanSecret(lean)).Should(BeTrue())
+
+		// the cached client holds the lean Secret like an informer with the transform
+		cached := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(lean).Build()
+		live := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(newSecret()).Build()
+		c := &leanSecretClient{Client: cached, live: live}
+		s := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(lean), s)).Should(Succeed())
+		Expect(s.Data).Should(HaveKeyWithValue("password", []byte("hunter2")))
+		Expect(isLeanSecret(s)).Should(BeFalse())
+		secrets := &corev1.SecretList{}
+		Expect(c.List(ctx, secrets)).Should(Succeed())
+		Expect(secrets.Items).Should(HaveLen(1))
+		Expect(secrets.Items[0].Data).Should(HaveKeyWithValue("password", []byte("hunter2")))
+	})
+
+	It("Should strip the cached Secrets when entering degraded mode and restore them when leaving it", func() {
+		ctx := context.Background()
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-src-lean-01", Namespace: "test-src-lean-ns-00"},
+			Data: map[string][]byte{"password": []byte("hunter2")}}
+		store := toolscache.NewStore(toolscache.MetaNamespaceKeyFunc)
+		Expect(store.Add(secret)).Should(Succeed())
+		var used uint64
+		g := &MemoryGuard{Limit: 1000, HighWatermark: 0.9, LowWatermark: 0.75, usage: func() uint64 { return used },
+			Secrets: store, Reader: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(secret.DeepCopy()).Build()}
+		cached := func() *corev1.Secret {
+			obj, exists, err := store.GetByKey("test-src-lean-ns-00/test-src-lean-01")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(exists).Should(BeTrue())
+			return obj.(*corev1.Secret)
+		}
+
+		used = 950
+		g.check(ctx)
+		Expect(cached().Data).Should(BeEmpty())
+		Expect(isLeanSecret(cached())).Should(BeTrue())
+		// readers of the Secret cached before keep their data
+		Expect(secret.Data).Should(HaveKey("password"))
+		Expect(isLeanSecret(secret)).Should(BeFalse())
+
+		used = 700
+		g.check(ctx)
+		Expect(cached().Data).Should(HaveKeyWithValue("password", []byte("hunter2")))
+		Expect(isLeanSecret(cached())).Should(BeFalse())
+	})
+})
@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/pkg/freshness"
+)
+
+const (
+	// mergeIntoKey names the object a source contributes its keys to in every selected namespace instead of being
+	// copied, e.g. "trusted-ca-bundle". Every source of the same kind merged into the same name contributes to one
+	// object per namespace.
+	mergeIntoKey = kopyPrefix + "merge-into"
+	// mergeConflictKey sets how keys provided by several sources with different values are merged, see
+	// MergeConflictPolicy. The sources merged into an object must agree on the policy.
+	mergeConflictKey = kopyPrefix + "merge-conflict"
+	// mergedLabel marks objects kopy merged from several sources
+	mergedLabel = kopyPrefix + "merged"
+	// mergedFromKey lists the sources of a merged object as namespace/name, separated by commas
+	mergedFromKey = kopyPrefix + "merged-from"
+)
+
+// MergeConflictPolicy decides the value of a key that several merged sources provide with different values
+type MergeConflictPolicy string
+
+const (
+	// MergeConflictFail leaves the merged object unchanged until the conflict is resolved, this is the default
+	MergeConflictFail MergeConflictPolicy = "fail"
+	// MergeConflictFirst takes the value of the first source in namespace/name order
+	MergeConflictFirst MergeConflictPolicy = "first"
+	// MergeConflictConcat joins the values in namespace/name order of the sources with a newline, e.g. to build a
+	// bundle of PEM certificates
+	MergeConflictConcat MergeConflictPolicy = "concat"
+)
+
+// errMergeConflict is returned when merged sources provide different values for a key and their policy is fail
+var errMergeConflict = errors.New("merged sources conflict")
+
+// mergeTarget returns the name of the object src is merged into, if any. CA bundle contributors are merged into the
+// bundle they are labeled with.
+func mergeTarget(src client.Object) (string, bool) {
+	if name := src.GetLabels()[caBundleLabel]; name != "" {
+		return name, true
+	}
+	name, ok := src.GetAnnotations()[mergeIntoKey]
+	return name, ok && name != ""
+}
+
+// mergeContributors returns the sources of kind merged into the object named name in ns, in namespace/name order.
+// Sources that are being deleted or don't select ns are left out, as are sources in ns itself.
+func mergeContributors(ctx context.Context, c client.Client, kind, name string, ns *corev1.Namespace) ([]client.Object, error) {
+	list, err := newObjectListForKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.List(ctx, list); err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	contributors := []client.Object{}
+	for _, item := range items {
+		src := item.(client.Object)
+		if target, ok := mergeTarget(src); !ok || target != name {
+			continue
+		}
+		if src.GetNamespace() == ns.Name || src.GetDeletionTimestamp() != nil || !namespaceContainsSyncLabel(src, ns) {
+			continue
+		}
+		contributors = append(contributors, src)
+	}
+	slices.SortFunc(contributors, func(a, b client.Object) int {
+		return strings.Compare(client.ObjectKeyFromObject(a).String(), client.ObjectKeyFromObject(b).String())
+	})
+	return contributors, nil
+}
+
+// mergeData merges the data of contributors key by key, or into a single CA bundle for CA bundle contributors
+func mergeData(contributors []client.Object) (map[string][]byte, error) {
+	if slices.ContainsFunc(contributors, isCABundleSource) {
+		return caBundleData(contributors)
+	}
+	policy := MergeConflictFail
+	for i, src := range contributors {
+		p := MergeConflictPolicy(src.GetAnnotations()[mergeConflictKey])
+		if p == "" {
+			p = MergeConflictFail
+		}
+		switch p {
+		case MergeConflictFail, MergeConflictFirst, MergeConflictConcat:
+		default:
+			return nil, fmt.Errorf("invalid %s %q on %s", mergeConflictKey, p, client.ObjectKeyFromObject(src))
+		}
+		if i > 0 && p != policy {
+			return nil, fmt.Errorf("%w: %s uses the %s policy but %s uses %s", errMergeConflict,
+				client.ObjectKeyFromObject(src), p, client.ObjectKeyFromObject(contributors[0]), policy)
+		}
+		policy = p
+	}
+	values := map[string][][]byte{}
+	for _, src := range contributors {
+		for k, v := range objectData(src) {
+			values[k] = append(values[k], v)
+		}
+	}
+	data := map[string][]byte{}
+	conflicts := []string{}
+	for k, vs := range values {
+		if !slices.ContainsFunc(vs, func(v []byte) bool { return !bytes.Equal(v, vs[0]) }) {
+			data[k] = vs[0]
+			continue
+		}
+		switch policy {
+		case MergeConflictFirst:
+			data[k] = vs[0]
+		case MergeConflictConcat:
+			data[k] = bytes.Join(vs, []byte("\n"))
+		default:
+			conflicts = append(conflicts, k)
+		}
+	}
+	if len(conflicts) > 0 {
+		slices.Sort(conflicts)
+		return nil, fmt.Errorf("%w: different values for %s", errMergeConflict, strings.Join(conflicts, ", "))
+	}
+	return data, nil
+}
+
+// newMergedObject builds the object of the kind of contributors named name in namespace from their merged data
+func newMergedObject(contributors []client.Object, name, namespace string, data map[string][]byte) (client.Object, error) {
+	from := make([]string, 0, len(contributors))
+	for _, src := range contributors {
+		from = append(from, client.ObjectKeyFromObject(src).String())
+	}
+	om := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{mergedLabel: "true"},
+		Annotations: map[string]string{
+			mergedFromKey:                    strings.Join(from, ","),
+			freshness.LastSyncTimeAnnotation: now().UTC().Format(time.RFC3339),
+		},
+	}
+	switch s := contributors[0].(type) {
+	case *corev1.Secret:
+		// a CA bundle doesn't carry the keys its contributors' types require, e.g. tls.key
+		if isCABundleSource(s) {
+			return &corev1.Secret{ObjectMeta: om, Data: data, Type: corev1.SecretTypeOpaque}, nil
+		}
+		return &corev1.Secret{ObjectMeta: om, Data: data, Type: s.Type}, nil
+	case *corev1.ConfigMap:
+		cm := &corev1.ConfigMap{ObjectMeta: om, Data: map[string]string{}, BinaryData: map[string][]byte{}}
+		for _, src := range contributors {
+			for k := range src.(*corev1.ConfigMap).BinaryData {
+				if v, ok := data[k]; ok {
+					cm.BinaryData[k] = v
+				}
+			}
+		}
+		for k, v := range data {
+			if _, ok := cm.BinaryData[k]; !ok {
+				cm.Data[k] = string(v)
+			}
+		}
+		return cm, nil
+	}
+	return nil, fmt.Errorf("unsupported kind %T", contributors[0])
+}
+
+// syncMerged writes the object of kind named name in namespace from the sources merged into it, or deletes it when
+// no source is merged into it anymore. Objects of the same name that kopy didn't merge are never overwritten.
+func syncMerged(ctx context.Context, c client.Client, kind, name, namespace string) error {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	existing, err := NewObjectForKind(kind)
+	if err != nil {
+		return err
+	}
+	err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, existing)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	found := err == nil
+	if found && existing.GetLabels()[mergedLabel] != "true" {
+		return fmt.Errorf("%w: %s %s/%s wasn't merged by kopy", errCopyConflict, kind, namespace, name)
+	}
+	contributors, err := mergeContributors(ctx, c, kind, name, ns)
+	if err != nil {
+		return err
+	}
+	if len(contributors) == 0 || ns.DeletionTimestamp != nil {
+		if found {
+			return client.IgnoreNotFound(c.Delete(ctx, existing))
+		}
+		return nil
+	}
+	data, err := mergeData(contributors)
+	if err != nil {
+		return fmt.Errorf("unable to merge %s %s/%s: %w", kind, namespace, name, err)
+	}
+	merged, err := newMergedObject(contributors, name, namespace, data)
+	if err != nil {
+		return err
+	}
+	if found {
+		preserveCopyMetadata(existing, merged)
+	}
+	if err := c.Create(ctx, merged); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		return c.Update(ctx, merged)
+	}
+	return nil
+}
+
+// syncMergeTargets writes the object src is merged into in each of namespaces and refreshes the other merged objects
+// that list src as one of their sources, e.g. in namespaces that are no longer selected. Regular copies of src, left
+// from before it was merged, are pruned.
+func syncMergeTargets(k Kopier, name string, namespaces []corev1.Namespace) error {
+	kind := kindOf(k.GetObject())
+	errs := []error{}
+	synced := map[types.NamespacedName]bool{}
+	for _, ns := range namespaces {
+		synced[types.NamespacedName{Namespace: ns.Name, Name: name}] = true
+		if err := syncMerged(k.GetContext(), k.GetClient(), kind, name, ns.Name); err != nil {
+			debugState.recordError(kind, err)
+			errs = append(errs, err)
+		}
+	}
+	if err := refreshMergedObjects(k.GetContext(), k.GetClient(), k.GetObject(), synced); err != nil {
+		errs = append(errs, err)
+	}
+	if err := k.PruneCopies(nil); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// refreshMergedObjects merges the objects that list src as one of their sources again, except for the objects in
+// skip. It is called when src stops contributing to some of them, e.g. because it was deleted or its selector changed.
+func refreshMergedObjects(ctx context.Context, c client.Client, src client.Object, skip map[types.NamespacedName]bool) error {
+	kind := kindOf(src)
+	list, err := newObjectListForKind(kind)
+	if err != nil {
+		return err
+	}
+	if err := c.List(ctx, list, client.MatchingLabels{mergedLabel: "true"}); err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	source := client.ObjectKeyFromObject(src).String()
+	errs := []error{}
+	for _, item := range items {
+		o := item.(client.Object)
+		key := client.ObjectKeyFromObject(o)
+		if skip[key] || !slices.Contains(strings.Split(o.GetAnnotations()[mergedFromKey], ","), source) {
+			continue
+		}
+		if err := syncMerged(ctx, c, kind, key.Name, key.Namespace); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
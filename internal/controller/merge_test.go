@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Merged sources\n", func() {
+	const target = "test-dst-merge-ns-00"
+	source := func(namespace, policy string, data map[string]string) *corev1.ConfigMap {
+		annotations := map[string]string{syncKey: "env=prod", mergeIntoKey: "trusted-ca-bundle"}
+		if policy != "" {
+			annotations[mergeConflictKey] = policy
+		}
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-merge-00", Namespace: namespace, Annotations: annotations},
+			Data:       data,
+		}
+	}
+	merged := func(c client.Client) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Namespace: target, Name: "trusted-ca-bundle"}, cm)).Should(Succeed())
+		return cm
+	}
+	newClient := func(objects ...client.Object) client.Client {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"env": "prod"}}}
+		return fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(append(objects, ns)...).Build()
+	}
+	It("Should merge the keys of every source into one object", func() {
+		c := newClient(
+			source("test-src-merge-ns-00", "", map[string]string{"team-a.pem": "a", "shared": "s"}),
+			source("test-src-merge-ns-01", "", map[string]string{"team-b.pem": "b", "shared": "s"}),
+		)
+		Expect(syncMerged(context.Background(), c, "configmap", "trusted-ca-bundle", target)).Should(Succeed())
+		cm := merged(c)
+		Expect(cm.Data).Should(Equal(map[string]string{"team-a.pem": "a", "team-b.pem": "b", "shared": "s"}))
+		Expect(cm.Annotations[mergedFromKey]).Should(Equal("test-src-merge-ns-00/test-src-merge-00,test-src-merge-ns-01/test-src-merge-00"))
+	})
+	DescribeTable("Resolving conflicting keys",
+		func(policy, expected string) {
+			c := newClient(
+				source("test-src-merge-ns-00", policy, map[string]string{"ca.crt": "a"}),
+				source("test-src-merge-ns-01", policy, map[string]string{"ca.crt": "b"}),
+			)
+			Expect(syncMerged(context.Background(), c, "configmap", "trusted-ca-bundle", target)).Should(Succeed())
+			Expect(merged(c).Data).Should(Equal(map[string]string{"ca.crt": expected}))
+		},
+		Entry("first", "first", "a"),
+		Entry("concat", "concat", "a\nb"),
+	)
+	It("Should not write the object while sources conflict", func() {
+		c := newClient(
+			source("test-src-merge-ns-00", "", map[string]string{"ca.crt": "a"}),
+			source("test-src-merge-ns-01", "", map[string]string{"ca.crt": "b"}),
+		)
+		err := syncMerged(context.Background(), c, "configmap", "trusted-ca-bundle", target)
+		Expect(err).Should(MatchError(errMergeConflict))
+		Expect(err).Should(MatchError(ContainSubstring("ca.crt")))
+		c = newClient(
+			source("test-src-merge-ns-00", "first", map[string]string{"ca.crt": "a"}),
+			source("test-src-merge-ns-01", "concat", map[string]string{"ca.crt": "a"}),
+		)
+		Expect(syncMerged(context.Background(), c, "configmap", "trusted-ca-bundle", target)).Should(MatchError(errMergeConflict))
+	})
+	It("Should never overwrite objects kopy didn't merge", func() {
+		c := newClient(
+			source("test-src-merge-ns-00", "", map[string]string{"ca.crt": "a"}),
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "trusted-ca-bundle", Namespace: target}},
+		)
+		Expect(syncMerged(context.Background(), c, "configmap", "trusted-ca-bundle", target)).Should(MatchError(errCopyConflict))
+	})
+	It("Should remove the contribution of a source that stopped selecting the namespace", func() {
+		a := source("test-src-merge-ns-00", "", map[string]string{"team-a.pem": "a"})
+		b := source("test-src-merge-ns-01", "", map[string]string{"team-b.pem": "b"})
+		c := newClient(a, b)
+		Expect(syncMerged(context.Background(), c, "configmap", "trusted-ca-bundle", target)).Should(Succeed())
+
+		a.Annotations[syncKey] = "env=dev"
+		Expect(c.Update(context.Background(), a)).Should(Succeed())
+		Expect(refreshMergedObjects(context.Background(), c, a, nil)).Should(Succeed())
+		Expect(merged(c).Data).Should(Equal(map[string]string{"team-b.pem": "b"}))
+
+		delete(b.Annotations, syncKey)
+		Expect(c.Update(context.Background(), b)).Should(Succeed())
+		Expect(refreshMergedObjects(context.Background(), c, b, nil)).Should(Succeed())
+		err := c.Get(context.Background(), types.NamespacedName{Namespace: target, Name: "trusted-ca-bundle"}, &corev1.ConfigMap{})
+		Expect(client.IgnoreNotFound(err)).Should(Succeed())
+		Expect(err).Should(HaveOccurred())
+	})
+})
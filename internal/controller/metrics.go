@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// cacheLagRetries counts reconciles that were requeued because a source wasn't in the cache yet
+	cacheLagRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kopy_cache_lag_retries_total",
+			Help: "Number of reconciles requeued because the source object was not found in the cache yet",
+		},
+		[]string{"kind"},
+	)
+	// queueShed counts requests that were delayed because the workqueue of a controller was over its threshold
+	queueShed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kopy_workqueue_shed_total",
+			Help: "Number of requests delayed because the workqueue depth was over the shedding threshold",
+		},
+		[]string{"controller"},
+	)
+	// transformWebhookCalls counts transform webhook calls by outcome: success, skip, ignored or error
+	transformWebhookCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kopy_transform_webhook_calls_total",
+			Help: "Number of transform webhook calls by webhook and outcome",
+		},
+		[]string{"webhook", "result"},
+	)
+	// copyMutationsTotal counts copies the API server returned with different data or metadata than kopy wrote
+	copyMutationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kopy_copy_mutations_total",
+			Help: "Number of copy writes that were changed by the API server, e.g. by a mutating webhook",
+		},
+		[]string{"kind"},
+	)
+	// controllerDisabled is 1 for controllers that were not started because kopy lacks permissions they need
+	controllerDisabled = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kopy_controller_disabled",
+			Help: "Whether a controller was disabled at startup because of missing permissions",
+		},
+		[]string{"controller"},
+	)
+	// eventsPublished counts the CloudEvents published to the event sink by type and outcome: success or error
+	eventsPublished = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kopy_cloudevents_published_total",
+			Help: "Number of CloudEvents published to the event sink by type and outcome",
+		},
+		[]string{"type", "result"},
+	)
+	// suppressedWarningsTotal counts the warning events dropped because their source suppresses them
+	suppressedWarningsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kopy_suppressed_warnings_total",
+			Help: "Number of warning events not recorded because their source suppresses them, by reason",
+		},
+		[]string{"reason"},
+	)
+	// fieldManagerConflicts counts the applies of copies that conflicted with the fields of another field manager
+	fieldManagerConflicts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kopy_field_manager_conflicts_total",
+			Help: "Number of copies kopy took fields of another field manager back from, by kind and manager",
+		},
+		[]string{"kind", "manager"},
+	)
+	// orphansCollected counts the copies released or deleted because their source no longer exists
+	orphansCollected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kopy_orphaned_copies_collected_total",
+			Help: "Number of copies released or deleted by the orphan collector because their source no longer exists",
+		},
+		[]string{"kind"},
+	)
+	// memoryDegradedGauge is 1 while kopy runs in degraded mode because its memory is close to its limit
+	memoryDegradedGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kopy_memory_degraded",
+			Help: "Whether kopy runs in degraded mode because its memory is close to its limit",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(cacheLagRetries, queueShed, transformWebhookCalls, copyMutationsTotal, controllerDisabled,
+		eventsPublished, suppressedWarningsTotal, fieldManagerConflicts,
+		orphansCollected, memoryDegradedGauge)
+}
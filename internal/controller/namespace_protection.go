@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// confirmDeleteKey is set to "true" on a namespace to confirm that it may be deleted although it holds sources with
+// copies in other namespaces
+const confirmDeleteKey = kopyPrefix + "confirm-delete"
+
+// +kubebuilder:webhook:path=/validate--v1-namespace,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=namespaces,verbs=delete,versions=v1,name=vnamespace-v1.kopy.kot-labs.com,admissionReviewVersions=v1
+
+// NamespaceDeletionValidator denies the deletion of namespaces that hold sources with copies in other namespaces
+// unless the deletion was confirmed with the confirm-delete annotation. Deleting the namespace of a source removes
+// the config of every tenant the source was copied to.
+type NamespaceDeletionValidator struct {
+	Client client.Reader
+	// Namespaces restricts the search for copies to an explicit list of namespaces, see Options.Namespaces
+	Namespaces []string
+}
+
+var _ admission.CustomValidator = &NamespaceDeletionValidator{}
+
+// SetupWebhookWithManager registers the validator with the webhook server of mgr
+func (v *NamespaceDeletionValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&corev1.Namespace{}).WithValidator(v).Complete()
+}
+
+// ValidateCreate allows every namespace to be created
+func (v *NamespaceDeletionValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate allows every namespace update
+func (v *NamespaceDeletionValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateDelete denies the deletion of a namespace holding sources with live copies unless it was confirmed, in
+// which case the orphaned copies are returned as a warning
+func (v *NamespaceDeletionValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return nil, fmt.Errorf("expected a Namespace but got %T", obj)
+	}
+	copies, err := v.copiesOfSourcesIn(ctx, ns.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(copies) == 0 {
+		return nil, nil
+	}
+	summary := describeCopies(copies)
+	if ns.Annotations[confirmDeleteKey] == "true" {
+		return admission.Warnings{fmt.Sprintf("deleting namespace %s orphans the copies of %s", ns.Name, summary)}, nil
+	}
+	return nil, fmt.Errorf("namespace %s holds sources with copies in other namespaces: %s; annotate the namespace "+
+		"with %s=true to delete it anyway", ns.Name, summary, confirmDeleteKey)
+}
+
+// copiesOfSourcesIn counts the copies in other namespaces by their source in namespace, e.g. "secret platform/db"
+func (v *NamespaceDeletionValidator) copiesOfSourcesIn(ctx context.Context, namespace string) (map[string]int, error) {
+	scopes := v.Namespaces
+	if len(scopes) == 0 {
+		scopes = []string{""}
+	}
+	copies := map[string]int{}
+	for _, kind := range enabledKinds() {
+		// sources are looked up once per name, copies of a source that isn't synced anymore aren't live
+		active := map[string]bool{}
+		for _, scope := range scopes {
+			list, err := newObjectListForKind(kind)
+			if err != nil {
+				return nil, err
+			}
+			if err := v.Client.List(ctx, list, client.InNamespace(scope), client.MatchingLabels{sourceLabelNamespace: namespace}); err != nil {
+				return nil, err
+			}
+			items, err := meta.ExtractList(list)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				cp := item.(client.Object)
+				if cp.GetNamespace() == namespace || cp.GetDeletionTimestamp() != nil {
+					continue
+				}
+				name := sourceNameOf(cp)
+				if _, ok := active[name]; !ok {
+					if active[name], err = v.isActiveSource(ctx, kind, types.NamespacedName{Namespace: namespace, Name: name}); err != nil {
+						return nil, err
+					}
+				}
+				if active[name] {
+					copies[fmt.Sprintf("%s %s/%s", kind, namespace, name)]++
+				}
+			}
+		}
+	}
+	return copies, nil
+}
+
+// isActiveSource returns true if the object of kind at key exists and carries the sync annotation
+func (v *NamespaceDeletionValidator) isActiveSource(ctx context.Context, kind string, key types.NamespacedName) (bool, error) {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return false, err
+	}
+	if err := v.Client.Get(ctx, key, src); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	_, ok := SyncSelector(src)
+	return ok, nil
+}
+
+// describeCopies lists the sources and their number of copies in name order
+func describeCopies(copies map[string]int) string {
+	sources := make([]string, 0, len(copies))
+	for source, n := range copies {
+		unit := "copies"
+		if n == 1 {
+			unit = "copy"
+		}
+		sources = append(sources, fmt.Sprintf("%s (%d %s)", source, n, unit))
+	}
+	sort.Strings(sources)
+	return strings.Join(sources, ", ")
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Namespace deletion protection\n", func() {
+	const sourceNamespace = "test-src-nsprotect-ns-00"
+	copyIn := func(name, namespace string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{
+			sourceLabelNamespace: sourceNamespace,
+			sourceLabelName:      name,
+		}}}
+	}
+	newValidator := func(objects ...client.Object) *NamespaceDeletionValidator {
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-nsprotect-00", Namespace: sourceNamespace, Annotations: map[string]string{syncKey: "env=prod"},
+		}}
+		// the sync annotation was removed from this source, its remaining copies aren't live
+		inactive := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-src-nsprotect-01", Namespace: sourceNamespace}}
+		objects = append(objects, src, inactive,
+			copyIn("test-src-nsprotect-00", "test-dst-nsprotect-ns-00"),
+			copyIn("test-src-nsprotect-00", "test-dst-nsprotect-ns-01"),
+			copyIn("test-src-nsprotect-01", "test-dst-nsprotect-ns-00"),
+		)
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(objects...).Build()
+		return &NamespaceDeletionValidator{Client: c}
+	}
+	It("Should deny deleting a namespace holding sources with copies", func() {
+		v := newValidator()
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: sourceNamespace}}
+		_, err := v.ValidateDelete(context.Background(), ns)
+		Expect(err).Should(MatchError(ContainSubstring("secret test-src-nsprotect-ns-00/test-src-nsprotect-00 (2 copies)")))
+		Expect(err).ShouldNot(MatchError(ContainSubstring("test-src-nsprotect-01")))
+	})
+	It("Should warn when the deletion was confirmed", func() {
+		v := newValidator()
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name: sourceNamespace, Annotations: map[string]string{confirmDeleteKey: "true"},
+		}}
+		warnings, err := v.ValidateDelete(context.Background(), ns)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(warnings).Should(HaveLen(1))
+		Expect(warnings[0]).Should(ContainSubstring("orphans the copies of secret"))
+	})
+	It("Should allow deleting namespaces without sources", func() {
+		v := newValidator()
+		for _, name := range []string{"test-dst-nsprotect-ns-00", "test-dst-nsprotect-ns-02"} {
+			warnings, err := v.ValidateDelete(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(warnings).Should(BeEmpty())
+		}
+	})
+	It("Should only look for copies in the namespaces kopy is restricted to", func() {
+		v := newValidator()
+		v.Namespaces = []string{sourceNamespace, "test-dst-nsprotect-ns-01"}
+		_, err := v.ValidateDelete(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: sourceNamespace}})
+		Expect(err).Should(MatchError(ContainSubstring("(1 copy)")))
+	})
+})
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// NamespaceInfoName is the name of the ConfigMap that describes the copies of a namespace to its tenants
+	NamespaceInfoName = "kopy-info"
+	// namespaceInfoLabel marks the info ConfigMaps kopy writes, so a tenant's own ConfigMap of the same name is
+	// never overwritten or deleted
+	namespaceInfoLabel = kopyPrefix + "namespace-info"
+	// namespaceInfoReadmeKey and namespaceInfoCopiesKey are the keys of the info ConfigMap
+	namespaceInfoReadmeKey = "README.md"
+	namespaceInfoCopiesKey = "copies.json"
+)
+
+// DefaultNamespaceInfoContact is how tenants request changes to copies unless the publisher is configured otherwise
+const DefaultNamespaceInfoContact = "Ask the owners of the source namespace to change the source, or to stop " +
+	"copying it into this namespace."
+
+// NamespaceInfoCopy is a copy listed in the info ConfigMap of its namespace
+type NamespaceInfoCopy struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Source is the namespace/name of the source of the copy
+	Source string `json:"source"`
+}
+
+var _ manager.Runnable = &NamespaceInfoPublisher{}
+var _ manager.LeaderElectionRunnable = &NamespaceInfoPublisher{}
+
+// NamespaceInfoPublisher periodically writes a kopy-info ConfigMap into every namespace that holds copies. It lists
+// the copies, their sources and how to request changes, so tenants can find out where the objects in their namespace
+// come from without access to the source namespaces. Info ConfigMaps of namespaces without copies are deleted.
+type NamespaceInfoPublisher struct {
+	client.Client
+	// Kinds are the kind names whose copies are listed
+	Kinds []string
+	// Contact tells tenants how to request changes, e.g. a ticket queue or chat channel. DefaultNamespaceInfoContact
+	// is used when empty.
+	Contact  string
+	Interval time.Duration
+}
+
+// Start publishes the info ConfigMaps every Interval until ctx is cancelled
+func (p *NamespaceInfoPublisher) Start(ctx context.Context) error {
+	log := ctrllog.Log.WithName("namespace-info")
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Publish(ctx); err != nil {
+			log.Error(err, "unable to publish namespace info")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns true so only the leader writes the info ConfigMaps
+func (p *NamespaceInfoPublisher) NeedLeaderElection() bool {
+	return true
+}
+
+// Publish writes the info ConfigMap of every namespace with copies and deletes the ones of namespaces without
+func (p *NamespaceInfoPublisher) Publish(ctx context.Context) error {
+	copies, err := p.listCopies(ctx)
+	if err != nil {
+		return err
+	}
+	namespaces := make([]string, 0, len(copies))
+	for namespace := range maps.Keys(copies) {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		if err := p.write(ctx, namespace, copies[namespace]); err != nil {
+			return fmt.Errorf("unable to write info of namespace %s: %w", namespace, err)
+		}
+	}
+
+	published := &corev1.ConfigMapList{}
+	if err := p.List(ctx, published, client.MatchingLabels{namespaceInfoLabel: "true"}); err != nil {
+		return err
+	}
+	for i := range published.Items {
+		cm := &published.Items[i]
+		if _, ok := copies[cm.Namespace]; ok || cm.Name != NamespaceInfoName {
+			continue
+		}
+		if err := p.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to delete info of namespace %s: %w", cm.Namespace, err)
+		}
+	}
+	return nil
+}
+
+// listCopies returns the copies of each namespace ordered by kind and name
+func (p *NamespaceInfoPublisher) listCopies(ctx context.Context) (map[string][]NamespaceInfoCopy, error) {
+	copies := map[string][]NamespaceInfoCopy{}
+	for _, kind := range p.Kinds {
+		list, err := newObjectListForKind(kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.List(ctx, list, client.HasLabels{sourceLabelNamespace}); err != nil {
+			return nil, err
+		}
+		if err := meta.EachListItem(list, func(obj runtime.Object) error {
+			cp, ok := obj.(client.Object)
+			if !ok {
+				return fmt.Errorf("unexpected object %T", obj)
+			}
+			copies[cp.GetNamespace()] = append(copies[cp.GetNamespace()],
+				NamespaceInfoCopy{Kind: kind, Name: cp.GetName(), Source: inventorySource(cp)})
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for _, list := range copies {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Kind != list[j].Kind {
+				return list[i].Kind < list[j].Kind
+			}
+			return list[i].Name < list[j].Name
+		})
+	}
+	return copies, nil
+}
+
+// write creates or updates the info ConfigMap of namespace. A ConfigMap of the same name that kopy didn't create is
+// left alone.
+func (p *NamespaceInfoPublisher) write(ctx context.Context, namespace string, copies []NamespaceInfoCopy) error {
+	data, err := p.namespaceInfo(namespace, copies)
+	if err != nil {
+		return err
+	}
+	cm := &corev1.ConfigMap{}
+	if err := p.Get(ctx, types.NamespacedName{Namespace: namespace, Name: NamespaceInfoName}, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: NamespaceInfoName, Namespace: namespace,
+				Labels: map[string]string{namespaceInfoLabel: "true", managedByLabel: "kopy"},
+			},
+			Data: data,
+		}
+		return client.IgnoreAlreadyExists(p.Create(ctx, cm))
+	}
+	if cm.Labels[namespaceInfoLabel] != "true" || maps.Equal(cm.Data, data) {
+		return nil
+	}
+	cm.Data = data
+	return p.Update(ctx, cm)
+}
+
+// namespaceInfo renders the data of the info ConfigMap of namespace
+func (p *NamespaceInfoPublisher) namespaceInfo(namespace string, copies []NamespaceInfoCopy) (map[string]string, error) {
+	list, err := json.Marshal(copies)
+	if err != nil {
+		return nil, err
+	}
+	contact := p.Contact
+	if contact == "" {
+		contact = DefaultNamespaceInfoContact
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Objects managed by kopy in %s\n\n", namespace)
+	b.WriteString("The objects below are copies that kopy keeps in sync with their source. Changes made to them in ")
+	b.WriteString("this namespace are overwritten by the next sync, change the source instead.\n\n")
+	b.WriteString("| Kind | Name | Source |\n|------|------|--------|\n")
+	for _, cp := range copies {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", cp.Kind, cp.Name, cp.Source)
+	}
+	b.WriteString("\n## Requesting changes\n\n")
+	b.WriteString(contact + "\n\n")
+	b.WriteString("Check whether a copy is current with `kopy origin <kind> " + namespace + "/<name>`.\n")
+	return map[string]string{namespaceInfoReadmeKey: b.String(), namespaceInfoCopiesKey: string(list)}, nil
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Namespace info\n", func() {
+	const (
+		sourceNamespace = "test-src-info-ns-00"
+		target          = "test-dst-info-ns-00"
+		tenant          = "test-dst-info-ns-01"
+	)
+	copyLabels := func(source string) map[string]string {
+		return map[string]string{sourceLabelNamespace: sourceNamespace, sourceLabelName: source}
+	}
+	info := func(c client.Client, namespace string) (*corev1.ConfigMap, error) {
+		cm := &corev1.ConfigMap{}
+		return cm, c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: NamespaceInfoName}, cm)
+	}
+
+	It("Should list the copies of each namespace and remove the list once they are gone", func() {
+		ctx := context.Background()
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-dst-info-00", Namespace: target, Labels: copyLabels("test-src-info-00")}}
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-info-01", Namespace: target, Labels: copyLabels("test-src-info-01")}}
+		own := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: NamespaceInfoName, Namespace: tenant},
+			Data:       map[string]string{"owner": "tenant"},
+		}
+		ownCopy := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-info-01", Namespace: tenant, Labels: copyLabels("test-src-info-01")}}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(secret, configMap, own, ownCopy).Build()
+		publisher := &NamespaceInfoPublisher{Client: c, Kinds: []string{"secret", "configmap"}, Contact: "#platform-help"}
+
+		Expect(publisher.Publish(ctx)).Should(Succeed())
+		cm, err := info(c, target)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(cm.Data[namespaceInfoCopiesKey]).Should(MatchJSON(`[
+			{"kind":"configmap","name":"test-src-info-01","source":"test-src-info-ns-00/test-src-info-01"},
+			{"kind":"secret","name":"test-dst-info-00","source":"test-src-info-ns-00/test-src-info-00"}]`))
+		Expect(cm.Data[namespaceInfoReadmeKey]).Should(And(
+			ContainSubstring("| secret | test-dst-info-00 | test-src-info-ns-00/test-src-info-00 |"),
+			ContainSubstring("#platform-help")))
+
+		By("Leaving a ConfigMap of the same name that kopy didn't write alone")
+		cm, err = info(c, tenant)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(cm.Data).Should(Equal(own.Data))
+
+		By("Deleting the info once the namespace has no copies")
+		Expect(c.Delete(ctx, secret)).Should(Succeed())
+		Expect(c.Delete(ctx, configMap)).Should(Succeed())
+		Expect(c.Delete(ctx, ownCopy)).Should(Succeed())
+		Expect(publisher.Publish(ctx)).Should(Succeed())
+		_, err = info(c, target)
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		_, err = info(c, tenant)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+})
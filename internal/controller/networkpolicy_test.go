@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("NetworkPolicy sync\n", func() {
+	const (
+		namespace = "test-src-networkpolicy-ns-00"
+		target    = "test-dst-networkpolicy-ns-00"
+	)
+	It("Should copy the spec of a network policy", func() {
+		ctx := context.Background()
+		src := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-networkpolicy-00", Namespace: namespace, Annotations: map[string]string{syncKey: "team=payments"}},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		}
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).Build()
+		reconcile := func() {
+			_, err := KopyReconcile(NewKopyNetworkPolicy(ctx, c, Options{}, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		cp := &networkingv1.NetworkPolicy{}
+		copyKey := types.NamespacedName{Namespace: target, Name: src.Name}
+
+		reconcile()
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Labels).Should(HaveKeyWithValue(sourceLabelNamespace, namespace))
+		Expect(cp.Finalizers).Should(ContainElement(syncFinalizer))
+		Expect(cp.Spec).Should(Equal(src.Spec))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		src.Spec.PolicyTypes = append(src.Spec.PolicyTypes, networkingv1.PolicyTypeEgress)
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Spec.PolicyTypes).Should(Equal(src.Spec.PolicyTypes))
+		Expect(cp.Annotations).Should(HaveKeyWithValue(sourceHashKey, dataRevision(src)))
+	})
+})
@@ -0,0 +1,302 @@
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// excludeFromBackupKey is set to "true" on a source to stamp its copies with Options.BackupExclusionLabels
+const excludeFromBackupKey = kopyPrefix + "exclude-from-backup"
+
+// managedByLabel is the recommended Kubernetes label for the tool managing an object
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// Options configures behavior shared by the kopy controllers
+type Options struct {
+	// BackupExclusionLabels are added to copies of sources that opt in with the exclude-from-backup annotation.
+	// Copies are derived data, so excluding them from backups reduces backup size and restore conflicts.
+	BackupExclusionLabels map[string]string
+
+	// CostLabels stamps the copies in a namespace with labels of the namespace, e.g. team or cost-center, so cost
+	// allocation tools attribute the copies to the namespace's owner. It maps the label of the copy to the label of the
+	// namespace it is read from, see ParseCostLabels.
+	CostLabels map[string]string
+
+	// Namespaces restricts kopy to an explicit list of namespaces. When set, namespaces are never listed or watched
+	// so kopy can run with Role-only RBAC in each of the namespaces.
+	Namespaces []string
+
+	// RBACSourceNamespaces are the namespaces Roles and RoleBindings are synced from. Annotated Roles and RoleBindings
+	// in other namespaces are refused, copying them would let anyone who can annotate them grant access in every
+	// selected namespace.
+	RBACSourceNamespaces []string
+
+	// ExcludedNamespaces never receive copies, whether they are selected by a sync selector, are subnamespaces of
+	// selected namespaces or are pinned targets. Copies already in them are pruned.
+	ExcludedNamespaces []string
+
+	// SyncDeadline is how long a selected namespace may lack the copy of a source before a SyncStuck event is
+	// emitted on the source. 0 disables stuck sync detection.
+	SyncDeadline time.Duration
+
+	// LegacyDomains are label domains of previous kopy installations, e.g. kopy.example.com. Copies labeled under
+	// one of them are adopted during reconcile instead of being overwritten or reported as conflicts.
+	LegacyDomains []string
+
+	// QueueShedThreshold is the workqueue depth of the Secret and ConfigMap controllers above which new requests are
+	// retried after QueueShedDelay instead of being queued right away. 0 disables load shedding.
+	QueueShedThreshold int
+
+	// QueueShedDelay is how long shed requests are delayed, with up to 50% jitter
+	QueueShedDelay time.Duration
+
+	// MaxConcurrentReconciles is how many requests the Secret and ConfigMap controllers reconcile in parallel, 0
+	// reconciles one at a time. Reconciles of the same source and its copies are always serialized.
+	MaxConcurrentReconciles int
+
+	// HNC treats the subnamespaces of selected namespaces, as created by the Hierarchical Namespace Controller,
+	// as targets too. The HNC exception annotations on a source limit which subnamespaces receive a copy.
+	HNC bool
+
+	// SigningKey signs the data hash of every copy so consumers can verify a copy was distributed by kopy.
+	// Copies are not signed when nil.
+	SigningKey *ecdsa.PrivateKey
+
+	// TransformWebhooks are called in order before every copy is written and may mutate the copy or skip the target
+	// namespace, see pkg/transform/v1 for the protocol
+	TransformWebhooks []*TransformWebhook
+
+	// DeniedCopyNames are the copies kopy never writes because an object of the same name is managed by the cluster,
+	// given as a name glob optionally prefixed by the kind name, e.g. secret/default-token-*. Nil uses
+	// DefaultDeniedCopyNames, an empty list denies no name.
+	DeniedCopyNames []string
+
+	// CopyNameSuffix is appended to the name of every copy, e.g. "-kopy", so copies never collide with objects of
+	// the same name owned by tenants. Copies are found through their origin labels instead of their name.
+	CopyNameSuffix string
+
+	// PostSyncHooks are the hooks sources can list in the post-sync-hooks annotation to run once all their copies
+	// are current
+	PostSyncHooks []*PostSyncHook
+
+	// EventSink receives a CloudEvent whenever the copies of a source were updated, failed to sync or were removed
+	// with their source. No events are published when nil.
+	EventSink EventSink
+
+	// RefreshInterval requeues every source after the interval so the last sync time of its copies is refreshed even
+	// if the source doesn't change, for workloads that check the freshness of their copies. 0 disables the refresh.
+	RefreshInterval time.Duration
+
+	// SecretMetadataOnly caches only the metadata of Secrets and reads Secrets from the API server when they are
+	// reconciled, so the memory of the controller doesn't grow with the data of Secrets kopy doesn't manage. The
+	// manager has to be created with Options.NewClient.
+	SecretMetadataOnly bool
+
+	// PruneGracePeriod defers the prune of a copy from a namespace that is no longer selected while pods in the
+	// namespace mount it or read it into their environment, for at most the grace period. 0 prunes copies right away.
+	PruneGracePeriod time.Duration
+
+	// CascadeDelete deletes the copies of a deleted source instead of releasing them to their namespaces, unless the
+	// deletion policy annotation of the source says otherwise
+	CascadeDelete bool
+
+	// CascadeGracePeriod keeps the copies of a deleted source with the pending deletion annotation for the grace
+	// period before CascadeDelete deletes them, so consumers can react and a source deleted by accident can be
+	// recreated without losing its copies. 0 deletes the copies right away.
+	CascadeGracePeriod time.Duration
+
+	// CascadeVerify keeps the finalizer of a deleted source until every copy it deleted is gone, so a copy held by
+	// the finalizer of another controller doesn't outlive the source unnoticed. It has no effect with a
+	// CascadeGracePeriod, whose copies are deleted by their own reconcile.
+	CascadeVerify bool
+
+	// TombstoneNamespace keeps a tombstone Secret of every copy CascadeDelete deletes in the namespace, so kopy restore
+	// can recreate the copy until the tombstone expires. Empty keeps no tombstones.
+	TombstoneNamespace string
+
+	// TombstoneRetention is how long the tombstones of deleted copies are kept
+	TombstoneRetention time.Duration
+
+	// CopyConcurrency is how many copies of a source without target groups are written in parallel. Values below 1
+	// write one copy at a time.
+	CopyConcurrency int
+
+	// ConfirmThreshold is the number of existing copies a change to a source may update without confirmation. Larger
+	// changes are held back until the source is annotated with the revision in its pending approval annotation,
+	// which protects against accidental mass rotation. 0 never asks for confirmation.
+	ConfirmThreshold int
+
+	// SourceQuotas enforces the KopySourceQuotas of the namespaces of sources: sources over the limit of their
+	// namespace are annotated as rejected and not synced. It is set when the kopysourcequota controller is enabled.
+	SourceQuotas bool
+
+	// PinnedTargets copy sources to fixed namespaces regardless of namespace labels. Pinned sources are synced even
+	// without the sync annotation.
+	PinnedTargets []PinnedTarget
+
+	// VClusterMode is how namespaces managed by a virtual cluster are treated, objects synced by a virtual cluster are
+	// never treated as sources unless it is VClusterSync
+	VClusterMode VClusterMode
+
+	// NamespaceReader reads target namespaces, usually from the cache of the manager, to find the virtual cluster managing them when copies
+	// are translated
+	NamespaceReader client.Reader
+
+	// PropagationBackend is how copies reach the remote clusters listed in the publish-clusters annotation of their
+	// source, see PropagationKarmada
+	PropagationBackend PropagationBackend
+
+	// RemoteClusters pushes sources to the remote clusters listed in their publish-clusters annotation. Sources are
+	// only synced locally when nil.
+	RemoteClusters *RemoteClusters
+
+	// Reports writes a KopyReport next to every source with the state of its copies in each target namespace
+	Reports bool
+
+	// SelectorLogSampleRate logs how every n-th selector evaluation parsed and matched at debug level, 0 disables it.
+	// Sources with the trace annotation are always logged.
+	SelectorLogSampleRate int
+}
+
+// refresh returns result with a requeue after the refresh interval unless it already requeues sooner
+func (o Options) refresh(result ctrl.Result) ctrl.Result {
+	interval := o.RefreshInterval
+	if memoryDegraded.Load() {
+		interval *= degradedRefreshFactor
+	}
+	if interval > 0 && (result.RequeueAfter == 0 || interval < result.RequeueAfter) {
+		result.RequeueAfter = interval
+	}
+	return result
+}
+
+// NamespaceScoped returns true if kopy is restricted to an explicit list of namespaces
+func (o Options) NamespaceScoped() bool {
+	return len(o.Namespaces) > 0
+}
+
+// syncNamespaces returns the namespaces selected by selector for the source src within its regions and zones, the
+// namespaces it is pinned to and its own namespace if it keeps a local copy
+func (o Options) syncNamespaces(ctx context.Context, c client.Client, src client.Object, selector labels.Selector) ([]corev1.Namespace, error) {
+	trace := o.selectorTraceFor(ctx, src)
+	trace.parsed(src, selector)
+	namespaces, err := o.selectedNamespaces(ctx, c, src, selector)
+	if err != nil {
+		return nil, err
+	}
+	namespaces = withinTopology(src, namespaces)
+	if len(o.PinnedTargets) > 0 {
+		if namespaces, err = o.addPinnedNamespaces(ctx, c, src, namespaces); err != nil {
+			return nil, err
+		}
+	}
+	if namespaces, err = o.addLocalNamespace(ctx, c, src, namespaces); err != nil {
+		return nil, err
+	}
+	namespaces = o.withoutVClusterNamespaces(namespaces)
+	trace.matched(ctx, c, o, src, selector, namespaces)
+	return namespaces, nil
+}
+
+// selectedNamespaces returns the namespaces selected by selector for the source src
+func (o Options) selectedNamespaces(ctx context.Context, c client.Client, src client.Object, selector labels.Selector) ([]corev1.Namespace, error) {
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}
+	if o.NamespaceScoped() {
+		return getScopedSyncNamespaces(ctx, c, req, selector, o.Namespaces, o.ExcludedNamespaces)
+	}
+	namespaces, err := getSyncNamespaces(ctx, c, req, selector, o.ExcludedNamespaces)
+	if err != nil || !o.HNC {
+		return namespaces, err
+	}
+	subnamespaces, err := hncSubnamespaces(ctx, c, src, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	return append(namespaces, filterSyncNamespaces(subnamespaces, src.GetNamespace(), labels.Everything(), o.ExcludedNamespaces)...), nil
+}
+
+// sourcesSelecting returns reconcile requests for the sources of the kind of list that should be copied to namespace
+// or already have copies in it
+func (o Options) sourcesSelecting(ctx context.Context, c client.Client, list client.ObjectList, namespace client.Object) []reconcile.Request {
+	req := sourcesSelecting(ctx, c, list, namespace)
+	if o.HNC {
+		req = append(req, hncSourcesSelecting(ctx, c, list, namespace)...)
+	}
+	req = append(req, o.pinnedSourcesFor(list, namespace)...)
+	// the sources of copies already in the namespace prune them if the namespace opted out
+	for _, r := range sourcesOfCopiesIn(ctx, c, list, namespace) {
+		if !slices.Contains(req, r) {
+			req = append(req, r)
+		}
+	}
+	return req
+}
+
+// copyLabels returns the labels that should be set on a copy of src
+func (o Options) copyLabels(src map[string]string, sourceNamespace, sourceName string) map[string]string {
+	labels := map[string]string{
+		sourceLabelNamespace: sourceNamespace,
+		sourceLabelName:      sourceName,
+	}
+	// copies with a suffixed name are labeled so consumers can find them without knowing the suffix
+	if o.CopyNameSuffix != "" {
+		labels[managedByLabel] = "kopy"
+	}
+	if exclude, _ := strconv.ParseBool(src[excludeFromBackupKey]); exclude {
+		for k, v := range o.BackupExclusionLabels {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// copyName returns the name of the copies of the source named sourceName
+func (o Options) copyName(sourceName string) string {
+	return sourceName + o.CopyNameSuffix
+}
+
+// copyOrigin returns the source namespace recorded on cp under the current domain or one of the legacy domains
+func (o Options) copyOrigin(cp client.Object) (origin string, legacy bool, ok bool) {
+	if origin, ok := cp.GetLabels()[sourceLabelNamespace]; ok {
+		return origin, false, true
+	}
+	for _, domain := range o.LegacyDomains {
+		if origin, ok := cp.GetLabels()[originLabel(domain)]; ok {
+			return origin, true, true
+		}
+	}
+	return "", false, false
+}
+
+// originLabel returns the label that records the source namespace of a copy under domain
+func originLabel(domain string) string {
+	return strings.TrimSuffix(domain, "/") + "/" + strings.TrimPrefix(sourceLabelNamespace, kopyPrefix)
+}
+
+// CountDomainCopies returns the number of objects of the enabled kinds labeled as copies under domain, e.g. by a
+// previous kopy installation that has to be listed in Options.LegacyDomains
+func CountDomainCopies(ctx context.Context, c client.Client, domain string) (int, error) {
+	count := 0
+	for _, kind := range enabledKinds() {
+		list, err := newObjectListForKind(kind)
+		if err != nil {
+			return 0, err
+		}
+		if err := c.List(ctx, list, client.HasLabels{originLabel(domain)}); err != nil {
+			return 0, err
+		}
+		count += meta.LenList(list)
+	}
+	return count, nil
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Origin describes the source object that a copy was synced from
+type Origin struct {
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	SourceName      string `json:"sourceName"`
+	SourceNamespace string `json:"sourceNamespace"`
+	SourceExists    bool   `json:"sourceExists"`
+	Current         bool   `json:"current"`
+}
+
+// NewObjectForKind returns an empty object for the supported kind names (secret, configmap, serviceaccount,
+// resourcequota, limitrange, networkpolicy, poddisruptionbudget, role, rolebinding, their plural and short names and
+// the kinds registered with RegisterSyncKind)
+func NewObjectForKind(kind string) (client.Object, error) {
+	k, ok := kindNamed(kind)
+	if !ok {
+		return nil, invalidRequest("unsupported kind %q", kind)
+	}
+	return k.newSource(), nil
+}
+
+// newObjectListForKind returns an empty list for the supported kind names
+func newObjectListForKind(kind string) (client.ObjectList, error) {
+	k, ok := kindNamed(kind)
+	if !ok {
+		return nil, invalidRequest("unsupported kind %q", kind)
+	}
+	return k.newSourceList(), nil
+}
+
+// LookupOrigin resolves the copy identified by key back to its source object using the origin labels on the copy.
+// Copies created before the origin name label was introduced fall back to the name of the copy.
+func LookupOrigin(ctx context.Context, c client.Client, kind string, key types.NamespacedName) (*Origin, error) {
+	cp, err := NewObjectForKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Get(ctx, key, cp); err != nil {
+		return nil, err
+	}
+	sourceNamespace, ok := cp.GetLabels()[sourceLabelNamespace]
+	if !ok {
+		return nil, failedPrecondition("%s %s is not a kopy copy", strings.ToLower(kind), key)
+	}
+	sourceName, ok := cp.GetLabels()[sourceLabelName]
+	if !ok {
+		sourceName = cp.GetName()
+	}
+	origin := &Origin{
+		Kind:            strings.ToLower(kind),
+		Name:            key.Name,
+		Namespace:       key.Namespace,
+		SourceName:      sourceName,
+		SourceNamespace: sourceNamespace,
+	}
+	src, _ := NewObjectForKind(kind)
+	if err := c.Get(ctx, types.NamespacedName{Namespace: sourceNamespace, Name: sourceName}, src); err != nil {
+		if apierrors.IsNotFound(err) {
+			return origin, nil
+		}
+		return nil, err
+	}
+	origin.SourceExists = true
+	origin.Current = copyIsCurrent(src, cp)
+	return origin, nil
+}
+
+// copyIsCurrent returns true if the payload of the copy matches the payload of the source
+func copyIsCurrent(src, cp client.Object) bool {
+	k, ok := kindForObject(src)
+	return ok && k.current(src, cp)
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// DefaultOrphanGCInterval is how often copies are checked for a source that no longer exists
+const DefaultOrphanGCInterval = 10 * time.Minute
+
+var _ manager.Runnable = &OrphanCollector{}
+var _ manager.LeaderElectionRunnable = &OrphanCollector{}
+
+// OrphanCollector periodically releases the copies whose source no longer exists, usually because it was deleted
+// while kopy wasn't running. Their finalizer is removed so they can be deleted, and with Delete they are deleted too.
+// Without the collector the finalizer of such a copy blocks deleting it and its namespace for good.
+type OrphanCollector struct {
+	client.Client
+	// Reader confirms a source is gone without the lag of the cache, e.g. the API reader of the manager. The client
+	// is used when nil.
+	Reader client.Reader
+	// Kinds are the kind names whose copies are collected
+	Kinds []string
+	// Delete deletes orphaned copies instead of only removing their finalizer
+	Delete bool
+	// MinAge is how old a copy must be to be collected, so a copy whose source isn't in the cache yet isn't taken for
+	// an orphan
+	MinAge   time.Duration
+	Interval time.Duration
+}
+
+// Start collects orphaned copies every Interval until ctx is cancelled
+func (o *OrphanCollector) Start(ctx context.Context) error {
+	log := ctrllog.Log.WithName("orphans")
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+	for {
+		collected, err := o.Collect(ctx)
+		if err != nil {
+			log.Error(err, "unable to collect orphaned copies")
+		}
+		if collected > 0 {
+			log.Info("collected orphaned copies", "copies", collected, "deleted", o.Delete)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns true so only the leader collects orphaned copies
+func (o *OrphanCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Collect releases or deletes the copies whose source no longer exists and returns how many were collected
+func (o *OrphanCollector) Collect(ctx context.Context) (int, error) {
+	reader := o.Reader
+	if reader == nil {
+		reader = o.Client
+	}
+	collected := 0
+	for _, kind := range o.Kinds {
+		list, err := newObjectListForKind(kind)
+		if err != nil {
+			return collected, err
+		}
+		if err := o.List(ctx, list, client.HasLabels{sourceLabelNamespace}); err != nil {
+			return collected, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return collected, err
+		}
+		// exists caches whether the source of the copies exists, so every source is read once per pass
+		exists := map[types.NamespacedName]bool{}
+		for _, item := range items {
+			cp, ok := item.(client.Object)
+			if !ok || now().Sub(cp.GetCreationTimestamp().Time) < o.MinAge {
+				continue
+			}
+			// released copies are only collected again to be deleted
+			if !o.Delete && !hasSyncFinalizer(cp) {
+				continue
+			}
+			key := types.NamespacedName{Namespace: cp.GetLabels()[sourceLabelNamespace], Name: sourceNameOf(cp)}
+			found, seen := exists[key]
+			if !seen {
+				src, _ := NewObjectForKind(kind)
+				err := reader.Get(ctx, key, src)
+				if client.IgnoreNotFound(err) != nil {
+					return collected, err
+				}
+				found = !apierrors.IsNotFound(err)
+				exists[key] = found
+			}
+			if found {
+				continue
+			}
+			if err := o.collect(ctx, cp); err != nil {
+				return collected, err
+			}
+			orphansCollected.WithLabelValues(kind).Inc()
+			collected++
+		}
+	}
+	return collected, nil
+}
+
+// collect removes the finalizer of the orphaned copy cp and deletes it if configured to
+func (o *OrphanCollector) collect(ctx context.Context, cp client.Object) error {
+	if removeSyncFinalizer(cp) {
+		if err := o.Update(ctx, cp); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+	}
+	if !o.Delete || cp.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	return client.IgnoreNotFound(o.Client.Delete(ctx, cp))
+}
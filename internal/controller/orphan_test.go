@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Orphaned copies\n", func() {
+	const (
+		sourceNamespace = "test-src-orphan-ns-00"
+		target          = "test-dst-orphan-ns-00"
+	)
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	copyOf := func(source, name string, age time.Duration) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: target, Finalizers: []string{syncFinalizer},
+			CreationTimestamp: metav1.NewTime(created.Add(-age)),
+			Labels:            map[string]string{sourceLabelNamespace: sourceNamespace, sourceLabelName: source},
+		}}
+	}
+	BeforeEach(func() { now = func() time.Time { return created } })
+	AfterEach(func() { now = time.Now })
+
+	It("Should release copies whose source no longer exists", func() {
+		ctx := context.Background()
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-src-orphan-00", Namespace: sourceNamespace}}
+		current := copyOf(src.Name, "test-src-orphan-00", time.Hour)
+		orphan := copyOf("test-src-orphan-01", "test-src-orphan-01", time.Hour)
+		young := copyOf("test-src-orphan-02", "test-src-orphan-02", time.Second)
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, current, orphan, young).Build()
+		collector := &OrphanCollector{Client: c, Kinds: []string{"secret"}, MinAge: time.Minute}
+
+		collected, err := collector.Collect(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(collected).Should(Equal(1))
+		for cp, finalizers := range map[*corev1.Secret][]string{current: {syncFinalizer}, orphan: nil, young: {syncFinalizer}} {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(cp), cp)).Should(Succeed())
+			Expect(cp.Finalizers).Should(Equal(finalizers))
+		}
+
+		By("Leaving released copies alone in the next pass")
+		Expect(collector.Collect(ctx)).Should(Equal(0))
+	})
+
+	It("Should delete orphaned copies if configured to", func() {
+		ctx := context.Background()
+		orphan := copyOf("test-src-orphan-03", "test-dst-orphan-03", time.Hour)
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(orphan).Build()
+		collector := &OrphanCollector{Client: c, Kinds: []string{"secret"}, Delete: true, MinAge: time.Minute}
+
+		Expect(collector.Collect(ctx)).Should(Equal(1))
+		err := c.Get(ctx, client.ObjectKeyFromObject(orphan), orphan)
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+	})
+})
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// permission is an API permission a controller needs
+type permission struct {
+	group       string
+	resource    string
+	subresource string
+	verbs       []string
+	// clusterScoped resources are checked cluster wide instead of in each namespace kopy is restricted to
+	clusterScoped bool
+	// unscopedOnly permissions are only needed when kopy isn't restricted to a list of namespaces
+	unscopedOnly bool
+}
+
+var (
+	// copies are written with a server-side apply
+	copyVerbs   = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+	writeVerbs  = []string{"get", "list", "watch", "create", "update", "delete"}
+	readVerbs   = []string{"get", "list", "watch"}
+	statusVerbs = []string{"get", "list", "watch", "update"}
+)
+
+// controllerPermissions are the permissions each controller can't run without besides the controllers of the kinds
+// kopy syncs, see permissionsOf
+var controllerPermissions = map[string][]permission{
+	"kopysubscription": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopysubscriptions", verbs: statusVerbs},
+		{resource: "secrets", verbs: copyVerbs},
+		{resource: "configmaps", verbs: copyVerbs},
+	},
+	"kopypublication": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopypublications", verbs: statusVerbs},
+		{resource: "secrets", verbs: readVerbs},
+		{resource: "configmaps", verbs: readVerbs},
+	},
+	"kopysync": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopysyncs", verbs: statusVerbs},
+		{resource: "secrets", verbs: readVerbs},
+		{resource: "configmaps", verbs: readVerbs},
+	},
+	"kopytoken": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopytokens", verbs: statusVerbs},
+		{resource: "secrets", verbs: copyVerbs},
+		{resource: "namespaces", verbs: readVerbs, clusterScoped: true},
+		{resource: "serviceaccounts", verbs: readVerbs},
+		{resource: "serviceaccounts", subresource: "token", verbs: []string{"create"}},
+	},
+	"kopysourcequota": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopysourcequotas", verbs: readVerbs},
+		{resource: "secrets", verbs: readVerbs},
+		{resource: "configmaps", verbs: readVerbs},
+	},
+}
+
+// MissingPermissions returns the permissions the controller named name lacks, e.g. "list secrets". When namespaces
+// is set the namespaced permissions are checked in each of them and cluster wide permissions are skipped.
+func MissingPermissions(ctx context.Context, c client.Client, name string, namespaces []string) ([]string, error) {
+	permissions, ok := permissionsOf(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown controller %q", name)
+	}
+	scopes := namespaces
+	if len(scopes) == 0 {
+		scopes = []string{""}
+	}
+	missing := []string{}
+	for _, p := range permissions {
+		if p.unscopedOnly && len(namespaces) > 0 {
+			continue
+		}
+		scopes := scopes
+		if p.clusterScoped {
+			scopes = []string{""}
+		}
+		for _, verb := range p.verbs {
+			for _, namespace := range scopes {
+				allowed, err := allowed(ctx, c, authorizationv1.ResourceAttributes{
+					Namespace: namespace, Verb: verb, Group: p.group, Resource: p.resource, Subresource: p.subresource,
+				})
+				if err != nil {
+					return nil, err
+				}
+				if !allowed {
+					missing = append(missing, describePermission(verb, p, namespace))
+				}
+			}
+		}
+	}
+	return missing, nil
+}
+
+// permissionsOf returns the permissions the controller named name can't run without. The controller of a built-in
+// kind needs to write the objects of its kind and to read namespaces.
+func permissionsOf(name string) ([]permission, bool) {
+	if k, ok := builtinKind(name); ok {
+		group, resource := k.apiResource()
+		return []permission{
+			{group: group, resource: resource, verbs: copyVerbs},
+			{resource: "namespaces", verbs: readVerbs, clusterScoped: true, unscopedOnly: true},
+		}, true
+	}
+	permissions, ok := controllerPermissions[name]
+	return permissions, ok
+}
+
+// allowed asks the API server whether kopy may perform the request described by attrs
+func allowed(ctx context.Context, c client.Client, attrs authorizationv1.ResourceAttributes) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attrs},
+	}
+	if err := c.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+func describePermission(verb string, p permission, namespace string) string {
+	resource := p.resource
+	if p.subresource != "" {
+		resource += "/" + p.subresource
+	}
+	if namespace == "" {
+		return verb + " " + resource
+	}
+	return fmt.Sprintf("%s %s in namespace %s", verb, resource, namespace)
+}
+
+// disable records that the controller named name isn't running because it lacks the missing permissions
+func (s *controllerState) disable(name string, missing []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disabled[name] = missing
+}
+
+// DisableController records that the controller named name was not started because it lacks the missing
+// permissions, so the debug state explains why the controller isn't running
+func DisableController(name string, missing []string) {
+	debugState.disable(name, missing)
+	controllerDisabled.WithLabelValues(name).Set(1)
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Missing permissions\n", func() {
+	// newReviewClient returns a client that answers access reviews with allow
+	newReviewClient := func(allow func(attrs *authorizationv1.ResourceAttributes) bool) client.Client {
+		return fake.NewClientBuilder().
+			WithScheme(clientgoscheme.Scheme).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.CreateOption) error {
+					review := o.(*authorizationv1.SelfSubjectAccessReview)
+					review.Status.Allowed = allow(review.Spec.ResourceAttributes)
+					return nil
+				},
+			}).
+			Build()
+	}
+	It("Should report the permissions a controller lacks", func() {
+		c := newReviewClient(func(attrs *authorizationv1.ResourceAttributes) bool {
+			return attrs.Resource != "secrets" || attrs.Verb == "get"
+		})
+		missing, err := MissingPermissions(context.Background(), c, "secret", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(missing).Should(Equal([]string{
+			"list secrets", "watch secrets", "create secrets", "update secrets", "patch secrets", "delete secrets",
+		}))
+		missing, err = MissingPermissions(context.Background(), c, "configmap", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(missing).Should(BeEmpty())
+	})
+	It("Should check namespaced permissions in each namespace when namespace scoped", func() {
+		c := newReviewClient(func(attrs *authorizationv1.ResourceAttributes) bool {
+			return attrs.Namespace == "team-a"
+		})
+		missing, err := MissingPermissions(context.Background(), c, "configmap", []string{"team-a", "team-b"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(missing).Should(ContainElement("list configmaps in namespace team-b"))
+		Expect(missing).ShouldNot(ContainElement(ContainSubstring("namespaces")))
+		Expect(missing).ShouldNot(ContainElement(ContainSubstring("team-a")))
+	})
+})
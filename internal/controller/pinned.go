@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+)
+
+// PinnedTarget copies a source to a fixed list of namespaces regardless of their labels, so core integrations such
+// as an ingress controller keep their copy when namespace labels are removed
+type PinnedTarget struct {
+	// Kind is secret or configmap
+	Kind string `json:"kind"`
+	// Source is the namespace/name of the source
+	Source string `json:"source"`
+	// Namespaces always receive a copy of the source while they exist
+	Namespaces []string `json:"namespaces"`
+}
+
+// LoadPinnedTargets reads the pinned targets from a YAML file with a top level "rules" list
+func LoadPinnedTargets(path string) ([]PinnedTarget, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := struct {
+		Rules []PinnedTarget `json:"rules"`
+	}{}
+	if err := yaml.UnmarshalStrict(b, &config); err != nil {
+		return nil, fmt.Errorf("unable to parse pinned targets %s: %w", path, err)
+	}
+	for _, rule := range config.Rules {
+		if _, err := NewObjectForKind(rule.Kind); err != nil {
+			return nil, fmt.Errorf("pinned target for %s: %w", rule.Source, err)
+		}
+		if namespace, name, ok := strings.Cut(rule.Source, "/"); !ok || namespace == "" || name == "" {
+			return nil, fmt.Errorf("pinned target source %q must be namespace/name", rule.Source)
+		}
+		if len(rule.Namespaces) == 0 {
+			return nil, fmt.Errorf("pinned target for %s has no namespaces", rule.Source)
+		}
+	}
+	return config.Rules, nil
+}
+
+// isPinned returns true if src has pinned target namespaces. Pinned sources are synced even without the sync
+// annotation.
+func (o Options) isPinned(src client.Object) bool {
+	return len(o.pinnedNamespaces(src)) > 0
+}
+
+// pinnedNamespaces returns the namespaces src is pinned to
+func (o Options) pinnedNamespaces(src client.Object) []string {
+	kind, key := kindOf(src), client.ObjectKeyFromObject(src).String()
+	namespaces := []string{}
+	for _, rule := range o.PinnedTargets {
+		if rule.Kind == kind && rule.Source == key {
+			namespaces = append(namespaces, rule.Namespaces...)
+		}
+	}
+	return namespaces
+}
+
+// addPinnedNamespaces adds the namespaces src is pinned to that exist and aren't in namespaces yet
+func (o Options) addPinnedNamespaces(ctx context.Context, c client.Client, src client.Object, namespaces []corev1.Namespace) ([]corev1.Namespace, error) {
+	selected := namespaceNames(namespaces)
+	for _, name := range o.pinnedNamespaces(src) {
+		if name == src.GetNamespace() || selected.Has(name) || slices.Contains(o.ExcludedNamespaces, name) {
+			continue
+		}
+		if o.NamespaceScoped() && !slices.Contains(o.Namespaces, name) {
+			continue
+		}
+		ns := corev1.Namespace{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, &ns); err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				continue
+			}
+			return nil, fmt.Errorf("unable to get pinned namespace %s: %w", name, err)
+		}
+		if ns.DeletionTimestamp == nil {
+			selected.Insert(name)
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces, nil
+}
+
+// pinnedSourcesFor returns reconcile requests for the sources of the kind of list that are pinned to namespace
+func (o Options) pinnedSourcesFor(list client.ObjectList, namespace client.Object) []reconcile.Request {
+	kind := kindOfList(list)
+	req := []reconcile.Request{}
+	for _, rule := range o.PinnedTargets {
+		if rule.Kind != kind || !slices.Contains(rule.Namespaces, namespace.GetName()) {
+			continue
+		}
+		sourceNamespace, name, _ := strings.Cut(rule.Source, "/")
+		req = append(req, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: sourceNamespace, Name: name}})
+	}
+	return req
+}
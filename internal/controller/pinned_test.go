@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Pinned targets\n", func() {
+	opts := Options{PinnedTargets: []PinnedTarget{{
+		Kind:       "secret",
+		Source:     "test-src-pinned-ns-00/test-src-pinned-00",
+		Namespaces: []string{"test-dst-pinned-ns-00", "test-dst-pinned-ns-01", "test-dst-pinned-ns-02"},
+	}}}
+	src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-src-pinned-00", Namespace: "test-src-pinned-ns-00"}}
+	It("Should load the sample rules", func() {
+		rules, err := LoadPinnedTargets(filepath.Join("..", "..", "config", "samples", "pinned", "targets.yaml"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rules).Should(HaveLen(2))
+	})
+	It("Should reject rules without a namespace/name source", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "targets.yaml")
+		Expect(os.WriteFile(path, []byte("rules:\n- kind: secret\n  source: wildcard-tls\n  namespaces: [a]\n"), 0o600)).Should(Succeed())
+		_, err := LoadPinnedTargets(path)
+		Expect(err).Should(MatchError(ContainSubstring("namespace/name")))
+	})
+	It("Should copy pinned sources to their namespaces regardless of labels", func() {
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-dst-pinned-ns-00"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-dst-pinned-ns-01", Labels: map[string]string{"env": "prod"}}},
+		).Build()
+		ks := NewKopySecret(context.Background(), c, opts, nil)
+		ks.Object = src
+		Expect(ks.SyncOptions()).Should(BeTrue())
+		namespaces, err := opts.syncNamespaces(context.Background(), c, src, labels.SelectorFromSet(labels.Set{"env": "prod"}))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(namespaceNames(namespaces).UnsortedList()).Should(ConsistOf("test-dst-pinned-ns-00", "test-dst-pinned-ns-01"))
+
+		namespaces, err = Options{}.syncNamespaces(context.Background(), c, src, ks.LabelSelector())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(namespaces).Should(BeEmpty())
+	})
+	It("Should reconcile pinned sources when their namespace changes", func() {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-dst-pinned-ns-02"}}
+		Expect(opts.pinnedSourcesFor(&corev1.SecretList{}, ns)).Should(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: "test-src-pinned-ns-00", Name: "test-src-pinned-00"}},
+		}))
+		Expect(opts.pinnedSourcesFor(&corev1.ConfigMapList{}, ns)).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("PodDisruptionBudget sync\n", func() {
+	const (
+		namespace = "test-src-pdb-ns-00"
+		target    = "test-dst-pdb-ns-00"
+	)
+	It("Should copy the spec of a pod disruption budget without its status", func() {
+		ctx := context.Background()
+		minAvailable := intstr.FromInt32(1)
+		src := &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-pdb-00", Namespace: namespace, Annotations: map[string]string{syncKey: "team=payments"}},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "ingress-gateway"}},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{CurrentHealthy: 3, DesiredHealthy: 1, ExpectedPods: 3},
+		}
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).Build()
+		reconcile := func() {
+			_, err := KopyReconcile(NewKopyPodDisruptionBudget(ctx, c, Options{}, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		cp := &policyv1.PodDisruptionBudget{}
+		copyKey := types.NamespacedName{Namespace: target, Name: src.Name}
+
+		reconcile()
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Labels).Should(HaveKeyWithValue(sourceLabelNamespace, namespace))
+		Expect(cp.Finalizers).Should(ContainElement(syncFinalizer))
+		Expect(cp.Spec).Should(Equal(src.Spec))
+		Expect(cp.Status.ExpectedPods).Should(BeZero())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		maxUnavailable := intstr.FromString("25%")
+		src.Spec.MinAvailable, src.Spec.MaxUnavailable = nil, &maxUnavailable
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Spec.MinAvailable).Should(BeNil())
+		Expect(cp.Spec.MaxUnavailable).Should(Equal(&maxUnavailable))
+		Expect(cp.Annotations).Should(HaveKeyWithValue(sourceHashKey, dataRevision(src)))
+	})
+})
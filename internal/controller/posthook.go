@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// postSyncHooksKey is set on a source to the comma separated names of the post-sync hooks that run once every
+	// copy of the source carries its current data
+	postSyncHooksKey = kopyPrefix + "post-sync-hooks"
+	// postSyncRevisionKey is set by kopy on a source to the data revision its post-sync hooks last ran for, and on the
+	// pod template of the Argo Rollouts patched by a hook to the source and revision that triggered the rollout
+	postSyncRevisionKey = kopyPrefix + "post-sync-revision"
+	// reasonPostSyncHooks is used for events on sources whose post-sync hooks ran
+	reasonPostSyncHooks = "PostSyncHooks"
+	// reasonPostSyncHookFailed is used for events on sources whose post-sync hooks failed or don't exist
+	reasonPostSyncHookFailed = "PostSyncHookFailed"
+)
+
+// argoRollout is the kind of the objects patched by rollout hooks
+var argoRollout = schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}
+
+// PostSyncHook runs once every copy of a source that lists it in the post-sync-hooks annotation carries the current
+// data of the source, e.g. to start the progressive delivery of the workload consuming the copies. Exactly one of
+// Rollout and Webhook is set.
+type PostSyncHook struct {
+	// Name is what sources list in the post-sync-hooks annotation
+	Name string `json:"name"`
+	// Rollout is an Argo Rollout whose pod template is annotated with the source and its revision, which starts a new
+	// rollout including its analysis
+	Rollout *PostSyncRollout `json:"rollout,omitempty"`
+	// Webhook receives a com.kot-labs.kopy.sync.converged CloudEvent
+	Webhook *PostSyncWebhook `json:"webhook,omitempty"`
+
+	sink EventSink
+}
+
+// PostSyncRollout identifies the Argo Rollout patched by a hook
+type PostSyncRollout struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// PostSyncWebhook is the endpoint a hook sends its event to
+type PostSyncWebhook struct {
+	// URL is an http, https or nats URL like the one of --event-sink, e.g. the webhook of a feature flag system
+	URL string `json:"url"`
+}
+
+// LoadPostSyncHooks reads the hooks from a YAML file with a top level "hooks" list
+func LoadPostSyncHooks(path string) ([]*PostSyncHook, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := struct {
+		Hooks []*PostSyncHook `json:"hooks"`
+	}{}
+	if err := yaml.UnmarshalStrict(b, &config); err != nil {
+		return nil, fmt.Errorf("unable to parse post-sync hooks %s: %w", path, err)
+	}
+	names := map[string]bool{}
+	for _, h := range config.Hooks {
+		if h.Name == "" || names[h.Name] {
+			return nil, fmt.Errorf("post-sync hooks need a unique name, got %q", h.Name)
+		}
+		names[h.Name] = true
+		switch {
+		case (h.Rollout == nil) == (h.Webhook == nil):
+			return nil, fmt.Errorf("post-sync hook %s needs exactly one of rollout and webhook", h.Name)
+		case h.Rollout != nil && (h.Rollout.Namespace == "" || h.Rollout.Name == ""):
+			return nil, fmt.Errorf("post-sync hook %s: the rollout needs a namespace and a name", h.Name)
+		case h.Webhook != nil:
+			if h.sink, err = NewEventSink(h.Webhook.URL); err != nil {
+				return nil, fmt.Errorf("post-sync hook %s: %w", h.Name, err)
+			}
+		}
+	}
+	return config.Hooks, nil
+}
+
+// run runs the hook for the data revision of src whose copies are in targets
+func (h *PostSyncHook) run(ctx context.Context, c client.Client, src client.Object, revision string, targets []string) error {
+	if h.Webhook != nil {
+		ctx, cancel := context.WithTimeout(ctx, eventPublishTimeout)
+		defer cancel()
+		return h.sink.Publish(ctx, newCloudEvent(EventSyncConverged, src, SyncEventData{Targets: targets}))
+	}
+	value := fmt.Sprintf("%s/%s/%s@%s", kindOf(src), src.GetNamespace(), src.GetName(), revision)
+	patch, err := json.Marshal(map[string]any{"spec": map[string]any{"template": map[string]any{
+		"metadata": map[string]any{"annotations": map[string]string{postSyncRevisionKey: value}},
+	}}})
+	if err != nil {
+		return err
+	}
+	rollout := &unstructured.Unstructured{}
+	rollout.SetGroupVersionKind(argoRollout)
+	rollout.SetNamespace(h.Rollout.Namespace)
+	rollout.SetName(h.Rollout.Name)
+	return c.Patch(ctx, rollout, client.RawPatch(types.MergePatchType, patch))
+}
+
+// postSyncHookNames returns the names of the post-sync hooks listed on src
+func postSyncHookNames(src client.Object) []string {
+	names := []string{}
+	for _, name := range strings.Split(src.GetAnnotations()[postSyncHooksKey], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// runPostSyncHooks runs the post-sync hooks listed on src once its copies in namespaces all carry the current data
+// revision of src. The revision is recorded on src so the hooks run once per revision; when a hook fails the hooks
+// run again with the next reconcile, so hooks must tolerate running more than once for a revision.
+func (o Options) runPostSyncHooks(ctx context.Context, c client.Client, recorder record.EventRecorder, src client.Object, namespaces []corev1.Namespace) error {
+	names := postSyncHookNames(src)
+	revision := dataRevision(src)
+	if len(names) == 0 || src.GetAnnotations()[postSyncRevisionKey] == revision {
+		return nil
+	}
+	targets := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		cp, err := NewObjectForKind(kindOf(src))
+		if err != nil {
+			return err
+		}
+		err = c.Get(ctx, types.NamespacedName{Namespace: ns.Name, Name: o.copyNameFor(src, ns.Name)}, cp)
+		if apierrors.IsNotFound(err) || (err == nil && cp.GetAnnotations()[sourceHashKey] != revision) {
+			// not converged yet, the hooks run with the reconcile that writes the last copy
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		targets = append(targets, ns.Name)
+	}
+	slices.Sort(targets)
+	for _, name := range names {
+		i := slices.IndexFunc(o.PostSyncHooks, func(h *PostSyncHook) bool { return h.Name == name })
+		if i < 0 {
+			if recorder != nil {
+				recorder.Eventf(src, corev1.EventTypeWarning, reasonPostSyncHookFailed, "Post-sync hook %s isn't configured", name)
+			}
+			continue
+		}
+		if err := o.PostSyncHooks[i].run(ctx, c, src, revision, targets); err != nil {
+			if recorder != nil {
+				recorder.Eventf(src, corev1.EventTypeWarning, reasonPostSyncHookFailed, "Post-sync hook %s failed: %v", name, err)
+			}
+			return fmt.Errorf("post-sync hook %s: %w", name, err)
+		}
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	annotations := src.GetAnnotations()
+	annotations[postSyncRevisionKey] = revision
+	src.SetAnnotations(annotations)
+	if err := c.Patch(ctx, src, patch); err != nil {
+		return err
+	}
+	if recorder != nil {
+		recorder.Eventf(src, corev1.EventTypeNormal, reasonPostSyncHooks, "Ran post-sync hooks %s for revision %s",
+			strings.Join(names, ", "), revision)
+	}
+	return nil
+}
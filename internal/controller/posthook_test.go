@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Post-sync hooks\n", func() {
+	const target = "test-dst-posthook-ns-00"
+	newSource := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-posthook-00", Namespace: "test-src-posthook-ns-00",
+				Annotations: map[string]string{syncKey: "env=prod", postSyncHooksKey: "rollout, flags"},
+			},
+			Data: map[string][]byte{"password": []byte("test-src-posthook-00")},
+		}
+	}
+	newClient := func(objs ...client.Object) client.Client {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(argoRollout, meta.RESTScopeNamespace)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+		rollout := &unstructured.Unstructured{}
+		rollout.SetGroupVersionKind(argoRollout)
+		rollout.SetNamespace("test-rollout-ns-00")
+		rollout.SetName("test-rollout-00")
+		return fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithRESTMapper(mapper).
+			WithObjects(append(objs, rollout)...).Build()
+	}
+
+	It("Should load the sample hooks", func() {
+		hooks, err := LoadPostSyncHooks(filepath.Join("..", "..", "config", "samples", "posthooks", "hooks.yaml"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(hooks).Should(HaveLen(2))
+	})
+	It("Should reject hooks without exactly one action", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "hooks.yaml")
+		Expect(os.WriteFile(path, []byte("hooks:\n- name: nothing\n"), 0o600)).Should(Succeed())
+		_, err := LoadPostSyncHooks(path)
+		Expect(err).Should(MatchError(ContainSubstring("exactly one of rollout and webhook")))
+	})
+
+	It("Should run the hooks once all copies are current", func() {
+		events := make(chan CloudEvent, 2)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			event := CloudEvent{}
+			if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+				events <- event
+			}
+		}))
+		defer server.Close()
+		sink, err := NewEventSink(server.URL)
+		Expect(err).ShouldNot(HaveOccurred())
+		opts := Options{PostSyncHooks: []*PostSyncHook{
+			{Name: "rollout", Rollout: &PostSyncRollout{Namespace: "test-rollout-ns-00", Name: "test-rollout-00"}},
+			{Name: "flags", Webhook: &PostSyncWebhook{URL: server.URL}, sink: sink},
+		}}
+		src := newSource()
+		cp, err := newCopy(src, target, opts)
+		Expect(err).ShouldNot(HaveOccurred())
+		cp.SetAnnotations(map[string]string{sourceHashKey: "outdated"})
+		c := newClient(src, cp)
+		namespaces := []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: target}}}
+		recorder := record.NewFakeRecorder(2)
+
+		Expect(opts.runPostSyncHooks(context.Background(), c, recorder, src, namespaces)).Should(Succeed())
+		Expect(events).ShouldNot(Receive())
+
+		cp.SetAnnotations(map[string]string{sourceHashKey: dataRevision(src)})
+		Expect(c.Update(context.Background(), cp)).Should(Succeed())
+		Expect(opts.runPostSyncHooks(context.Background(), c, recorder, src, namespaces)).Should(Succeed())
+		Expect(events).Should(Receive(And(
+			HaveField("Type", EventSyncConverged),
+			HaveField("Data.Targets", ConsistOf(target)),
+		)))
+		rollout := &unstructured.Unstructured{}
+		rollout.SetGroupVersionKind(argoRollout)
+		Expect(c.Get(context.Background(), client.ObjectKey{Namespace: "test-rollout-ns-00", Name: "test-rollout-00"}, rollout)).Should(Succeed())
+		value, _, _ := unstructured.NestedString(rollout.Object, "spec", "template", "metadata", "annotations", postSyncRevisionKey)
+		Expect(value).Should(Equal("secret/test-src-posthook-ns-00/test-src-posthook-00@" + dataRevision(src)))
+		Expect(src.Annotations).Should(HaveKeyWithValue(postSyncRevisionKey, dataRevision(src)))
+		Expect(<-recorder.Events).Should(ContainSubstring(reasonPostSyncHooks))
+
+		// the hooks run once per revision
+		Expect(opts.runPostSyncHooks(context.Background(), c, recorder, src, namespaces)).Should(Succeed())
+		Expect(events).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// pruneDeferredKey records on a copy since when its prune is deferred because pods still use it
+	pruneDeferredKey = kopyPrefix + "prune-deferred-since"
+	// reasonPruneDeferred is used for events on copies that aren't pruned yet because pods still use them
+	reasonPruneDeferred = "PruneDeferred"
+	// reasonPruneGracePeriodExpired is used for events on copies that are pruned although pods still use them
+	reasonPruneGracePeriodExpired = "PruneGracePeriodExpired"
+	// pruneDeferredRequeueAfter is how often a source with deferred prunes checks the consumers of its copies again
+	pruneDeferredRequeueAfter = 30 * time.Second
+)
+
+// errPruneDeferred is returned by PruneCopies when some copies weren't pruned because pods still use them
+var errPruneDeferred = errors.New("prune deferred while pods use the copy")
+
+// deferPrune returns true if the prune of cp has to wait because running pods in its namespace mount it or read it
+// into their environment. The first deferral is recorded on the copy, once PruneGracePeriod has passed since then
+// the copy is pruned anyway.
+func (o Options) deferPrune(ctx context.Context, c client.Client, recorder record.EventRecorder, cp client.Object) (bool, error) {
+	if o.PruneGracePeriod == 0 {
+		return false, nil
+	}
+	consumers, err := consumersOf(ctx, c, cp)
+	if err != nil || len(consumers) == 0 {
+		return false, err
+	}
+	since, err := time.Parse(time.RFC3339, cp.GetAnnotations()[pruneDeferredKey])
+	if err != nil {
+		since = time.Now()
+		annotations := cp.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[pruneDeferredKey] = since.UTC().Format(time.RFC3339)
+		cp.SetAnnotations(annotations)
+		if err := c.Update(ctx, cp); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+	}
+	if time.Since(since) >= o.PruneGracePeriod {
+		recorder.Eventf(cp, corev1.EventTypeWarning, reasonPruneGracePeriodExpired,
+			"Pruning copy after %s although it is still used by pods %s", o.PruneGracePeriod, strings.Join(consumers, ", "))
+		return false, nil
+	}
+	recorder.Eventf(cp, corev1.EventTypeWarning, reasonPruneDeferred,
+		"Copy is no longer selected but still used by pods %s, pruning it once they are gone or after %s",
+		strings.Join(consumers, ", "), since.Add(o.PruneGracePeriod).UTC().Format(time.RFC3339))
+	return true, nil
+}
+
+// consumersOf returns the names of the pods in the namespace of cp that haven't terminated and use cp
+func consumersOf(ctx context.Context, c client.Client, cp client.Object) ([]string, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(cp.GetNamespace())); err != nil {
+		return nil, err
+	}
+	kind := kindOf(cp)
+	consumers := []string{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if slices.Contains(podReferences(pod, kind), cp.GetName()) {
+			consumers = append(consumers, pod.Name)
+		}
+	}
+	return consumers, nil
+}
+
+// podReferences returns the names of the objects of kind, secret or configmap, pod mounts as a volume, reads into
+// the environment of its containers or pulls images with, and for serviceaccount the service account pod runs as
+func podReferences(pod *corev1.Pod, kind string) []string {
+	names := []string{}
+	for _, v := range pod.Spec.Volumes {
+		switch {
+		case kind == "secret" && v.Secret != nil:
+			names = append(names, v.Secret.SecretName)
+		case kind == "configmap" && v.ConfigMap != nil:
+			names = append(names, v.ConfigMap.Name)
+		case v.Projected != nil:
+			for _, s := range v.Projected.Sources {
+				if kind == "secret" && s.Secret != nil {
+					names = append(names, s.Secret.Name)
+				}
+				if kind == "configmap" && s.ConfigMap != nil {
+					names = append(names, s.ConfigMap.Name)
+				}
+			}
+		}
+	}
+	if kind == "secret" {
+		for _, ref := range pod.Spec.ImagePullSecrets {
+			names = append(names, ref.Name)
+		}
+	}
+	if kind == "serviceaccount" && pod.Spec.ServiceAccountName != "" {
+		names = append(names, pod.Spec.ServiceAccountName)
+	}
+	containers := slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers)
+	for _, ec := range pod.Spec.EphemeralContainers {
+		containers = append(containers, corev1.Container{Env: ec.Env, EnvFrom: ec.EnvFrom})
+	}
+	for _, container := range containers {
+		for _, from := range container.EnvFrom {
+			if kind == "secret" && from.SecretRef != nil {
+				names = append(names, from.SecretRef.Name)
+			}
+			if kind == "configmap" && from.ConfigMapRef != nil {
+				names = append(names, from.ConfigMapRef.Name)
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if kind == "secret" && env.ValueFrom.SecretKeyRef != nil {
+				names = append(names, env.ValueFrom.SecretKeyRef.Name)
+			}
+			if kind == "configmap" && env.ValueFrom.ConfigMapKeyRef != nil {
+				names = append(names, env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Prune grace period\n", func() {
+	const namespace = "test-dst-prune-ns-00"
+	newCopy := func(annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-prune-00", Namespace: namespace, Annotations: annotations,
+		}}
+	}
+	newPod := func(name string, phase corev1.PodPhase, volume corev1.VolumeSource) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       corev1.PodSpec{Volumes: []corev1.Volume{{Name: "v", VolumeSource: volume}}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	mounted := corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "test-src-prune-00"}}
+	newClient := func(objects ...client.Object) client.Client {
+		return newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(objects...).Build()
+	}
+	opts := Options{PruneGracePeriod: time.Hour}
+
+	It("Should find the copies pods mount or read into their environment", func() {
+		pod := &corev1.Pod{Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{ConfigMap: &corev1.ConfigMapProjection{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "ca"},
+				}}},
+			}}}},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+			Containers: []corev1.Container{{
+				EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "db"},
+				}}},
+				Env: []corev1.EnvVar{{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}, Key: "level",
+				}}}},
+			}},
+		}}
+		Expect(podReferences(pod, "secret")).Should(ConsistOf("registry", "db"))
+		Expect(podReferences(pod, "configmap")).Should(ConsistOf("ca", "settings"))
+	})
+	It("Should defer the prune while running pods use the copy", func() {
+		cp := newCopy(nil)
+		c := newClient(cp, newPod("web", corev1.PodRunning, mounted), newPod("job", corev1.PodSucceeded, mounted))
+		recorder := record.NewFakeRecorder(1)
+		wait, err := opts.deferPrune(context.Background(), c, recorder, cp)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(wait).Should(BeTrue())
+		Expect(<-recorder.Events).Should(And(ContainSubstring(reasonPruneDeferred), ContainSubstring("web"), Not(ContainSubstring("job"))))
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(cp), cp)).Should(Succeed())
+		Expect(cp.Annotations).Should(HaveKey(pruneDeferredKey))
+	})
+	It("Should prune copies that aren't used or whose grace period passed", func() {
+		cp := newCopy(nil)
+		recorder := record.NewFakeRecorder(1)
+		wait, err := opts.deferPrune(context.Background(), newClient(cp), recorder, cp)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(wait).Should(BeFalse())
+
+		since := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+		cp = newCopy(map[string]string{pruneDeferredKey: since})
+		wait, err = opts.deferPrune(context.Background(), newClient(cp, newPod("web", corev1.PodRunning, mounted)), recorder, cp)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(wait).Should(BeFalse())
+		Expect(<-recorder.Events).Should(ContainSubstring(reasonPruneGracePeriodExpired))
+	})
+	It("Should prune the copies of the old selector when the sync annotation changes", func() {
+		ctx := context.Background()
+		const (
+			sourceNamespace = "test-src-prune-ns-01"
+			oldTarget       = "test-dst-prune-ns-01-old"
+			newTarget       = "test-dst-prune-ns-01-new"
+		)
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-prune-01", Namespace: sourceNamespace,
+				Annotations: map[string]string{syncKey: "team=a"}},
+			Data: map[string][]byte{"password": []byte("test-src-prune-01")},
+		}
+		c := newClient(src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: sourceNamespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: oldTarget, Labels: map[string]string{"team": "a"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: newTarget, Labels: map[string]string{"team": "b"}}},
+		)
+		reconcile := func() {
+			_, err := KopyReconcile(NewKopySecret(ctx, c, Options{}, nil), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		copyIn := func(namespace string) error {
+			return c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: src.Name}, &corev1.Secret{})
+		}
+		reconcile()
+		reconcile()
+		Expect(copyIn(oldTarget)).Should(Succeed())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		src.Annotations[syncKey] = "team=b"
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(copyIn(newTarget)).Should(Succeed())
+		Expect(apierrors.IsNotFound(copyIn(oldTarget))).Should(BeTrue())
+	})
+})
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// quarantineKey is set on a source suspected to be compromised, to the reason of the quarantine. The copies of a
+	// quarantined source are kept as they are instead of being updated, restored or pruned, and are annotated with
+	// the same reason until the quarantine is lifted.
+	quarantineKey = kopyPrefix + "quarantine"
+	// reasonQuarantined is used for events on sources whose copies were frozen by a quarantine
+	reasonQuarantined = "Quarantined"
+)
+
+// isQuarantined returns true if the source o is quarantined
+func isQuarantined(o client.Object) bool {
+	_, ok := o.GetAnnotations()[quarantineKey]
+	return ok
+}
+
+// quarantineCopies annotates the copies of the quarantined source src with its quarantine reason instead of syncing
+// them. recorder may be nil.
+func quarantineCopies(ctx context.Context, c client.Client, recorder record.EventRecorder, src client.Object) error {
+	copies, err := newObjectListForKind(kindOf(src))
+	if err != nil {
+		return err
+	}
+	if err := c.List(ctx, copies, listOptions(src)); err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(copies)
+	if err != nil {
+		return err
+	}
+	reason := src.GetAnnotations()[quarantineKey]
+	errs := []error{}
+	frozen := 0
+	for _, item := range items {
+		cp := item.(client.Object)
+		if !isCopyOf(cp, src) {
+			continue
+		}
+		if v, ok := cp.GetAnnotations()[quarantineKey]; ok && v == reason {
+			continue
+		}
+		patch := client.MergeFrom(cp.DeepCopyObject().(client.Object))
+		annotations := cp.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[quarantineKey] = reason
+		cp.SetAnnotations(annotations)
+		if err := c.Patch(ctx, cp, patch); err != nil {
+			errs = append(errs, fmt.Errorf("unable to quarantine copy in namespace %s: %w", cp.GetNamespace(), err))
+			continue
+		}
+		frozen++
+	}
+	if frozen > 0 && recorder != nil {
+		recorder.Eventf(src, corev1.EventTypeWarning, reasonQuarantined, "Source is quarantined, froze %d copies: %s", frozen, reason)
+	}
+	return errors.Join(errs...)
+}
+
+// QuarantineSource quarantines the source of kind for reason, e.g. while the rotation of a credential suspected to be
+// compromised is coordinated. Its copies are kept but no longer updated until the quarantine is lifted.
+func QuarantineSource(ctx context.Context, c client.Client, kind string, key types.NamespacedName, reason string) error {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return err
+	}
+	if err := c.Get(ctx, key, src); err != nil {
+		return err
+	}
+	if _, ok := SyncSelector(src); !ok {
+		return failedPrecondition("%s %s is not a kopy source", kind, key)
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	annotations := src.GetAnnotations()
+	annotations[quarantineKey] = reason
+	src.SetAnnotations(annotations)
+	return c.Patch(ctx, src, patch)
+}
+
+// LiftQuarantine lifts the quarantine of the source of kind, kopy then syncs its copies again and drops the quarantine
+// annotation from them
+func LiftQuarantine(ctx context.Context, c client.Client, kind string, key types.NamespacedName) error {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return err
+	}
+	if err := c.Get(ctx, key, src); err != nil {
+		return err
+	}
+	if !isQuarantined(src) {
+		return failedPrecondition("%s %s is not quarantined", kind, key)
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	annotations := src.GetAnnotations()
+	delete(annotations, quarantineKey)
+	src.SetAnnotations(annotations)
+	return c.Patch(ctx, src, patch)
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Quarantined sources\n", func() {
+	const (
+		namespace = "test-src-quarantine-ns-00"
+		target    = "test-dst-quarantine-ns-00"
+	)
+	It("Should freeze the copies of a quarantined source until the quarantine is lifted", func() {
+		ctx := context.Background()
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-quarantine-00", Namespace: namespace, Annotations: map[string]string{syncKey: "env=prod"}},
+			Data:       map[string][]byte{"password": []byte("leaked")},
+		}
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"env": "prod"}}},
+		).Build()
+		recorder := record.NewFakeRecorder(10)
+		key := client.ObjectKeyFromObject(src)
+		reconcile := func(req types.NamespacedName) {
+			_, err := KopyReconcile(NewKopySecret(ctx, c, Options{}, recorder), ctrl.Request{NamespacedName: req}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		cp := &corev1.Secret{}
+		copyKey := types.NamespacedName{Namespace: target, Name: src.Name}
+
+		reconcile(key)
+		Expect(QuarantineSource(ctx, c, "secret", key, "INC-42")).Should(Succeed())
+		Expect(c.Get(ctx, key, src)).Should(Succeed())
+		src.Data = map[string][]byte{"password": []byte("rotated")}
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile(key)
+		reconcile(copyKey)
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Annotations).Should(HaveKeyWithValue(quarantineKey, "INC-42"))
+		Expect(cp.Data).Should(HaveKeyWithValue("password", []byte("leaked")))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(reasonSynced)))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(reasonCopySynced)))
+		Expect(recorder.Events).Should(Receive(ContainSubstring(reasonQuarantined)))
+
+		Expect(LiftQuarantine(ctx, c, "secret", key)).Should(Succeed())
+		Expect(LiftQuarantine(ctx, c, "secret", key)).Should(MatchError(ErrFailedPrecondition))
+		Expect(LiftQuarantine(ctx, c, "widget", key)).Should(MatchError(ErrInvalidRequest))
+		reconcile(key)
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Annotations).ShouldNot(HaveKey(quarantineKey))
+		Expect(cp.Data).Should(HaveKeyWithValue("password", []byte("rotated")))
+	})
+})
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// sheddingQueue delays new requests while the queue holds at least threshold requests, so an event storm such as a
+// cluster restore is spread out over time instead of hammering the API server
+type sheddingQueue struct {
+	workqueue.TypedRateLimitingInterface[reconcile.Request]
+	controller string
+	threshold  int
+	delay      time.Duration
+}
+
+// Add queues item, or retries it after a jittered delay if the queue is over its threshold
+func (q *sheddingQueue) Add(item reconcile.Request) {
+	if q.Len() >= q.threshold {
+		queueShed.WithLabelValues(q.controller).Inc()
+		q.TypedRateLimitingInterface.AddAfter(item, wait.Jitter(q.delay, 0.5))
+		return
+	}
+	q.TypedRateLimitingInterface.Add(item)
+}
+
+// newQueue returns the NewQueue func for controller.Options, or nil to use the default queue when shedding is disabled
+func (o Options) newQueue() func(string, workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+	if o.QueueShedThreshold <= 0 {
+		return nil
+	}
+	return func(controllerName string, rateLimiter workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		return &sheddingQueue{
+			TypedRateLimitingInterface: workqueue.NewTypedRateLimitingQueueWithConfig(rateLimiter,
+				workqueue.TypedRateLimitingQueueConfig[reconcile.Request]{Name: controllerName}),
+			controller: controllerName,
+			threshold:  o.QueueShedThreshold,
+			delay:      o.QueueShedDelay,
+		}
+	}
+}
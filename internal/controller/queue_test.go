@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Workqueue load shedding\n", func() {
+	It("Should use the default queue when shedding is disabled", func() {
+		Expect(Options{}.newQueue()).Should(BeNil())
+	})
+	It("Should delay requests while the queue is over the threshold", func() {
+		opts := Options{QueueShedThreshold: 1, QueueShedDelay: time.Second}
+		q := opts.newQueue()("test-shedding", workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		defer q.ShutDown()
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "a", Name: "first"}})
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "a", Name: "second"}})
+		Expect(q.Len()).Should(Equal(1))
+		Eventually(q.Len, 3*time.Second, interval).Should(Equal(2))
+	})
+})
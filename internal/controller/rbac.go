@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rbacFeature is a part of kopy that can be turned on and off and the permissions it needs on top of the
+// controllers it requires
+type rbacFeature struct {
+	permissions []permission
+	// requires are the controllers that must be enabled with the feature
+	requires []string
+	// leaderElection permissions are granted in the namespace kopy runs in
+	leaderElection bool
+}
+
+var (
+	eventVerbs  = []string{"create", "patch"}
+	updateVerbs = []string{"update"}
+	leaseVerbs  = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+)
+
+// rbacFeatures are the permissions of the features that aren't a controller
+var rbacFeatures = map[string]rbacFeature{
+	"namespace-deletion-protection": {
+		permissions: []permission{
+			{resource: "secrets", verbs: []string{"list"}},
+			{resource: "configmaps", verbs: []string{"list"}},
+		},
+		requires: []string{"secret", "configmap"},
+	},
+	"inventory": {
+		permissions: []permission{{resource: "configmaps", verbs: []string{"get", "create", "update"}}},
+		requires:    []string{"secret", "configmap"},
+	},
+	"namespace-info": {
+		permissions: []permission{{resource: "configmaps", verbs: []string{"get", "list", "create", "update", "delete"}}},
+		requires:    []string{"configmap"},
+	},
+	"prune-grace-period": {
+		permissions: []permission{{resource: "pods", verbs: readVerbs}},
+	},
+	"post-sync-hooks": {
+		permissions: []permission{{group: "argoproj.io", resource: "rollouts", verbs: []string{"patch"}}},
+	},
+	"admin-api": {
+		permissions: []permission{
+			{group: "authentication.k8s.io", resource: "tokenreviews", verbs: []string{"create"}, clusterScoped: true},
+			{group: "authorization.k8s.io", resource: "subjectaccessreviews", verbs: []string{"create"}, clusterScoped: true},
+		},
+	},
+	"karmada": {
+		permissions: []permission{{group: "policy.karmada.io", resource: "propagationpolicies", verbs: writeVerbs}},
+	},
+	// the kubeconfig Secrets are read in the namespace of kopy, the remote clusters need the permissions of the
+	// controllers on their side
+	"remote-clusters": {
+		permissions:    []permission{{resource: "secrets", verbs: []string{"get"}}},
+		leaderElection: true,
+	},
+	"reports": {
+		permissions: []permission{
+			{group: "sync.kopy.kot-labs.com", resource: "kopyreports", verbs: writeVerbs},
+			{group: "sync.kopy.kot-labs.com", resource: "kopyreports", subresource: "status", verbs: updateVerbs},
+		},
+	},
+	"leader-election": {
+		permissions: []permission{
+			{group: "coordination.k8s.io", resource: "leases", verbs: leaseVerbs},
+			{resource: "configmaps", verbs: leaseVerbs},
+			{resource: "events", verbs: eventVerbs},
+		},
+		leaderElection: true,
+	},
+}
+
+// controllerExtraPermissions are the permissions controllers use beyond the ones they can't run without. They aren't
+// checked at startup because a controller still works without them, just with less feedback. The controllers of the
+// kinds kopy syncs update the finalizers of their objects and emit events, see extraPermissionsOf.
+var controllerExtraPermissions = map[string][]permission{
+	"kopysubscription": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopysubscriptions", subresource: "status", verbs: updateVerbs},
+		{group: "sync.kopy.kot-labs.com", resource: "kopysubscriptions", subresource: "finalizers", verbs: updateVerbs},
+		{resource: "events", verbs: eventVerbs},
+	},
+	"kopypublication": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopypublications", subresource: "status", verbs: updateVerbs},
+		{group: "sync.kopy.kot-labs.com", resource: "kopypublications", subresource: "finalizers", verbs: updateVerbs},
+		{resource: "secrets", verbs: []string{"patch"}},
+		{resource: "configmaps", verbs: []string{"patch"}},
+	},
+	"kopysync": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopysyncs", subresource: "status", verbs: updateVerbs},
+		{group: "sync.kopy.kot-labs.com", resource: "kopysyncs", subresource: "finalizers", verbs: updateVerbs},
+		{resource: "secrets", verbs: []string{"patch"}},
+		{resource: "configmaps", verbs: []string{"patch"}},
+	},
+	"kopytoken": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopytokens", subresource: "status", verbs: updateVerbs},
+		{group: "sync.kopy.kot-labs.com", resource: "kopytokens", subresource: "finalizers", verbs: updateVerbs},
+		{resource: "events", verbs: eventVerbs},
+	},
+	"kopysourcequota": {
+		{group: "sync.kopy.kot-labs.com", resource: "kopysourcequotas", subresource: "status", verbs: updateVerbs},
+		{resource: "secrets", verbs: []string{"patch"}},
+		{resource: "configmaps", verbs: []string{"patch"}},
+		{resource: "events", verbs: eventVerbs},
+	},
+}
+
+// selfSubjectAccessReview is needed by the permission check kopy runs before starting each controller
+var selfSubjectAccessReview = permission{
+	group: "authorization.k8s.io", resource: "selfsubjectaccessreviews", verbs: []string{"create"}, clusterScoped: true,
+}
+
+// DefaultFeatures are the features of a default install
+var DefaultFeatures = []string{"secret", "configmap", "serviceaccount", "resourcequota", "limitrange", "networkpolicy", "poddisruptionbudget", "kopysubscription", "kopypublication", "kopysync", "kopytoken", "kopysourcequota", "leader-election"}
+
+// extraPermissionsOf returns the permissions the controller named name uses beyond the ones it can't run without
+func extraPermissionsOf(name string) []permission {
+	if k, ok := builtinKind(name); ok {
+		group, resource := k.apiResource()
+		return []permission{
+			{group: group, resource: resource, subresource: "finalizers", verbs: updateVerbs},
+			{resource: "events", verbs: eventVerbs},
+		}
+	}
+	return controllerExtraPermissions[name]
+}
+
+// Features returns the names of the features RBAC can be generated for
+func Features() []string {
+	names := BuiltinKinds()
+	for name := range controllerPermissions {
+		names = append(names, name)
+	}
+	for name := range rbacFeatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RBACOptions configure the roles generated by GenerateRBAC
+type RBACOptions struct {
+	// Name of the generated ClusterRole and Roles
+	Name string
+	// Namespaces kopy is restricted to, the namespaced permissions are granted in a Role in each of them
+	Namespaces []string
+	// LeaderElectionNamespace is the namespace kopy runs in, where the leader election Role is created
+	LeaderElectionNamespace string
+}
+
+// GenerateRBAC returns the ClusterRole and Roles with the least privileges the features need. Unscoped installs get a
+// single ClusterRole, scoped installs a ClusterRole with the cluster wide permissions and a Role in each namespace.
+func GenerateRBAC(features []string, opts RBACOptions) ([]client.Object, error) {
+	cluster, namespaced, election := []permission{selfSubjectAccessReview}, []permission{}, []permission{}
+	for _, name := range features {
+		if permissions, ok := permissionsOf(name); ok {
+			namespaced = append(namespaced, permissions...)
+			namespaced = append(namespaced, extraPermissionsOf(name)...)
+			continue
+		}
+		feature, ok := rbacFeatures[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown feature %q, expected one of %s", name, strings.Join(Features(), ", "))
+		}
+		for _, required := range feature.requires {
+			if !slices.Contains(features, required) {
+				return nil, fmt.Errorf("feature %q requires %q", name, required)
+			}
+		}
+		if feature.leaderElection {
+			election = append(election, feature.permissions...)
+			continue
+		}
+		namespaced = append(namespaced, feature.permissions...)
+	}
+	scoped := len(opts.Namespaces) > 0
+	local := []permission{}
+	for _, p := range namespaced {
+		switch {
+		case p.unscopedOnly && scoped:
+		case p.clusterScoped || !scoped:
+			cluster = append(cluster, p)
+		default:
+			local = append(local, p)
+		}
+	}
+
+	objects := []client.Object{&rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{Name: opts.Name},
+		Rules:      policyRules(cluster),
+	}}
+	if scoped && len(local) > 0 {
+		for _, namespace := range opts.Namespaces {
+			objects = append(objects, role(opts.Name, namespace, local))
+		}
+	}
+	if len(election) > 0 {
+		objects = append(objects, role(opts.Name+"-leader-election", opts.LeaderElectionNamespace, election))
+	}
+	return objects, nil
+}
+
+func role(name, namespace string, permissions []permission) *rbacv1.Role {
+	return &rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Rules:      policyRules(permissions),
+	}
+}
+
+// policyRules merges permissions into rules, one per API group and set of verbs, in the order controller-gen
+// writes them
+func policyRules(permissions []permission) []rbacv1.PolicyRule {
+	verbs := map[string]map[string]bool{}
+	for _, p := range permissions {
+		key := p.group + "\x00" + p.resource
+		if p.subresource != "" {
+			key += "/" + p.subresource
+		}
+		if verbs[key] == nil {
+			verbs[key] = map[string]bool{}
+		}
+		for _, verb := range p.verbs {
+			verbs[key][verb] = true
+		}
+	}
+	rules := map[string]*rbacv1.PolicyRule{}
+	for key, set := range verbs {
+		group, resource, _ := strings.Cut(key, "\x00")
+		sorted := make([]string, 0, len(set))
+		for verb := range set {
+			sorted = append(sorted, verb)
+		}
+		sort.Strings(sorted)
+		ruleKey := group + "\x00" + strings.Join(sorted, ",")
+		if rules[ruleKey] == nil {
+			rules[ruleKey] = &rbacv1.PolicyRule{APIGroups: []string{group}, Verbs: sorted}
+		}
+		rules[ruleKey].Resources = append(rules[ruleKey].Resources, resource)
+	}
+	keys := make([]string, 0, len(rules))
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]rbacv1.PolicyRule, 0, len(keys))
+	for _, key := range keys {
+		sort.Strings(rules[key].Resources)
+		result = append(result, *rules[key])
+	}
+	return result
+}
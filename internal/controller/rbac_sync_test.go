@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Role and RoleBinding sync\n", func() {
+	const (
+		namespace = "test-src-rbac-ns-00"
+		target    = "test-dst-rbac-ns-00"
+	)
+	var (
+		ctx context.Context
+		c   client.Client
+	)
+	opts := Options{RBACSourceNamespaces: []string{namespace}}
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).Build()
+	})
+
+	It("Should copy the rules of a role", func() {
+		src := &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-role-00", Namespace: namespace, Annotations: map[string]string{syncKey: "team=payments"}},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "list"}}},
+		}
+		Expect(c.Create(ctx, src)).Should(Succeed())
+		_, err := KopyReconcile(NewKopyRole(ctx, c, opts, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		cp := &rbacv1.Role{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: target, Name: src.Name}, cp)).Should(Succeed())
+		Expect(cp.Labels).Should(HaveKeyWithValue(sourceLabelNamespace, namespace))
+		Expect(cp.Rules).Should(Equal(src.Rules))
+	})
+
+	It("Should replace the copy of a role binding when its role reference changes", func() {
+		// like the API server the copy can't be updated, or applied, with a different role reference, the source is recreated
+		// with the new role reference by its owner which is left out here
+		c = interceptor.NewClient(interceptor.NewClient(c.(client.WithWatch), interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if rb, ok := obj.(*rbacv1.RoleBinding); ok && rb.Namespace == target {
+					existing := &rbacv1.RoleBinding{}
+					if err := c.Get(ctx, client.ObjectKeyFromObject(rb), existing); err == nil && existing.RoleRef != rb.RoleRef {
+						return apierrors.NewInvalid(rbacv1.SchemeGroupVersion.WithKind("RoleBinding").GroupKind(), rb.Name, nil)
+					}
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		}), interceptor.Funcs{Patch: fakeApply})
+		src := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-rolebinding-00", Namespace: namespace, Annotations: map[string]string{syncKey: "team=payments"}},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "payments-developers"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+		}
+		Expect(c.Create(ctx, src)).Should(Succeed())
+		reconcile := func() {
+			_, err := KopyReconcile(NewKopyRoleBinding(ctx, c, opts, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		cp := &rbacv1.RoleBinding{}
+		copyKey := types.NamespacedName{Namespace: target, Name: src.Name}
+
+		reconcile()
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Subjects).Should(Equal(src.Subjects))
+		Expect(cp.RoleRef).Should(Equal(src.RoleRef))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		src.RoleRef.Name = "edit"
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.RoleRef.Name).Should(Equal("edit"))
+		Expect(cp.Finalizers).Should(ContainElement(syncFinalizer))
+	})
+	It("Should refuse roles outside the RBAC source namespaces", func() {
+		src := &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-role-01", Namespace: namespace, Annotations: map[string]string{syncKey: "team=payments"}},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"*"}}},
+		}
+		Expect(c.Create(ctx, src)).Should(Succeed())
+		for _, opts := range []Options{{}, {RBACSourceNamespaces: []string{"platform-rbac"}}} {
+			recorder := record.NewFakeRecorder(10)
+			_, err := KopyReconcile(NewKopyRole(ctx, c, opts, recorder), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(recorder.Events).Should(Receive(ContainSubstring(reasonRBACSourceRefused)))
+			Expect(apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Namespace: target, Name: src.Name}, &rbacv1.Role{}))).Should(BeTrue())
+
+			By("Refusing to write the copy through other paths too")
+			err = NewKopyRole(ctx, c, opts, nil).Copy(src, target)
+			Expect(err).Should(MatchError(errRBACSourceRefused))
+			Expect(isPermanentSyncError(err)).Should(BeTrue())
+		}
+	})
+})
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+var _ = Describe("RBAC generation\n", func() {
+	It("Should grant the namespaced permissions cluster wide when kopy isn't scoped", func() {
+		objects, err := GenerateRBAC([]string{"secret"}, RBACOptions{Name: "kopy"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(objects).Should(HaveLen(1))
+		role := objects[0].(*rbacv1.ClusterRole)
+		Expect(role.Rules).Should(ContainElements(
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get", "list", "watch"}},
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"create", "delete", "get", "list", "patch", "update", "watch"}},
+			rbacv1.PolicyRule{APIGroups: []string{"authorization.k8s.io"}, Resources: []string{"selfsubjectaccessreviews"}, Verbs: []string{"create"}},
+		))
+		Expect(role.Rules).ShouldNot(ContainElement(HaveField("Resources", ContainElement("configmaps"))))
+	})
+	It("Should grant the namespaced permissions in a Role in each namespace when kopy is scoped", func() {
+		objects, err := GenerateRBAC([]string{"secret", "leader-election"}, RBACOptions{
+			Name: "kopy", Namespaces: []string{"team-a", "team-b"}, LeaderElectionNamespace: "kopy-system",
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(objects).Should(HaveLen(4))
+		Expect(objects[0].(*rbacv1.ClusterRole).Rules).Should(HaveLen(1))
+		for i, namespace := range []string{"team-a", "team-b"} {
+			role := objects[i+1].(*rbacv1.Role)
+			Expect(role.Namespace).Should(Equal(namespace))
+			Expect(role.Rules).ShouldNot(ContainElement(HaveField("Resources", ContainElement("namespaces"))))
+		}
+		election := objects[3].(*rbacv1.Role)
+		Expect(election.Name).Should(Equal("kopy-leader-election"))
+		Expect(election.Namespace).Should(Equal("kopy-system"))
+		Expect(election.Rules).Should(ContainElement(HaveField("Resources", ContainElement("leases"))))
+	})
+	It("Should reject unknown features and features without the controllers they need", func() {
+		_, err := GenerateRBAC([]string{"secrets"}, RBACOptions{})
+		Expect(err).Should(MatchError(ContainSubstring(`unknown feature "secrets"`)))
+		_, err = GenerateRBAC([]string{"configmap", "inventory"}, RBACOptions{})
+		Expect(err).Should(MatchError(ContainSubstring(`requires "secret"`)))
+	})
+})
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reasonRBACSourceRefused is used for events on Roles and RoleBindings that aren't synced from their namespace
+const reasonRBACSourceRefused = "RBACSourceRefused"
+
+// errRBACSourceRefused is returned when a Role or RoleBinding is copied from a namespace that isn't one of the
+// RBACSourceNamespaces
+var errRBACSourceRefused = errors.New("rbac source refused")
+
+// rbacKinds are the kinds whose copies grant access in their namespace. ParseGVK refuses to register them as sync
+// kinds, so they are only synced by their own controllers.
+var rbacKinds = map[string]bool{"role": true, "rolebinding": true}
+
+// checkRBACSource returns errRBACSourceRefused if src grants access in the namespaces it is copied to and isn't in
+// one of the RBACSourceNamespaces. Anyone who can annotate a Role in a namespace could grant themselves the
+// permissions of kopy in every selected namespace otherwise.
+func (o Options) checkRBACSource(src client.Object) error {
+	if !rbacKinds[kindOf(src)] || slices.Contains(o.RBACSourceNamespaces, src.GetNamespace()) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s %s/%s, %s isn't an RBAC source namespace", errRBACSourceRefused, kindOf(src),
+		src.GetNamespace(), src.GetName(), src.GetNamespace())
+}
+
+// refusedRBACSource returns true if src must not be synced because of checkRBACSource and emits an event on src
+func (o Options) refusedRBACSource(recorder record.EventRecorder, src client.Object) bool {
+	err := o.checkRBACSource(src)
+	if err == nil {
+		return false
+	}
+	if recorder != nil {
+		recorder.Eventf(src, corev1.EventTypeWarning, reasonRBACSourceRefused, "Not synced: %s", err)
+	}
+	return true
+}
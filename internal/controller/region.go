@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// regionKey restricts a source to the namespaces in one of the listed regions, e.g. eu-west-1,eu-central-1, so
+	// region specific credentials only land in the namespaces of the workloads of that region
+	regionKey = kopyPrefix + "region"
+	// zoneKey restricts a source to the namespaces in one of the listed zones
+	zoneKey = kopyPrefix + "zone"
+)
+
+// topologyKeys maps the annotations that restrict a source to the well-known topology keys of the namespaces
+var topologyKeys = []struct{ annotation, namespaceKey string }{
+	{regionKey, corev1.LabelTopologyRegion},
+	{zoneKey, corev1.LabelTopologyZone},
+}
+
+// topologyValues returns the values listed in the annotation key of src and whether src is restricted by it
+func topologyValues(src client.Object, key string) (sets.Set[string], bool) {
+	v, ok := src.GetAnnotations()[key]
+	if !ok {
+		return nil, false
+	}
+	values := sets.New[string]()
+	for _, value := range strings.Split(v, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values.Insert(value)
+		}
+	}
+	return values, true
+}
+
+// namespaceTopology returns the value of the topology key of ns, read from its labels or, for namespaces whose
+// labels are owned by other tooling, its annotations
+func namespaceTopology(ns client.Object, key string) string {
+	if v, ok := ns.GetLabels()[key]; ok {
+		return v
+	}
+	return ns.GetAnnotations()[key]
+}
+
+// topologyMismatch describes why ns is outside the regions and zones src is restricted to, "" if it isn't. Namespaces
+// without topology metadata are outside of every restriction.
+func topologyMismatch(src, ns client.Object) string {
+	for _, t := range topologyKeys {
+		values, ok := topologyValues(src, t.annotation)
+		if !ok {
+			continue
+		}
+		v := namespaceTopology(ns, t.namespaceKey)
+		if v == "" {
+			return fmt.Sprintf("%s isn't set on the namespace, the source is restricted to %s", t.namespaceKey,
+				strings.Join(sets.List(values), ", "))
+		}
+		if !values.Has(v) {
+			return fmt.Sprintf("%s=%s isn't one of %s", t.namespaceKey, v, strings.Join(sets.List(values), ", "))
+		}
+	}
+	return ""
+}
+
+// hasTopology returns true if src is restricted to regions or zones
+func hasTopology(src client.Object) bool {
+	for _, t := range topologyKeys {
+		if _, ok := src.GetAnnotations()[t.annotation]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// withinTopology returns the namespaces located in the regions and zones src is restricted to
+func withinTopology(src client.Object, namespaces []corev1.Namespace) []corev1.Namespace {
+	if !hasTopology(src) {
+		return namespaces
+	}
+	result := make([]corev1.Namespace, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if topologyMismatch(src, &ns) == "" {
+			result = append(result, ns)
+		}
+	}
+	return result
+}
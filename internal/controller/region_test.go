@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Topology aware targeting\n", func() {
+	const (
+		namespace = "test-src-region-ns-00"
+		labeled   = "test-dst-region-ns-00"
+		annotated = "test-dst-region-ns-01"
+		other     = "test-dst-region-ns-02"
+		unknown   = "test-dst-region-ns-03"
+	)
+	var (
+		ctx context.Context
+		c   client.Client
+		src *corev1.Secret
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		src = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-region-00", Namespace: namespace,
+				Annotations: map[string]string{syncKey: "team=payments", regionKey: "eu-west-1, eu-central-1"},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		team := map[string]string{"team": "payments"}
+		c = newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: labeled, Labels: map[string]string{
+				"team": "payments", corev1.LabelTopologyRegion: "eu-west-1",
+			}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: annotated, Labels: team,
+				Annotations: map[string]string{corev1.LabelTopologyRegion: "eu-central-1"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: other, Labels: map[string]string{
+				"team": "payments", corev1.LabelTopologyRegion: "us-east-1",
+			}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: unknown, Labels: team}},
+		).Build()
+	})
+	reconcile := func() {
+		_, err := KopyReconcile(NewKopySecret(ctx, c, Options{}, record.NewFakeRecorder(20)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+	copyIn := func(namespace string) error {
+		return c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: src.Name}, &corev1.Secret{})
+	}
+
+	It("Should only copy to the selected namespaces in the regions of the source", func() {
+		reconcile()
+		Expect(copyIn(labeled)).Should(Succeed())
+		Expect(copyIn(annotated)).Should(Succeed())
+		Expect(apierrors.IsNotFound(copyIn(other))).Should(BeTrue())
+		Expect(apierrors.IsNotFound(copyIn(unknown))).Should(BeTrue())
+
+		explanation, err := Explain(ctx, c, "secret", client.ObjectKeyFromObject(src), other, Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(explanation.Receives).Should(BeFalse())
+		Expect(explanation.Checks).Should(ContainElement(And(
+			HaveField("Name", "topology"),
+			HaveField("Detail", ContainSubstring("us-east-1 isn't one of eu-central-1, eu-west-1")),
+		)))
+	})
+
+	It("Should prune copies from namespaces that moved to another region", func() {
+		reconcile()
+		ns := &corev1.Namespace{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: labeled}, ns)).Should(Succeed())
+		ns.Labels[corev1.LabelTopologyRegion] = "us-east-1"
+		Expect(c.Update(ctx, ns)).Should(Succeed())
+		reconcile()
+		Expect(apierrors.IsNotFound(copyIn(labeled))).Should(BeTrue())
+		Expect(copyIn(annotated)).Should(Succeed())
+	})
+})
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// releasePageSize is how many objects ReleaseAll lists per request, sources aren't labeled so every object of a kind
+// is listed
+const releasePageSize = 500
+
+// releaseKinds are the built-in kinds ReleaseAll releases unless others are given
+var releaseKinds = []string{
+	"secret", "configmap", "serviceaccount", "resourcequota", "limitrange", "networkpolicy", "poddisruptionbudget",
+	"role", "rolebinding",
+}
+
+// ReleaseOptions configure ReleaseAll
+type ReleaseOptions struct {
+	// Kinds are the kind names to release, the built-in kinds and the kinds registered with RegisterSyncKind if empty
+	Kinds []string
+	// OriginLabels also strips the origin labels from copies. Copies then become plain objects that a later kopy
+	// installation neither updates nor prunes.
+	OriginLabels bool
+	// QPS limits the updates per second, 0 doesn't limit them
+	QPS float32
+	// DryRun only reports the objects that would be released
+	DryRun bool
+	// Progress is called for every object after it was released
+	Progress func(ReleasedObject)
+}
+
+// ReleasedObject is an object ReleaseAll stripped of the kopy finalizers and labels listed
+type ReleasedObject struct {
+	Kind       string   `json:"kind"`
+	Namespace  string   `json:"namespace"`
+	Name       string   `json:"name"`
+	Finalizers []string `json:"finalizers,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+}
+
+// ReleaseAll strips the kopy finalizers from every source and copy in the cluster, and with OriginLabels the origin
+// labels from every copy. It is meant for uninstalling kopy, whose finalizers otherwise block deleting the objects
+// and their namespaces once the controller is gone, and should run after the controller was stopped so it doesn't
+// add them back.
+func ReleaseAll(ctx context.Context, c client.Client, opts ReleaseOptions) ([]ReleasedObject, error) {
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = append(slices.Clone(releaseKinds), RegisteredSyncKinds()...)
+	}
+	var limiter flowcontrol.RateLimiter
+	if opts.QPS > 0 {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(opts.QPS, 1)
+	}
+	released := []ReleasedObject{}
+	for _, kind := range kinds {
+		list, err := newObjectListForKind(kind)
+		if err != nil {
+			return released, err
+		}
+		listOpts := &client.ListOptions{Limit: releasePageSize}
+		for {
+			if err := c.List(ctx, list, listOpts); err != nil {
+				return released, fmt.Errorf("unable to list %s: %w", kind, err)
+			}
+			items, err := meta.ExtractList(list)
+			if err != nil {
+				return released, err
+			}
+			for _, item := range items {
+				obj, ok := item.(client.Object)
+				if !ok {
+					return released, fmt.Errorf("unexpected object %T", item)
+				}
+				r := releasedObject(kind, obj, opts.OriginLabels)
+				if len(r.Finalizers) == 0 && len(r.Labels) == 0 {
+					continue
+				}
+				if !opts.DryRun {
+					if limiter != nil {
+						if err := limiter.Wait(ctx); err != nil {
+							return released, err
+						}
+					}
+					if err := release(ctx, c, kind, obj, opts.OriginLabels); err != nil {
+						return released, fmt.Errorf("unable to release %s %s: %w", kind, client.ObjectKeyFromObject(obj), err)
+					}
+				}
+				released = append(released, r)
+				if opts.Progress != nil {
+					opts.Progress(r)
+				}
+			}
+			listOpts.Continue = list.GetContinue()
+			if listOpts.Continue == "" {
+				break
+			}
+		}
+	}
+	return released, nil
+}
+
+// isKopyFinalizer returns true for the finalizers kopy sets on sources and copies
+func isKopyFinalizer(f string) bool {
+	return isSyncFinalizer(f) || strings.HasPrefix(f, remoteFinalizerPrefix)
+}
+
+// releaseLabels are the labels kopy sets on copies
+var releaseLabels = []string{sourceLabelNamespace, sourceLabelName, managedByLabel}
+
+// releasedObject returns what releasing obj strips from it
+func releasedObject(kind string, obj client.Object, originLabels bool) ReleasedObject {
+	r := ReleasedObject{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	for _, f := range obj.GetFinalizers() {
+		if isKopyFinalizer(f) {
+			r.Finalizers = append(r.Finalizers, f)
+		}
+	}
+	if _, ok := obj.GetLabels()[sourceLabelNamespace]; ok && originLabels {
+		for _, l := range releaseLabels {
+			if _, ok := obj.GetLabels()[l]; ok {
+				r.Labels = append(r.Labels, l)
+			}
+		}
+	}
+	return r
+}
+
+// release strips the kopy finalizers and, with originLabels, the kopy labels from obj. The object is read again when
+// it changed in the meantime.
+func release(ctx context.Context, c client.Client, kind string, obj client.Object, originLabels bool) error {
+	key := client.ObjectKeyFromObject(obj)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if obj == nil {
+			latest, err := NewObjectForKind(kind)
+			if err != nil {
+				return err
+			}
+			if err := c.Get(ctx, key, latest); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			obj = latest
+		}
+		base := obj.DeepCopyObject().(client.Object)
+		obj.SetFinalizers(slices.DeleteFunc(obj.GetFinalizers(), isKopyFinalizer))
+		if _, ok := obj.GetLabels()[sourceLabelNamespace]; ok && originLabels {
+			labels := obj.GetLabels()
+			for _, l := range releaseLabels {
+				delete(labels, l)
+			}
+			obj.SetLabels(labels)
+		}
+		err := c.Patch(ctx, obj, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+		if apierrors.IsConflict(err) {
+			// the object is read again before the retry
+			obj = nil
+		}
+		return client.IgnoreNotFound(err)
+	})
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Release all\n", func() {
+	const (
+		namespace = "test-src-release-ns-00"
+		target    = "test-dst-release-ns-00"
+	)
+	var (
+		c     client.Client
+		src   *corev1.Secret
+		cp    *corev1.ConfigMap
+		plain *corev1.Secret
+	)
+	BeforeEach(func() {
+		src = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-release-00", Namespace: namespace,
+			Annotations: map[string]string{syncKey: "team=payments"},
+			Finalizers:  []string{syncFinalizer, remoteFinalizerPrefix + "eu-west", "example.com/keep"},
+		}}
+		cp = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-release-01", Namespace: target,
+			Labels:     map[string]string{sourceLabelNamespace: namespace, sourceLabelName: "test-src-release-01", "app": "web"},
+			Finalizers: []string{syncFinalizer},
+		}}
+		plain = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-src-release-02", Namespace: namespace}}
+		c = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, cp, plain).Build()
+	})
+
+	It("Should only list the objects to release in a dry run", func() {
+		released, err := ReleaseAll(context.Background(), c, ReleaseOptions{DryRun: true})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(released).Should(ConsistOf(
+			ReleasedObject{Kind: "secret", Namespace: namespace, Name: src.Name,
+				Finalizers: []string{syncFinalizer, remoteFinalizerPrefix + "eu-west"}},
+			ReleasedObject{Kind: "configmap", Namespace: target, Name: cp.Name, Finalizers: []string{syncFinalizer}},
+		))
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Finalizers).Should(ContainElement(syncFinalizer))
+	})
+
+	It("Should strip the kopy finalizers and origin labels and report the progress", func() {
+		ctx := context.Background()
+		progress := []string{}
+		released, err := ReleaseAll(ctx, c, ReleaseOptions{OriginLabels: true, QPS: 100, Progress: func(r ReleasedObject) {
+			progress = append(progress, r.Namespace+"/"+r.Name)
+		}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(released).Should(HaveLen(2))
+		Expect(progress).Should(Equal([]string{namespace + "/" + src.Name, target + "/" + cp.Name}))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Finalizers).Should(Equal([]string{"example.com/keep"}))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cp), cp)).Should(Succeed())
+		Expect(cp.Finalizers).Should(BeEmpty())
+		Expect(cp.Labels).Should(Equal(map[string]string{"app": "web"}))
+
+		By("Finding nothing left to release")
+		released, err = ReleaseAll(ctx, c, ReleaseOptions{OriginLabels: true})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(released).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,280 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// remoteFinalizerPrefix prefixes the finalizer kopy sets on a source for every remote cluster it is pushed to, so
+	// the copies in the cluster are cleaned up before the source is gone
+	remoteFinalizerPrefix = "remote.kopy.kot-labs.com/"
+	// kubeconfigKey is the key of the kubeconfig in the Secret of a remote cluster
+	kubeconfigKey = "kubeconfig"
+	// reasonRemoteSyncFailed is used for events on sources whose copies couldn't be written to a remote cluster
+	reasonRemoteSyncFailed = "RemoteSyncFailed"
+	// remoteRetryAfter is how long a source waits before the clusters it failed to sync to are retried
+	remoteRetryAfter = time.Minute
+)
+
+// RemoteClusters pushes sources to the remote clusters listed in their publish-clusters annotation. Every remote
+// cluster is a Secret named after the cluster in Namespace that holds its kubeconfig under the kubeconfig key.
+// Clients are kept until the Secret changes.
+type RemoteClusters struct {
+	// Reader reads the Secrets of the remote clusters, usually the API reader of the manager so only the namespace
+	// of kopy has to be readable
+	Reader    client.Reader
+	Namespace string
+	Scheme    *runtime.Scheme
+
+	mu      sync.Mutex
+	clients map[string]remoteClient
+}
+
+// remoteClient is the client of a remote cluster built from the Secret with resourceVersion
+type remoteClient struct {
+	resourceVersion string
+	client          client.Client
+}
+
+// errRemoteClusterMissing is returned for clusters without a kubeconfig Secret
+var errRemoteClusterMissing = errors.New("remote cluster has no kubeconfig secret")
+
+// client returns the client of cluster, built again whenever its Secret changed
+func (r *RemoteClusters) client(ctx context.Context, cluster string) (client.Client, error) {
+	secret := &corev1.Secret{}
+	if err := r.Reader.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: cluster}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s/%s", errRemoteClusterMissing, r.Namespace, cluster)
+		}
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rc, ok := r.clients[cluster]; ok && rc.resourceVersion == secret.ResourceVersion {
+		return rc.client, nil
+	}
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(secret.Data[kubeconfigKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig of remote cluster %s: %w", cluster, err)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: r.Scheme})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client of remote cluster %s: %w", cluster, err)
+	}
+	if r.clients == nil {
+		r.clients = map[string]remoteClient{}
+	}
+	r.clients[cluster] = remoteClient{resourceVersion: secret.ResourceVersion, client: c}
+	return c, nil
+}
+
+// remoteFinalizers returns the remote clusters src holds a finalizer for
+func remoteFinalizers(src client.Object) []string {
+	clusters := []string{}
+	for _, f := range src.GetFinalizers() {
+		if cluster, ok := strings.CutPrefix(f, remoteFinalizerPrefix); ok {
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters
+}
+
+// syncRemoteClusters pushes src to the namespaces selected by selector in each of its remote clusters and cleans up
+// the clusters it is no longer published to. A cluster that fails is reported with an event and retried after
+// remoteRetryAfter without holding back the others. recorder may be nil.
+func (o Options) syncRemoteClusters(ctx context.Context, c client.Client, recorder record.EventRecorder, src client.Object, selector labels.Selector) (time.Duration, error) {
+	if o.RemoteClusters == nil {
+		return 0, nil
+	}
+	clusters := publishClusters(src)
+	// the finalizers are added before the first copy is written so copies never outlive their source unnoticed
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	added := false
+	for _, cluster := range clusters {
+		added = ctrlutil.AddFinalizer(src, remoteFinalizerPrefix+cluster) || added
+	}
+	if added {
+		if err := c.Patch(ctx, src, patch); err != nil {
+			return 0, err
+		}
+	}
+	failed := false
+	for _, cluster := range remoteFinalizers(src) {
+		var err error
+		if slices.Contains(clusters, cluster) {
+			err = o.pushToCluster(ctx, cluster, src, selector)
+		} else {
+			err = o.cleanupCluster(ctx, c, cluster, src, true)
+		}
+		if err != nil {
+			failed = true
+			ctrllog.FromContext(ctx).Error(err, "unable to sync remote cluster", "cluster", cluster)
+			if recorder != nil {
+				recorder.Eventf(src, corev1.EventTypeWarning, reasonRemoteSyncFailed, "Unable to sync to cluster %s: %v", cluster, err)
+			}
+		}
+	}
+	if failed {
+		return remoteRetryAfter, nil
+	}
+	return 0, nil
+}
+
+// syncRemoteCopies pushes the source of k to its remote clusters and returns result with a requeue for the clusters
+// that failed unless it already requeues sooner
+func syncRemoteCopies(k Kopier, result ctrl.Result) (ctrl.Result, error) {
+	retry, err := k.GetOptions().syncRemoteClusters(k.GetContext(), k.GetClient(), k.GetRecorder(), k.GetObject(), k.LabelSelector())
+	if err != nil {
+		return result, err
+	}
+	if retry > 0 && (result.RequeueAfter == 0 || retry < result.RequeueAfter) {
+		result.RequeueAfter = retry
+	}
+	return result, nil
+}
+
+// pushToCluster writes the copies of src to the namespaces selected by selector in cluster and prunes the copies in
+// namespaces that are no longer selected. Remote copies carry no finalizer since nothing in the remote cluster would
+// remove it.
+func (o Options) pushToCluster(ctx context.Context, cluster string, src client.Object, selector labels.Selector) error {
+	rc, err := o.RemoteClusters.client(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}
+	namespaces, err := getSyncNamespaces(ctx, rc, req, selector, o.ExcludedNamespaces)
+	if err != nil {
+		return err
+	}
+	namespaces = withinTopology(src, namespaces)
+	selected := namespaceNames(namespaces)
+	errs := []error{}
+	for _, ns := range namespaces {
+		cp, err := newCopy(src, ns.Name, o)
+		if err != nil {
+			return err
+		}
+		removeSyncFinalizer(cp)
+		stampCostLabels(cp, o.costLabels(&ns))
+		cp, err = o.prepareCopy(ctx, rc, src, cp)
+		if errors.Is(err, errTransformSkipped) {
+			continue
+		}
+		if err == nil {
+			err = o.checkCopyDenied(kindOf(cp), cp.GetName(), ns.Name)
+		}
+		if err == nil {
+			err = writeCopy(ctx, rc, nil, src, cp)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", ns.Name, err))
+		}
+	}
+	copies, err := remoteCopies(ctx, rc, src)
+	if err != nil {
+		return err
+	}
+	for _, cp := range copies {
+		if selected.Has(cp.GetNamespace()) {
+			continue
+		}
+		if err := rc.Delete(ctx, cp); client.IgnoreNotFound(err) != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", cp.GetNamespace(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cleanupCluster deletes the copies of src in cluster, or releases them like the local copies of a deleted source
+// unless del is set, and removes the finalizer of the cluster from src. Clusters without a kubeconfig Secret can't be
+// cleaned up and are only dropped from the finalizers.
+func (o Options) cleanupCluster(ctx context.Context, c client.Client, cluster string, src client.Object, del bool) error {
+	rc, err := o.RemoteClusters.client(ctx, cluster)
+	if err != nil && !errors.Is(err, errRemoteClusterMissing) {
+		return err
+	}
+	if err == nil {
+		copies, err := remoteCopies(ctx, rc, src)
+		if err != nil {
+			return err
+		}
+		for _, cp := range copies {
+			if del {
+				err = client.IgnoreNotFound(rc.Delete(ctx, cp))
+			} else {
+				labels := cp.GetLabels()
+				delete(labels, sourceLabelNamespace)
+				delete(labels, sourceLabelName)
+				cp.SetLabels(labels)
+				err = rc.Update(ctx, cp)
+			}
+			if err != nil {
+				return fmt.Errorf("namespace %s: %w", cp.GetNamespace(), err)
+			}
+		}
+	} else {
+		ctrllog.FromContext(ctx).Info("remote cluster is gone, leaving its copies behind", "cluster", cluster)
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	if ctrlutil.RemoveFinalizer(src, remoteFinalizerPrefix+cluster) {
+		return c.Patch(ctx, src, patch)
+	}
+	return nil
+}
+
+// releaseRemoteClusters cleans up every remote cluster of src once src is deleted or no longer synced. The copies
+// are deleted if src is deleted and its copies cascade, and released otherwise.
+func (o Options) releaseRemoteClusters(ctx context.Context, c client.Client, src client.Object) error {
+	if o.RemoteClusters == nil {
+		return nil
+	}
+	del := src.GetDeletionTimestamp() != nil && o.cascades(src)
+	errs := []error{}
+	for _, cluster := range remoteFinalizers(src) {
+		if err := o.cleanupCluster(ctx, c, cluster, src, del); err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", cluster, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// remoteCopies returns the copies of src in the cluster of rc
+func remoteCopies(ctx context.Context, rc client.Client, src client.Object) ([]client.Object, error) {
+	list, err := newObjectListForKind(kindOf(src))
+	if err != nil {
+		return nil, err
+	}
+	if err := rc.List(ctx, list, listOptions(src)); err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	copies := []client.Object{}
+	for _, item := range items {
+		if cp, ok := item.(client.Object); ok && isCopyOf(cp, src) {
+			copies = append(copies, cp)
+		}
+	}
+	return copies, nil
+}
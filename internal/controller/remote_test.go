@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Remote clusters\n", func() {
+	const (
+		kopyNamespace = "test-kopy-remote-ns-00"
+		namespace     = "test-src-remote-ns-00"
+		target        = "test-dst-remote-ns-00"
+	)
+	var (
+		ctx      context.Context
+		c        client.Client
+		remote   client.Client
+		src      *corev1.Secret
+		opts     Options
+		recorder *record.FakeRecorder
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		src = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-remote-00", Namespace: namespace,
+				Annotations: map[string]string{syncKey: "team=payments", publishClustersKey: "member1"},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		kubeconfig := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "member1", Namespace: kopyNamespace}}
+		c = newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src, kubeconfig,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).Build()
+		remote = newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).Build()
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(kubeconfig), kubeconfig)).Should(Succeed())
+		// the client of member1 is cached for the current version of its secret instead of built from a kubeconfig
+		opts = Options{RemoteClusters: &RemoteClusters{
+			Reader: c, Namespace: kopyNamespace, Scheme: clientgoscheme.Scheme,
+			clients: map[string]remoteClient{"member1": {resourceVersion: kubeconfig.ResourceVersion, client: remote}},
+		}}
+		recorder = record.NewFakeRecorder(10)
+	})
+	reconcile := func() ctrl.Result {
+		result, err := KopyReconcile(NewKopySecret(ctx, c, opts, recorder), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		return result
+	}
+	copyKey := types.NamespacedName{Namespace: target, Name: "test-src-remote-00"}
+
+	It("Should push a source to its remote clusters and clean up a cluster it is no longer published to", func() {
+		reconcile()
+		Expect(c.Get(ctx, copyKey, &corev1.Secret{})).Should(Succeed())
+		cp := &corev1.Secret{}
+		Expect(remote.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Data).Should(Equal(src.Data))
+		Expect(cp.Labels).Should(HaveKeyWithValue(sourceLabelNamespace, namespace))
+		Expect(cp.Finalizers).ShouldNot(ContainElement(syncFinalizer))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Finalizers).Should(ContainElement(remoteFinalizerPrefix + "member1"))
+
+		delete(src.Annotations, publishClustersKey)
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(apierrors.IsNotFound(remote.Get(ctx, copyKey, &corev1.Secret{}))).Should(BeTrue())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Finalizers).ShouldNot(ContainElement(remoteFinalizerPrefix + "member1"))
+	})
+
+	It("Should keep syncing the other clusters when a cluster fails", func() {
+		src.Annotations[publishClustersKey] = "member1,member2"
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		result := reconcile()
+		Expect(result.RequeueAfter).Should(Equal(time.Minute))
+		Expect(remote.Get(ctx, copyKey, &corev1.Secret{})).Should(Succeed())
+		events := []string{}
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		Expect(events).Should(ContainElement(And(ContainSubstring(reasonRemoteSyncFailed), ContainSubstring("member2"))))
+	})
+
+	It("Should release the remote copies of a deleted source", func() {
+		reconcile()
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(c.Delete(ctx, src)).Should(Succeed())
+		reconcile()
+		cp := &corev1.Secret{}
+		Expect(remote.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Labels).ShouldNot(HaveKey(sourceLabelNamespace))
+		Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(src), src))).Should(BeTrue())
+	})
+})
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+// conditionPropagated is the condition of a KopyReport that is true once every target holds a current copy
+const conditionPropagated = "Propagated"
+
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopyreports,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=sync.kopy.kot-labs.com,resources=kopyreports/status,verbs=get;update;patch
+
+// reportName returns the name of the KopyReport of src, e.g. secret-db
+func reportName(src client.Object) string {
+	return kindOf(src) + "-" + src.GetName()
+}
+
+// writeReport writes the KopyReport of src with the state of its copies in namespaces after a sync whose updated
+// and failed copies are recorded in outcome. The targets keep the time they were last synced until their copy is
+// written again, so a source that didn't change doesn't update its report.
+func (o Options) writeReport(ctx context.Context, c client.Client, src client.Object, namespaces []corev1.Namespace, outcome *syncOutcome) error {
+	if !o.Reports {
+		return nil
+	}
+	report := &syncv1alpha1.KopyReport{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: src.GetNamespace(), Name: reportName(src)}, report)
+	if apierrors.IsNotFound(err) {
+		if report, err = newReport(c, src); err == nil {
+			err = c.Create(ctx, report)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get report %s: %w", reportName(src), err)
+	}
+	status := reportStatus(report.Status, namespaces, outcome, metav1.NewTime(now()))
+	// suppressed warnings are listed so they can be audited
+	if suppressed := suppressedWarnings(src); suppressed.Len() > 0 {
+		status.Suppressed = sets.List(suppressed)
+	}
+	meta.SetStatusCondition(&status.Conditions, fieldConflictCondition(fieldConflicts.conflicts(src)))
+	if equality.Semantic.DeepEqual(report.Status, status) {
+		return nil
+	}
+	report.Status = status
+	if err := c.Status().Update(ctx, report); err != nil {
+		return fmt.Errorf("unable to update report %s: %w", report.Name, err)
+	}
+	return nil
+}
+
+// newReport returns the KopyReport of src, owned by src so it is garbage collected with it
+func newReport(c client.Client, src client.Object) (*syncv1alpha1.KopyReport, error) {
+	gvk, err := apiutil.GVKForObject(src, c.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	return &syncv1alpha1.KopyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      reportName(src),
+			Namespace: src.GetNamespace(),
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind, Name: src.GetName(), UID: src.GetUID(),
+			}},
+		},
+		Spec: syncv1alpha1.KopyReportSpec{Source: syncv1alpha1.ReportedSource{
+			APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind, Name: src.GetName(),
+		}},
+	}, nil
+}
+
+// reportStatus returns previous updated with the outcome of a sync to namespaces at t
+func reportStatus(previous syncv1alpha1.KopyReportStatus, namespaces []corev1.Namespace, outcome *syncOutcome, t metav1.Time) syncv1alpha1.KopyReportStatus {
+	last := map[string]syncv1alpha1.ReportedTarget{}
+	for _, target := range previous.Namespaces {
+		last[target.Namespace] = target
+	}
+	status := syncv1alpha1.KopyReportStatus{Conditions: slices.Clone(previous.Conditions)}
+	for _, name := range sets.List(namespaceNames(namespaces)) {
+		target := syncv1alpha1.ReportedTarget{Namespace: name, State: syncv1alpha1.TargetPending, LastSyncTime: last[name].LastSyncTime}
+		switch {
+		case outcome.errors[name] != "":
+			target.State, target.Error = syncv1alpha1.TargetFailed, outcome.errors[name]
+			status.Failed++
+		case slices.Contains(outcome.current, name):
+			target.State = syncv1alpha1.TargetSynced
+			if slices.Contains(outcome.targets, name) || last[name].State != syncv1alpha1.TargetSynced || target.LastSyncTime == nil {
+				target.LastSyncTime = &t
+			}
+			status.Synced++
+		}
+		if target.LastSyncTime != nil && (status.LastSyncTime == nil || status.LastSyncTime.Before(target.LastSyncTime)) {
+			status.LastSyncTime = target.LastSyncTime
+		}
+		status.Namespaces = append(status.Namespaces, target)
+	}
+	status.Targets = int32(len(status.Namespaces))
+	condition := metav1.Condition{Type: conditionPropagated, Status: metav1.ConditionTrue, Reason: "Synced",
+		Message: fmt.Sprintf("%d of %d targets hold a copy of the current data", status.Synced, status.Targets)}
+	switch {
+	case status.Failed > 0:
+		condition.Status, condition.Reason = metav1.ConditionFalse, "SyncFailed"
+	case status.Synced < status.Targets:
+		condition.Status, condition.Reason = metav1.ConditionFalse, "Pending"
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+	return status
+}
+
+// deleteReport deletes the KopyReport of src once src is no longer synced and forgets the field manager conflicts of
+// its copies
+func (o Options) deleteReport(ctx context.Context, c client.Client, src client.Object) error {
+	fieldConflicts.forget(src)
+	if !o.Reports {
+		return nil
+	}
+	report := &syncv1alpha1.KopyReport{ObjectMeta: metav1.ObjectMeta{Namespace: src.GetNamespace(), Name: reportName(src)}}
+	return client.IgnoreNotFound(c.Delete(ctx, report))
+}
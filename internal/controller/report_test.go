@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+var _ = Describe("Source reports\n", func() {
+	const (
+		namespace = "test-src-report-ns-00"
+		healthy   = "test-dst-report-ns-00"
+		broken    = "test-dst-report-ns-01"
+	)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	AfterEach(func() { now = time.Now })
+
+	It("Should report the state of every target and delete the report once the source isn't synced", func() {
+		ctx := context.Background()
+		now = func() time.Time { return start }
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-report-00", Namespace: namespace,
+				Annotations: map[string]string{syncKey: "team=payments"},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).Should(Succeed())
+		Expect(syncv1alpha1.AddToScheme(s)).Should(Succeed())
+		failing := true
+		c := interceptor.NewClient(fake.NewClientBuilder().WithScheme(s).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: healthy, Labels: map[string]string{"team": "payments"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: broken, Labels: map[string]string{"team": "payments"}}},
+		).WithStatusSubresource(&syncv1alpha1.KopyReport{}).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.CreateOption) error {
+				if failing && o.GetNamespace() == broken {
+					return errors.New("admission denied")
+				}
+				return c.Create(ctx, o, opts...)
+			},
+		}).Build(), interceptor.Funcs{Patch: fakeApply})
+		reconcile := func() {
+			_, _ = KopyReconcile(NewKopySecret(ctx, c, Options{Reports: true}, record.NewFakeRecorder(20)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+		}
+		report := &syncv1alpha1.KopyReport{}
+		key := types.NamespacedName{Namespace: namespace, Name: "secret-" + src.Name}
+
+		By("Reporting the failed target")
+		reconcile()
+		Expect(c.Get(ctx, key, report)).Should(Succeed())
+		Expect(report.Spec.Source).Should(Equal(syncv1alpha1.ReportedSource{APIVersion: "v1", Kind: "Secret", Name: src.Name}))
+		Expect(report.OwnerReferences).Should(ConsistOf(HaveField("Name", src.Name)))
+		Expect(report.Status.Targets).Should(BeEquivalentTo(2))
+		Expect(report.Status.Synced).Should(BeEquivalentTo(1))
+		Expect(report.Status.Failed).Should(BeEquivalentTo(1))
+		Expect(report.Status.Namespaces).Should(ConsistOf(
+			And(HaveField("Namespace", healthy), HaveField("State", syncv1alpha1.TargetSynced),
+				HaveField("LastSyncTime.Time", BeTemporally("==", start))),
+			And(HaveField("Namespace", broken), HaveField("State", syncv1alpha1.TargetFailed),
+				HaveField("Error", ContainSubstring("admission denied")), HaveField("LastSyncTime", BeNil())),
+		))
+		Expect(meta.IsStatusConditionFalse(report.Status.Conditions, conditionPropagated)).Should(BeTrue())
+
+		By("Keeping the sync time of copies that didn't change")
+		failing = false
+		now = func() time.Time { return start.Add(time.Hour) }
+		reconcile()
+		Expect(c.Get(ctx, key, report)).Should(Succeed())
+		Expect(report.Status.Failed).Should(BeZero())
+		Expect(report.Status.Namespaces).Should(ConsistOf(
+			And(HaveField("Namespace", healthy), HaveField("LastSyncTime.Time", BeTemporally("==", start))),
+			And(HaveField("Namespace", broken), HaveField("State", syncv1alpha1.TargetSynced),
+				HaveField("LastSyncTime.Time", BeTemporally("==", start.Add(time.Hour)))),
+		))
+		Expect(report.Status.LastSyncTime.Time).Should(BeTemporally("==", start.Add(time.Hour)))
+		Expect(meta.IsStatusConditionTrue(report.Status.Conditions, conditionPropagated)).Should(BeTrue())
+
+		By("Deleting the report when the sync annotation is removed")
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		delete(src.Annotations, syncKey)
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(apierrors.IsNotFound(c.Get(ctx, key, report))).Should(BeTrue())
+	})
+})
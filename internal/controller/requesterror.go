@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidRequest is matched by the errors of the exported helpers that are caused by a malformed request, e.g. an
+	// unsupported kind or an invalid label change
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrFailedPrecondition is matched by the errors of the exported helpers whose object isn't in the state the
+	// request requires, e.g. a resync of an object that isn't a kopy source
+	ErrFailedPrecondition = errors.New("failed precondition")
+)
+
+// requestError is an error caused by the request rather than by the API server, its message is the message of err
+type requestError struct {
+	err    error
+	reason error
+}
+
+func (e requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e requestError) Unwrap() error {
+	return e.err
+}
+
+func (e requestError) Is(target error) bool {
+	return target == e.reason
+}
+
+// invalidRequest returns an error matching ErrInvalidRequest
+func invalidRequest(format string, args ...any) error {
+	return requestError{err: fmt.Errorf(format, args...), reason: ErrInvalidRequest}
+}
+
+// failedPrecondition returns an error matching ErrFailedPrecondition
+func failedPrecondition(format string, args ...any) error {
+	return requestError{err: fmt.Errorf(format, args...), reason: ErrFailedPrecondition}
+}
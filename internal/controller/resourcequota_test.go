@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ResourceQuota and LimitRange sync\n", func() {
+	const (
+		namespace = "test-src-quota-ns-00"
+		target    = "test-dst-quota-ns-00"
+	)
+	var (
+		ctx context.Context
+		c   client.Client
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"tier": "tenant"}}},
+		).Build()
+	})
+
+	It("Should copy the spec but not the usage of a resource quota", func() {
+		src := &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-quota-00", Namespace: namespace, Annotations: map[string]string{syncKey: "tier=tenant"}},
+			Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")}},
+			Status:     corev1.ResourceQuotaStatus{Used: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("3")}},
+		}
+		Expect(c.Create(ctx, src)).Should(Succeed())
+		_, err := KopyReconcile(NewKopyResourceQuota(ctx, c, Options{}, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		cp := &corev1.ResourceQuota{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: target, Name: src.Name}, cp)).Should(Succeed())
+		Expect(cp.Finalizers).Should(ContainElement(syncFinalizer))
+		Expect(cp.Spec.Hard.Pods().String()).Should(Equal("10"))
+		Expect(cp.Status.Used).Should(BeEmpty())
+	})
+
+	It("Should copy the limits of a limit range", func() {
+		src := &corev1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-limits-00", Namespace: namespace, Annotations: map[string]string{syncKey: "tier=tenant"}},
+			Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+				Type:           corev1.LimitTypeContainer,
+				DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			}}},
+		}
+		Expect(c.Create(ctx, src)).Should(Succeed())
+		_, err := KopyReconcile(NewKopyLimitRange(ctx, c, Options{}, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		cp := &corev1.LimitRange{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: target, Name: src.Name}, cp)).Should(Succeed())
+		Expect(cp.Labels).Should(HaveKeyWithValue(sourceLabelNamespace, namespace))
+		Expect(cp.Spec).Should(Equal(src.Spec))
+	})
+})
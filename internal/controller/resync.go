@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resyncRequestedKey is set on a namespace to force every source that selects the namespace to be synced again,
+// e.g. after restoring the namespace from a backup that dropped the copies
+const resyncRequestedKey = kopyPrefix + "resync-requested"
+
+const (
+	// resyncRequestKey is set on a source, usually to the current time, to verify and repair every copy of the source
+	// right away without editing its data
+	resyncRequestKey = kopyPrefix + "resync-request"
+	// resyncObservedKey is set by kopy to the value of resyncRequestKey once the request was handled, so automation
+	// can wait for it
+	resyncObservedKey = kopyPrefix + "resync-observed"
+)
+
+// SourceRef identifies a source object
+type SourceRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// SourcesForNamespace returns every source of the enabled kinds whose sync selector matches namespace
+func SourcesForNamespace(ctx context.Context, c client.Client, namespace *corev1.Namespace) ([]SourceRef, error) {
+	topology, err := ExportTopology(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]SourceRef, 0, len(topology.Sources))
+	for _, s := range topology.Sources {
+		if s.Namespace == namespace.Name {
+			continue
+		}
+		ls, err := ParseSyncSelector(s.Selector)
+		if err != nil {
+			continue
+		}
+		if ls.Matches(labels.Set(namespace.Labels)) {
+			sources = append(sources, SourceRef{Kind: s.Kind, Namespace: s.Namespace, Name: s.Name})
+		}
+	}
+	return sources, nil
+}
+
+// RequestNamespaceResync stamps the namespace with the resync annotation. The namespace update is picked up by the
+// namespace watches of the controllers which then resync every source that selects the namespace.
+func RequestNamespaceResync(ctx context.Context, c client.Client, name string) ([]SourceRef, error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+		return nil, err
+	}
+	sources, err := SourcesForNamespace(ctx, c, ns)
+	if err != nil {
+		return nil, err
+	}
+	patch := client.MergeFrom(ns.DeepCopy())
+	if ns.Annotations == nil {
+		ns.Annotations = map[string]string{}
+	}
+	ns.Annotations[resyncRequestedKey] = time.Now().UTC().Format(time.RFC3339)
+	if err := c.Patch(ctx, ns, patch); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// RequestSourceResync stamps the source of kind with the resync request annotation and returns its value. The update
+// triggers a reconcile of the source that rewrites every copy and then sets the resync observed annotation to the
+// same value.
+func RequestSourceResync(ctx context.Context, c client.Client, kind string, key types.NamespacedName) (string, error) {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return "", err
+	}
+	if err := c.Get(ctx, key, src); err != nil {
+		return "", err
+	}
+	if _, ok := SyncSelector(src); !ok {
+		return "", failedPrecondition("%s %s is not a kopy source", kind, key)
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	annotations := src.GetAnnotations()
+	request := time.Now().UTC().Format(time.RFC3339Nano)
+	annotations[resyncRequestKey] = request
+	src.SetAnnotations(annotations)
+	if err := c.Patch(ctx, src, patch); err != nil {
+		return "", err
+	}
+	return request, nil
+}
+
+// ResyncObserved returns true once kopy handled the resync request of the source of kind
+func ResyncObserved(ctx context.Context, c client.Client, kind string, key types.NamespacedName, request string) (bool, error) {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return false, err
+	}
+	if err := c.Get(ctx, key, src); err != nil {
+		return false, err
+	}
+	return src.GetAnnotations()[resyncObservedKey] == request, nil
+}
+
+// observeResyncRequest records that the pending resync request of src, if any, was handled by a sync of its copies
+// that ended with result. Requests stay pending while the sync is requeued.
+func observeResyncRequest(ctx context.Context, c client.Client, src client.Object, result ctrl.Result) error {
+	annotations := src.GetAnnotations()
+	request, ok := annotations[resyncRequestKey]
+	if !ok || result.RequeueAfter > 0 || annotations[resyncObservedKey] == request {
+		return nil
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	annotations[resyncObservedKey] = request
+	src.SetAnnotations(annotations)
+	return c.Patch(ctx, src, patch)
+}
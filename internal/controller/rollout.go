@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxTargetsPerMinuteKey limits how many target namespaces receive a changed copy of a source per minute
+	maxTargetsPerMinuteKey = kopyPrefix + "max-targets-per-minute"
+	// rolloutProgressKey records the start of the current rollout minute and how many copies were written in it, so
+	// the limit holds across controller restarts
+	rolloutProgressKey = kopyPrefix + "rollout-progress"
+
+	rolloutPeriod = time.Minute
+)
+
+// rollout limits how many out of date copies of a source are written per rolloutPeriod
+type rollout struct {
+	limit   int
+	start   time.Time
+	written int
+}
+
+// newRollout returns the rollout of src at t, or nil if src doesn't limit its propagation rate
+func newRollout(src client.Object, t time.Time) (*rollout, error) {
+	annotations := src.GetAnnotations()
+	v, ok := annotations[maxTargetsPerMinuteKey]
+	if !ok {
+		return nil, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 {
+		return nil, fmt.Errorf("invalid %s %q: must be a positive number", maxTargetsPerMinuteKey, v)
+	}
+	r := &rollout{limit: limit, start: t}
+	// progress that can't be parsed or belongs to a past period starts a new period
+	start, written, ok := strings.Cut(annotations[rolloutProgressKey], " ")
+	if !ok {
+		return r, nil
+	}
+	s, err := time.Parse(time.RFC3339, start)
+	if err != nil || !t.Before(s.Add(rolloutPeriod)) || s.After(t) {
+		return r, nil
+	}
+	if n, err := strconv.Atoi(written); err == nil && n > 0 {
+		r.start, r.written = s, n
+	}
+	return r, nil
+}
+
+// take returns true if one more copy may be written in the current period
+func (r *rollout) take() bool {
+	if r.written >= r.limit {
+		return false
+	}
+	r.written++
+	return true
+}
+
+// retryAfter returns how long after t the next period starts
+func (r *rollout) retryAfter(t time.Time) time.Duration {
+	return r.start.Add(rolloutPeriod).Sub(t)
+}
+
+// progress returns the value of the rollout progress annotation
+func (r *rollout) progress() string {
+	return r.start.UTC().Format(time.RFC3339) + " " + strconv.Itoa(r.written)
+}
+
+// saveProgress records the progress of r on src so a restarted controller continues the current period
+func (r *rollout) saveProgress(ctx context.Context, c client.Client, src client.Object) error {
+	if src.GetAnnotations()[rolloutProgressKey] == r.progress() {
+		return nil
+	}
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	annotations := src.GetAnnotations()
+	annotations[rolloutProgressKey] = r.progress()
+	src.SetAnnotations(annotations)
+	return c.Patch(ctx, src, patch)
+}
+
+// needsPropagation returns true if the copy of src in targetNamespace is missing or its data differs from src
+func needsPropagation(ctx context.Context, c client.Client, src client.Object, targetNamespace string) bool {
+	cp, err := findCopy(ctx, c, src, targetNamespace)
+	if err != nil {
+		return true
+	}
+	for _, d := range diffData(objectData(src), objectData(cp)) {
+		if d.Status != KeyUnchanged {
+			return true
+		}
+	}
+	return false
+}
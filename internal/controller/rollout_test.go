@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testRolloutSnapshot = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-src-rollout-ns-00
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-rollout-ns-00
+    labels:
+      env: prod
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-rollout-ns-01
+    labels:
+      env: prod
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-rollout-ns-02
+    labels:
+      env: prod
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: test-src-rollout-00
+    namespace: test-src-rollout-ns-00
+    annotations:
+      kopy.kot-labs.com/sync: env=prod
+      kopy.kot-labs.com/max-targets-per-minute: "2"
+  data:
+    password: dGVzdC1zcmMtcm9sbG91dC0wMA==
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: test-src-rollout-00
+    namespace: test-target-rollout-ns-02
+    labels:
+      kopy.kot-labs.com/origin.namespace: test-src-rollout-ns-00
+      kopy.kot-labs.com/origin.name: test-src-rollout-00
+    finalizers:
+    - kopy.kot-labs.com/finalizer
+  data:
+    password: dGVzdC1zcmMtcm9sbG91dC0wMA==
+`
+
+var _ = Describe("Propagation rate limit\n", func() {
+	start := time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+	simulateAt := func(t time.Time, progress string) *SimulationResult {
+		now = func() time.Time { return t }
+		objects, err := LoadSnapshot(strings.NewReader(testRolloutSnapshot))
+		Expect(err).ShouldNot(HaveOccurred())
+		if progress != "" {
+			for _, o := range objects {
+				if o.GetNamespace() == "test-src-rollout-ns-00" {
+					o.GetAnnotations()[rolloutProgressKey] = progress
+				}
+			}
+		}
+		result, err := Simulate(context.Background(), objects, Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result.Errors).Should(BeEmpty())
+		return result
+	}
+	created := func(target string) SimulatedAction {
+		return SimulatedAction{Verb: "create", Kind: "secret", Namespace: target, Name: "test-src-rollout-00"}
+	}
+	AfterEach(func() {
+		now = time.Now
+	})
+	It("Should only write as many out of date copies per minute as the source allows", func() {
+		result := simulateAt(start, "")
+		Expect(result.Actions).Should(ContainElements(created("test-target-rollout-ns-00"), created("test-target-rollout-ns-01")))
+		Expect(result.Converged).Should(BeTrue())
+	})
+	It("Should continue the current minute after a restart", func() {
+		By("Reconciling within the minute of the recorded progress")
+		result := simulateAt(start.Add(30*time.Second), start.Format(time.RFC3339)+" 2")
+		Expect(result.Actions).ShouldNot(ContainElement(created("test-target-rollout-ns-00")))
+
+		By("Reconciling after the minute of the recorded progress")
+		result = simulateAt(start.Add(90*time.Second), start.Format(time.RFC3339)+" 2")
+		Expect(result.Actions).Should(ContainElement(created("test-target-rollout-ns-00")))
+	})
+	It("Should not count copies that are up to date", func() {
+		result := simulateAt(start, start.Format(time.RFC3339)+" 1")
+		Expect(result.Actions).Should(ContainElement(created("test-target-rollout-ns-00")))
+		Expect(result.Actions).ShouldNot(ContainElement(created("test-target-rollout-ns-01")))
+	})
+})
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// nextSuffix is appended to the name of a source for the next version of the source staged for a rotation
+	nextSuffix = "-next"
+	// nextOfKey is set on the next version of a source to the name of the source it replaces once promoted
+	nextOfKey = kopyPrefix + "next-of"
+)
+
+// nextAnnotations are the annotations of a source the next version takes over, so it is synced to the same targets
+var nextAnnotations = []string{syncKey, excludeFromBackupKey, maxTargetsPerMinuteKey}
+
+// nextName returns the name of the next version of the source name
+func nextName(name string) string {
+	return name + nextSuffix
+}
+
+// setNextAnnotations sets the annotations next takes over from src on next. A source that keeps a local copy keeps
+// one for its next version too, named after the local copy.
+func setNextAnnotations(src, next client.Object) {
+	annotations := next.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for _, k := range append(nextAnnotations, localCopyKey) {
+		delete(annotations, k)
+		if v, ok := src.GetAnnotations()[k]; ok {
+			annotations[k] = v
+		}
+	}
+	if v, ok := annotations[localCopyKey]; ok {
+		annotations[localCopyKey] = nextName(v)
+	}
+	annotations[nextOfKey] = src.GetName()
+	next.SetAnnotations(annotations)
+}
+
+// swapPayload exchanges the data of the objects a and b of the same kind
+func swapPayload(a, b client.Object) error {
+	switch a := a.(type) {
+	case *corev1.Secret:
+		b := b.(*corev1.Secret)
+		if a.Type != b.Type {
+			return fmt.Errorf("secret %s is of type %s but %s is of type %s", a.Name, a.Type, b.Name, b.Type)
+		}
+		a.Data, b.Data = b.Data, a.Data
+	case *corev1.ConfigMap:
+		b := b.(*corev1.ConfigMap)
+		a.Data, b.Data = b.Data, a.Data
+		a.BinaryData, b.BinaryData = b.BinaryData, a.BinaryData
+	case *unstructured.Unstructured:
+		b := b.(*unstructured.Unstructured)
+		pa, pb := unstructuredPayload(a), unstructuredPayload(b)
+		for k := range pa {
+			delete(a.Object, k)
+		}
+		for k := range pb {
+			delete(b.Object, k)
+		}
+		for k, v := range pb {
+			a.Object[k] = v
+		}
+		for k, v := range pa {
+			b.Object[k] = v
+		}
+	default:
+		return fmt.Errorf("unsupported kind %T", a)
+	}
+	return nil
+}
+
+// StageNext creates the next version of the source of kind next to it, named with the -next suffix, with the data of
+// the source and the annotations that sync it to the same targets. The data of the next version is then replaced
+// with the new credentials, which kopy syncs to every target next to the current copy.
+func StageNext(ctx context.Context, c client.Client, kind string, key types.NamespacedName) error {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return err
+	}
+	if err := c.Get(ctx, key, src); err != nil {
+		return err
+	}
+	if _, ok := src.GetAnnotations()[syncKey]; !ok {
+		return fmt.Errorf("%s %s is not a source", kind, key)
+	}
+	if of, ok := src.GetAnnotations()[nextOfKey]; ok {
+		return fmt.Errorf("%s %s is already the next version of %s", kind, key, of)
+	}
+	next := src.DeepCopyObject().(client.Object)
+	next.SetName(nextName(src.GetName()))
+	next.SetResourceVersion("")
+	next.SetUID("")
+	next.SetGeneration(0)
+	next.SetCreationTimestamp(metav1.Time{})
+	next.SetDeletionTimestamp(nil)
+	next.SetFinalizers(nil)
+	next.SetOwnerReferences(nil)
+	next.SetManagedFields(nil)
+	next.SetAnnotations(nil)
+	setNextAnnotations(src, next)
+	return c.Create(ctx, next)
+}
+
+// PromoteNext swaps the data of the source of kind with the data of its next version, so every copy of the source
+// switches to the new data with a single write while the copies of the next version keep the previous data for a
+// rollback, which is another promotion. The next version is deleted once it is no longer needed, which removes its
+// copies.
+func PromoteNext(ctx context.Context, c client.Client, kind string, key types.NamespacedName) error {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return err
+	}
+	if err := c.Get(ctx, key, src); err != nil {
+		return err
+	}
+	next, _ := NewObjectForKind(kind)
+	if err := c.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: nextName(key.Name)}, next); err != nil {
+		return fmt.Errorf("unable to get the next version of %s %s: %w", kind, key, err)
+	}
+	if next.GetAnnotations()[nextOfKey] != src.GetName() {
+		return fmt.Errorf("%s %s/%s is not the next version of %s", kind, next.GetNamespace(), next.GetName(), key)
+	}
+	if err := swapPayload(src, next); err != nil {
+		return err
+	}
+	setNextAnnotations(src, next)
+	// the source is written first, a failure of the second write only loses the previous data kept for a rollback
+	if err := c.Update(ctx, src); err != nil {
+		return err
+	}
+	if err := c.Update(ctx, next); err != nil {
+		return fmt.Errorf("promoted %s %s but unable to keep the previous data on its next version: %w", kind, key, err)
+	}
+	return nil
+}
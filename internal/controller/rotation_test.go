@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Credential rotation\n", func() {
+	const (
+		namespace = "test-src-rotation-ns-00"
+		target    = "test-dst-rotation-ns-00"
+	)
+	It("Should sync the next version of a source next to it and swap their data on promotion", func() {
+		ctx := context.Background()
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-rotation-00", Namespace: namespace, Annotations: map[string]string{syncKey: "env=prod"}},
+			Data:       map[string][]byte{"password": []byte("current")},
+		}
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"env": "prod"}}},
+		).Build()
+		key := client.ObjectKeyFromObject(src)
+		nextKey := types.NamespacedName{Namespace: namespace, Name: src.Name + nextSuffix}
+		reconcile := func(req types.NamespacedName) {
+			_, err := KopyReconcile(NewKopySecret(ctx, c, Options{}, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: req}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		copyData := func(name string) map[string][]byte {
+			cp := &corev1.Secret{}
+			Expect(c.Get(ctx, types.NamespacedName{Namespace: target, Name: name}, cp)).Should(Succeed())
+			return cp.Data
+		}
+
+		By("Staging the next version")
+		reconcile(key)
+		Expect(StageNext(ctx, c, "secret", key)).Should(Succeed())
+		Expect(StageNext(ctx, c, "secret", nextKey)).ShouldNot(Succeed())
+		next := &corev1.Secret{}
+		Expect(c.Get(ctx, nextKey, next)).Should(Succeed())
+		Expect(next.Annotations).Should(And(HaveKeyWithValue(nextOfKey, src.Name), HaveKeyWithValue(syncKey, "env=prod")))
+		next.Data = map[string][]byte{"password": []byte("rotated")}
+		Expect(c.Update(ctx, next)).Should(Succeed())
+		reconcile(nextKey)
+		Expect(copyData(nextKey.Name)).Should(HaveKeyWithValue("password", []byte("rotated")))
+		Expect(copyData(src.Name)).Should(HaveKeyWithValue("password", []byte("current")))
+
+		By("Promoting the next version")
+		Expect(PromoteNext(ctx, c, "secret", key)).Should(Succeed())
+		reconcile(key)
+		reconcile(nextKey)
+		Expect(copyData(src.Name)).Should(HaveKeyWithValue("password", []byte("rotated")))
+		Expect(copyData(nextKey.Name)).Should(HaveKeyWithValue("password", []byte("current")))
+		Expect(PromoteNext(ctx, c, "secret", nextKey)).ShouldNot(Succeed())
+	})
+})
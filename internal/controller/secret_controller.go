@@ -2,27 +2,33 @@ package controller
 
 import (
 	"context"
-	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
-	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // SecretReconciler reconciles a Secret object
 type SecretReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme  *runtime.Scheme
+	Options Options
+
+	recorder record.EventRecorder
+	tracker  *syncTracker
 }
 
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=secrets/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -34,49 +40,48 @@ type SecretReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.4/pkg/reconcile
 func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	ks := NewKopySecret(ctx, r.Client)
-	return KopyReconcile(ks, req)
+	ks := NewKopySecret(ctx, r.Client, r.Options, r.recorder)
+	result, err := KopyReconcile(ks, req, r.tracker)
+	debugState.recordError("secret", err)
+	return result, err
 }
 
+// watchNamespaces maps a namespace event to the source Secrets whose sync selector matches the namespace
 func (r *SecretReconciler) watchNamespaces(ctx context.Context, namespace client.Object) []reconcile.Request {
-	log := ctrllog.FromContext(ctx)
 	if isNamespaceMarkedForDelete(ctx, r.Client, namespace.GetName()) {
 		return nil
 	}
-	secrets := &corev1.SecretList{}
-	if err := r.List(ctx, secrets); err != nil {
-		log.Info("unable to grab a list of secrets")
-		return nil
-	}
-	req := make([]reconcile.Request, len(secrets.Items))
-	for i, s := range secrets.Items {
-		v, ok := s.Annotations[syncKey]
-		if !ok {
-			continue
-		}
-		syncLabel := strings.Split(v, "=")
-		labelKey := syncLabel[0]
-		labelValue := syncLabel[1]
-		nsLabels := namespace.GetLabels()
-		if nsLabels[labelKey] == labelValue {
-			req[i] = reconcile.Request{NamespacedName: types.NamespacedName{
-				Namespace: s.GetNamespace(),
-				Name:      s.GetName(),
-			}}
-			log.Info("need to add reconcile queue", "secret", s.GetName(), "sourceNamespace", s.GetNamespace(), "targetNamespace", namespace.GetName())
-		}
-
-	}
-	return req
+	_, sources := r.Options.secretSources()
+	return r.Options.sourcesSelecting(ctx, r.Client, sources, namespace)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Secret{}).
-		Watches(&corev1.Namespace{},
+	r.recorder = mgr.GetEventRecorderFor("kopy-secret-controller")
+	r.tracker = newSyncTracker(r.Options.SyncDeadline, r.recorder)
+	forOpts := []builder.ForOption{}
+	if r.Options.SecretMetadataOnly {
+		forOpts = append(forOpts, builder.OnlyMetadata)
+	}
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Secret{}, forOpts...).
+		WithOptions(controller.Options{
+			NewQueue:                r.Options.newQueue(),
+			MaxConcurrentReconciles: r.Options.MaxConcurrentReconciles,
+		})
+	debugState.watch("secret", "Secret")
+	// namespaces can only be watched with cluster wide permissions
+	if !r.Options.NamespaceScoped() {
+		debugState.watch("secret", "Secret", "Namespace")
+		source, _ := r.Options.secretSources()
+		if err := setupSyncSelectorIndex(mgr, source); err != nil {
+			return err
+		}
+		// only labels, annotations and the deletion timestamp of namespaces are used
+		b = b.Watches(&corev1.Namespace{},
 			handler.EnqueueRequestsFromMapFunc(r.watchNamespaces),
-			// builder.WithPredicates(p),
-		).
-		Complete(r)
+			builder.OnlyMetadata,
+		)
+	}
+	return b.Complete(r)
 }
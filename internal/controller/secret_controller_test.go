@@ -19,9 +19,14 @@ import (
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
+	"github.com/flynshue/kopy/pkg/testenv"
+
 	cryptorand "crypto/rand"
 )
 
@@ -37,8 +42,9 @@ var _ = Describe("Secret Controller\n", func() {
 			}{
 				name: "test-secret-00", namespace: "test-src-secret-ns-00", secret: &corev1.Secret{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(func() bool {
 				err := tc.GetNamespace(src.namespace, &corev1.Namespace{})
 				return err == nil
@@ -120,8 +126,9 @@ var _ = Describe("Secret Controller\n", func() {
 			}{
 				name: "test-secret-01", namespace: "test-src-secret-ns-01", secret: &corev1.Secret{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(func() bool {
 				err := tc.GetNamespace(src.namespace, &corev1.Namespace{})
 				return err == nil
@@ -166,8 +173,9 @@ var _ = Describe("Secret Controller\n", func() {
 			}{
 				namespace: "test-src-secret-ns-02", secret: &corev1.Secret{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			By("Creating new source secret with 253 characters")
@@ -206,8 +214,9 @@ var _ = Describe("Secret Controller\n", func() {
 				name: "test-secret-03", namespace: "test-src-secret-ns-03", secret: &corev1.Secret{},
 			}
 			tc = NewTestClient(context.Background())
-			_, err := tc.CreateNamespace(src.namespace, nil)
-			Expect(err).ToNot(HaveOccurred())
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			label := &syncLabel{key: testLabelKey, value: src.name}
@@ -226,14 +235,16 @@ var _ = Describe("Secret Controller\n", func() {
 				{name: "target-tt-secret-02", namespace: &corev1.Namespace{}, secret: &corev1.Secret{}},
 				{name: "target-tt-secret-03", namespace: &corev1.Namespace{}, secret: &corev1.Secret{}},
 			}
-			for _, t := range testCases {
+			for i := range testCases {
+				t := &testCases[i]
 				t.namespace, err = tc.CreateNamespace(t.name, label)
 				Expect(err).ShouldNot(HaveOccurred())
+				t.name = t.namespace.Name
 				Eventually(tc.GetNamespace(t.name, t.namespace), timeout, interval).Should(Succeed())
-				Eventually(func() bool {
-					tc.GetSecret(src.name, t.name, t.secret)
-					return reflect.DeepEqual(src.secret.Data, t.secret.Data)
-				}, timeout, interval).Should(BeTrue())
+				t.secret.Name, t.secret.Namespace = src.name, t.name
+				Eventually(testenv.Fetch(tc.ctx, k8sClient, t.secret), timeout, interval).Should(And(
+					testenv.BeSyncedFrom(src.secret), testenv.HaveKopyFinalizer(),
+				))
 			}
 
 			By("Deleting source secret")
@@ -244,21 +255,9 @@ var _ = Describe("Secret Controller\n", func() {
 				return apierrors.IsNotFound(err)
 			}, timeout, interval).Should(BeTrue())
 
-			By("Verifying finalizer has been removed from copies")
+			By("Verifying finalizer and labels have been removed from copies")
 			for _, t := range testCases {
-				Eventually(func() bool {
-					tc.GetSecret(src.name, t.name, t.secret)
-					return !slices.Contains(t.secret.Finalizers, syncFinalizer)
-				}, timeout, interval).Should(BeTrue())
-			}
-
-			By("Verifying label has been removed from copies")
-			for _, t := range testCases {
-				Eventually(func() bool {
-					tc.GetSecret(src.name, t.name, t.secret)
-					_, ok := t.secret.Labels[sourceLabelNamespace]
-					return !ok
-				}, timeout, interval).Should(BeTrue())
+				Eventually(testenv.Fetch(tc.ctx, k8sClient, t.secret), timeout, interval).Should(testenv.BeOrphaned())
 			}
 		})
 	})
@@ -273,8 +272,9 @@ var _ = Describe("Secret Controller\n", func() {
 				name: "test-src-secret-04", namespace: "test-src-secret-ns-04", secret: &corev1.Secret{},
 			}
 			tc = NewTestClient(context.Background())
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			label := &syncLabel{key: "kopy-sync", value: src.name}
@@ -323,8 +323,9 @@ var _ = Describe("Secret Controller\n", func() {
 			}{
 				name: "test-src-secret-07", namespace: "test-src-secret-ns-07", secret: &corev1.Secret{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 			label := &syncLabel{key: testLabelKey, value: src.name}
 			configJson := `{"auths":{"https://registry.kopy.io":{"username":"kopy","password":"kopysecret"}}}`
@@ -371,8 +372,9 @@ var _ = Describe("Secret Controller\n", func() {
 			}{
 				name: "test-src-secret-08", namespace: "test-src-secret-ns-08", secret: &corev1.Secret{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 			label := &syncLabel{key: testLabelKey, value: src.name}
 			certs, key, err := generateSelfSignedCert("k8s.kopy.io")
@@ -444,8 +446,9 @@ var _ = Describe("Secret Controller\n", func() {
 			}{
 				name: "test-src-secret-09", namespace: "test-src-secret-ns-09", secret: &corev1.Secret{},
 			}
-			_, err := tc.CreateNamespace(src.namespace, nil)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 			label := &syncLabel{key: testLabelKey, value: src.name}
 			data := map[string][]byte{"password": []byte(src.name)}
@@ -482,8 +485,9 @@ var _ = Describe("Secret Controller\n", func() {
 			}{
 				name: "test-src-secret-09", namespace: "test-src-secret-dup-ns-09", secret: &corev1.Secret{},
 			}
-			_, err = tc.CreateNamespace(duplicate.namespace, nil)
+			duplicateNamespace, err := tc.CreateNamespace(duplicate.namespace, nil)
 			Expect(err).ShouldNot(HaveOccurred())
+			duplicate.namespace = duplicateNamespace.Name
 			Eventually(tc.GetNamespace(duplicate.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			duplicate.secret, err = tc.CreateSecret(duplicate.name, duplicate.namespace, label, data, corev1.SecretTypeOpaque)
@@ -521,8 +525,9 @@ var _ = Describe("Secret Controller\n", func() {
 				name: "test-src-secret-10", namespace: "test-src-secret-ns-10", secret: &corev1.Secret{},
 			}
 			label := &syncLabel{key: testLabelKey, value: src.name}
-			_, err := tc.CreateNamespace(src.namespace, label)
+			srcNamespace, err := tc.CreateNamespace(src.namespace, label)
 			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
 			Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 			By("Creating new source secret")
@@ -559,6 +564,195 @@ var _ = Describe("Secret Controller\n", func() {
 
 		})
 	})
+	Context("When looking up the origin of a copy", func() {
+		It("Should resolve the copy back to the source secret", func() {
+			By("Creating source namespace and secret")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				secret    *corev1.Secret
+			}{
+				name: "test-src-secret-11", namespace: "test-src-secret-ns-11", secret: &corev1.Secret{},
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			data := map[string][]byte{"password": []byte(src.name)}
+			src.secret, err = tc.CreateSecret(src.name, src.namespace, label, data, corev1.SecretTypeOpaque)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Creating target namespace and waiting for copy")
+			targetNamespace, err := tc.CreateNamespace("test-target-secret-ns-11", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			copy := &corev1.Secret{}
+			Eventually(func() error {
+				return tc.GetSecret(src.name, targetNamespace.Name, copy)
+			}, timeout, interval).Should(Succeed())
+			Expect(copy.Labels).Should(HaveKeyWithValue(sourceLabelName, src.name))
+
+			By("Looking up the origin of the copy")
+			key := types.NamespacedName{Namespace: targetNamespace.Name, Name: src.name}
+			Eventually(func() bool {
+				origin, err := LookupOrigin(tc.ctx, k8sClient, "secret", key)
+				if err != nil {
+					return false
+				}
+				return origin.SourceNamespace == src.namespace && origin.SourceName == src.name && origin.Current
+			}, timeout, interval).Should(BeTrue())
+
+			By("Verify the source secret is not reported as a copy")
+			_, err = LookupOrigin(tc.ctx, k8sClient, "secret", types.NamespacedName{Namespace: src.namespace, Name: src.name})
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+	Context("When source secret opts in to backup exclusion", func() {
+		It("Should add the backup exclusion labels to the copies", func() {
+			By("Creating source namespace and secret")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				secret    *corev1.Secret
+			}{
+				name: "test-src-secret-12", namespace: "test-src-secret-ns-12", secret: &corev1.Secret{},
+			}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			data := map[string][]byte{"password": []byte(src.name)}
+			src.secret, err = tc.CreateSecret(src.name, src.namespace, label, data, corev1.SecretTypeOpaque)
+			Expect(err).ShouldNot(HaveOccurred())
+			Eventually(func() error {
+				if err := tc.GetSecret(src.name, src.namespace, src.secret); err != nil {
+					return err
+				}
+				src.secret.Annotations[excludeFromBackupKey] = "true"
+				return tc.UpdateSecret(src.secret)
+			}, timeout, interval).Should(Succeed())
+
+			By("Creating target namespace and checking the copy labels")
+			targetNamespace, err := tc.CreateNamespace("test-target-secret-ns-12", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			Eventually(func() map[string]string {
+				copy := &corev1.Secret{}
+				tc.GetSecret(src.name, targetNamespace.Name, copy)
+				return copy.Labels
+			}, timeout, interval).Should(HaveKeyWithValue("velero.io/exclude-from-backup", "true"))
+		})
+	})
+	Context("When the target namespace has a copy of a different source", func() {
+		It("Should emit a SyncBlocked event on the source secret", func() {
+			By("Creating target namespace with a conflicting copy")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				secret    *corev1.Secret
+			}{
+				name: "test-src-secret-13", namespace: "test-src-secret-ns-13", secret: &corev1.Secret{},
+			}
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			targetNamespace, err := tc.CreateNamespace("test-target-secret-ns-13", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			conflict := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      src.name,
+					Namespace: targetNamespace.Name,
+					Labels:    map[string]string{sourceLabelNamespace: "another-source-ns"},
+				},
+			}
+			Expect(k8sClient.Create(tc.ctx, conflict)).ShouldNot(HaveOccurred())
+
+			By("Creating source namespace and secret")
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			data := map[string][]byte{"password": []byte(src.name)}
+			src.secret, err = tc.CreateSecret(src.name, src.namespace, label, data, corev1.SecretTypeOpaque)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Verifying the event was emitted")
+			Eventually(func() []string {
+				events := &corev1.EventList{}
+				if err := k8sClient.List(tc.ctx, events, client.InNamespace(src.namespace)); err != nil {
+					return nil
+				}
+				reasons := []string{}
+				for _, e := range events.Items {
+					if e.InvolvedObject.Name == src.name {
+						reasons = append(reasons, e.Reason)
+					}
+				}
+				return reasons
+			}, timeout, interval).Should(ContainElement(reasonSyncBlocked))
+		})
+	})
+	Context("When annotation is removed from source", func() {
+		It("Should remove finalizer from source and copies", func() {
+			By("Creating source namespace and secret")
+			tc = NewTestClient(context.Background())
+			src := struct {
+				name      string
+				namespace string
+				secret    *corev1.Secret
+			}{
+				name: "test-src-secret-14", namespace: "test-src-secret-ns-14", secret: &corev1.Secret{},
+			}
+			label := &syncLabel{key: testLabelKey, value: src.name}
+			srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			src.namespace = srcNamespace.Name
+			data := map[string][]byte{"password": []byte(src.name)}
+			src.secret, err = tc.CreateSecret(src.name, src.namespace, label, data, corev1.SecretTypeOpaque)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Creating target namespace and waiting for copy")
+			targetNamespace, err := tc.CreateNamespace("test-target-secret-ns-14", label)
+			Expect(err).ShouldNot(HaveOccurred())
+			targetSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return tc.GetSecret(src.name, targetNamespace.Name, targetSecret)
+			}, timeout, interval).Should(Succeed())
+
+			By("Removing annotations from source")
+			Eventually(func() error {
+				if err := tc.GetSecret(src.name, src.namespace, src.secret); err != nil {
+					return err
+				}
+				src.secret.Annotations = map[string]string{}
+				return tc.UpdateSecret(src.secret)
+			}, timeout, interval).Should(Succeed())
+
+			By("Verifying finalizers have been removed")
+			Eventually(func() bool {
+				tc.GetSecret(src.name, targetNamespace.Name, targetSecret)
+				return slices.Contains(targetSecret.Finalizers, syncFinalizer)
+			}, timeout, interval).Should(BeFalse())
+			Expect(targetSecret.Labels).ShouldNot(HaveKey(sourceLabelNamespace))
+			Eventually(func() bool {
+				tc.GetSecret(src.name, src.namespace, src.secret)
+				return slices.Contains(src.secret.Finalizers, syncFinalizer)
+			}, timeout, interval).Should(BeFalse())
+
+			By("Verifying the copy records why it was released")
+			Eventually(func() []string {
+				events := &corev1.EventList{}
+				if err := k8sClient.List(tc.ctx, events, client.InNamespace(targetNamespace.Name)); err != nil {
+					return nil
+				}
+				reasons := []string{}
+				for _, e := range events.Items {
+					if e.InvolvedObject.Name == src.name {
+						reasons = append(reasons, e.Reason)
+					}
+				}
+				return reasons
+			}, timeout, interval).Should(ContainElement(reasonCopyReleased))
+		})
+	})
 	if useKind {
 		Context("When namespace that contains copy is deleted", func() {
 			It("The namespace should be deleted properly", func() {
@@ -571,8 +765,9 @@ var _ = Describe("Secret Controller\n", func() {
 				}{
 					name: "test-src-secret-05", namespace: "test-src-secret-ns-05", secret: &corev1.Secret{},
 				}
-				_, err := tc.CreateNamespace(src.namespace, nil)
+				srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
 				Expect(err).ShouldNot(HaveOccurred())
+				src.namespace = srcNamespace.Name
 				Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 				label := &syncLabel{key: testLabelKey, value: src.name}
 				data := map[string][]byte{"password": []byte(src.name)}
@@ -630,8 +825,9 @@ var _ = Describe("Secret Controller\n", func() {
 					name: "test-secret-06", namespace: "test-src-secret-ns-06", secret: &corev1.Secret{},
 				}
 				tc = NewTestClient(context.Background())
-				_, err := tc.CreateNamespace(src.namespace, nil)
-				Expect(err).ToNot(HaveOccurred())
+				srcNamespace, err := tc.CreateNamespace(src.namespace, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				src.namespace = srcNamespace.Name
 				Eventually(tc.GetNamespace(src.namespace, &corev1.Namespace{}), timeout, interval).Should(Succeed())
 
 				label := &syncLabel{key: testLabelKey, value: src.name}
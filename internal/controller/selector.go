@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SyncAnnotation is the annotation that selects the namespaces a source is copied to
+const SyncAnnotation = syncKey
+
+// SyncSelector returns the value of the sync annotation on o and whether it is set
+func SyncSelector(o client.Object) (string, bool) {
+	v, ok := o.GetAnnotations()[syncKey]
+	return v, ok
+}
+
+// SetSyncSelector sets the sync annotation on o to selector
+func SetSyncSelector(o client.Object, selector string) {
+	annotations := o.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[syncKey] = selector
+	o.SetAnnotations(annotations)
+}
+
+// ParseSyncSelector parses the value of a sync annotation in any supported format into a label selector
+func ParseSyncSelector(v string) (labels.Selector, error) {
+	format, body := DetectSyncFormat(v)
+	codec, ok := syncFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid sync selector %q: unsupported format %q", v, format)
+	}
+	ls, err := codec.parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync selector %q: %w", v, err)
+	}
+	return ls, nil
+}
+
+// AddSyncTarget returns the selector with target added. A target ns/<name> adds the namespace to the namespace name
+// requirement, any other target is parsed as a selector and its requirements are added to selector. A bare label key
+// is refused since it could be meant as either. The result keeps the format of selector.
+func AddSyncTarget(selector, target string) (string, error) {
+	v, err := addSyncTarget(selector, target)
+	if err != nil {
+		return "", err
+	}
+	return inSyncFormatOf(selector, v)
+}
+
+func addSyncTarget(selector, target string) (string, error) {
+	reqs, err := requirements(selector)
+	if err != nil {
+		return "", err
+	}
+	target, isNamespace, err := parseSyncTarget(target)
+	if err != nil {
+		return "", err
+	}
+	if isNamespace {
+		i := slices.IndexFunc(reqs, isNamespaceNameRequirement)
+		if i < 0 {
+			if len(reqs) > 0 {
+				return "", fmt.Errorf("selector %q selects namespaces by label; namespace %s can only be added to a selector made of namespace names", selector, target)
+			}
+			r, err := labels.NewRequirement(corev1.LabelMetadataName, selection.In, []string{target})
+			if err != nil {
+				return "", err
+			}
+			return labels.NewSelector().Add(*r).String(), nil
+		}
+		values := reqs[i].Values().Insert(target)
+		r, err := labels.NewRequirement(corev1.LabelMetadataName, selection.In, values.UnsortedList())
+		if err != nil {
+			return "", err
+		}
+		reqs[i] = *r
+		return labels.NewSelector().Add(reqs...).String(), nil
+	}
+	targetReqs, err := requirements(target)
+	if err != nil {
+		return "", err
+	}
+	for _, tr := range targetReqs {
+		if !slices.ContainsFunc(reqs, func(r labels.Requirement) bool { return r.Equal(tr) }) {
+			reqs = append(reqs, tr)
+		}
+	}
+	return labels.NewSelector().Add(reqs...).String(), nil
+}
+
+// RemoveSyncTarget returns the selector with target removed. It refuses to return an empty selector because an
+// empty selector matches every namespace in the cluster. The result keeps the format of selector.
+func RemoveSyncTarget(selector, target string) (string, error) {
+	v, err := removeSyncTarget(selector, target)
+	if err != nil {
+		return "", err
+	}
+	return inSyncFormatOf(selector, v)
+}
+
+func removeSyncTarget(selector, target string) (string, error) {
+	reqs, err := requirements(selector)
+	if err != nil {
+		return "", err
+	}
+	target, isNamespace, err := parseSyncTarget(target)
+	if err != nil {
+		return "", err
+	}
+	if isNamespace {
+		i := slices.IndexFunc(reqs, isNamespaceNameRequirement)
+		if i < 0 || !reqs[i].Values().Has(target) {
+			return "", fmt.Errorf("namespace %s is not listed in selector %q", target, selector)
+		}
+		values := reqs[i].Values().Delete(target)
+		if values.Len() == 0 {
+			reqs = slices.Delete(reqs, i, i+1)
+		} else {
+			r, err := labels.NewRequirement(corev1.LabelMetadataName, selection.In, values.UnsortedList())
+			if err != nil {
+				return "", err
+			}
+			reqs[i] = *r
+		}
+	} else {
+		targetReqs, err := requirements(target)
+		if err != nil {
+			return "", err
+		}
+		for _, tr := range targetReqs {
+			i := slices.IndexFunc(reqs, func(r labels.Requirement) bool { return r.Equal(tr) })
+			if i < 0 {
+				return "", fmt.Errorf("requirement %q is not part of selector %q", tr.String(), selector)
+			}
+			reqs = slices.Delete(reqs, i, i+1)
+		}
+	}
+	if len(reqs) == 0 {
+		return "", fmt.Errorf("removing %s would leave an empty selector that matches every namespace; remove the sync annotation instead", target)
+	}
+	return labels.NewSelector().Add(reqs...).String(), nil
+}
+
+// MatchingNamespaces returns the names of the namespaces that selector would sync a source in sourceNamespace to
+func MatchingNamespaces(ctx context.Context, c client.Client, sourceNamespace, selector string) ([]string, error) {
+	ls, err := ParseSyncSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: sourceNamespace}}
+	namespaces, err := getSyncNamespaces(ctx, c, req, ls, nil)
+	if err != nil {
+		return nil, err
+	}
+	return namespaceNames(namespaces).UnsortedList(), nil
+}
+
+// inSyncFormatOf converts the v1 selector v to the format of the sync annotation value original
+func inSyncFormatOf(original, v string) (string, error) {
+	format, _ := DetectSyncFormat(original)
+	if format == SyncFormatV1 {
+		return v, nil
+	}
+	return ConvertSyncSelector(v, format)
+}
+
+func requirements(selector string) ([]labels.Requirement, error) {
+	ls, err := ParseSyncSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	reqs, _ := ls.Requirements()
+	return reqs, nil
+}
+
+// syncTargetNamespacePrefix marks a target of AddSyncTarget and RemoveSyncTarget as a namespace name
+const syncTargetNamespacePrefix = "ns/"
+
+// parseSyncTarget returns the namespace name of a target ns/<name>, or the target itself if it is a selector. A bare
+// label key like tier is refused, it would select the namespaces with the label but is easily meant as a namespace.
+func parseSyncTarget(target string) (string, bool, error) {
+	if name, ok := strings.CutPrefix(target, syncTargetNamespacePrefix); ok {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return "", false, fmt.Errorf("invalid namespace name %q: %s", name, strings.Join(errs, ", "))
+		}
+		return name, true, nil
+	}
+	if len(validation.IsDNS1123Label(target)) == 0 {
+		return "", false, fmt.Errorf("ambiguous target %q, use %s%s for the namespace or a requirement with a value, "+
+			"e.g. %s=<value>, for the label", target, syncTargetNamespacePrefix, target, target)
+	}
+	return target, false, nil
+}
+
+func isNamespaceNameRequirement(r labels.Requirement) bool {
+	return r.Key() == corev1.LabelMetadataName && (r.Operator() == selection.In || r.Operator() == selection.Equals)
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// SyncFormat is the version of the format of a sync annotation value
+type SyncFormat string
+
+const (
+	// SyncFormatV1 is a label selector string, e.g. "env=prod,team in (a,b)"
+	SyncFormatV1 SyncFormat = "v1"
+	// SyncFormatV2 is a JSON encoded metav1.LabelSelector, e.g. {"matchLabels":{"env":"prod"}}
+	SyncFormatV2 SyncFormat = "v2"
+)
+
+// syncFormatPrefix matches an explicit format version such as "v2:" at the start of a sync annotation. A colon isn't
+// valid in a label key, so the prefix can't be mistaken for a v1 selector.
+var syncFormatPrefix = regexp.MustCompile(`^(v[0-9]+):`)
+
+// syncFormatCodec converts between a format of the sync annotation and a label selector
+type syncFormatCodec struct {
+	parse  func(v string) (labels.Selector, error)
+	format func(ls labels.Selector) (string, error)
+}
+
+// syncFormats holds the codecs of all supported sync annotation formats; new formats are added here
+var syncFormats = map[SyncFormat]syncFormatCodec{
+	SyncFormatV1: {parse: parseSyncV1, format: formatSyncV1},
+	SyncFormatV2: {parse: parseSyncV2, format: formatSyncV2},
+}
+
+// DetectSyncFormat returns the format of the sync annotation value v and the value without an explicit format prefix.
+// Values without a prefix are JSON when they start with "{" and label selector strings otherwise.
+func DetectSyncFormat(v string) (SyncFormat, string) {
+	if m := syncFormatPrefix.FindStringSubmatch(v); m != nil {
+		return SyncFormat(m[1]), v[len(m[0]):]
+	}
+	if strings.HasPrefix(strings.TrimSpace(v), "{") {
+		return SyncFormatV2, v
+	}
+	return SyncFormatV1, v
+}
+
+// ConvertSyncSelector converts the sync annotation value v to the format to
+func ConvertSyncSelector(v string, to SyncFormat) (string, error) {
+	codec, ok := syncFormats[to]
+	if !ok {
+		return "", fmt.Errorf("unsupported sync annotation format %q", to)
+	}
+	ls, err := ParseSyncSelector(v)
+	if err != nil {
+		return "", err
+	}
+	out, err := codec.format(ls)
+	if err != nil {
+		return "", fmt.Errorf("unable to convert sync selector %q to %s: %w", v, to, err)
+	}
+	return out, nil
+}
+
+func parseSyncV1(v string) (labels.Selector, error) {
+	return labels.Parse(v)
+}
+
+func formatSyncV1(ls labels.Selector) (string, error) {
+	return ls.String(), nil
+}
+
+func parseSyncV2(v string) (labels.Selector, error) {
+	var selector metav1.LabelSelector
+	dec := json.NewDecoder(strings.NewReader(v))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&selector); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after label selector")
+	}
+	return metav1.LabelSelectorAsSelector(&selector)
+}
+
+// formatSyncV2 fails for selectors using the gt and lt operators, which metav1.LabelSelector can't express
+func formatSyncV2(ls labels.Selector) (string, error) {
+	reqs, _ := ls.Requirements()
+	selector := metav1.LabelSelector{}
+	for _, r := range reqs {
+		expr := metav1.LabelSelectorRequirement{Key: r.Key(), Values: r.Values().List()}
+		switch r.Operator() {
+		case selection.Equals, selection.DoubleEquals:
+			// matchLabels holds one value per key, repeated keys fall back to an expression
+			if _, ok := selector.MatchLabels[r.Key()]; !ok {
+				if selector.MatchLabels == nil {
+					selector.MatchLabels = map[string]string{}
+				}
+				selector.MatchLabels[r.Key()] = expr.Values[0]
+				continue
+			}
+			expr.Operator = metav1.LabelSelectorOpIn
+		case selection.In:
+			expr.Operator = metav1.LabelSelectorOpIn
+		case selection.NotEquals, selection.NotIn:
+			expr.Operator = metav1.LabelSelectorOpNotIn
+		case selection.Exists:
+			expr.Operator, expr.Values = metav1.LabelSelectorOpExists, nil
+		case selection.DoesNotExist:
+			expr.Operator, expr.Values = metav1.LabelSelectorOpDoesNotExist, nil
+		default:
+			return "", fmt.Errorf("operator %q is not supported by %s", r.Operator(), SyncFormatV2)
+		}
+		selector.MatchExpressions = append(selector.MatchExpressions, expr)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(selector); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
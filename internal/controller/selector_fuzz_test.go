@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// FuzzParseSyncSelector checks that the sync annotation parser never panics and that every selector it accepts
+// survives a round trip through each format it can be converted to.
+// Run with: go test -run '^$' -fuzz FuzzParseSyncSelector ./internal/controller/
+func FuzzParseSyncSelector(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"env=prod",
+		"kubernetes.io/metadata.name in (team-a,team-b)",
+		"env!=prod,!legacy,tier",
+		"replicas>2",
+		`{"matchLabels":{"env":"prod"}}`,
+		`{"matchExpressions":[{"key":"team","operator":"In","values":["a","b"]}]}`,
+		`v1:env=prod`,
+		`v2:{"matchLabels":{"env":"prod"}}`,
+		`v9:env=prod`,
+		`{"matchLabels":{"env":"prod"}}{}`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, v string) {
+		ls, err := ParseSyncSelector(v)
+		if err != nil {
+			return
+		}
+		for format := range syncFormats {
+			converted, err := ConvertSyncSelector(v, format)
+			if err != nil {
+				continue
+			}
+			got, err := ParseSyncSelector(converted)
+			if err != nil {
+				t.Fatalf("%s conversion %q of %q doesn't parse: %v", format, converted, v, err)
+			}
+			if canonicalSelector(got) != canonicalSelector(ls) {
+				t.Fatalf("%s conversion %q of %q selects %q, want %q", format, converted, v, got.String(), ls.String())
+			}
+		}
+	})
+}
+
+// FuzzSyncSelectorSerialization checks that serializing a parsed selector is stable: the serialized form parses to
+// the same selector and serializes to the same string again
+func FuzzSyncSelectorSerialization(f *testing.F) {
+	for _, seed := range []string{
+		"env=prod",
+		"env==prod,team in (b,a)",
+		"!legacy,tier notin (db)",
+		"replicas>2,replicas<5",
+		`{"matchLabels":{"env":"prod"},"matchExpressions":[{"key":"env","operator":"NotIn","values":["dev"]}]}`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, v string) {
+		ls, err := ParseSyncSelector(v)
+		if err != nil {
+			return
+		}
+		for format, codec := range syncFormats {
+			once, err := codec.format(ls)
+			if err != nil {
+				continue
+			}
+			reparsed, err := ParseSyncSelector(once)
+			if err != nil {
+				t.Fatalf("%s serialization %q of %q doesn't parse: %v", format, once, v, err)
+			}
+			twice, err := codec.format(reparsed)
+			if err != nil {
+				t.Fatalf("%s serialization of %q failed after a round trip: %v", format, v, err)
+			}
+			if once != twice {
+				t.Fatalf("%s serialization of %q is unstable: %q then %q", format, v, once, twice)
+			}
+		}
+	})
+}
+
+// FuzzNamespaceContainsSyncLabel checks that the selector used to list target namespaces and the check used when a
+// namespace changes agree, and that a malformed annotation never selects a namespace
+func FuzzNamespaceContainsSyncLabel(f *testing.F) {
+	f.Add("env=prod", "env", "prod")
+	f.Add("env in (prod,staging)", "env", "dev")
+	f.Add("!env", "team", "a")
+	f.Add(`{"matchLabels":{"env":"prod"}}`, "env", "prod")
+	f.Add("env=", "env", "")
+	f.Add("env in (", "env", "prod")
+	f.Fuzz(func(t *testing.T, annotation, key, value string) {
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:        "src",
+			Namespace:   "src-ns",
+			Annotations: map[string]string{syncKey: annotation},
+		}}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "target", Labels: map[string]string{key: value}}}
+		ks := &KopySecret{Object: src}
+		ls := ks.LabelSelector()
+		if ls == nil {
+			t.Fatalf("LabelSelector of %q is nil", annotation)
+		}
+		contains := namespaceContainsSyncLabel(src, ns)
+		if contains != ls.Matches(labels.Set(ns.Labels)) {
+			t.Fatalf("namespaceContainsSyncLabel and LabelSelector disagree on %q for %s=%s", annotation, key, value)
+		}
+		if _, err := ParseSyncSelector(annotation); err != nil && contains {
+			t.Fatalf("malformed annotation %q selects %s=%s", annotation, key, value)
+		}
+	})
+}
+
+// canonicalSelector returns the distinct requirements of ls in a stable order with equivalent operators written the
+// same way
+func canonicalSelector(ls labels.Selector) string {
+	reqs, _ := ls.Requirements()
+	canonical := make([]string, 0, len(reqs))
+	for _, r := range reqs {
+		op := r.Operator()
+		switch {
+		case op == selection.DoubleEquals, op == selection.In && r.Values().Len() == 1:
+			op = selection.Equals
+		case op == selection.NotIn && r.Values().Len() == 1:
+			op = selection.NotEquals
+		}
+		cr, err := labels.NewRequirement(r.Key(), op, r.Values().List())
+		if err != nil {
+			return ls.String()
+		}
+		canonical = append(canonical, cr.String())
+	}
+	slices.Sort(canonical)
+	canonical = slices.Compact(canonical)
+	return strings.Join(canonical, ",")
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sync selector editing\n", func() {
+	DescribeTable("Adding a target",
+		func(selector, target, expected string) {
+			v, err := AddSyncTarget(selector, target)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(v).Should(Equal(expected))
+		},
+		Entry("namespace to empty selector", "", "ns/team-a", "kubernetes.io/metadata.name in (team-a)"),
+		Entry("namespace to namespace selector", "kubernetes.io/metadata.name in (team-a)", "ns/team-b", "kubernetes.io/metadata.name in (team-a,team-b)"),
+		Entry("prefixed label key", "app=foo", "example.com/tier", "app=foo,example.com/tier"),
+		Entry("requirement to label selector", "app=foo", "env!=prod", "app=foo,env!=prod"),
+		Entry("existing requirement", "app=foo", "app=foo", "app=foo"),
+	)
+	DescribeTable("Removing a target",
+		func(selector, target, expected string) {
+			v, err := RemoveSyncTarget(selector, target)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(v).Should(Equal(expected))
+		},
+		Entry("namespace from namespace selector", "kubernetes.io/metadata.name in (team-a,team-b)", "ns/team-b", "kubernetes.io/metadata.name in (team-a)"),
+		Entry("requirement from label selector", "app=foo,env!=prod", "env!=prod", "app=foo"),
+	)
+	It("Should refuse malformed or unsafe edits", func() {
+		_, err := AddSyncTarget("app=foo", "ns/team-b")
+		Expect(err).Should(HaveOccurred())
+		_, err = AddSyncTarget("app==", "ns/team-b")
+		Expect(err).Should(HaveOccurred())
+		_, err = AddSyncTarget("app=foo", "ns/Team_B")
+		Expect(err).Should(HaveOccurred())
+		_, err = RemoveSyncTarget("kubernetes.io/metadata.name in (team-a)", "ns/team-a")
+		Expect(err).Should(HaveOccurred())
+		_, err = RemoveSyncTarget("app=foo", "env=prod")
+		Expect(err).Should(HaveOccurred())
+	})
+	It("Should refuse a bare label key that could be meant as a namespace", func() {
+		_, err := AddSyncTarget("app=foo", "tier")
+		Expect(err).Should(MatchError(ContainSubstring("ns/tier")))
+		_, err = RemoveSyncTarget("kubernetes.io/metadata.name in (team-a,team-b)", "team-b")
+		Expect(err).Should(HaveOccurred())
+	})
+	DescribeTable("Parsing sync annotation formats",
+		func(v, expected string) {
+			ls, err := ParseSyncSelector(v)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ls.String()).Should(Equal(expected))
+		},
+		Entry("v1 label selector", "env=prod,team in (a,b)", "env=prod,team in (a,b)"),
+		Entry("v1 with explicit version", "v1:env=prod", "env=prod"),
+		Entry("v2 JSON selector", `{"matchLabels":{"env":"prod"},"matchExpressions":[{"key":"team","operator":"In","values":["a","b"]}]}`, "env=prod,team in (a,b)"),
+		Entry("v2 with explicit version", `v2:{"matchLabels":{"env":"prod"}}`, "env=prod"),
+	)
+	It("Should refuse unknown or malformed formats", func() {
+		_, err := ParseSyncSelector("v9:env=prod")
+		Expect(err).Should(HaveOccurred())
+		_, err = ParseSyncSelector(`{"matchLabel":{"env":"prod"}}`)
+		Expect(err).Should(HaveOccurred())
+	})
+	It("Should convert between formats", func() {
+		v, err := ConvertSyncSelector("env=prod", SyncFormatV2)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(v).Should(Equal(`{"matchLabels":{"env":"prod"}}`))
+		v, err = ConvertSyncSelector(v, SyncFormatV1)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(v).Should(Equal("env=prod"))
+		_, err = ConvertSyncSelector("replicas>2", SyncFormatV2)
+		Expect(err).Should(HaveOccurred())
+	})
+	It("Should keep the format when editing targets", func() {
+		v, err := AddSyncTarget(`{"matchExpressions":[{"key":"kubernetes.io/metadata.name","operator":"In","values":["team-a"]}]}`, "ns/team-b")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(v).Should(Equal(`{"matchExpressions":[{"key":"kubernetes.io/metadata.name","operator":"In","values":["team-a","team-b"]}]}`))
+	})
+})
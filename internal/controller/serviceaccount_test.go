@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ServiceAccount sync\n", func() {
+	const (
+		namespace = "test-src-serviceaccount-ns-00"
+		target    = "test-dst-serviceaccount-ns-00"
+	)
+	It("Should copy the image pull secret references of a service account", func() {
+		ctx := context.Background()
+		automount := false
+		src := &corev1.ServiceAccount{
+			ObjectMeta:                   metav1.ObjectMeta{Name: "test-src-serviceaccount-00", Namespace: namespace, Annotations: map[string]string{syncKey: "team=payments"}},
+			ImagePullSecrets:             []corev1.LocalObjectReference{{Name: "registry"}},
+			Secrets:                      []corev1.ObjectReference{{Name: "test-src-serviceaccount-00-token"}},
+			AutomountServiceAccountToken: &automount,
+		}
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).Build()
+		reconcile := func() {
+			_, err := KopyReconcile(NewKopyServiceAccount(ctx, c, Options{}, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		cp := &corev1.ServiceAccount{}
+		copyKey := types.NamespacedName{Namespace: target, Name: src.Name}
+
+		reconcile()
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.Labels).Should(HaveKeyWithValue(sourceLabelNamespace, namespace))
+		Expect(cp.ImagePullSecrets).Should(Equal(src.ImagePullSecrets))
+		Expect(cp.AutomountServiceAccountToken).Should(HaveValue(BeFalse()))
+		Expect(cp.Secrets).Should(BeEmpty())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		src.ImagePullSecrets = append(src.ImagePullSecrets, corev1.LocalObjectReference{Name: "mirror"})
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		reconcile()
+		Expect(c.Get(ctx, copyKey, cp)).Should(Succeed())
+		Expect(cp.ImagePullSecrets).Should(Equal(src.ImagePullSecrets))
+		Expect(cp.Annotations).Should(HaveKeyWithValue(sourceHashKey, dataRevision(src)))
+	})
+})
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/internal/kopycrypto"
+)
+
+const (
+	// dataHashKey is the hex encoded SHA-256 digest of the identity and payload of a signed copy
+	dataHashKey = kopyPrefix + "data-hash"
+	// signatureKey is the base64 encoded ECDSA signature of the data hash, made with the controller key
+	signatureKey = kopyPrefix + "signature"
+)
+
+// errInvalidSignature is returned when a copy is unsigned or its signature doesn't match its data
+var errInvalidSignature = errors.New("invalid signature")
+
+// copyDigest returns the digest of the identity, origin and payload of the copy cp
+func copyDigest(cp client.Object) []byte {
+	h := kopycrypto.NewHasher()
+	h.Field(kindOf(cp))
+	h.Field(cp.GetNamespace())
+	h.Field(cp.GetName())
+	h.Field(cp.GetLabels()[sourceLabelNamespace])
+	h.Field(cp.GetLabels()[sourceLabelName])
+	if s, ok := cp.(*corev1.Secret); ok {
+		h.Field(string(s.Type))
+	}
+	data := objectData(cp)
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h.Field(k)
+		h.Field(string(data[k]))
+	}
+	return h.Sum()
+}
+
+// signCopy stores the data hash of cp and its signature made with key in the annotations of cp
+func signCopy(cp client.Object, key *ecdsa.PrivateKey) error {
+	digest := copyDigest(cp)
+	sig, err := kopycrypto.Sign(key, digest)
+	if err != nil {
+		return fmt.Errorf("unable to sign copy: %w", err)
+	}
+	annotations := cp.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[dataHashKey] = hex.EncodeToString(digest)
+	annotations[signatureKey] = base64.StdEncoding.EncodeToString(sig)
+	cp.SetAnnotations(annotations)
+	return nil
+}
+
+// VerifyCopy checks that cp was signed by the controller key matching pub and hasn't been modified since
+func VerifyCopy(cp client.Object, pub *ecdsa.PublicKey) error {
+	v, ok := cp.GetAnnotations()[signatureKey]
+	if !ok {
+		return fmt.Errorf("%w: copy is not signed", errInvalidSignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidSignature, err)
+	}
+	if !kopycrypto.Verify(pub, copyDigest(cp), sig) {
+		return fmt.Errorf("%w: data or signature was modified", errInvalidSignature)
+	}
+	return nil
+}
+
+// VerifyCopyByKey gets the copy identified by key and verifies its signature
+func VerifyCopyByKey(ctx context.Context, c client.Client, kind string, key types.NamespacedName, pub *ecdsa.PublicKey) error {
+	cp, err := NewObjectForKind(kind)
+	if err != nil {
+		return err
+	}
+	if err := c.Get(ctx, key, cp); err != nil {
+		return err
+	}
+	return VerifyCopy(cp, pub)
+}
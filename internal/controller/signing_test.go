@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Signing\n", func() {
+	Context("When a copy is signed", func() {
+		var key *ecdsa.PrivateKey
+		var cp client.Object
+
+		BeforeEach(func() {
+			var err error
+			key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ShouldNot(HaveOccurred())
+			src := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-src-signing-00", Namespace: "test-src-signing-ns-00"},
+				Data:       map[string][]byte{"password": []byte("test-src-signing-00")},
+				Type:       corev1.SecretTypeOpaque,
+			}
+			opts := Options{SigningKey: key}
+			cp, err = newCopy(src, "test-target-signing-ns-00", opts)
+			Expect(err).ShouldNot(HaveOccurred())
+			cp, err = opts.prepareCopy(context.Background(), nil, src, cp)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("Should verify with the matching public key", func() {
+			Expect(cp.GetAnnotations()).Should(HaveKey(dataHashKey))
+			Expect(VerifyCopy(cp, &key.PublicKey)).Should(Succeed())
+		})
+
+		It("Should fail to verify when the data is modified", func() {
+			cp.(*corev1.Secret).Data["password"] = []byte("tampered")
+			Expect(VerifyCopy(cp, &key.PublicKey)).Should(MatchError(errInvalidSignature))
+		})
+
+		It("Should fail to verify when the copy is moved to another namespace", func() {
+			cp.SetNamespace("test-target-signing-ns-01")
+			Expect(VerifyCopy(cp, &key.PublicKey)).Should(MatchError(errInvalidSignature))
+		})
+
+		It("Should fail to verify with another key", func() {
+			other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(VerifyCopy(cp, &other.PublicKey)).Should(MatchError(errInvalidSignature))
+		})
+	})
+})
@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/flynshue/kopy/pkg/freshness"
+)
+
+// simulationRounds bounds how often every object is reconciled; kopy converges in a few rounds because copies only
+// change when their source does
+const simulationRounds = 10
+
+// SimulatedAction is a write kopy would make to the cluster
+type SimulatedAction struct {
+	Verb      string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// SimulationResult is the outcome of running kopy against a cluster snapshot
+type SimulationResult struct {
+	Actions []SimulatedAction
+	// Errors maps namespace/name of the objects whose reconcile failed to the error
+	Errors map[string]error
+	// Converged is false when kopy was still writing after the last round
+	Converged bool
+}
+
+// LoadSnapshot reads the Namespaces and the objects of the enabled kinds from a YAML or JSON dump such as the output
+// of "kubectl get namespaces,secrets,configmaps -A -o yaml". Documents may be single objects or Lists; other kinds are
+// skipped.
+func LoadSnapshot(r io.Reader) ([]client.Object, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	decoder := clientgoscheme.Codecs.UniversalDeserializer()
+	var objects []client.Object
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc) == 0 {
+			continue
+		}
+		o, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			if runtime.IsNotRegisteredError(err) {
+				continue
+			}
+			return nil, err
+		}
+		decoded, err := snapshotObjects(o)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, decoded...)
+	}
+}
+
+func snapshotObjects(o runtime.Object) ([]client.Object, error) {
+	switch obj := o.(type) {
+	case *corev1.List:
+		var objects []client.Object
+		for _, item := range obj.Items {
+			o, _, err := clientgoscheme.Codecs.UniversalDeserializer().Decode(item.Raw, nil, nil)
+			if err != nil {
+				if runtime.IsNotRegisteredError(err) {
+					continue
+				}
+				return nil, err
+			}
+			decoded, err := snapshotObjects(o)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, decoded...)
+		}
+		return objects, nil
+	}
+	co, ok := o.(client.Object)
+	if !ok {
+		return nil, nil
+	}
+	if _, isNamespace := co.(*corev1.Namespace); !isNamespace && !slices.Contains(enabledKinds(), kindOf(co)) {
+		return nil, nil
+	}
+	// the fake client assigns its own resource versions
+	co.SetResourceVersion("")
+	return []client.Object{co}, nil
+}
+
+// fakeApply emulates server-side apply on the fake client c, which can't apply patches: an apply patch creates obj or
+// replaces the object in its place with an update. Other patches are passed on to c.
+func fakeApply(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Patch(ctx, obj, patch, opts...)
+	}
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); apierrors.IsNotFound(err) {
+		return c.Create(ctx, obj)
+	} else if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return c.Update(ctx, obj)
+}
+
+// Simulate runs the kopy reconcile loop for every object of the enabled kinds in objects against an in-memory client and
+// returns the writes it would make, without touching a cluster
+func Simulate(ctx context.Context, objects []client.Object, opts Options) (*SimulationResult, error) {
+	result := &SimulationResult{Errors: map[string]error{}}
+	// copies of a target group may be written in parallel
+	var mu sync.Mutex
+	record := func(verb string, o client.Object) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Actions = append(result.Actions, SimulatedAction{
+			Verb: verb, Kind: kindOf(o), Namespace: o.GetNamespace(), Name: o.GetName(),
+		})
+	}
+	// kopy applies copies, the apply patches are turned into the creates and updates that are recorded
+	c := interceptor.NewClient(fake.NewClientBuilder().
+		WithScheme(clientgoscheme.Scheme).
+		WithObjects(objects...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.CreateOption) error {
+				if err := c.Create(ctx, o, opts...); err != nil {
+					return err
+				}
+				record("create", o)
+				return nil
+			},
+			Update: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.UpdateOption) error {
+				existing := o.DeepCopyObject().(client.Object)
+				if err := c.Get(ctx, client.ObjectKeyFromObject(o), existing); err != nil {
+					return err
+				}
+				if err := c.Update(ctx, o, opts...); err != nil {
+					return err
+				}
+				// kopy rewrites copies on every reconcile; the API server drops updates that change nothing
+				if !isNoopUpdate(existing, o) {
+					record("update", o)
+				}
+				return nil
+			},
+			Patch: func(ctx context.Context, c client.WithWatch, o client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if err := c.Patch(ctx, o, patch, opts...); err != nil {
+					return err
+				}
+				record("patch", o)
+				return nil
+			},
+			Delete: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.DeleteOption) error {
+				if err := c.Delete(ctx, o, opts...); err != nil {
+					return err
+				}
+				record("delete", o)
+				return nil
+			},
+		}).
+		Build(), interceptor.Funcs{Patch: fakeApply})
+	ctx = ctrllog.IntoContext(ctx, ctrllog.Log.WithName("simulate"))
+	for round := 0; round < simulationRounds; round++ {
+		before := len(result.Actions)
+		for _, kind := range enabledKinds() {
+			keys, err := simulationKeys(ctx, c, kind)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range keys {
+				k, err := newKopier(ctx, c, kind, opts)
+				if err != nil {
+					return nil, err
+				}
+				_, err = KopyReconcile(k, ctrl.Request{NamespacedName: key}, nil)
+				if err != nil && client.IgnoreNotFound(err) != nil {
+					result.Errors[fmt.Sprintf("%s %s", kind, key)] = err
+				}
+			}
+		}
+		if len(result.Actions) == before {
+			result.Converged = true
+			break
+		}
+	}
+	return result, nil
+}
+
+// simulationKeys returns the keys of all objects of kind in a stable order
+func simulationKeys(ctx context.Context, c client.Client, kind string) ([]types.NamespacedName, error) {
+	list, err := newObjectListForKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.List(ctx, list); err != nil {
+		return nil, err
+	}
+	var keys []types.NamespacedName
+	if err := meta.EachListItem(list, func(o runtime.Object) error {
+		keys = append(keys, client.ObjectKeyFromObject(o.(client.Object)))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys, nil
+}
+
+// isNoopUpdate returns true if updated only differs from existing in fields set by the server or in the last sync
+// time and source resourceVersion of a copy
+func isNoopUpdate(existing, updated client.Object) bool {
+	want := updated.DeepCopyObject().(client.Object)
+	want.SetResourceVersion(existing.GetResourceVersion())
+	want.GetObjectKind().SetGroupVersionKind(existing.GetObjectKind().GroupVersionKind())
+	if t, ok := existing.GetAnnotations()[freshness.LastSyncTimeAnnotation]; ok && want.GetAnnotations() != nil {
+		annotations := want.GetAnnotations()
+		annotations[freshness.LastSyncTimeAnnotation] = t
+		want.SetAnnotations(annotations)
+	}
+	if v, ok := existing.GetAnnotations()[sourceResourceVersionKey]; ok && want.GetAnnotations() != nil {
+		annotations := want.GetAnnotations()
+		annotations[sourceResourceVersionKey] = v
+		want.SetAnnotations(annotations)
+	}
+	return equality.Semantic.DeepEqual(existing, want)
+}
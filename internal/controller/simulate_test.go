@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testSnapshot = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-src-simulate-ns-00
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-simulate-ns-00
+    labels:
+      env: prod
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-simulate-ns-01
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: test-deployment-simulate-00
+    namespace: test-src-simulate-ns-00
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test-src-simulate-00
+  namespace: test-src-simulate-ns-00
+  resourceVersion: "42"
+  annotations:
+    kopy.kot-labs.com/sync: env=prod
+data:
+  password: dGVzdC1zcmMtc2ltdWxhdGUtMDA=
+`
+
+var _ = Describe("Simulation\n", func() {
+	Context("When simulating a cluster snapshot", func() {
+		It("Should report the copies kopy would create", func() {
+			objects, err := LoadSnapshot(strings.NewReader(testSnapshot))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(objects).Should(HaveLen(4))
+
+			result, err := Simulate(context.Background(), objects, Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.Converged).Should(BeTrue())
+			Expect(result.Errors).Should(BeEmpty())
+			Expect(result.Actions).Should(ConsistOf(
+				SimulatedAction{Verb: "update", Kind: "secret", Namespace: "test-src-simulate-ns-00", Name: "test-src-simulate-00"},
+				SimulatedAction{Verb: "create", Kind: "secret", Namespace: "test-target-simulate-ns-00", Name: "test-src-simulate-00"},
+			))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+var _ manager.Runnable = &StandbyCacheWarmer{}
+var _ manager.LeaderElectionRunnable = &StandbyCacheWarmer{}
+
+// StandbyCacheWarmer is a manager.Runnable that starts the informers of the enabled controllers on every replica. The
+// controllers only start watching once their replica is elected leader, so without it a replica that takes over has
+// to list every Secret and ConfigMap first, which takes minutes on very large clusters. A warm standby also serves the
+// read-only api from a synced cache.
+type StandbyCacheWarmer struct {
+	Cache   cache.Cache
+	Options Options
+	// Controllers are the names of the enabled controllers, e.g. "secret"
+	Controllers []string
+	// Objects are informed in addition to the ones the controllers watch, e.g. the Pods the usage api reads
+	Objects []client.Object
+}
+
+// Start starts the informers and blocks until ctx is cancelled
+func (w *StandbyCacheWarmer) Start(ctx context.Context) error {
+	log := ctrllog.Log.WithName("standby")
+	start := time.Now()
+	for _, obj := range append(w.Options.watchedObjects(w.Controllers), w.Objects...) {
+		if _, err := w.Cache.GetInformer(ctx, obj, cache.BlockUntilSynced(false)); err != nil {
+			return err
+		}
+	}
+	if !w.Cache.WaitForCacheSync(ctx) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return errors.New("unable to sync the standby cache")
+	}
+	log.Info("cache is warm", "controllers", w.Controllers, "duration", time.Since(start).String())
+	<-ctx.Done()
+	return nil
+}
+
+// NeedLeaderElection returns false so replicas that aren't the leader keep their caches warm
+func (w *StandbyCacheWarmer) NeedLeaderElection() bool {
+	return false
+}
+
+// watchedObjects returns the objects the controllers named controllers watch, as the type the informer is started
+// for, so the controllers reuse the informers once they start
+func (o Options) watchedObjects(controllers []string) []client.Object {
+	secret, _ := o.secretSources()
+	namespace := &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"}}
+	objects := []client.Object{}
+	for _, name := range controllers {
+		// the controllers of the kinds kopy syncs are named after their kind
+		k, syncsKind := kindNamed(name)
+		syncsKind = syncsKind && k.kindName() == name
+		switch {
+		case name == "secret":
+			objects = append(objects, secret)
+		case syncsKind:
+			objects = append(objects, k.newSource())
+		case name == "kopysubscription":
+			objects = append(objects, &syncv1alpha1.KopySubscription{}, secret, &corev1.ConfigMap{})
+		case name == "kopypublication":
+			objects = append(objects, &syncv1alpha1.KopyPublication{}, secret, &corev1.ConfigMap{})
+		case name == "kopysync":
+			objects = append(objects, &syncv1alpha1.KopySync{}, secret, &corev1.ConfigMap{})
+		case name == "kopysourcequota":
+			objects = append(objects, &syncv1alpha1.KopySourceQuota{}, secret, &corev1.ConfigMap{})
+		case name == "kopytoken":
+			objects = append(objects, &syncv1alpha1.KopyToken{}, secret, namespace, &corev1.ServiceAccount{})
+		}
+		if syncsKind && !o.NamespaceScoped() {
+			objects = append(objects, namespace)
+		}
+		if syncsKind && o.PruneGracePeriod > 0 {
+			objects = append(objects, &corev1.Pod{})
+		}
+	}
+	return objects
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+// informedCache records the informers started by kind, metadata only informers are recorded as metadata/<kind>
+type informedCache struct {
+	informertest.FakeInformers
+	kinds []string
+}
+
+func (c *informedCache) GetInformer(ctx context.Context, obj client.Object, opts ...cache.InformerGetOption) (cache.Informer, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	kind := gvk.Kind
+	if _, ok := obj.(*metav1.PartialObjectMetadata); ok {
+		kind = "metadata/" + kind
+	}
+	c.kinds = append(c.kinds, kind)
+	return c.FakeInformers.GetInformerForKind(ctx, gvk, opts...)
+}
+
+var _ = Describe("Warm standby\n", func() {
+	newWarmer := func(opts Options, controllers ...string) (*StandbyCacheWarmer, *informedCache) {
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).Should(Succeed())
+		Expect(syncv1alpha1.AddToScheme(s)).Should(Succeed())
+		informers := &informedCache{FakeInformers: informertest.FakeInformers{Scheme: s}}
+		return &StandbyCacheWarmer{Cache: informers, Options: opts, Controllers: controllers, Objects: []client.Object{&corev1.Pod{}}}, informers
+	}
+	start := func(w *StandbyCacheWarmer) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- w.Start(ctx) }()
+		// Start blocks once the informers are synced
+		Consistently(done).ShouldNot(Receive())
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	}
+	It("Should start the informers the enabled controllers watch", func() {
+		w, informers := newWarmer(Options{}, "secret", "kopytoken")
+		start(w)
+		Expect(informers.kinds).Should(ConsistOf("Secret", "metadata/Namespace", "Secret", "KopyToken", "metadata/Namespace", "ServiceAccount", "Pod"))
+	})
+	It("Should start the informers of namespace scoped and metadata only caches", func() {
+		w, informers := newWarmer(Options{Namespaces: []string{"test-src-standby-ns-00"}, SecretMetadataOnly: true}, "secret", "configmap")
+		start(w)
+		Expect(informers.kinds).Should(ConsistOf("metadata/Secret", "ConfigMap", "Pod"))
+	})
+})
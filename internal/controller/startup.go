@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// sourceHashKey is set on every copy to the revision of the source data it was synced from
+	sourceHashKey = kopyPrefix + "source-hash"
+	// sourceResourceVersionKey is set on every copy to the resourceVersion of the source it was last synced from
+	sourceResourceVersionKey = kopyPrefix + "source-resource-version"
+	// reasonStartupSyncLate is used for events on sources whose copies weren't caught up within the startup sync
+	// deadline
+	reasonStartupSyncLate = "StartupSyncLate"
+	// DefaultStartupSyncDeadline is how long the startup sync waits for sources that changed while kopy was down
+	DefaultStartupSyncDeadline = 5 * time.Minute
+)
+
+// StaleSources returns the sources with copies whose source hash doesn't match the current data of the source,
+// usually because the source changed while kopy wasn't running. Copies synced before kopy recorded source hashes count
+// as stale.
+func StaleSources(ctx context.Context, c client.Client) ([]SourceRef, error) {
+	stale := []SourceRef{}
+	for _, kind := range enabledKinds() {
+		list, err := newObjectListForKind(kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.List(ctx, list, client.HasLabels{sourceLabelNamespace}); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		// revisions caches the current revision of every source, "" for objects that aren't sources anymore
+		revisions := map[types.NamespacedName]string{}
+		for _, item := range items {
+			cp, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			key := types.NamespacedName{Namespace: cp.GetLabels()[sourceLabelNamespace], Name: sourceNameOf(cp)}
+			revision, seen := revisions[key]
+			if !seen {
+				src, _ := NewObjectForKind(kind)
+				err := c.Get(ctx, key, src)
+				if client.IgnoreNotFound(err) != nil {
+					return nil, err
+				}
+				if _, ok := SyncSelector(src); err == nil && ok && src.GetDeletionTimestamp() == nil {
+					revision = dataRevision(src)
+				}
+				revisions[key] = revision
+			}
+			if revision == "" || cp.GetAnnotations()[sourceHashKey] == revision {
+				continue
+			}
+			stale = append(stale, SourceRef{Kind: kind, Namespace: key.Namespace, Name: key.Name})
+			// each source is reported once
+			revisions[key] = ""
+		}
+	}
+	return stale, nil
+}
+
+var _ manager.Runnable = &StartupSync{}
+var _ manager.LeaderElectionRunnable = &StartupSync{}
+
+// StartupSync catches up copies that missed updates of their sources while kopy wasn't running. Once the replica is
+// the leader and its caches are synced it requests a resync of every stale source and waits for the requests to be
+// observed. Sources that are still pending after Deadline are reported with a StartupSyncLate warning event.
+type StartupSync struct {
+	client.Client
+	Recorder record.EventRecorder
+	Deadline time.Duration
+	// Interval is how often the pending resync requests are checked
+	Interval time.Duration
+}
+
+// Start runs the startup sync once and returns
+func (s *StartupSync) Start(ctx context.Context) error {
+	log := ctrllog.Log.WithName("startup-sync")
+	stale, err := StaleSources(ctx, s.Client)
+	if err != nil {
+		log.Error(err, "unable to find sources that changed while kopy was down")
+		return nil
+	}
+	log.Info("resyncing sources that changed while kopy was down", "sources", len(stale))
+	pending := map[SourceRef]string{}
+	for _, ref := range stale {
+		request, err := RequestSourceResync(ctx, s.Client, ref.Kind, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name})
+		if err != nil {
+			log.Error(err, "unable to request resync", "kind", ref.Kind, "namespace", ref.Namespace, "name", ref.Name)
+			continue
+		}
+		pending[ref] = request
+	}
+	deadline := time.NewTimer(s.Deadline)
+	defer deadline.Stop()
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline.C:
+			for ref := range pending {
+				s.reportLate(ctx, ref)
+			}
+			log.Info("sources weren't resynced within the startup sync deadline", "sources", len(pending), "deadline", s.Deadline.String())
+			return nil
+		case <-ticker.C:
+		}
+		for ref, request := range pending {
+			observed, err := ResyncObserved(ctx, s.Client, ref.Kind, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, request)
+			if observed || apierrors.IsNotFound(err) {
+				delete(pending, ref)
+			}
+		}
+	}
+	log.Info("copies are current with their sources")
+	return nil
+}
+
+// reportLate emits a StartupSyncLate event on the source ref
+func (s *StartupSync) reportLate(ctx context.Context, ref SourceRef) {
+	src, err := NewObjectForKind(ref.Kind)
+	if err != nil {
+		return
+	}
+	if err := s.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, src); err != nil {
+		return
+	}
+	s.Recorder.Eventf(src, corev1.EventTypeWarning, reasonStartupSyncLate,
+		"Copies missed changes while kopy was down and weren't resynced within %s", s.Deadline)
+}
+
+// NeedLeaderElection returns true so only the leader requests resyncs
+func (s *StartupSync) NeedLeaderElection() bool {
+	return true
+}
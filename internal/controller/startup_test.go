@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+var _ = Describe("Startup sync\n", func() {
+	const sourceNamespace = "test-src-startup-ns-00"
+	newSource := func(name, password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: sourceNamespace, Annotations: map[string]string{syncKey: "env=prod"}},
+			Data:       map[string][]byte{"password": []byte(password)},
+		}
+	}
+	copyOf := func(src *corev1.Secret, namespace string) *corev1.Secret {
+		cp, err := newCopy(src, namespace, Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		return cp.(*corev1.Secret)
+	}
+
+	It("Should find the sources that changed after their copies were synced", func() {
+		current, changed, legacy := newSource("test-src-startup-00", "v1"), newSource("test-src-startup-01", "v1"), newSource("test-src-startup-02", "v1")
+		objects := []client.Object{copyOf(current, "test-dst-startup-ns-00"), copyOf(changed, "test-dst-startup-ns-00"), copyOf(changed, "test-dst-startup-ns-01")}
+		legacyCopy := copyOf(legacy, "test-dst-startup-ns-00")
+		delete(legacyCopy.Annotations, sourceHashKey)
+		changed.Data = map[string][]byte{"password": []byte("v2")}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(append(objects, current, changed, legacy, legacyCopy)...).Build()
+
+		stale, err := StaleSources(context.Background(), c)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stale).Should(ConsistOf(
+			SourceRef{Kind: "secret", Namespace: sourceNamespace, Name: "test-src-startup-01"},
+			SourceRef{Kind: "secret", Namespace: sourceNamespace, Name: "test-src-startup-02"},
+		))
+	})
+
+	It("Should request a resync of stale sources and report the ones not caught up in time", func() {
+		src := newSource("test-src-startup-00", "v1")
+		cp := copyOf(src, "test-dst-startup-ns-00")
+		src.Data = map[string][]byte{"password": []byte("v2")}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, cp).Build()
+		recorder := record.NewFakeRecorder(1)
+		s := &StartupSync{Client: c, Recorder: recorder, Deadline: 50 * time.Millisecond, Interval: 10 * time.Millisecond}
+		Expect(s.Start(context.Background())).Should(Succeed())
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		Expect(src.Annotations).Should(HaveKey(resyncRequestKey))
+		Expect(<-recorder.Events).Should(ContainSubstring(reasonStartupSyncLate))
+	})
+})
+
+var _ = Describe("Controller downtime\n", func() {
+	It("Should apply source updates missed while the manager was stopped when it starts again", func() {
+		if useKind {
+			Skip("needs a dedicated API server that no other manager watches")
+		}
+		By("Starting a dedicated API server")
+		env := &envtest.Environment{BinaryAssetsDirectory: testEnv.BinaryAssetsDirectory}
+		cfg, err := env.Start()
+		Expect(err).ShouldNot(HaveOccurred())
+		DeferCleanup(env.Stop)
+		c, err := client.New(cfg, client.Options{Scheme: clientgoscheme.Scheme})
+		Expect(err).ShouldNot(HaveOccurred())
+		startManager := func() func() {
+			skipNameValidation := true
+			mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+				Scheme:                 clientgoscheme.Scheme,
+				Metrics:                metricsserver.Options{BindAddress: "0"},
+				HealthProbeBindAddress: "0",
+				Controller:             config.Controller{SkipNameValidation: &skipNameValidation},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect((&SecretReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}).SetupWithManager(mgr)).Should(Succeed())
+			Expect(mgr.Add(&StartupSync{
+				Client: mgr.GetClient(), Recorder: mgr.GetEventRecorderFor("kopy-startup-sync"),
+				Deadline: timeout, Interval: interval,
+			})).Should(Succeed())
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(mgr.Start(ctx)).Should(Succeed())
+			}()
+			return func() {
+				cancel()
+				Eventually(done, timeout, interval).Should(BeClosed())
+			}
+		}
+
+		By("Syncing a source while the manager runs")
+		for _, ns := range []*corev1.Namespace{
+			{ObjectMeta: metav1.ObjectMeta{Name: "test-src-downtime-ns-00"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "test-dst-downtime-ns-00", Labels: map[string]string{"env": "downtime"}}},
+		} {
+			Expect(c.Create(context.Background(), ns)).Should(Succeed())
+		}
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-src-downtime-00", Namespace: "test-src-downtime-ns-00", Annotations: map[string]string{syncKey: "env=downtime"}},
+			Data:       map[string][]byte{"password": []byte("v1")},
+		}
+		Expect(c.Create(context.Background(), src)).Should(Succeed())
+		stop := startManager()
+		cp := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: src.Name, Namespace: "test-dst-downtime-ns-00"}}
+		Eventually(func() ([]byte, error) {
+			err := c.Get(context.Background(), client.ObjectKeyFromObject(cp), cp)
+			return cp.Data["password"], err
+		}, timeout, interval).Should(Equal([]byte("v1")))
+		stop()
+
+		By("Updating the source while the manager is stopped")
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		src.Data = map[string][]byte{"password": []byte("v2")}
+		Expect(c.Update(context.Background(), src)).Should(Succeed())
+		Consistently(func() ([]byte, error) {
+			err := c.Get(context.Background(), client.ObjectKeyFromObject(cp), cp)
+			return cp.Data["password"], err
+		}, time.Second, interval).Should(Equal([]byte("v1")))
+
+		By("Starting the manager again")
+		stop = startManager()
+		DeferCleanup(stop)
+		Eventually(func() ([]byte, error) {
+			err := c.Get(context.Background(), client.ObjectKeyFromObject(cp), cp)
+			return cp.Data["password"], err
+		}, timeout, interval).Should(Equal([]byte("v2")))
+		Expect(cp.Annotations).Should(HaveKeyWithValue(sourceHashKey, dataRevision(src)))
+		Eventually(func() (bool, error) {
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(src), src); err != nil {
+				return false, err
+			}
+			return src.Annotations[resyncObservedKey] != "" && src.Annotations[resyncObservedKey] == src.Annotations[resyncRequestKey], nil
+		}, timeout, interval).Should(BeTrue())
+	})
+})
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// topErrorReasons is how many error reasons are reported per controller
+const topErrorReasons = 10
+
+// DebugStatePath is where the debug state is served on the metrics server
+const DebugStatePath = "/debug/state"
+
+// controllerState collects the internal state of the kopy controllers that isn't visible through the API server
+type controllerState struct {
+	mu             sync.Mutex
+	watches        map[string][]string
+	errors         map[string]map[string]int
+	disabled       map[string][]string
+	lastFullResync time.Time
+	conditions     []metav1.Condition
+}
+
+// debugState is shared by the controllers of a manager, like the prometheus metrics
+var debugState = newControllerState()
+
+func newControllerState() *controllerState {
+	return &controllerState{
+		watches:  map[string][]string{},
+		errors:   map[string]map[string]int{},
+		disabled: map[string][]string{},
+	}
+}
+
+// watch records the kinds the controller named name watches
+func (s *controllerState) watch(name string, kinds ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watches[name] = kinds
+}
+
+// setCondition sets condition on the controller, replacing the condition of the same type
+func (s *controllerState) setCondition(condition metav1.Condition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta.SetStatusCondition(&s.conditions, condition)
+}
+
+// recordError counts err for the controller named name by its reason, nil errors are ignored
+func (s *controllerState) recordError(name string, err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.errors[name] == nil {
+		s.errors[name] = map[string]int{}
+	}
+	s.errors[name][errorReason(err)]++
+}
+
+// errorReason returns a short reason for err, e.g. CopyConflict or Forbidden
+func errorReason(err error) string {
+	switch {
+	case errors.Is(err, errCopyConflict):
+		return "CopyConflict"
+	case errors.Is(err, errCopyDenied):
+		return "CopyDenied"
+	case errors.Is(err, errRBACSourceRefused):
+		return "RBACSourceRefused"
+	case errors.Is(err, errSourceNotCached):
+		return "SourceNotCached"
+	case errors.Is(err, errInvalidSignature):
+		return "InvalidSignature"
+	case errors.Is(err, errMergeConflict):
+		return "MergeConflict"
+	}
+	if reason := apierrors.ReasonForError(err); reason != "" {
+		return string(reason)
+	}
+	return "Other"
+}
+
+// DebugState is the internal state of the kopy controllers served at DebugStatePath
+type DebugState struct {
+	// Watches are the kinds watched by each controller
+	Watches map[string][]string `json:"watches"`
+	// Disabled are the controllers that were not started and the permissions they lack
+	Disabled map[string][]string `json:"disabled,omitempty"`
+	// Indexes are the number of sources and distinct namespace labels in the sync selector index of each kind
+	Indexes map[string]IndexState `json:"indexes"`
+	// QueueDepths are the current workqueue depths by controller
+	QueueDepths map[string]float64 `json:"queueDepths"`
+	// TopErrors are the most frequent error reasons by controller since the controller started
+	TopErrors map[string][]ErrorCount `json:"topErrors"`
+	// LastFullResync is when the caches last finished listing every watched object
+	LastFullResync *time.Time `json:"lastFullResync,omitempty"`
+	// Conditions report the state of the controller itself, e.g. Degraded while its memory is close to its limit
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// IndexState describes the sync selector index of a kind
+type IndexState struct {
+	Sources int `json:"sources"`
+	Keys    int `json:"keys"`
+}
+
+// ErrorCount is how often an error reason occurred
+type ErrorCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// snapshot returns the current DebugState. Index sizes are computed from the objects in the cache of c.
+func (s *controllerState) snapshot(ctx context.Context, c client.Client) (*DebugState, error) {
+	state := &DebugState{
+		Watches:     map[string][]string{},
+		Disabled:    map[string][]string{},
+		Indexes:     map[string]IndexState{},
+		QueueDepths: map[string]float64{},
+		TopErrors:   map[string][]ErrorCount{},
+	}
+	s.mu.Lock()
+	for name, kinds := range s.watches {
+		state.Watches[name] = slices.Clone(kinds)
+	}
+	for name, missing := range s.disabled {
+		state.Disabled[name] = slices.Clone(missing)
+	}
+	// only kinds with a running controller are listed, the cache can't sync kinds kopy may not watch
+	kinds := []string{}
+	for _, kind := range enabledKinds() {
+		if _, ok := s.watches[kind]; ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	for name, reasons := range s.errors {
+		counts := make([]ErrorCount, 0, len(reasons))
+		for reason, n := range reasons {
+			counts = append(counts, ErrorCount{Reason: reason, Count: n})
+		}
+		sort.Slice(counts, func(i, j int) bool {
+			if counts[i].Count != counts[j].Count {
+				return counts[i].Count > counts[j].Count
+			}
+			return counts[i].Reason < counts[j].Reason
+		})
+		state.TopErrors[name] = counts[:min(len(counts), topErrorReasons)]
+	}
+	if !s.lastFullResync.IsZero() {
+		t := s.lastFullResync
+		state.LastFullResync = &t
+	}
+	state.Conditions = slices.Clone(s.conditions)
+	s.mu.Unlock()
+
+	for _, kind := range kinds {
+		list, err := newObjectListForKind(kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.List(ctx, list); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		var idx IndexState
+		keys := sets.New[string]()
+		for _, item := range items {
+			values := indexSyncSelector(item.(client.Object))
+			if len(values) > 0 {
+				idx.Sources++
+				keys.Insert(values...)
+			}
+		}
+		idx.Keys = keys.Len()
+		state.Indexes[kind] = idx
+	}
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range families {
+		if f.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "name" {
+					state.QueueDepths[l.GetValue()] = m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return state, nil
+}
+
+// DebugStateHandler serves the DebugState of the controllers as JSON
+func DebugStateHandler(c client.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, err := debugState.snapshot(r.Context(), c)
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(state)
+	})
+}
+
+var _ manager.Runnable = &CacheSyncRecorder{}
+
+// CacheSyncRecorder records when the caches of the manager finished their initial list of every watched object as
+// the last full resync of the debug state
+type CacheSyncRecorder struct {
+	Cache interface {
+		WaitForCacheSync(ctx context.Context) bool
+	}
+}
+
+// Start waits for the caches to sync and records the time
+func (r *CacheSyncRecorder) Start(ctx context.Context) error {
+	if r.Cache.WaitForCacheSync(ctx) {
+		debugState.mu.Lock()
+		debugState.lastFullResync = time.Now()
+		debugState.mu.Unlock()
+	}
+	return nil
+}
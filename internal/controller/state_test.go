@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Debug state\n", func() {
+	It("Should report watches, index sizes and the most frequent errors", func() {
+		source := func(name, selector string) *corev1.Secret {
+			return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "test-src-state-ns-00", Annotations: map[string]string{syncKey: selector},
+			}}
+		}
+		c := fake.NewClientBuilder().
+			WithScheme(clientgoscheme.Scheme).
+			WithObjects(source("test-src-state-00", "env=prod"), source("test-src-state-01", "env in (dev,prod)")).
+			Build()
+		s := newControllerState()
+		s.watch("secret", "Secret", "Namespace")
+		s.recordError("secret", nil)
+		s.recordError("secret", apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "test", nil))
+		for range 2 {
+			s.recordError("secret", fmt.Errorf("%w: test", errCopyConflict))
+		}
+
+		state, err := s.snapshot(context.Background(), c)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(state.Watches).Should(HaveKeyWithValue("secret", []string{"Secret", "Namespace"}))
+		Expect(state.Indexes).Should(HaveKeyWithValue("secret", IndexState{Sources: 2, Keys: 2}))
+		Expect(state.TopErrors["secret"]).Should(Equal([]ErrorCount{
+			{Reason: "CopyConflict", Count: 2},
+			{Reason: "Forbidden", Count: 1},
+		}))
+		Expect(state.LastFullResync).Should(BeNil())
+	})
+})
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CopyState is the state of the copy of a source in one of its target namespaces
+type CopyState string
+
+const (
+	// CopySynced copies have the same data as their source
+	CopySynced CopyState = "Synced"
+	// CopyOutOfDate copies differ from their source
+	CopyOutOfDate CopyState = "OutOfDate"
+	// CopyMissing copies don't exist in a selected namespace
+	CopyMissing CopyState = "Missing"
+)
+
+// CopyStatus is the state of a copy in a target namespace
+type CopyStatus struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name,omitempty"`
+	State     CopyState `json:"state"`
+}
+
+// SourceStatus summarizes the copies of a source
+type SourceStatus struct {
+	Kind      string       `json:"kind"`
+	Namespace string       `json:"namespace"`
+	Name      string       `json:"name"`
+	Selector  string       `json:"selector"`
+	Targets   int          `json:"targets"`
+	Synced    int          `json:"synced"`
+	OutOfDate int          `json:"outOfDate"`
+	Missing   int          `json:"missing"`
+	Copies    []CopyStatus `json:"copies,omitempty"`
+}
+
+// SyncStatus returns the status of every source in the cluster, without the individual copies
+func SyncStatus(ctx context.Context, c client.Client) ([]SourceStatus, error) {
+	topology, err := ExportTopology(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]SourceStatus, 0, len(topology.Sources))
+	for _, s := range topology.Sources {
+		status, err := CopiesOf(ctx, c, s.Kind, types.NamespacedName{Namespace: s.Namespace, Name: s.Name})
+		if err != nil {
+			return nil, err
+		}
+		status.Copies = nil
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// CopiesOf returns the status of the source of kind with key and of its copy in every target namespace
+func CopiesOf(ctx context.Context, c client.Client, kind string, key types.NamespacedName) (*SourceStatus, error) {
+	src, err := NewObjectForKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Get(ctx, key, src); err != nil {
+		return nil, err
+	}
+	selector, _ := SyncSelector(src)
+	status := &SourceStatus{Kind: kindOf(src), Namespace: key.Namespace, Name: key.Name, Selector: selector, Copies: []CopyStatus{}}
+	targets, err := MatchingNamespaces(ctx, c, key.Namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(targets)
+	srcData := objectData(src)
+	for _, target := range targets {
+		cp, err := findCopy(ctx, c, src, target)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		copyStatus := CopyStatus{Namespace: target, State: CopyMissing}
+		if !apierrors.IsNotFound(err) {
+			copyStatus.Name, copyStatus.State = cp.GetName(), CopySynced
+			for _, d := range diffData(srcData, objectData(cp)) {
+				if d.Status != KeyUnchanged {
+					copyStatus.State = CopyOutOfDate
+					break
+				}
+			}
+		}
+		switch copyStatus.State {
+		case CopySynced:
+			status.Synced++
+		case CopyOutOfDate:
+			status.OutOfDate++
+		case CopyMissing:
+			status.Missing++
+		}
+		status.Copies = append(status.Copies, copyStatus)
+	}
+	status.Targets = len(targets)
+	return status, nil
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Sync status\n", func() {
+	It("Should report synced, out of date and missing copies", func() {
+		namespace := func(name string) *corev1.Namespace {
+			return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"env": "prod"}}}
+		}
+		copyIn := func(namespace, password string) *corev1.Secret {
+			return &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-src-status-00", Namespace: namespace, Labels: map[string]string{
+					sourceLabelNamespace: "test-src-status-ns-00",
+					sourceLabelName:      "test-src-status-00",
+				}},
+				Data: map[string][]byte{"password": []byte(password)},
+			}
+		}
+		src := copyIn("test-src-status-ns-00", "test-src-status-00")
+		src.Labels = nil
+		src.Annotations = map[string]string{syncKey: "env=prod"}
+		c := fake.NewClientBuilder().
+			WithScheme(clientgoscheme.Scheme).
+			WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-src-status-ns-00"}},
+				namespace("test-target-status-ns-00"), namespace("test-target-status-ns-01"), namespace("test-target-status-ns-02"),
+				src,
+				copyIn("test-target-status-ns-00", "test-src-status-00"),
+				copyIn("test-target-status-ns-01", "stale"),
+			).
+			Build()
+
+		status, err := CopiesOf(context.Background(), c, "secret", types.NamespacedName{Namespace: "test-src-status-ns-00", Name: "test-src-status-00"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(status.Copies).Should(Equal([]CopyStatus{
+			{Namespace: "test-target-status-ns-00", Name: "test-src-status-00", State: CopySynced},
+			{Namespace: "test-target-status-ns-01", Name: "test-src-status-00", State: CopyOutOfDate},
+			{Namespace: "test-target-status-ns-02", State: CopyMissing},
+		}))
+
+		statuses, err := SyncStatus(context.Background(), c)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(statuses).Should(Equal([]SourceStatus{{
+			Kind: "secret", Namespace: "test-src-status-ns-00", Name: "test-src-status-00", Selector: "env=prod",
+			Targets: 3, Synced: 1, OutOfDate: 1, Missing: 1,
+		}}))
+	})
+})
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// reasonSyncStuck is used for events when a copy has been missing from a selected namespace past the deadline
+	reasonSyncStuck = "SyncStuck"
+	// reasonSyncBlocked is used for events when a copy can't be created until someone intervenes
+	reasonSyncBlocked = "SyncBlocked"
+)
+
+// errCopyConflict is returned when the target namespace already has a copy of a different source
+var errCopyConflict = errors.New("copy conflict")
+
+// syncTarget identifies the copy of a source in a target namespace
+type syncTarget struct {
+	kind      string
+	namespace string
+	name      string
+	target    string
+}
+
+// syncTracker tracks how long selected namespaces have lacked the copy of a source and emits an event on the
+// source once a copy is stuck past the deadline. Blockers that won't resolve on their own, such as a policy
+// denying the copy, are reported right away.
+type syncTracker struct {
+	mu       sync.Mutex
+	deadline time.Duration
+	recorder record.EventRecorder
+	pending  map[syncTarget]time.Time
+	reported sets.Set[syncTarget]
+}
+
+// newSyncTracker returns a syncTracker, or nil if deadline is 0 which disables stuck sync detection
+func newSyncTracker(deadline time.Duration, recorder record.EventRecorder) *syncTracker {
+	if deadline <= 0 {
+		return nil
+	}
+	return &syncTracker{
+		deadline: deadline,
+		recorder: suppressWarnings(recorder),
+		pending:  map[syncTarget]time.Time{},
+		reported: sets.New[syncTarget](),
+	}
+}
+
+// Synced clears any failures recorded for the copy of src in target
+func (t *syncTracker) Synced(src client.Object, target string) {
+	if t == nil {
+		return
+	}
+	key := newSyncTarget(src, target)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, key)
+	t.reported.Delete(key)
+}
+
+// Failed records that the copy of src in target could not be synced because of err and returns how long to wait
+// before checking the copy again
+func (t *syncTracker) Failed(src client.Object, target string, err error) time.Duration {
+	if t == nil {
+		return 0
+	}
+	key := newSyncTarget(src, target)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	since, ok := t.pending[key]
+	if !ok {
+		since = time.Now()
+		t.pending[key] = since
+	}
+	if t.reported.Has(key) {
+		return 0
+	}
+	if isPermanentSyncError(err) {
+		t.reported.Insert(key)
+		t.recorder.Eventf(src, corev1.EventTypeWarning, reasonSyncBlocked,
+			"copy in namespace %s is blocked: %s", target, err)
+		return 0
+	}
+	waiting := time.Since(since)
+	if waiting < t.deadline {
+		return t.deadline - waiting
+	}
+	t.reported.Insert(key)
+	t.recorder.Eventf(src, corev1.EventTypeWarning, reasonSyncStuck,
+		"copy in namespace %s has been missing for %s: %s", target, waiting.Round(time.Second), err)
+	return 0
+}
+
+// Retain forgets the copies of src in namespaces that are not in targets, e.g. when they are no longer selected
+func (t *syncTracker) Retain(src client.Object, targets sets.Set[string]) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.pending {
+		if key == newSyncTarget(src, key.target) && !targets.Has(key.target) {
+			delete(t.pending, key)
+			t.reported.Delete(key)
+		}
+	}
+}
+
+func newSyncTarget(src client.Object, target string) syncTarget {
+	return syncTarget{kind: kindOf(src), namespace: src.GetNamespace(), name: src.GetName(), target: target}
+}
+
+// isPermanentSyncError returns true for errors that won't go away by retrying, e.g. an admission policy denying
+// the copy, a copy of a different source already in the target namespace, a copy name on the deny list or a Role
+// outside the RBAC source namespaces
+func isPermanentSyncError(err error) bool {
+	return apierrors.IsForbidden(err) || apierrors.IsInvalid(err) || errors.Is(err, errCopyConflict) ||
+		errors.Is(err, errCopyDenied) || errors.Is(err, errRBACSourceRefused)
+}
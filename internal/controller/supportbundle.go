@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// redacted replaces annotation values that may carry or reveal the data of an object in a support bundle
+const redacted = "<redacted>"
+
+// dataDerivedAnnotations are kopy annotations whose values are computed from the data of an object. A hash of a short
+// credential can be brute forced, so support bundles don't carry them.
+var dataDerivedAnnotations = []string{
+	sourceHashKey, dataHashKey, dataRevisionKey, signatureKey, postSyncRevisionKey, pendingApprovalKey, confirmKey,
+}
+
+// SupportObjects returns the redacted metadata of every source and copy for a support bundle. Objects are listed as
+// metadata only, so their data is never sent to the caller. Of the annotations only the kopy annotations that don't
+// derive from the data keep their values, e.g. last-applied-configuration holds the data of objects applied with
+// kubectl.
+func SupportObjects(ctx context.Context, c client.Client) ([]metav1.PartialObjectMetadata, error) {
+	objects := []metav1.PartialObjectMetadata{}
+	for _, kind := range []string{"Secret", "ConfigMap", "ServiceAccount"} {
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(kind + "List"))
+		if err := c.List(ctx, list); err != nil {
+			return nil, err
+		}
+		for _, o := range list.Items {
+			_, source := o.Annotations[syncKey]
+			_, isCopy := o.Labels[sourceLabelNamespace]
+			if !source && !isCopy {
+				continue
+			}
+			o.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: kind}
+			redactMetadata(&o.ObjectMeta)
+			objects = append(objects, o)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		a, b := objects[i], objects[j]
+		return a.Kind+"/"+a.Namespace+"/"+a.Name < b.Kind+"/"+b.Namespace+"/"+b.Name
+	})
+	return objects, nil
+}
+
+// redactMetadata removes everything from m that may carry or reveal the data of the object
+func redactMetadata(m *metav1.ObjectMeta) {
+	m.ManagedFields = nil
+	for k := range m.Annotations {
+		if !strings.HasPrefix(k, kopyPrefix) {
+			m.Annotations[k] = redacted
+		}
+	}
+	for _, k := range dataDerivedAnnotations {
+		if _, ok := m.Annotations[k]; ok {
+			m.Annotations[k] = redacted
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Support bundles\n", func() {
+	It("Should only carry the redacted metadata of sources and copies", func() {
+		src := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-support-00", Namespace: "test-src-support-ns-00",
+				Annotations: map[string]string{
+					syncKey: "env=prod",
+					"kubectl.kubernetes.io/last-applied-configuration": `{"data":{"password":"aHVudGVyMg=="}}`,
+				},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		cp, err := newCopy(src, "test-dst-support-ns-00", Options{})
+		Expect(err).ShouldNot(HaveOccurred())
+		unrelated := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-support-00", Namespace: "test-src-support-ns-00"}}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, cp, unrelated).Build()
+
+		objects, err := SupportObjects(context.Background(), c)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(objects).Should(HaveLen(2))
+		b, err := json.Marshal(objects)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(string(b)).ShouldNot(Or(
+			ContainSubstring("hunter2"), ContainSubstring("aHVudGVyMg=="), ContainSubstring(dataRevision(src)),
+		))
+		Expect(objects[0].Annotations).Should(HaveKeyWithValue(sourceHashKey, redacted))
+		Expect(objects[1].Annotations).Should(And(
+			HaveKeyWithValue(syncKey, "env=prod"),
+			HaveKeyWithValue("kubectl.kubernetes.io/last-applied-configuration", redacted),
+		))
+	})
+})
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// suppressKey lists the warnings that aren't recorded as events for a source, e.g. "conflict,copy-mutated". Noisy
+// conditions that are accepted for a source then don't flood its events and the alerts built on them. The suppressed
+// warnings are listed in the KopyReport of the source.
+const suppressKey = kopyPrefix + "suppress"
+
+// warningConflict suppresses the SyncFailed warnings of copies that belong to another source
+const warningConflict = "conflict"
+
+// suppressibleWarnings are the warnings that can be suppressed and the event reasons they cover. The conflict
+// warning is a SyncFailed event caused by a conflicting copy, which syncGroup suppresses itself.
+var suppressibleWarnings = map[string][]string{
+	warningConflict:         nil,
+	"sync-failed":           {reasonSyncFailed},
+	"copy-mutated":          {reasonCopyMutated},
+	"source-stale":          {reasonSourceStale},
+	"sync-stuck":            {reasonSyncStuck, reasonSyncBlocked},
+	"remote-sync-failed":    {reasonRemoteSyncFailed},
+	"post-sync-hook-failed": {reasonPostSyncHookFailed},
+	"pending-approval":      {reasonPendingApproval},
+}
+
+// suppressedWarnings returns the warnings suppressed for src, names kopy doesn't know are ignored
+func suppressedWarnings(src client.Object) sets.Set[string] {
+	suppressed := sets.New[string]()
+	v, ok := src.GetAnnotations()[suppressKey]
+	if !ok {
+		return suppressed
+	}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := suppressibleWarnings[name]; ok {
+			suppressed.Insert(name)
+		}
+	}
+	return suppressed
+}
+
+// suppresses returns true if src suppresses the warning event with reason
+func suppresses(src client.Object, reason string) bool {
+	for name := range suppressedWarnings(src) {
+		if slices.Contains(suppressibleWarnings[name], reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressingRecorder drops the warning events of objects that suppress them
+type suppressingRecorder struct {
+	record.EventRecorder
+}
+
+// suppressWarnings returns recorder dropping the warnings suppressed by the object of the event, nil if recorder is
+// nil
+func suppressWarnings(recorder record.EventRecorder) record.EventRecorder {
+	if recorder == nil {
+		return nil
+	}
+	if _, ok := recorder.(suppressingRecorder); ok {
+		return recorder
+	}
+	return suppressingRecorder{recorder}
+}
+
+// suppressed returns true if the warning event with reason of object is suppressed, and counts it
+func (r suppressingRecorder) suppressed(object runtime.Object, eventtype, reason string) bool {
+	if eventtype != corev1.EventTypeWarning {
+		return false
+	}
+	src, ok := object.(client.Object)
+	if !ok || !suppresses(src, reason) {
+		return false
+	}
+	suppressedWarningsTotal.WithLabelValues(reason).Inc()
+	return true
+}
+
+func (r suppressingRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if !r.suppressed(object, eventtype, reason) {
+		r.EventRecorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (r suppressingRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if !r.suppressed(object, eventtype, reason) {
+		r.EventRecorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r suppressingRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	if !r.suppressed(object, eventtype, reason) {
+		r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	}
+}
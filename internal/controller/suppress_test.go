@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	syncv1alpha1 "github.com/flynshue/kopy/api/v1alpha1"
+)
+
+var _ = Describe("Suppressed warnings\n", func() {
+	const (
+		namespace = "test-src-suppress-ns-00"
+		target    = "test-dst-suppress-ns-00"
+	)
+	events := func(recorder *record.FakeRecorder) []string {
+		recorded := []string{}
+		for len(recorder.Events) > 0 {
+			recorded = append(recorded, <-recorder.Events)
+		}
+		return recorded
+	}
+
+	It("Should only drop the warnings the source suppresses", func() {
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-suppress-00", Namespace: namespace,
+			Annotations: map[string]string{suppressKey: "Copy-Mutated, size-warning"},
+		}}
+		Expect(sets.List(suppressedWarnings(src))).Should(Equal([]string{"copy-mutated"}))
+		fakeRecorder := record.NewFakeRecorder(5)
+		recorder := suppressWarnings(fakeRecorder)
+		recorder.Eventf(src, corev1.EventTypeWarning, reasonCopyMutated, "Copy was mutated")
+		recorder.Eventf(src, corev1.EventTypeWarning, reasonSourceStale, "Source is stale")
+		recorder.Eventf(src, corev1.EventTypeNormal, reasonCopyMutated, "Copy was mutated")
+		recorder.Eventf(&corev1.Secret{}, corev1.EventTypeWarning, reasonCopyMutated, "Copy was mutated")
+		Expect(events(fakeRecorder)).Should(Equal([]string{
+			"Warning SourceStale Source is stale",
+			"Normal CopyMutated Copy was mutated",
+			"Warning CopyMutated Copy was mutated",
+		}))
+		Expect(suppressWarnings(nil)).Should(BeNil())
+	})
+
+	It("Should suppress conflicts and list the suppressed warnings in the report", func() {
+		ctx := context.Background()
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-suppress-01", Namespace: namespace,
+			Annotations: map[string]string{syncKey: "team=payments"},
+		}}
+		conflicting := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: src.Name, Namespace: target,
+			Labels: map[string]string{sourceLabelNamespace: "test-src-suppress-ns-01", sourceLabelName: src.Name},
+		}}
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).Should(Succeed())
+		Expect(syncv1alpha1.AddToScheme(s)).Should(Succeed())
+		c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+			src, conflicting,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"team": "payments"}}},
+		).WithStatusSubresource(&syncv1alpha1.KopyReport{}).Build()
+		reconcile := func() []string {
+			recorder := record.NewFakeRecorder(20)
+			_, _ = KopyReconcile(NewKopySecret(ctx, c, Options{Reports: true}, recorder),
+				ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)}, nil)
+			return events(recorder)
+		}
+		Expect(reconcile()).Should(ContainElement(HavePrefix("Warning SyncFailed")))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(src), src)).Should(Succeed())
+		src.Annotations[suppressKey] = "conflict"
+		Expect(c.Update(ctx, src)).Should(Succeed())
+		Expect(reconcile()).ShouldNot(ContainElement(HavePrefix("Warning SyncFailed")))
+		report := &syncv1alpha1.KopyReport{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "secret-" + src.Name}, report)).Should(Succeed())
+		Expect(report.Status.Suppressed).Should(Equal([]string{"conflict"}))
+		Expect(report.Status.Failed).Should(BeEquivalentTo(1))
+	})
+})
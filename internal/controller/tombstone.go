@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/flynshue/kopy/internal/kopycrypto"
+)
+
+const (
+	// tombstoneLabel marks the Secrets that record a copy deleted with its source, its value is the kind of the copy
+	tombstoneLabel = kopyPrefix + "tombstone"
+	// tombstoneOfKey, tombstoneSourceKey, tombstoneDeletedKey and tombstoneExpiresKey annotate a tombstone with the
+	// copy and source it records and when the copy was deleted and the tombstone expires
+	tombstoneOfKey      = kopyPrefix + "tombstone-of"
+	tombstoneSourceKey  = kopyPrefix + "tombstone-source"
+	tombstoneDeletedKey = kopyPrefix + "deleted-at"
+	tombstoneExpiresKey = kopyPrefix + "expires-at"
+	// tombstoneObjectKey is the data key of a tombstone that holds the deleted copy
+	tombstoneObjectKey = "object.json"
+	// DefaultTombstoneRetention is how long deleted copies can be restored by default
+	DefaultTombstoneRetention = 24 * time.Hour
+)
+
+// Tombstone is the record of a copy deleted with its source, kept until it expires so the copy can be restored
+type Tombstone struct {
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	DeletedAt time.Time `json:"deletedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// tombstoneName returns the name of the tombstone of the copy of kind identified by key
+func tombstoneName(kind string, key types.NamespacedName) string {
+	h := kopycrypto.Sum([]byte(kind + "/" + key.String()))
+	return "kopy-tombstone-" + hex.EncodeToString(h[:])[:16]
+}
+
+// recordTombstone keeps the copy cp in a tombstone in TombstoneNamespace before it is deleted with its source. The
+// tombstone is a Secret because the copy may be one.
+func (o Options) recordTombstone(ctx context.Context, c client.Client, cp client.Object) error {
+	if o.TombstoneNamespace == "" {
+		return nil
+	}
+	kind := kindOf(cp)
+	obj := cp.DeepCopyObject().(client.Object)
+	obj.SetResourceVersion("")
+	obj.SetUID("")
+	obj.SetCreationTimestamp(metav1.Time{})
+	obj.SetDeletionTimestamp(nil)
+	obj.SetManagedFields(nil)
+	obj.SetOwnerReferences(nil)
+	obj.SetGeneration(0)
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	key := client.ObjectKeyFromObject(cp)
+	deleted := now().UTC()
+	tombstone := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tombstoneName(kind, key),
+			Namespace: o.TombstoneNamespace,
+			Labels:    map[string]string{tombstoneLabel: kind},
+			Annotations: map[string]string{
+				tombstoneOfKey:      key.String(),
+				tombstoneSourceKey:  inventorySource(cp),
+				tombstoneDeletedKey: deleted.Format(time.RFC3339),
+				tombstoneExpiresKey: deleted.Add(o.TombstoneRetention).Format(time.RFC3339),
+			},
+		},
+		Data: map[string][]byte{tombstoneObjectKey: b},
+	}
+	if err := c.Create(ctx, tombstone); !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(tombstone), existing); err != nil {
+		return err
+	}
+	existing.Labels, existing.Annotations, existing.Data = tombstone.Labels, tombstone.Annotations, tombstone.Data
+	return c.Update(ctx, existing)
+}
+
+// tombstoneOf returns the record of the tombstone Secret s
+func tombstoneOf(s *corev1.Secret) Tombstone {
+	namespace, name, _ := strings.Cut(s.Annotations[tombstoneOfKey], "/")
+	deleted, _ := time.Parse(time.RFC3339, s.Annotations[tombstoneDeletedKey])
+	expires, _ := time.Parse(time.RFC3339, s.Annotations[tombstoneExpiresKey])
+	return Tombstone{
+		Kind: s.Labels[tombstoneLabel], Namespace: namespace, Name: name,
+		Source: s.Annotations[tombstoneSourceKey], DeletedAt: deleted, ExpiresAt: expires,
+	}
+}
+
+// ListTombstones returns the tombstones in namespace that haven't expired, most recently deleted first
+func ListTombstones(ctx context.Context, c client.Client, namespace string) ([]Tombstone, error) {
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, client.InNamespace(namespace), client.HasLabels{tombstoneLabel}); err != nil {
+		return nil, err
+	}
+	tombstones := []Tombstone{}
+	for i := range secrets.Items {
+		if t := tombstoneOf(&secrets.Items[i]); now().Before(t.ExpiresAt) {
+			tombstones = append(tombstones, t)
+		}
+	}
+	sort.Slice(tombstones, func(i, j int) bool { return tombstones[i].DeletedAt.After(tombstones[j].DeletedAt) })
+	return tombstones, nil
+}
+
+// RestoreCopy recreates the copy of kind identified by key from its tombstone in namespace and deletes the tombstone.
+// The restored copy isn't managed by kopy anymore: it has neither the kopy finalizer nor the origin labels, so it is
+// only synced again if its source is recreated.
+func RestoreCopy(ctx context.Context, c client.Client, namespace, kind string, key types.NamespacedName) (*Tombstone, error) {
+	cp, err := NewObjectForKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	kind = kindOf(cp)
+	s := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: tombstoneName(kind, key)}, s); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("no tombstone of %s %s in namespace %s", kind, key, namespace)
+		}
+		return nil, err
+	}
+	t := tombstoneOf(s)
+	if !now().Before(t.ExpiresAt) {
+		return nil, fmt.Errorf("the tombstone of %s %s expired at %s", kind, key, t.ExpiresAt.Format(time.RFC3339))
+	}
+	if err := json.Unmarshal(s.Data[tombstoneObjectKey], cp); err != nil {
+		return nil, fmt.Errorf("unable to read the tombstone of %s %s: %w", kind, key, err)
+	}
+	removeSyncFinalizer(cp)
+	labels, annotations := cp.GetLabels(), cp.GetAnnotations()
+	delete(labels, sourceLabelNamespace)
+	delete(labels, sourceLabelName)
+	delete(annotations, pendingDeletionKey)
+	cp.SetLabels(labels)
+	cp.SetAnnotations(annotations)
+	if err := c.Create(ctx, cp); err != nil {
+		return nil, err
+	}
+	return &t, client.IgnoreNotFound(c.Delete(ctx, s))
+}
+
+var _ manager.Runnable = &TombstoneCollector{}
+var _ manager.LeaderElectionRunnable = &TombstoneCollector{}
+
+// TombstoneCollector periodically deletes the tombstones in Namespace that expired
+type TombstoneCollector struct {
+	client.Client
+	Namespace string
+	Interval  time.Duration
+}
+
+// Start deletes expired tombstones every Interval until ctx is cancelled
+func (t *TombstoneCollector) Start(ctx context.Context) error {
+	log := ctrllog.Log.WithName("tombstones")
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+	for {
+		if err := t.Collect(ctx); err != nil {
+			log.Error(err, "unable to delete expired tombstones", "namespace", t.Namespace)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns true so only the leader deletes tombstones
+func (t *TombstoneCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Collect deletes the tombstones that expired
+func (t *TombstoneCollector) Collect(ctx context.Context) error {
+	secrets := &corev1.SecretList{}
+	if err := t.List(ctx, secrets, client.InNamespace(t.Namespace), client.HasLabels{tombstoneLabel}); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		if now().Before(tombstoneOf(&secrets.Items[i]).ExpiresAt) {
+			continue
+		}
+		if err := t.Delete(ctx, &secrets.Items[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Tombstones\n", func() {
+	const tombstoneNamespace = "test-kopy-system"
+	start := time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+	opts := Options{CascadeDelete: true, TombstoneNamespace: tombstoneNamespace, TombstoneRetention: time.Hour}
+	newSource := func() *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-tombstone-00", Namespace: "test-src-tombstone-ns-00", Finalizers: []string{syncFinalizer},
+			DeletionTimestamp: &metav1.Time{Time: start}, Annotations: map[string]string{syncKey: "env=prod"},
+		}}
+	}
+	newCopy := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-tombstone-00", Namespace: "test-dst-tombstone-ns-00", Finalizers: []string{syncFinalizer},
+				Labels: map[string]string{
+					sourceLabelNamespace: "test-src-tombstone-ns-00", sourceLabelName: "test-src-tombstone-00", "team": "a",
+				},
+			},
+			Data: map[string][]byte{"password": []byte("test-src-tombstone-00")},
+		}
+	}
+	BeforeEach(func() {
+		now = func() time.Time { return start }
+	})
+	AfterEach(func() {
+		now = time.Now
+	})
+
+	It("Should restore a copy deleted with its source as an unmanaged copy", func() {
+		src, cp := newSource(), newCopy()
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, cp).Build()
+		Expect(opts.cascadeSource(context.Background(), c, record.NewFakeRecorder(1), src)).Should(Succeed())
+		Expect(apierrors.IsNotFound(c.Get(context.Background(), client.ObjectKeyFromObject(cp), &corev1.Secret{}))).Should(BeTrue())
+
+		tombstones, err := ListTombstones(context.Background(), c, tombstoneNamespace)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(tombstones).Should(Equal([]Tombstone{{
+			Kind: "secret", Namespace: "test-dst-tombstone-ns-00", Name: "test-src-tombstone-00",
+			Source: "test-src-tombstone-ns-00/test-src-tombstone-00", DeletedAt: start, ExpiresAt: start.Add(time.Hour),
+		}}))
+
+		_, err = RestoreCopy(context.Background(), c, tombstoneNamespace, "secrets", client.ObjectKeyFromObject(cp))
+		Expect(err).ShouldNot(HaveOccurred())
+		restored := &corev1.Secret{}
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(cp), restored)).Should(Succeed())
+		Expect(restored.Data).Should(Equal(cp.Data))
+		Expect(restored.Labels).Should(Equal(map[string]string{"team": "a"}))
+		Expect(restored.Finalizers).Should(BeEmpty())
+		Expect(ListTombstones(context.Background(), c, tombstoneNamespace)).Should(BeEmpty())
+	})
+
+	It("Should not restore copies or keep tombstones after the retention", func() {
+		src, cp := newSource(), newCopy()
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(src, cp).Build()
+		Expect(opts.cascadeSource(context.Background(), c, record.NewFakeRecorder(1), src)).Should(Succeed())
+
+		now = func() time.Time { return start.Add(time.Hour) }
+		_, err := RestoreCopy(context.Background(), c, tombstoneNamespace, "secret", client.ObjectKeyFromObject(cp))
+		Expect(err).Should(MatchError(ContainSubstring("expired")))
+		Expect((&TombstoneCollector{Client: c, Namespace: tombstoneNamespace}).Collect(context.Background())).Should(Succeed())
+		secrets := &corev1.SecretList{}
+		Expect(c.List(context.Background(), secrets, client.InNamespace(tombstoneNamespace))).Should(Succeed())
+		Expect(secrets.Items).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// kopyPrefix is the prefix shared by all kopy labels and annotations
+const kopyPrefix = Domain + "/"
+
+// Domain is the domain of the labels and annotations kopy reads and writes
+const Domain = "kopy.kot-labs.com"
+
+// Topology is a snapshot of all sources managed by kopy and the namespaces they sync to
+type Topology struct {
+	Sources []SourceTopology `json:"sources"`
+}
+
+// SourceTopology describes a single source, its kopy annotations and the namespaces it syncs to
+type SourceTopology struct {
+	Kind        string            `json:"kind"`
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	Selector    string            `json:"selector"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Targets     []string          `json:"targets,omitempty"`
+}
+
+// ExportTopology lists every source of the enabled kinds that carries the sync annotation along with its target
+// namespaces
+func ExportTopology(ctx context.Context, c client.Client) (*Topology, error) {
+	objects := []client.Object{}
+	for _, kind := range enabledKinds() {
+		list, err := newObjectListForKind(kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.List(ctx, list); err != nil {
+			return nil, err
+		}
+		if err := meta.EachListItem(list, func(o runtime.Object) error {
+			objects = append(objects, o.(client.Object))
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	topology := &Topology{}
+	for _, o := range objects {
+		selector, ok := SyncSelector(o)
+		if !ok {
+			continue
+		}
+		targets, err := MatchingNamespaces(ctx, c, o.GetNamespace(), selector)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(targets)
+		topology.Sources = append(topology.Sources, SourceTopology{
+			Kind:        kindOf(o),
+			Namespace:   o.GetNamespace(),
+			Name:        o.GetName(),
+			Selector:    selector,
+			Annotations: kopyAnnotations(o),
+			Targets:     targets,
+		})
+	}
+	return topology, nil
+}
+
+// ApplySourceTopology sets the kopy annotations from s on the object in the cluster.
+// Annotations are only modified on o; the caller is responsible for updating the object.
+func ApplySourceTopology(o client.Object, s SourceTopology) {
+	annotations := o.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for k, v := range s.Annotations {
+		if strings.HasPrefix(k, kopyPrefix) {
+			annotations[k] = v
+		}
+	}
+	annotations[syncKey] = s.Selector
+	o.SetAnnotations(annotations)
+}
+
+// kopyAnnotations returns the annotations on o that are owned by kopy, excluding the sync annotation itself
+func kopyAnnotations(o client.Object) map[string]string {
+	var annotations map[string]string
+	for k, v := range o.GetAnnotations() {
+		if k == syncKey || !strings.HasPrefix(k, kopyPrefix) {
+			continue
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// kindOf returns the kind name used by the CLI and api for o
+func kindOf(o client.Object) string {
+	if k, ok := kindForObject(o); ok {
+		return k.kindName()
+	}
+	if u, ok := o.(*unstructured.Unstructured); ok {
+		return unstructuredKindName(u.GroupVersionKind())
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// traceKey turns on verbose tracing of the selector of a source until the RFC3339 time it is set to, e.g.
+// 2024-05-01T15:00:00Z. Every sync then logs how the selector was parsed and why each namespace matched or not,
+// without enabling debug logs for every source.
+const traceKey = kopyPrefix + "trace"
+
+// selectorSamples counts the selector evaluations, every SelectorLogSampleRate-th one is logged at debug level
+var selectorSamples atomic.Uint64
+
+// selectorTrace logs how the selector of a source was parsed and evaluated. It logs nothing unless enabled.
+type selectorTrace struct {
+	log     logr.Logger
+	enabled bool
+	// traced is set for sources with the trace annotation, their decisions for namespaces that don't match are
+	// logged too
+	traced bool
+}
+
+// selectorTraceFor returns the trace of src: verbose if src is traced, at debug level for a sample of the other
+// sources, disabled otherwise
+func (o Options) selectorTraceFor(ctx context.Context, src client.Object) selectorTrace {
+	log := ctrllog.FromContext(ctx).WithName("selector").WithValues("kind", kindOf(src), "namespace", src.GetNamespace(), "name", src.GetName())
+	if v, ok := src.GetAnnotations()[traceKey]; ok {
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			log.Error(err, "invalid trace annotation, expected an RFC3339 time", "trace", v)
+		} else if now().Before(until) {
+			return selectorTrace{log: log.WithValues("traceUntil", v), enabled: true, traced: true}
+		}
+	}
+	debug := log.V(1)
+	if o.SelectorLogSampleRate > 0 && debug.Enabled() && selectorSamples.Add(1)%uint64(o.SelectorLogSampleRate) == 0 {
+		return selectorTrace{log: debug, enabled: true}
+	}
+	return selectorTrace{}
+}
+
+// parsed logs the sync annotation of src and the selector it was parsed into, or just the selector if src has none
+func (t selectorTrace) parsed(src client.Object, selector labels.Selector) {
+	if !t.enabled {
+		return
+	}
+	v, ok := SyncSelector(src)
+	if !ok {
+		// pinned sources and KopyTokens are selected without a sync annotation
+		t.log.Info("evaluating the selector", "selector", selector.String())
+		return
+	}
+	format, _ := DetectSyncFormat(v)
+	if _, err := ParseSyncSelector(v); err != nil {
+		t.log.Info("unable to parse the sync annotation, no namespace is selected", "syncAnnotation", v, "format", format, "error", err.Error())
+		return
+	}
+	t.log.Info("parsed the sync annotation", "syncAnnotation", v, "format", format, "selector", selector.String())
+}
+
+// matched logs the namespaces selected for src. Traced sources also log why every other namespace wasn't selected,
+// which costs a list of all namespaces and is only done while the trace annotation is set.
+func (t selectorTrace) matched(ctx context.Context, c client.Client, o Options, src client.Object, selector labels.Selector, namespaces []corev1.Namespace) {
+	if !t.enabled {
+		return
+	}
+	selected := namespaceNames(namespaces)
+	t.log.Info("selected namespaces", "count", selected.Len(), "namespaces", sets.List(selected))
+	if !t.traced {
+		return
+	}
+	all := &corev1.NamespaceList{}
+	if err := c.List(ctx, all); err != nil {
+		t.log.Info("unable to list namespaces to trace the selector", "error", err.Error())
+		return
+	}
+	for _, ns := range all.Items {
+		if selected.Has(ns.Name) {
+			t.log.Info("namespace matched", "targetNamespace", ns.Name, "labels", labels.Set(ns.Labels).String())
+			continue
+		}
+		t.log.Info("namespace not matched", "targetNamespace", ns.Name, "reason", o.unmatchedReason(src, &ns, selector))
+	}
+}
+
+// unmatchedReason describes why the namespace ns isn't selected for src
+func (o Options) unmatchedReason(src client.Object, ns *corev1.Namespace, selector labels.Selector) string {
+	switch {
+	case ns.Name == src.GetNamespace():
+		return "is the namespace of the source"
+	case slices.Contains(o.ExcludedNamespaces, ns.Name):
+		return "is excluded"
+	case o.NamespaceScoped() && !slices.Contains(o.Namespaces, ns.Name):
+		return "is outside the namespaces kopy is scoped to"
+	case ns.DeletionTimestamp != nil:
+		return "is terminating"
+	}
+	nsLabels := labels.Set(ns.Labels)
+	reqs, selectable := selector.Requirements()
+	if !selectable {
+		return "the selector selects no namespace"
+	}
+	for _, r := range reqs {
+		if !r.Matches(nsLabels) {
+			return fmt.Sprintf("requirement %s not met, %s", r.String(), explainRequirement(r, nsLabels))
+		}
+	}
+	if mismatch := topologyMismatch(src, ns); mismatch != "" {
+		return mismatch
+	}
+	if o.VClusterMode == VClusterSkip && isVClusterManaged(ns) {
+		return "is managed by a virtual cluster"
+	}
+	return "not selected"
+}
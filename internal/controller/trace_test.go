@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var _ = Describe("Selector tracing\n", func() {
+	const (
+		namespace = "test-src-trace-ns-00"
+		matching  = "test-dst-trace-ns-00"
+		other     = "test-dst-trace-ns-01"
+	)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var (
+		c    client.Client
+		src  *corev1.Secret
+		logs []string
+		ctx  context.Context
+	)
+	BeforeEach(func() {
+		now = func() time.Time { return start }
+		DeferCleanup(func() { now = time.Now })
+		src = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-src-trace-00", Namespace: namespace,
+			Annotations: map[string]string{syncKey: "team=payments"},
+		}}
+		c = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: matching, Labels: map[string]string{"team": "payments"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: other, Labels: map[string]string{"team": "billing"}}},
+		).Build()
+		logs = nil
+		log := funcr.New(func(prefix, args string) { logs = append(logs, args) }, funcr.Options{Verbosity: 1})
+		ctx = ctrllog.IntoContext(context.Background(), log)
+	})
+	syncNamespaces := func(opts Options) {
+		ls, err := ParseSyncSelector(src.Annotations[syncKey])
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = opts.syncNamespaces(ctx, c, src, ls)
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+
+	It("Should log why every namespace matched or not while the source is traced", func() {
+		src.Annotations[traceKey] = start.Add(time.Hour).Format(time.RFC3339)
+		syncNamespaces(Options{})
+		Expect(logs).Should(ContainElements(
+			And(ContainSubstring("parsed the sync annotation"), ContainSubstring(`"selector"="team=payments"`)),
+			And(ContainSubstring("namespace matched"), ContainSubstring(matching)),
+			And(ContainSubstring("namespace not matched"), ContainSubstring(other), ContainSubstring("found team=billing")),
+			And(ContainSubstring("namespace not matched"), ContainSubstring(namespace), ContainSubstring("namespace of the source")),
+		))
+	})
+
+	It("Should stop tracing once the trace annotation expired", func() {
+		src.Annotations[traceKey] = start.Add(-time.Minute).Format(time.RFC3339)
+		syncNamespaces(Options{})
+		Expect(logs).Should(BeEmpty())
+	})
+
+	It("Should log a sample of the selector evaluations at debug level", func() {
+		for range 4 {
+			syncNamespaces(Options{SelectorLogSampleRate: 2})
+		}
+		Expect(logs).Should(HaveLen(4))
+		Expect(logs).Should(HaveEach(ContainSubstring(`"level"=1`)))
+		Expect(logs).ShouldNot(ContainElement(ContainSubstring("namespace not matched")))
+	})
+})
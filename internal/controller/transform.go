@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	transformv1 "github.com/flynshue/kopy/pkg/transform/v1"
+)
+
+// FailurePolicy decides what happens to a copy when its transform webhook can't be reached or returns an error
+type FailurePolicy string
+
+const (
+	// FailurePolicyFail fails the sync of the copy so it is retried, the copy is never written untransformed
+	FailurePolicyFail FailurePolicy = "Fail"
+	// FailurePolicyIgnore writes the copy as if the webhook wasn't configured
+	FailurePolicyIgnore FailurePolicy = "Ignore"
+
+	// defaultTransformTimeout is used for webhooks without a timeout
+	defaultTransformTimeout = 5 * time.Second
+)
+
+// errTransformSkipped is returned when a transform webhook asked kopy not to write a copy
+var errTransformSkipped = errors.New("copy skipped by transform webhook")
+
+// TransformWebhookConfig is the configuration of a single transform webhook
+type TransformWebhookConfig struct {
+	// Name identifies the webhook in logs and metrics
+	Name string `json:"name"`
+	// URL is the https endpoint requests are POSTed to
+	URL string `json:"url"`
+	// CAFile is a PEM bundle used to verify the webhook's serving certificate instead of the system roots
+	CAFile string `json:"caFile,omitempty"`
+	// CertFile and KeyFile are the client certificate kopy presents to the webhook
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// Timeout bounds each request, defaults to 5s
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// FailurePolicy defaults to Fail
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// TransformWebhook calls an external transformer before a copy is written
+type TransformWebhook struct {
+	TransformWebhookConfig
+	httpClient *http.Client
+}
+
+// LoadTransformWebhooks reads the webhooks from a YAML file with a top level "webhooks" list. The webhooks are called
+// in the order they are listed.
+func LoadTransformWebhooks(path string) ([]*TransformWebhook, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := struct {
+		Webhooks []TransformWebhookConfig `json:"webhooks"`
+	}{}
+	if err := yaml.UnmarshalStrict(b, &config); err != nil {
+		return nil, fmt.Errorf("unable to parse transform webhooks %s: %w", path, err)
+	}
+	webhooks := make([]*TransformWebhook, 0, len(config.Webhooks))
+	for _, c := range config.Webhooks {
+		w, err := NewTransformWebhook(c)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+// NewTransformWebhook validates config and sets up the TLS client of the webhook
+func NewTransformWebhook(config TransformWebhookConfig) (*TransformWebhook, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("transform webhook %s has no name", config.URL)
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("transform webhook %s: url must be an https URL", config.Name)
+	}
+	switch config.FailurePolicy {
+	case "":
+		config.FailurePolicy = FailurePolicyFail
+	case FailurePolicyFail, FailurePolicyIgnore:
+	default:
+		return nil, fmt.Errorf("transform webhook %s: unknown failure policy %q", config.Name, config.FailurePolicy)
+	}
+	if config.Timeout.Duration == 0 {
+		config.Timeout.Duration = defaultTransformTimeout
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.CAFile != "" {
+		ca, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("transform webhook %s: %w", config.Name, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("transform webhook %s: no certificates in %s", config.Name, config.CAFile)
+		}
+	}
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("transform webhook %s: %w", config.Name, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &TransformWebhook{
+		TransformWebhookConfig: config,
+		httpClient: &http.Client{
+			Timeout:   config.Timeout.Duration,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+// call sends cp to the webhook and returns the transformed copy. It returns errTransformSkipped when the webhook
+// asked to skip the copy.
+func (w *TransformWebhook) call(ctx context.Context, op transformv1.Operation, src, cp client.Object) (client.Object, error) {
+	source, err := json.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+	object, err := json.Marshal(cp)
+	if err != nil {
+		return nil, err
+	}
+	req := transformv1.Request{
+		APIVersion:      transformv1.APIVersion,
+		UID:             string(uuid.NewUUID()),
+		Operation:       op,
+		TargetNamespace: cp.GetNamespace(),
+		Source:          source,
+		Object:          object,
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := w.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, msg)
+	}
+	var res transformv1.Response
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("unable to decode response: %w", err)
+	}
+	if res.APIVersion != transformv1.APIVersion || res.UID != req.UID {
+		return nil, fmt.Errorf("response for %s %q doesn't match request", res.APIVersion, res.UID)
+	}
+	if res.Skip {
+		return nil, fmt.Errorf("%w %s: %s", errTransformSkipped, w.Name, res.Message)
+	}
+	if len(res.Object) == 0 {
+		return cp, nil
+	}
+	// decode into an empty object, unmarshaling into a copy of cp would merge removed keys back in
+	transformed, err := NewObjectForKind(kindOf(cp))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(res.Object, transformed); err != nil {
+		return nil, fmt.Errorf("unable to decode transformed object: %w", err)
+	}
+	restoreCopyIdentity(cp, transformed)
+	return transformed, nil
+}
+
+// restoreCopyIdentity resets the fields a transform webhook must not change so a webhook can't move a copy or detach
+// it from its source
+func restoreCopyIdentity(cp, transformed client.Object) {
+	transformed.SetName(cp.GetName())
+	transformed.SetNamespace(cp.GetNamespace())
+	transformed.GetObjectKind().SetGroupVersionKind(cp.GetObjectKind().GroupVersionKind())
+	labels := transformed.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range cp.GetLabels() {
+		labels[k] = v
+	}
+	transformed.SetLabels(labels)
+	addSyncFinalizer(transformed)
+}
+
+// prepareCopy runs the transform webhooks on the copy cp of src in order and signs the result. It returns
+// errTransformSkipped if a webhook asked to skip the copy.
+func (o Options) prepareCopy(ctx context.Context, c client.Client, src, cp client.Object) (client.Object, error) {
+	if len(o.TransformWebhooks) > 0 {
+		op := transformv1.OperationUpdate
+		existing := cp.DeepCopyObject().(client.Object)
+		if err := c.Get(ctx, client.ObjectKeyFromObject(cp), existing); apierrors.IsNotFound(err) {
+			op = transformv1.OperationCreate
+		}
+		log := ctrllog.FromContext(ctx)
+		for _, w := range o.TransformWebhooks {
+			transformed, err := w.call(ctx, op, src, cp)
+			switch {
+			case err == nil:
+				transformWebhookCalls.WithLabelValues(w.Name, "success").Inc()
+				cp = transformed
+			case errors.Is(err, errTransformSkipped):
+				transformWebhookCalls.WithLabelValues(w.Name, "skip").Inc()
+				return nil, err
+			case w.FailurePolicy == FailurePolicyIgnore:
+				transformWebhookCalls.WithLabelValues(w.Name, "ignored").Inc()
+				log.Error(err, "transform webhook failed, writing copy without it", "webhook", w.Name)
+			default:
+				transformWebhookCalls.WithLabelValues(w.Name, "error").Inc()
+				return nil, fmt.Errorf("transform webhook %s failed: %w", w.Name, err)
+			}
+		}
+	}
+	if o.SigningKey != nil {
+		if err := signCopy(cp, o.SigningKey); err != nil {
+			return nil, err
+		}
+	}
+	return cp, nil
+}
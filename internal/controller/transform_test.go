@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	transformv1 "github.com/flynshue/kopy/pkg/transform/v1"
+)
+
+// newTestTransformWebhook starts a TLS server that answers transform requests with respond
+func newTestTransformWebhook(policy FailurePolicy, respond func(req transformv1.Request) (transformv1.Response, int)) *TransformWebhook {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req transformv1.Request
+		Expect(json.NewDecoder(r.Body).Decode(&req)).Should(Succeed())
+		res, status := respond(req)
+		res.APIVersion, res.UID = transformv1.APIVersion, req.UID
+		w.WriteHeader(status)
+		Expect(json.NewEncoder(w).Encode(res)).Should(Succeed())
+	}))
+	DeferCleanup(server.Close)
+	w, err := NewTransformWebhook(TransformWebhookConfig{Name: "test", URL: server.URL, FailurePolicy: policy})
+	Expect(err).ShouldNot(HaveOccurred())
+	w.httpClient = server.Client()
+	return w
+}
+
+var _ = Describe("Transform webhooks\n", func() {
+	Context("When a transform webhook is configured", func() {
+		var src *corev1.Secret
+		var opts Options
+
+		BeforeEach(func() {
+			src = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-src-transform-00", Namespace: "test-src-transform-ns-00"},
+				Data:       map[string][]byte{"password": []byte("test-src-transform-00")},
+				Type:       corev1.SecretTypeOpaque,
+			}
+		})
+
+		prepare := func() (*corev1.Secret, error) {
+			c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+			cp, err := newCopy(src, "test-target-transform-ns-00", opts)
+			Expect(err).ShouldNot(HaveOccurred())
+			cp, err = opts.prepareCopy(context.Background(), c, src, cp)
+			if err != nil {
+				return nil, err
+			}
+			return cp.(*corev1.Secret), nil
+		}
+
+		It("Should write the mutated copy but keep its identity", func() {
+			opts.TransformWebhooks = []*TransformWebhook{newTestTransformWebhook(FailurePolicyFail, func(req transformv1.Request) (transformv1.Response, int) {
+				Expect(req.Operation).Should(Equal(transformv1.OperationCreate))
+				Expect(req.TargetNamespace).Should(Equal("test-target-transform-ns-00"))
+				cp := &corev1.Secret{}
+				Expect(json.Unmarshal(req.Object, cp)).Should(Succeed())
+				cp.Data["password"] = []byte("rotated")
+				cp.Name, cp.Namespace, cp.Labels = "moved", "elsewhere", nil
+				b, _ := json.Marshal(cp)
+				return transformv1.Response{Object: b}, http.StatusOK
+			})}
+			cp, err := prepare()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cp.Data).Should(HaveKeyWithValue("password", []byte("rotated")))
+			Expect(cp.Name).Should(Equal(src.Name))
+			Expect(cp.Namespace).Should(Equal("test-target-transform-ns-00"))
+			Expect(cp.Labels).Should(HaveKeyWithValue(sourceLabelNamespace, src.Namespace))
+			Expect(cp.Finalizers).Should(ContainElement(syncFinalizer))
+		})
+
+		It("Should skip the copy when asked to", func() {
+			opts.TransformWebhooks = []*TransformWebhook{newTestTransformWebhook(FailurePolicyFail, func(transformv1.Request) (transformv1.Response, int) {
+				return transformv1.Response{Skip: true, Message: "not for this namespace"}, http.StatusOK
+			})}
+			_, err := prepare()
+			Expect(err).Should(MatchError(errTransformSkipped))
+		})
+
+		It("Should apply the failure policy when the webhook fails", func() {
+			failing := func(transformv1.Request) (transformv1.Response, int) {
+				return transformv1.Response{}, http.StatusInternalServerError
+			}
+			opts.TransformWebhooks = []*TransformWebhook{newTestTransformWebhook(FailurePolicyFail, failing)}
+			_, err := prepare()
+			Expect(err).Should(HaveOccurred())
+
+			opts.TransformWebhooks = []*TransformWebhook{newTestTransformWebhook(FailurePolicyIgnore, failing)}
+			cp, err := prepare()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cp.Data).Should(Equal(src.Data))
+		})
+
+		It("Should refuse webhooks that aren't served over https", func() {
+			_, err := NewTransformWebhook(TransformWebhookConfig{Name: "test", URL: "http://transformer.example.com"})
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ Kopier = &KopyUnstructured{}
+
+// KopyUnstructured copies objects of a kind registered with RegisterSyncKind
+type KopyUnstructured = Kopy[*unstructured.Unstructured]
+
+// NewKopyUnstructured creates a new instance of KopyUnstructured for gvk, recorder is used to emit events and may be nil
+func NewKopyUnstructured(ctx context.Context, c client.Client, gvk schema.GroupVersionKind, opts Options, recorder record.EventRecorder) *KopyUnstructured {
+	return newKopy(ctx, c, unstructuredKind(gvk), opts, recorder)
+}
+
+func unstructuredKind(gvk schema.GroupVersionKind) kopyKind[*unstructured.Unstructured] {
+	return kopyKind[*unstructured.Unstructured]{
+		name:      unstructuredKindName(gvk),
+		newObject: func() *unstructured.Unstructured { return newUnstructured(gvk) },
+		newList:   func() client.ObjectList { return newUnstructuredList(gvk) },
+		copySpec: func(src, dst *unstructured.Unstructured) {
+			dst.Object = runtime.DeepCopyJSON(unstructuredPayload(src))
+			dst.SetGroupVersionKind(src.GroupVersionKind())
+		},
+		isCurrent: func(src, cp *unstructured.Unstructured) bool {
+			return reflect.DeepEqual(unstructuredPayload(src), unstructuredPayload(cp))
+		},
+		payload: func(o *unstructured.Unstructured) map[string][]byte {
+			return jsonPayload(unstructuredPayload(o))
+		},
+	}
+}
+
+// syncKinds are the kinds registered with RegisterSyncKind, keyed by their kind name
+var syncKinds = struct {
+	sync.RWMutex
+	gvks map[string]schema.GroupVersionKind
+}{gvks: map[string]schema.GroupVersionKind{}}
+
+// ParseGVK parses a kind given as group/version,Kind, e.g. cert-manager.io/v1,Certificate or v1,PodTemplate for a
+// kind of the core group
+func ParseGVK(s string) (schema.GroupVersionKind, error) {
+	apiVersion, kind, ok := strings.Cut(s, ",")
+	kind = strings.TrimSpace(kind)
+	if !ok || kind == "" {
+		return schema.GroupVersionKind{}, fmt.Errorf("kind %q must be given as group/version,Kind", s)
+	}
+	gv, err := schema.ParseGroupVersion(strings.TrimSpace(apiVersion))
+	if err != nil || gv.Version == "" {
+		return schema.GroupVersionKind{}, fmt.Errorf("kind %q has an invalid group/version %q", s, apiVersion)
+	}
+	gvk := gv.WithKind(kind)
+	for _, name := range BuiltinKinds() {
+		if k, _ := builtinKind(name); k.qualifiedName() == unstructuredKindName(gvk) {
+			return schema.GroupVersionKind{}, fmt.Errorf("%s is synced by its own controller, %q can't be added", gvk.Kind, s)
+		}
+	}
+	return gvk, nil
+}
+
+// RegisterSyncKind makes gvk a kind that kopy syncs with unstructured objects. The kind is looked up by its kind name
+// wherever kopy takes a kind name, e.g. in pinned targets, subscriptions and the REST API, so kinds are registered
+// before the configuration referring to them is loaded.
+func RegisterSyncKind(gvk schema.GroupVersionKind) {
+	syncKinds.Lock()
+	defer syncKinds.Unlock()
+	syncKinds.gvks[unstructuredKindName(gvk)] = gvk
+	kinds.Lock()
+	defer kinds.Unlock()
+	kinds.byName[unstructuredKindName(gvk)] = unstructuredKind(gvk)
+}
+
+// RegisteredSyncKinds returns the sorted names of the kinds registered with RegisterSyncKind
+func RegisteredSyncKinds() []string {
+	syncKinds.RLock()
+	defer syncKinds.RUnlock()
+	return slices.Sorted(maps.Keys(syncKinds.gvks))
+}
+
+// unstructuredKindName returns the name of gvk used in logs, events and kind arguments, the lower case kind qualified
+// by its group like kubectl resources, e.g. certificate.cert-manager.io
+func unstructuredKindName(gvk schema.GroupVersionKind) string {
+	name := strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		name += "." + gvk.Group
+	}
+	return name
+}
+
+func newUnstructured(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(gvk)
+	return o
+}
+
+func newUnstructuredList(gvk schema.GroupVersionKind) *unstructured.UnstructuredList {
+	l := &unstructured.UnstructuredList{}
+	l.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return l
+}
+
+// unstructuredPayload returns the top level fields of o that are copied, everything but its type, metadata and status
+func unstructuredPayload(o *unstructured.Unstructured) map[string]any {
+	payload := map[string]any{}
+	for k, v := range o.Object {
+		switch k {
+		case "apiVersion", "kind", "metadata", "status":
+			continue
+		}
+		payload[k] = v
+	}
+	return payload
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Unstructured kinds\n", func() {
+	const (
+		namespace = "test-src-gvk-ns-00"
+		target    = "test-dst-gvk-ns-00"
+	)
+	certificate := schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+	DescribeTable("Parsing kinds",
+		func(s string, expected schema.GroupVersionKind, ok bool) {
+			gvk, err := ParseGVK(s)
+			if !ok {
+				Expect(err).Should(HaveOccurred())
+				return
+			}
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(gvk).Should(Equal(expected))
+		},
+		Entry("a kind of a group", "cert-manager.io/v1,Certificate", certificate, true),
+		Entry("a kind of the core group", "v1,PodTemplate", schema.GroupVersionKind{Version: "v1", Kind: "PodTemplate"}, true),
+		Entry("without a kind", "cert-manager.io/v1", schema.GroupVersionKind{}, false),
+		Entry("without a version", "cert-manager.io/,Certificate", schema.GroupVersionKind{}, false),
+		Entry("Secrets", "v1,Secret", schema.GroupVersionKind{}, false),
+		Entry("ServiceAccounts", "v1,ServiceAccount", schema.GroupVersionKind{}, false),
+		Entry("RoleBindings", "rbac.authorization.k8s.io/v1,RoleBinding", schema.GroupVersionKind{}, false),
+	)
+
+	It("Should copy the spec of a registered kind to selected namespaces", func() {
+		ctx := context.Background()
+		RegisterSyncKind(certificate)
+		src, err := NewObjectForKind("certificate.cert-manager.io")
+		Expect(err).ShouldNot(HaveOccurred())
+		cert := src.(*unstructured.Unstructured)
+		cert.SetNamespace(namespace)
+		cert.SetName("test-src-gvk-00")
+		cert.SetAnnotations(map[string]string{syncKey: "env=prod"})
+		Expect(unstructured.SetNestedField(cert.Object, "example.com", "spec", "commonName")).Should(Succeed())
+		Expect(unstructured.SetNestedField(cert.Object, "True", "status", "ready")).Should(Succeed())
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(certificate, meta.RESTScopeNamespace)
+		c := newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithRESTMapper(mapper).WithObjects(
+			cert,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"env": "prod"}}},
+		).Build()
+		req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cert)}
+		reconcile := func() {
+			_, err := KopyReconcile(NewKopyUnstructured(ctx, c, certificate, Options{}, nil), req, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		reconcile()
+		cp := newUnstructured(certificate)
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: target, Name: cert.GetName()}, cp)).Should(Succeed())
+		Expect(cp.Object).Should(HaveKeyWithValue("spec", HaveKeyWithValue("commonName", "example.com")))
+		Expect(cp.Object).ShouldNot(HaveKey("status"))
+		Expect(isCopyOf(cp, cert)).Should(BeTrue())
+		Expect(kindOf(cp)).Should(Equal("certificate.cert-manager.io"))
+
+		Expect(c.Get(ctx, req.NamespacedName, cert)).Should(Succeed())
+		Expect(unstructured.SetNestedField(cert.Object, "example.org", "spec", "commonName")).Should(Succeed())
+		Expect(c.Update(ctx, cert)).Should(Succeed())
+		reconcile()
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cp), cp)).Should(Succeed())
+		Expect(copyIsCurrent(cert, cp)).Should(BeTrue())
+	})
+})
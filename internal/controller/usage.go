@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultBlastRadius is the number of workloads using the copies of a source from which on the source is reported
+// as high blast radius
+const DefaultBlastRadius = 10
+
+// SourceUsage reports the workloads that use the copies of a source
+type SourceUsage struct {
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	Copies    []CopyUsage `json:"copies"`
+	// Workloads is the number of distinct workloads using any copy of the source
+	Workloads int `json:"workloads"`
+	// Unused are the namespaces with a copy no pod uses, the candidates for pruning
+	Unused []string `json:"unused"`
+	// HighBlastRadius is set when at least the blast radius threshold of workloads use the copies, so a bad change
+	// to the source would break many workloads at once
+	HighBlastRadius bool `json:"highBlastRadius"`
+}
+
+// CopyUsage lists the workloads using a copy, e.g. Deployment/web or Pod/debug
+type CopyUsage struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Workloads []string `json:"workloads"`
+}
+
+// podReferencedKinds are the kinds pods reference by name, see podReferences. The usage of the copies of other kinds
+// can't be told from pods.
+var podReferencedKinds = []string{"secret", "configmap", "serviceaccount"}
+
+// AnalyzeUsage cross-references the volumes, environment, image pull secrets and service accounts of the pods that haven't terminated
+// with the copies managed by kopy. Sources whose copies are used by at least blastRadius workloads are marked as
+// high blast radius.
+func AnalyzeUsage(ctx context.Context, c client.Client, blastRadius int) ([]SourceUsage, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods); err != nil {
+		return nil, err
+	}
+	referenced := []string{}
+	for _, kind := range enabledKinds() {
+		if slices.Contains(podReferencedKinds, kind) {
+			referenced = append(referenced, kind)
+		}
+	}
+	// consumers maps kind/namespace/name of the objects pods reference to the workloads of the pods
+	consumers := map[string]sets.Set[string]{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, kind := range referenced {
+			for _, name := range podReferences(pod, kind) {
+				key := kind + "/" + pod.Namespace + "/" + name
+				if consumers[key] == nil {
+					consumers[key] = sets.New[string]()
+				}
+				consumers[key].Insert(workloadOf(pod))
+			}
+		}
+	}
+
+	sources := map[string]*SourceUsage{}
+	workloads := map[string]sets.Set[string]{}
+	for _, kind := range referenced {
+		list, err := newObjectListForKind(kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.List(ctx, list, client.HasLabels{sourceLabelNamespace}); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			cp, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			kind := kindOf(cp)
+			key := kind + "/" + inventorySource(cp)
+			s, ok := sources[key]
+			if !ok {
+				s = &SourceUsage{
+					Kind: kind, Namespace: cp.GetLabels()[sourceLabelNamespace], Name: sourceNameOf(cp),
+					Copies: []CopyUsage{}, Unused: []string{},
+				}
+				sources[key] = s
+				workloads[key] = sets.New[string]()
+			}
+			used := sets.List(consumers[kind+"/"+cp.GetNamespace()+"/"+cp.GetName()])
+			s.Copies = append(s.Copies, CopyUsage{Namespace: cp.GetNamespace(), Name: cp.GetName(), Workloads: used})
+			if len(used) == 0 {
+				s.Unused = append(s.Unused, cp.GetNamespace())
+			}
+			for _, w := range used {
+				workloads[key].Insert(cp.GetNamespace() + "/" + w)
+			}
+		}
+	}
+
+	usage := make([]SourceUsage, 0, len(sources))
+	for key, s := range sources {
+		s.Workloads = workloads[key].Len()
+		s.HighBlastRadius = blastRadius > 0 && s.Workloads >= blastRadius
+		sort.Slice(s.Copies, func(i, j int) bool { return s.Copies[i].Namespace < s.Copies[j].Namespace })
+		sort.Strings(s.Unused)
+		usage = append(usage, *s)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Workloads != usage[j].Workloads {
+			return usage[i].Workloads > usage[j].Workloads
+		}
+		return usage[i].Kind+"/"+usage[i].Namespace+"/"+usage[i].Name < usage[j].Kind+"/"+usage[j].Namespace+"/"+usage[j].Name
+	})
+	return usage, nil
+}
+
+// workloadOf returns the workload that owns pod as kind/name. Pods of a ReplicaSet are attributed to the Deployment
+// the ReplicaSet was created for.
+func workloadOf(pod *corev1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		if hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok && owner.Kind == "ReplicaSet" {
+			return "Deployment/" + strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+		return owner.Kind + "/" + owner.Name
+	}
+	return "Pod/" + pod.Name
+}
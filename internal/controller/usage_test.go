@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Copy usage\n", func() {
+	const source = "test-src-usage-00"
+	newCopy := func(namespace string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: source, Namespace: namespace, Labels: map[string]string{
+			sourceLabelNamespace: "test-src-usage-ns-00", sourceLabelName: source,
+		}}}
+	}
+	newPod := func(name, namespace, replicaSet string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{EnvFrom: []corev1.EnvFromSource{{
+				SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: source}},
+			}}}}},
+		}
+		if replicaSet != "" {
+			controller := true
+			pod.Labels = map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "5d8f"}
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: replicaSet + "-5d8f", Controller: &controller}}
+		}
+		return pod
+	}
+	It("Should report the workloads using each copy and the unused copies", func() {
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects([]client.Object{
+			newCopy("test-dst-usage-ns-00"), newCopy("test-dst-usage-ns-01"), newCopy("test-dst-usage-ns-02"),
+			newPod("web-5d8f-a", "test-dst-usage-ns-00", "web"), newPod("web-5d8f-b", "test-dst-usage-ns-00", "web"),
+			newPod("debug", "test-dst-usage-ns-01", ""),
+		}...).Build()
+
+		usage, err := AnalyzeUsage(context.Background(), c, 2)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(usage).Should(HaveLen(1))
+		Expect(usage[0].Name).Should(Equal(source))
+		Expect(usage[0].Copies).Should(ConsistOf(
+			CopyUsage{Namespace: "test-dst-usage-ns-00", Name: source, Workloads: []string{"Deployment/web"}},
+			CopyUsage{Namespace: "test-dst-usage-ns-01", Name: source, Workloads: []string{"Pod/debug"}},
+			CopyUsage{Namespace: "test-dst-usage-ns-02", Name: source, Workloads: []string{}},
+		))
+		Expect(usage[0].Unused).Should(Equal([]string{"test-dst-usage-ns-02"}))
+		Expect(usage[0].Workloads).Should(Equal(2))
+		Expect(usage[0].HighBlastRadius).Should(BeTrue())
+	})
+})
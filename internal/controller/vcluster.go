@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flynshue/kopy/internal/kopycrypto"
+)
+
+// vclusterManagedByLabel is set by vcluster on the host namespaces and the host objects it syncs for a virtual
+// cluster, its value is the name of the virtual cluster
+const vclusterManagedByLabel = "vcluster.loft.sh/managed-by"
+
+// VClusterMode is how kopy treats namespaces that are managed by a virtual cluster
+type VClusterMode string
+
+const (
+	// VClusterSync treats namespaces managed by a virtual cluster like every other namespace
+	VClusterSync VClusterMode = ""
+	// VClusterSkip never copies to namespaces managed by a virtual cluster
+	VClusterSkip VClusterMode = "skip"
+	// VClusterTranslate names copies in namespaces managed by a virtual cluster like vcluster names the host objects
+	// it syncs, <name>-x-<source namespace>-x-<virtual cluster>, so copies don't collide with objects of the virtual
+	// cluster
+	VClusterTranslate VClusterMode = "translate"
+)
+
+// ParseVClusterMode parses the value of the --vcluster-namespaces flag, sync, skip or translate
+func ParseVClusterMode(v string) (VClusterMode, error) {
+	switch v {
+	case "sync", "":
+		return VClusterSync, nil
+	case string(VClusterSkip), string(VClusterTranslate):
+		return VClusterMode(v), nil
+	}
+	return "", fmt.Errorf("invalid vcluster namespaces mode %q, expected sync, skip or translate", v)
+}
+
+// isVClusterManaged returns true if o was synced by a virtual cluster or is a namespace managed by one
+func isVClusterManaged(o client.Object) bool {
+	_, ok := o.GetLabels()[vclusterManagedByLabel]
+	return ok
+}
+
+// vclusterOf returns the virtual cluster managing namespace if copies to it are translated, "" otherwise
+func (o Options) vclusterOf(namespace string) string {
+	if o.VClusterMode != VClusterTranslate || o.NamespaceReader == nil {
+		return ""
+	}
+	ns := &corev1.Namespace{}
+	if err := o.NamespaceReader.Get(context.Background(), types.NamespacedName{Name: namespace}, ns); err != nil {
+		return ""
+	}
+	return ns.Labels[vclusterManagedByLabel]
+}
+
+// withoutVClusterNamespaces removes the namespaces managed by a virtual cluster from namespaces when they are skipped
+func (o Options) withoutVClusterNamespaces(namespaces []corev1.Namespace) []corev1.Namespace {
+	if o.VClusterMode != VClusterSkip {
+		return namespaces
+	}
+	result := make([]corev1.Namespace, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if !isVClusterManaged(&ns) {
+			result = append(result, ns)
+		}
+	}
+	return result
+}
+
+// vclusterName translates name like vcluster translates the names of the objects it syncs to the host. Names longer
+// than a label value are shortened with a hash of the full name.
+func vclusterName(name, namespace, vcluster string) string {
+	full := name + "-x-" + namespace + "-x-" + vcluster
+	if len(full) <= 63 {
+		return full
+	}
+	digest := kopycrypto.Sum([]byte(full))
+	return full[:52] + "-" + hex.EncodeToString(digest[:])[:10]
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Virtual cluster namespaces\n", func() {
+	const (
+		namespace = "test-src-vcluster-ns-00"
+		plain     = "test-dst-vcluster-ns-00"
+		virtual   = "test-dst-vcluster-ns-01"
+	)
+	var (
+		ctx context.Context
+		c   client.Client
+		src *corev1.Secret
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		src = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-vcluster-00", Namespace: namespace,
+				Annotations: map[string]string{syncKey: "team=payments"},
+			},
+			Data: map[string][]byte{"password": []byte("hunter2")},
+		}
+		c = newFakeClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			src,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: plain, Labels: map[string]string{"team": "payments"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: virtual, Labels: map[string]string{
+				"team": "payments", vclusterManagedByLabel: "tenant-a",
+			}}},
+		).Build()
+	})
+	reconcile := func(opts Options, obj client.Object) {
+		opts.NamespaceReader = c
+		_, err := KopyReconcile(NewKopySecret(ctx, c, opts, record.NewFakeRecorder(10)), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+
+	It("Should skip namespaces managed by a virtual cluster", func() {
+		reconcile(Options{VClusterMode: VClusterSkip}, src)
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: plain, Name: src.Name}, &corev1.Secret{})).Should(Succeed())
+		Expect(apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Namespace: virtual, Name: src.Name}, &corev1.Secret{}))).Should(BeTrue())
+	})
+
+	It("Should translate the names of copies in namespaces managed by a virtual cluster", func() {
+		reconcile(Options{VClusterMode: VClusterTranslate}, src)
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: plain, Name: src.Name}, &corev1.Secret{})).Should(Succeed())
+		translated := src.Name + "-x-" + namespace + "-x-tenant-a"
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: virtual, Name: translated}, &corev1.Secret{})).Should(Succeed())
+	})
+
+	It("Should not treat objects synced by a virtual cluster as sources", func() {
+		synced := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-src-vcluster-01", Namespace: virtual,
+				Labels:      map[string]string{vclusterManagedByLabel: "tenant-a"},
+				Annotations: map[string]string{syncKey: "team=payments"},
+			},
+		}
+		Expect(c.Create(ctx, synced)).Should(Succeed())
+		reconcile(Options{VClusterMode: VClusterSkip}, synced)
+		Expect(apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Namespace: plain, Name: synced.Name}, &corev1.Secret{}))).Should(BeTrue())
+	})
+
+	It("Should shorten translated names like vcluster", func() {
+		name := vclusterName(strings.Repeat("a", 40), namespace, "tenant-a")
+		Expect(name).Should(HaveLen(63))
+		Expect(name).Should(HavePrefix(strings.Repeat("a", 40) + "-x-"))
+		Expect(vclusterName("db", "team", "tenant-a")).Should(Equal("db-x-team-x-tenant-a"))
+	})
+})
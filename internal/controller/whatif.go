@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CopyChange is a copy that would be added to or pruned from a namespace
+type CopyChange struct {
+	// Action is "add" or "prune"
+	Action          string `json:"action"`
+	Kind            string `json:"kind"`
+	SourceNamespace string `json:"sourceNamespace"`
+	SourceName      string `json:"sourceName"`
+}
+
+// ApplyLabelChanges returns a copy of current with the label changes applied. Changes use the syntax of kubectl
+// label: key=value sets a label and key- removes it.
+func ApplyLabelChanges(current map[string]string, changes []string) (map[string]string, error) {
+	proposed := maps.Clone(current)
+	if proposed == nil {
+		proposed = map[string]string{}
+	}
+	for _, change := range changes {
+		if key, ok := strings.CutSuffix(change, "-"); ok && !strings.Contains(change, "=") {
+			delete(proposed, key)
+			continue
+		}
+		key, value, ok := strings.Cut(change, "=")
+		if !ok {
+			return nil, invalidRequest("invalid label change %q, expected key=value or key-", change)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, invalidRequest("invalid label key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, invalidRequest("invalid label value %q: %s", value, strings.Join(errs, ", "))
+		}
+		proposed[key] = value
+	}
+	return proposed, nil
+}
+
+// PreviewLabelChange reports the copies that would be added to or pruned from namespace if its labels were changed
+// as described by changes, without changing the namespace. Copies of sources in namespace itself aren't affected by
+// its labels.
+func PreviewLabelChange(ctx context.Context, c client.Client, namespace string, changes []string) ([]CopyChange, error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return nil, err
+	}
+	proposed := ns.DeepCopy()
+	labels, err := ApplyLabelChanges(ns.Labels, changes)
+	if err != nil {
+		return nil, err
+	}
+	proposed.Labels = labels
+
+	result := []CopyChange{}
+	for _, kind := range enabledKinds() {
+		list, _ := newObjectListForKind(kind)
+		if err := c.List(ctx, list); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			src := item.(client.Object)
+			if src.GetNamespace() == namespace {
+				continue
+			}
+			if _, ok := SyncSelector(src); !ok {
+				continue
+			}
+			before, after := namespaceContainsSyncLabel(src, ns), namespaceContainsSyncLabel(src, proposed)
+			if before == after {
+				continue
+			}
+			change := CopyChange{Action: "add", Kind: kind, SourceNamespace: src.GetNamespace(), SourceName: src.GetName()}
+			if before {
+				change.Action = "prune"
+			}
+			result = append(result, change)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		return a.Action+"/"+a.Kind+"/"+a.SourceNamespace+"/"+a.SourceName < b.Action+"/"+b.Kind+"/"+b.SourceNamespace+"/"+b.SourceName
+	})
+	return result, nil
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Namespace label change preview\n", func() {
+	It("Should report the copies a label change adds and prunes", func() {
+		const target = "test-dst-whatif-ns-00"
+		source := func(name, namespace, selector string) *corev1.Secret {
+			return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: map[string]string{syncKey: selector}}}
+		}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target, Labels: map[string]string{"env": "prod"}}},
+			source("test-src-whatif-00", "test-src-whatif-ns-00", "env=prod"),
+			source("test-src-whatif-01", "test-src-whatif-ns-00", "tenant=payments"),
+			source("test-src-whatif-02", "test-src-whatif-ns-00", "team=ops"),
+			source("test-src-whatif-03", target, "tenant=payments"),
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-src-whatif-04", Namespace: "test-src-whatif-ns-00"}},
+		).Build()
+
+		changes, err := PreviewLabelChange(context.Background(), c, target, []string{"tenant=payments", "env-"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(changes).Should(Equal([]CopyChange{
+			{Action: "add", Kind: "secret", SourceNamespace: "test-src-whatif-ns-00", SourceName: "test-src-whatif-01"},
+			{Action: "prune", Kind: "secret", SourceNamespace: "test-src-whatif-ns-00", SourceName: "test-src-whatif-00"},
+		}))
+
+		_, err = PreviewLabelChange(context.Background(), c, target, []string{"tenant"})
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("Should report the copies of every enabled kind", func() {
+		const target = "test-dst-whatif-ns-01"
+		annotations := map[string]string{syncKey: "tenant=payments"}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: target}},
+			&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test-src-whatif-05", Namespace: "test-src-whatif-ns-01", Annotations: annotations}},
+			&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "test-src-whatif-06", Namespace: "test-src-whatif-ns-01", Annotations: annotations}},
+		).Build()
+
+		changes, err := PreviewLabelChange(context.Background(), c, target, []string{"tenant=payments"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(changes).Should(Equal([]CopyChange{
+			{Action: "add", Kind: "networkpolicy", SourceNamespace: "test-src-whatif-ns-01", SourceName: "test-src-whatif-06"},
+			{Action: "add", Kind: "serviceaccount", SourceNamespace: "test-src-whatif-ns-01", SourceName: "test-src-whatif-05"},
+		}))
+
+		By("Leaving out the kinds whose controllers don't run")
+		EnableKinds([]string{"secret", "configmap", "serviceaccount"})
+		DeferCleanup(func() { EnableKinds(nil) })
+		changes, err = PreviewLabelChange(context.Background(), c, target, []string{"tenant=payments"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(changes).Should(Equal([]CopyChange{
+			{Action: "add", Kind: "serviceaccount", SourceNamespace: "test-src-whatif-ns-01", SourceName: "test-src-whatif-05"},
+		}))
+	})
+})
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// syncWindowKey restricts propagation of a source to change windows, e.g. "Mon-Fri 09:00-17:00 Europe/Berlin".
+	// Several windows are separated by ";".
+	syncWindowKey = kopyPrefix + "sync-window"
+	// syncUrgentKey is set to "true" on a source to propagate it right away regardless of its sync window
+	syncUrgentKey = kopyPrefix + "sync-urgent"
+)
+
+// now returns the current time, tests replace it to run reconciles inside or outside of a sync window
+var now = time.Now
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// syncWindow is a recurring time of day on some days of the week
+type syncWindow struct {
+	days       [7]bool
+	start, end int // minutes since midnight, end before start spans midnight
+	loc        *time.Location
+}
+
+// parseSyncWindows parses the value of the sync window annotation
+func parseSyncWindows(v string) ([]syncWindow, error) {
+	windows := []syncWindow{}
+	for _, s := range strings.Split(v, ";") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		w, err := parseSyncWindow(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sync window %q: %w", strings.TrimSpace(s), err)
+		}
+		windows = append(windows, w)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no sync window in %q", v)
+	}
+	return windows, nil
+}
+
+// parseSyncWindow parses a window of the form "<days> <HH:MM>-<HH:MM> [time zone]", days is "*" or a comma
+// separated list of days and day ranges like "Mon-Fri,Sun". The time zone defaults to UTC.
+func parseSyncWindow(s string) (syncWindow, error) {
+	w := syncWindow{loc: time.UTC}
+	fields := strings.Fields(s)
+	if len(fields) < 2 || len(fields) > 3 {
+		return w, fmt.Errorf("expected \"<days> <HH:MM>-<HH:MM> [time zone]\"")
+	}
+	if err := w.parseDays(fields[0]); err != nil {
+		return w, err
+	}
+	from, to, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return w, fmt.Errorf("expected a time range, got %q", fields[1])
+	}
+	var err error
+	if w.start, err = parseTimeOfDay(from); err != nil {
+		return w, err
+	}
+	if w.end, err = parseTimeOfDay(to); err != nil {
+		return w, err
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("time range %q is empty", fields[1])
+	}
+	if len(fields) == 3 {
+		if w.loc, err = time.LoadLocation(fields[2]); err != nil {
+			return w, err
+		}
+	}
+	return w, nil
+}
+
+func (w *syncWindow) parseDays(s string) error {
+	if s == "*" {
+		for d := range w.days {
+			w.days[d] = true
+		}
+		return nil
+	}
+	for _, r := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(r, "-")
+		first, ok := weekdays[strings.ToLower(from)]
+		if !ok {
+			return fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[strings.ToLower(to)]; !ok {
+				return fmt.Errorf("unknown day %q", to)
+			}
+		}
+		// ranges may wrap around the end of the week, e.g. Sat-Mon
+		for d := first; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseTimeOfDay(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	hour, herr := strconv.Atoi(h)
+	minute, merr := strconv.Atoi(m)
+	// 24:00 is allowed as the end of a window that lasts until midnight
+	if !ok || herr != nil || merr != nil || hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// contains returns true if t is inside of the window
+func (w syncWindow) contains(t time.Time) bool {
+	t = t.In(w.loc)
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+	}
+	// the window spans midnight and belongs to the day it starts on
+	yesterday := (t.Weekday() + 6) % 7
+	return (w.days[t.Weekday()] && minute >= w.start) || (w.days[yesterday] && minute < w.end)
+}
+
+// next returns the next time after t the window opens
+func (w syncWindow) next(t time.Time) time.Time {
+	t = t.In(w.loc)
+	for d := 0; d <= 7; d++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+d, 0, 0, 0, 0, w.loc)
+		open := day.Add(time.Duration(w.start) * time.Minute)
+		if w.days[day.Weekday()] && open.After(t) {
+			return open
+		}
+	}
+	// unreachable for a window with at least one day
+	return t.Add(7 * 24 * time.Hour)
+}
+
+// syncWindowDelay returns how long propagation of src has to wait for one of its sync windows to open, 0 if src
+// has no sync window, is inside of one or is marked as urgent
+func syncWindowDelay(src client.Object, t time.Time) (time.Duration, error) {
+	annotations := src.GetAnnotations()
+	v, ok := annotations[syncWindowKey]
+	if !ok {
+		return 0, nil
+	}
+	if urgent, _ := strconv.ParseBool(annotations[syncUrgentKey]); urgent {
+		return 0, nil
+	}
+	windows, err := parseSyncWindows(v)
+	if err != nil {
+		return 0, err
+	}
+	var delay time.Duration
+	for _, w := range windows {
+		if w.contains(t) {
+			return 0, nil
+		}
+		if d := w.next(t).Sub(t); delay == 0 || d < delay {
+			delay = d
+		}
+	}
+	return delay, nil
+}
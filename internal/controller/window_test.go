@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testSyncWindowSnapshot = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-src-window-ns-00
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: test-target-window-ns-00
+    labels:
+      env: prod
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: test-src-window-00
+    namespace: test-src-window-ns-00
+    annotations:
+      kopy.kot-labs.com/sync: env=prod
+      kopy.kot-labs.com/sync-window: Mon-Fri 09:00-17:00 UTC
+  data:
+    password: dGVzdC1zcmMtd2luZG93LTAw
+`
+
+var _ = Describe("Sync windows\n", func() {
+	// 2024-06-05 is a Wednesday
+	wednesday := func(hour, minute int) time.Time {
+		return time.Date(2024, time.June, 5, hour, minute, 0, 0, time.UTC)
+	}
+	DescribeTable("Delaying propagation until a sync window opens",
+		func(window string, t time.Time, expected time.Duration) {
+			src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{syncWindowKey: window}}}
+			delay, err := syncWindowDelay(src, t)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(delay).Should(Equal(expected))
+		},
+		Entry("inside of the window", "Mon-Fri 09:00-17:00 UTC", wednesday(12, 0), time.Duration(0)),
+		Entry("before the window opens", "Mon-Fri 09:00-17:00 UTC", wednesday(8, 30), 30*time.Minute),
+		Entry("after the window closed", "Mon-Fri 09:00-17:00 UTC", wednesday(17, 0), 16*time.Hour),
+		Entry("weekend only", "Sat,Sun 00:00-24:00", wednesday(12, 0), 60*time.Hour),
+		Entry("spanning midnight", "* 22:00-02:00", wednesday(1, 0), time.Duration(0)),
+		Entry("range wrapping the week", "Sat-Mon 10:00-11:00", wednesday(10, 0), 72*time.Hour),
+		Entry("earliest of several windows", "Thu 06:00-07:00; Wed 20:00-21:00", wednesday(12, 0), 8*time.Hour),
+		Entry("in another time zone", "Wed 09:00-17:00 America/New_York", wednesday(12, 0), time.Hour),
+	)
+	It("Should propagate urgent sources right away", func() {
+		src := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			syncWindowKey: "Sat 09:00-17:00",
+			syncUrgentKey: "true",
+		}}}
+		Expect(syncWindowDelay(src, wednesday(12, 0))).Should(Equal(time.Duration(0)))
+	})
+	DescribeTable("Rejecting malformed sync windows",
+		func(window string) {
+			_, err := parseSyncWindows(window)
+			Expect(err).Should(HaveOccurred())
+		},
+		Entry("missing time range", "Mon-Fri"),
+		Entry("unknown day", "Mon-Fry 09:00-17:00"),
+		Entry("invalid time", "Mon 09:00-25:00"),
+		Entry("empty range", "Mon 09:00-09:00"),
+		Entry("unknown time zone", "Mon 09:00-17:00 Mars/Olympus"),
+		Entry("empty", " ; "),
+	)
+	Context("When a source has a sync window", func() {
+		AfterEach(func() {
+			now = time.Now
+		})
+		It("Should only write copies inside of the window", func() {
+			objects, err := LoadSnapshot(strings.NewReader(testSyncWindowSnapshot))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			By("Reconciling outside of the window")
+			now = func() time.Time { return wednesday(18, 0) }
+			result, err := Simulate(context.Background(), objects, Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.Errors).Should(BeEmpty())
+			Expect(result.Actions).ShouldNot(ContainElement(
+				SimulatedAction{Verb: "create", Kind: "secret", Namespace: "test-target-window-ns-00", Name: "test-src-window-00"},
+			))
+
+			By("Reconciling inside of the window")
+			now = func() time.Time { return wednesday(10, 0) }
+			objects, err = LoadSnapshot(strings.NewReader(testSyncWindowSnapshot))
+			Expect(err).ShouldNot(HaveOccurred())
+			result, err = Simulate(context.Background(), objects, Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.Errors).Should(BeEmpty())
+			Expect(result.Actions).Should(ContainElement(
+				SimulatedAction{Verb: "create", Kind: "secret", Namespace: "test-target-window-ns-00", Name: "test-src-window-00"},
+			))
+		})
+	})
+})
@@ -0,0 +1,9 @@
+//go:build boringcrypto
+
+package kopycrypto
+
+// restrict TLS to FIPS approved versions, cipher suites and curves
+import _ "crypto/tls/fipsonly"
+
+// FIPS is true when kopy is built with GOEXPERIMENT=boringcrypto
+const FIPS = true
@@ -0,0 +1,130 @@
+// Package kopycrypto contains all hashing and signing done by kopy. It only uses FIPS 140 approved algorithms,
+// SHA-256 and ECDSA over the P-256 and P-384 curves, so kopy can be built against a validated module with
+// GOEXPERIMENT=boringcrypto, see FIPS.
+package kopycrypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"os"
+)
+
+// Size is the size of the digests of Sum and Hasher in bytes
+const Size = sha256.Size
+
+// Sum returns the SHA-256 digest of b. It is for digests that have to match the ones of other tools, e.g. the names
+// vcluster derives, or of a single value; a Hasher digests a sequence of fields.
+func Sum(b []byte) [Size]byte {
+	return sha256.Sum256(b)
+}
+
+// Hasher computes the SHA-256 digest of a sequence of fields. Every field is length prefixed so that different
+// sequences can't produce the same input.
+type Hasher struct {
+	h hash.Hash
+}
+
+// NewHasher returns an empty Hasher
+func NewHasher() *Hasher {
+	return &Hasher{h: sha256.New()}
+}
+
+// Field adds v to the digest
+func (h *Hasher) Field(v string) {
+	_ = binary.Write(h.h, binary.BigEndian, uint64(len(v)))
+	h.h.Write([]byte(v))
+}
+
+// Sum returns the digest of all fields added so far
+func (h *Hasher) Sum() []byte {
+	return h.h.Sum(nil)
+}
+
+// Sign returns the ASN.1 encoded ECDSA signature of digest
+func Sign(key *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	if err := checkCurve(key.Curve); err != nil {
+		return nil, err
+	}
+	return ecdsa.SignASN1(rand.Reader, key, digest)
+}
+
+// Verify returns true if sig is a valid signature of digest made with the private key of pub
+func Verify(pub *ecdsa.PublicKey, digest, sig []byte) bool {
+	if checkCurve(pub.Curve) != nil {
+		return false
+	}
+	return ecdsa.VerifyASN1(pub, digest, sig)
+}
+
+// LoadPrivateKey reads a PEM encoded ECDSA private key in SEC 1 or PKCS #8 form, as generated by
+// "openssl ecparam -name prime256v1 -genkey" or "cosign generate-key-pair" after decryption
+func LoadPrivateKey(path string) (*ecdsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		k, pkcs8Err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if pkcs8Err != nil {
+			return nil, fmt.Errorf("unable to parse signing key %s: %w", path, pkcs8Err)
+		}
+		ecKey, ok := k.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("signing key %s is not an ECDSA key", path)
+		}
+		key = ecKey
+	}
+	if err := checkCurve(key.Curve); err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// LoadPublicKey reads a PEM encoded ECDSA public key
+func LoadPublicKey(path string) (*ecdsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	k, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse verification key %s: %w", path, err)
+	}
+	key, ok := k.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("verification key %s is not an ECDSA key", path)
+	}
+	if err := checkCurve(key.Curve); err != nil {
+		return nil, fmt.Errorf("verification key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// checkCurve rejects curves that aren't approved for FIPS 140 signatures
+func checkCurve(c elliptic.Curve) error {
+	switch c {
+	case elliptic.P256(), elliptic.P384():
+		return nil
+	}
+	return fmt.Errorf("unsupported curve %s, use P-256 or P-384", c.Params().Name)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM block", path)
+	}
+	return block, nil
+}
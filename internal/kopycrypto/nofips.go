@@ -0,0 +1,6 @@
+//go:build !boringcrypto
+
+package kopycrypto
+
+// FIPS is true when kopy is built with GOEXPERIMENT=boringcrypto
+const FIPS = false
@@ -0,0 +1,231 @@
+// Package adminapi defines the kopy gRPC admin service used by platform automation and a typed client for it.
+// Messages are plain Go structs sent as JSON with the "json" content subtype, so neither side needs generated code.
+// Calls are authenticated with a Kubernetes token and authorized with a SubjectAccessReview of the verb named after
+// the method, e.g. resync, on the sources resource of the Group API group in the namespace of the source.
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	// ServiceName is the full name of the admin service
+	ServiceName = "kopy.admin.v1.Admin"
+	// Group is the API group of the RBAC rules that grant access to the admin service, e.g. the verbs resync and
+	// explain on the resource sources
+	Group = "admin.kopy.kot-labs.com"
+	// Resource is the resource of the RBAC rules that grant access to the admin service
+	Resource = "sources"
+)
+
+// SourceRef identifies a source by its kind name, e.g. secret, namespace and name
+type SourceRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// GetSource returns the source a request acts on, the admin service authorizes calls in its namespace
+func (r SourceRef) GetSource() SourceRef {
+	return r
+}
+
+// ResyncRequest asks kopy to rewrite every copy of a source
+type ResyncRequest struct {
+	SourceRef
+}
+
+// ResyncResponse carries the resync request kopy sets as observed once every copy was rewritten
+type ResyncResponse struct {
+	Request string `json:"request"`
+}
+
+// PauseRequest freezes the copies of a source by quarantining it for Reason
+type PauseRequest struct {
+	SourceRef
+	Reason string `json:"reason"`
+}
+
+// PauseResponse is the empty response of Pause
+type PauseResponse struct{}
+
+// ResumeRequest lifts the quarantine of a paused source
+type ResumeRequest struct {
+	SourceRef
+}
+
+// ResumeResponse is the empty response of Resume
+type ResumeResponse struct{}
+
+// ApproveRequest confirms the pending change of a source that updates more copies than the confirmation threshold
+type ApproveRequest struct {
+	SourceRef
+}
+
+// ApproveResponse describes the change that was confirmed
+type ApproveResponse struct {
+	Revision string `json:"revision"`
+	Copies   int    `json:"copies"`
+}
+
+// ExplainRequest asks why TargetNamespace does or doesn't receive a copy of a source
+type ExplainRequest struct {
+	SourceRef
+	TargetNamespace string `json:"targetNamespace"`
+}
+
+// ExplainResponse reports the checks that decide whether the target namespace receives a copy
+type ExplainResponse struct {
+	Receives bool `json:"receives"`
+	// Copy is the state of the copy in the namespace, e.g. missing, current or stale
+	Copy   string  `json:"copy"`
+	Checks []Check `json:"checks"`
+}
+
+// Check is one of the checks of an explanation
+type Check struct {
+	Name string `json:"name"`
+	// Result is pass, fail or info
+	Result string `json:"result"`
+	Detail string `json:"detail"`
+}
+
+// AdminServer is implemented by the kopy manager to serve the admin service
+type AdminServer interface {
+	Resync(context.Context, *ResyncRequest) (*ResyncResponse, error)
+	Pause(context.Context, *PauseRequest) (*PauseResponse, error)
+	Resume(context.Context, *ResumeRequest) (*ResumeResponse, error)
+	Approve(context.Context, *ApproveRequest) (*ApproveResponse, error)
+	Explain(context.Context, *ExplainRequest) (*ExplainResponse, error)
+}
+
+// RegisterAdminServer registers srv as the admin service of s
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("Resync", AdminServer.Resync),
+		unary("Pause", AdminServer.Pause),
+		unary("Resume", AdminServer.Resume),
+		unary("Approve", AdminServer.Approve),
+		unary("Explain", AdminServer.Explain),
+	},
+}
+
+// unary returns the description of the unary method named method that decodes its request and calls call
+func unary[Req, Resp any](method string, call func(AdminServer, context.Context, *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(AdminServer), ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + method}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return call(srv.(AdminServer), ctx, req.(*Req))
+			})
+		},
+	}
+}
+
+// Verb returns the RBAC verb that authorizes calls of the method named by fullMethod, e.g. resync for
+// /kopy.admin.v1.Admin/Resync
+func Verb(fullMethod string) string {
+	return strings.ToLower(fullMethod[strings.LastIndex(fullMethod, "/")+1:])
+}
+
+// Client is a typed client of the admin service
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewClient returns a client of the admin service served on cc. Pass the token of the caller with a BearerToken as
+// per RPC credentials of the connection.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+// Resync asks kopy to rewrite every copy of a source
+func (c *Client) Resync(ctx context.Context, req *ResyncRequest, opts ...grpc.CallOption) (*ResyncResponse, error) {
+	return invoke[ResyncResponse](ctx, c.cc, "Resync", req, opts)
+}
+
+// Pause freezes the copies of a source until it is resumed
+func (c *Client) Pause(ctx context.Context, req *PauseRequest, opts ...grpc.CallOption) (*PauseResponse, error) {
+	return invoke[PauseResponse](ctx, c.cc, "Pause", req, opts)
+}
+
+// Resume syncs the copies of a paused source again
+func (c *Client) Resume(ctx context.Context, req *ResumeRequest, opts ...grpc.CallOption) (*ResumeResponse, error) {
+	return invoke[ResumeResponse](ctx, c.cc, "Resume", req, opts)
+}
+
+// Approve confirms the pending change of a source
+func (c *Client) Approve(ctx context.Context, req *ApproveRequest, opts ...grpc.CallOption) (*ApproveResponse, error) {
+	return invoke[ApproveResponse](ctx, c.cc, "Approve", req, opts)
+}
+
+// Explain reports why a namespace does or doesn't receive a copy of a source
+func (c *Client) Explain(ctx context.Context, req *ExplainRequest, opts ...grpc.CallOption) (*ExplainResponse, error) {
+	return invoke[ExplainResponse](ctx, c.cc, "Explain", req, opts)
+}
+
+func invoke[Resp any](ctx context.Context, cc grpc.ClientConnInterface, method string, req any, opts []grpc.CallOption) (*Resp, error) {
+	resp := new(Resp)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(codecName)}, opts...)
+	if err := cc.Invoke(ctx, "/"+ServiceName+"/"+method, req, resp, opts...); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BearerToken authenticates calls with a Kubernetes token, e.g. the token of a service account. The token is only
+// sent over TLS unless AllowInsecure is set, e.g. for a port-forward to the manager.
+type BearerToken struct {
+	Token         string
+	AllowInsecure bool
+}
+
+// GetRequestMetadata sets the authorization header of a call
+func (t BearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.Token}, nil
+}
+
+// RequireTransportSecurity returns true unless AllowInsecure is set
+func (t BearerToken) RequireTransportSecurity() bool {
+	return !t.AllowInsecure
+}
+
+const codecName = "json"
+
+// codec encodes the messages of the admin service as JSON
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return codecName
+}
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
@@ -0,0 +1,71 @@
+// Package freshness lets workloads refuse to become ready while the kopy copies they consume are stale. kopy stamps
+// every copy it writes with the time it last synced the copy from its source; a readiness probe built from Handler
+// fails while a copy wasn't synced within the allowed age, e.g. because kopy is down or lost access to a namespace.
+package freshness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LastSyncTimeAnnotation is the RFC3339 time kopy last synced a copy from its source
+const LastSyncTimeAnnotation = "kopy.kot-labs.com/last-sync-time"
+
+// ErrNotStamped is returned for objects without a last sync time, e.g. objects that aren't kopy copies
+var ErrNotStamped = errors.New("object has no " + LastSyncTimeAnnotation + " annotation")
+
+// LastSyncTime returns the last sync time in the annotations of a copy
+func LastSyncTime(annotations map[string]string) (time.Time, error) {
+	v, ok := annotations[LastSyncTimeAnnotation]
+	if !ok {
+		return time.Time{}, ErrNotStamped
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: %w", LastSyncTimeAnnotation, v, err)
+	}
+	return t, nil
+}
+
+// Check returns an error if the copy with annotations wasn't synced within maxAge of now
+func Check(annotations map[string]string, maxAge time.Duration, now time.Time) error {
+	t, err := LastSyncTime(annotations)
+	if err != nil {
+		return err
+	}
+	if age := now.Sub(t); age > maxAge {
+		return fmt.Errorf("copy was last synced %s ago, more than %s", age.Truncate(time.Second), maxAge)
+	}
+	return nil
+}
+
+// CheckObject reads the copy at key into obj, e.g. an empty *corev1.Secret, and checks it was synced within maxAge.
+// The workload needs RBAC to get the copy.
+func CheckObject(ctx context.Context, c client.Reader, key client.ObjectKey, obj client.Object, maxAge time.Duration) error {
+	if err := c.Get(ctx, key, obj); err != nil {
+		return err
+	}
+	if err := Check(obj.GetAnnotations(), maxAge, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// Handler serves a readiness endpoint that answers 200 while every check passes and 503 with the first error
+// otherwise
+func Handler(checks ...func(ctx context.Context) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, check := range checks {
+			if err := check(r.Context()); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+}
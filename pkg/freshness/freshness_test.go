@@ -0,0 +1,78 @@
+package freshness
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFreshness(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Freshness Suite")
+}
+
+var _ = Describe("Freshness\n", func() {
+	synced := time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+	stamped := map[string]string{LastSyncTimeAnnotation: synced.Format(time.RFC3339)}
+
+	It("Should read the last sync time of a copy", func() {
+		t, err := LastSyncTime(stamped)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(t).Should(BeTemporally("==", synced))
+
+		_, err = LastSyncTime(map[string]string{"team": "payments"})
+		Expect(err).Should(MatchError(ErrNotStamped))
+		_, err = LastSyncTime(nil)
+		Expect(err).Should(MatchError(ErrNotStamped))
+
+		_, err = LastSyncTime(map[string]string{LastSyncTimeAnnotation: "yesterday"})
+		Expect(err).Should(HaveOccurred())
+		Expect(err).ShouldNot(MatchError(ErrNotStamped))
+		Expect(err.Error()).Should(ContainSubstring(`"yesterday"`))
+	})
+
+	DescribeTable("Checking the age of a copy",
+		func(annotations map[string]string, age time.Duration, fresh bool) {
+			err := Check(annotations, time.Hour, synced.Add(age))
+			if fresh {
+				Expect(err).ShouldNot(HaveOccurred())
+			} else {
+				Expect(err).Should(HaveOccurred())
+			}
+		},
+		Entry("just synced", stamped, time.Duration(0), true),
+		Entry("exactly max age", stamped, time.Hour, true),
+		Entry("older than max age", stamped, time.Hour+time.Second, false),
+		Entry("not stamped", map[string]string{}, time.Duration(0), false),
+	)
+
+	It("Should answer 200 while every check passes and 503 with the first error otherwise", func() {
+		serve := func(checks ...func(ctx context.Context) error) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			Handler(checks...).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			return w
+		}
+		pass := func(context.Context) error { return nil }
+		fail := func(msg string) func(context.Context) error {
+			return func(context.Context) error { return errors.New(msg) }
+		}
+
+		w := serve(pass, pass)
+		Expect(w.Code).Should(Equal(http.StatusOK))
+		Expect(w.Body.String()).Should(Equal("ok"))
+
+		w = serve()
+		Expect(w.Code).Should(Equal(http.StatusOK))
+
+		w = serve(pass, fail("db-credentials is stale"), fail("tls is stale"))
+		Expect(w.Code).Should(Equal(http.StatusServiceUnavailable))
+		Expect(w.Body.String()).Should(ContainSubstring("db-credentials is stale"))
+		Expect(w.Body.String()).ShouldNot(ContainSubstring("tls is stale"))
+	})
+})
@@ -0,0 +1,350 @@
+// Package fixtures loads declarative kopy scenarios from YAML: the namespaces and sources to start with, followed by
+// steps that change them and the copies expected once kopy converged. New behavior can be covered by adding a scenario
+// file instead of writing the setup and assertions of a spec by hand.
+//
+//	name: prunes copies from namespaces that are no longer selected
+//	namespaces:
+//	- name: team-a
+//	  labels: {team: payments}
+//	objects:
+//	- apiVersion: v1
+//	  kind: Secret
+//	  metadata:
+//	    name: db
+//	    namespace: platform
+//	    annotations: {kopy.kot-labs.com/sync: team=payments}
+//	  stringData: {password: hunter2}
+//	steps:
+//	- name: copies to the selected namespaces
+//	  expect:
+//	    copies:
+//	    - {kind: Secret, namespace: team-a, name: db, source: platform/db, data: {password: hunter2}}
+package fixtures
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/flynshue/kopy/pkg/testenv"
+)
+
+// Scenario is a kopy scenario loaded from a YAML file
+type Scenario struct {
+	// Name describes the behavior the scenario covers, the file name is used if it is empty
+	Name string `json:"name"`
+	// Path is the file the scenario was loaded from
+	Path       string      `json:"-"`
+	Namespaces []Namespace `json:"namespaces,omitempty"`
+	// Objects are the manifests created before the first step, usually sources
+	Objects []unstructured.Unstructured `json:"objects,omitempty"`
+	Steps   []Step                      `json:"steps"`
+}
+
+// Namespace is a namespace created before the first step. The namespaces of the objects of the scenario are created
+// without labels unless they are listed.
+type Namespace struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Step changes the objects of a scenario and describes the copies expected once kopy converged
+type Step struct {
+	Name string `json:"name"`
+	// Apply are manifests that are created, or update the objects with the same kind, namespace and name
+	Apply []unstructured.Unstructured `json:"apply,omitempty"`
+	// Delete are the objects deleted before kopy converges
+	Delete []ObjectRef `json:"delete,omitempty"`
+	Expect Expectation `json:"expect"`
+}
+
+// ObjectRef refers to an object of a scenario. APIVersion defaults to v1.
+type ObjectRef struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// Copy is a copy expected in a namespace
+type Copy struct {
+	ObjectRef `json:",inline"`
+	// Source is the namespace/name of the source the copy is labeled with, not checked if empty
+	Source string `json:"source,omitempty"`
+	// Data is the data the copy is expected to hold in plain text, not checked if empty
+	Data map[string]string `json:"data,omitempty"`
+	// Labels and Annotations must be set on the copy, other labels and annotations are ignored
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Expectation is the state of the cluster expected once kopy converged
+type Expectation struct {
+	Copies []Copy      `json:"copies,omitempty"`
+	Absent []ObjectRef `json:"absent,omitempty"`
+}
+
+// Load reads the scenario in the file path
+func Load(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scenario{}
+	if err := yaml.UnmarshalStrict(b, s); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	s.Path = path
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(s.Steps) == 0 {
+		return nil, fmt.Errorf("invalid scenario %s: no steps", path)
+	}
+	return s, nil
+}
+
+// LoadDir reads every .yaml file in dir as a scenario, sorted by file name
+func LoadDir(dir string) ([]*Scenario, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	scenarios := make([]*Scenario, 0, len(paths))
+	for _, path := range paths {
+		s, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, s)
+	}
+	return scenarios, nil
+}
+
+// Kinds returns the kinds of the objects the scenario creates or applies, lowercased like the kinds kopy syncs
+func (s *Scenario) Kinds() []string {
+	kinds := map[string]bool{}
+	add := func(objects []unstructured.Unstructured) {
+		for _, o := range objects {
+			if o.GetKind() != "Namespace" {
+				kinds[strings.ToLower(o.GetKind())] = true
+			}
+		}
+	}
+	add(s.Objects)
+	for _, step := range s.Steps {
+		add(step.Apply)
+	}
+	result := []string{}
+	for kind := range kinds {
+		result = append(result, kind)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Setup creates the namespaces and objects of the scenario
+func (s *Scenario) Setup(ctx context.Context, c client.Client) error {
+	namespaces := map[string]Namespace{}
+	for _, o := range s.Objects {
+		if o.GetNamespace() != "" {
+			namespaces[o.GetNamespace()] = Namespace{Name: o.GetNamespace()}
+		}
+	}
+	for _, ns := range s.Namespaces {
+		namespaces[ns.Name] = ns
+	}
+	for _, ns := range namespaces {
+		o := &unstructured.Unstructured{}
+		o.SetAPIVersion("v1")
+		o.SetKind("Namespace")
+		o.SetName(ns.Name)
+		o.SetLabels(ns.Labels)
+		o.SetAnnotations(ns.Annotations)
+		if err := c.Create(ctx, o); err != nil {
+			return fmt.Errorf("unable to create namespace %s: %w", ns.Name, err)
+		}
+	}
+	for _, o := range s.Objects {
+		if err := c.Create(ctx, normalize(o)); err != nil {
+			return fmt.Errorf("unable to create %s %s/%s: %w", o.GetKind(), o.GetNamespace(), o.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// Run applies the step: the manifests of Apply are created or update the existing objects and the objects of
+// Delete are deleted
+func (st Step) Run(ctx context.Context, c client.Client) error {
+	for _, o := range st.Apply {
+		o := normalize(o)
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(o.GroupVersionKind())
+		err := c.Get(ctx, client.ObjectKeyFromObject(o), existing)
+		switch {
+		case apierrors.IsNotFound(err):
+			err = c.Create(ctx, o)
+		case err == nil:
+			o.SetResourceVersion(existing.GetResourceVersion())
+			o.SetFinalizers(existing.GetFinalizers())
+			err = c.Update(ctx, o)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to apply %s %s/%s: %w", o.GetKind(), o.GetNamespace(), o.GetName(), err)
+		}
+	}
+	for _, ref := range st.Delete {
+		if err := client.IgnoreNotFound(c.Delete(ctx, ref.object())); err != nil {
+			return fmt.Errorf("unable to delete %s: %w", ref, err)
+		}
+	}
+	return nil
+}
+
+// Verify returns an error describing every expectation the cluster doesn't meet, nil if it meets all of them. It
+// can be polled with Eventually against a running manager:
+//
+//	Eventually(func() error { return step.Expect.Verify(ctx, c) }).Should(Succeed())
+func (e Expectation) Verify(ctx context.Context, c client.Client) error {
+	errs := []error{}
+	for _, want := range e.Copies {
+		cp := want.object()
+		if err := c.Get(ctx, client.ObjectKeyFromObject(cp), cp); err != nil {
+			errs = append(errs, fmt.Errorf("copy %s: %w", want.ObjectRef, err))
+			continue
+		}
+		if err := want.verify(cp); err != nil {
+			errs = append(errs, fmt.Errorf("copy %s: %w", want.ObjectRef, err))
+		}
+	}
+	for _, ref := range e.Absent {
+		err := c.Get(ctx, client.ObjectKeyFromObject(ref.object()), ref.object())
+		if err == nil {
+			errs = append(errs, fmt.Errorf("%s: expected to be absent", ref))
+		} else if !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("%s: %w", ref, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run sets up the scenario and runs its steps, calling converge after each step to let kopy sync and verifying the
+// expectations of the step afterwards. It stops at the first step whose expectations aren't met.
+func Run(ctx context.Context, c client.Client, s *Scenario, converge func(context.Context) error) error {
+	if err := s.Setup(ctx, c); err != nil {
+		return err
+	}
+	for i, step := range s.Steps {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if err := step.Run(ctx, c); err != nil {
+			return fmt.Errorf("step %s: %w", name, err)
+		}
+		if err := converge(ctx); err != nil {
+			return fmt.Errorf("step %s: %w", name, err)
+		}
+		if err := step.Expect.Verify(ctx, c); err != nil {
+			return fmt.Errorf("step %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (r ObjectRef) String() string {
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+func (r ObjectRef) object() *unstructured.Unstructured {
+	o := &unstructured.Unstructured{}
+	apiVersion := r.APIVersion
+	if apiVersion == "" {
+		apiVersion = "v1"
+	}
+	gv, _ := schema.ParseGroupVersion(apiVersion)
+	o.SetGroupVersionKind(gv.WithKind(r.Kind))
+	o.SetNamespace(r.Namespace)
+	o.SetName(r.Name)
+	return o
+}
+
+// verify checks the origin, data, labels and annotations of the copy cp
+func (want Copy) verify(cp *unstructured.Unstructured) error {
+	if want.Source != "" {
+		source := cp.GetLabels()[testenv.OriginNamespaceLabel] + "/" + cp.GetLabels()[testenv.OriginNameLabel]
+		if source != want.Source {
+			return fmt.Errorf("expected source %s, got %s", want.Source, source)
+		}
+	}
+	if len(want.Data) > 0 {
+		data, err := plainData(cp)
+		if err != nil {
+			return err
+		}
+		if !maps.Equal(data, want.Data) {
+			return fmt.Errorf("expected data %v, got %v", want.Data, data)
+		}
+	}
+	for _, m := range []struct {
+		what      string
+		want, got map[string]string
+	}{{"label", want.Labels, cp.GetLabels()}, {"annotation", want.Annotations, cp.GetAnnotations()}} {
+		for k, v := range m.want {
+			if got, ok := m.got[k]; !ok || got != v {
+				return fmt.Errorf("expected %s %s=%s, got %q", m.what, k, v, got)
+			}
+		}
+	}
+	return nil
+}
+
+// normalize returns a copy of o with the stringData of Secrets moved to data like the API server does, so scenarios
+// can be run against fake clients too
+func normalize(o unstructured.Unstructured) *unstructured.Unstructured {
+	result := o.DeepCopy()
+	stringData, ok, _ := unstructured.NestedStringMap(result.Object, "stringData")
+	if !ok || result.GetKind() != "Secret" {
+		return result
+	}
+	data, _, _ := unstructured.NestedStringMap(result.Object, "data")
+	if data == nil {
+		data = map[string]string{}
+	}
+	for k, v := range stringData {
+		data[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	unstructured.RemoveNestedField(result.Object, "stringData")
+	_ = unstructured.SetNestedStringMap(result.Object, data, "data")
+	return result
+}
+
+// plainData returns the data of cp as plain text, decoding the base64 data of Secrets
+func plainData(cp *unstructured.Unstructured) (map[string]string, error) {
+	data, _, err := unstructured.NestedStringMap(cp.Object, "data")
+	if err != nil || cp.GetKind() != "Secret" {
+		return data, err
+	}
+	for k, v := range data {
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data %s: %w", k, err)
+		}
+		data[k] = string(b)
+	}
+	return data, nil
+}
@@ -0,0 +1,89 @@
+package testenv
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/onsi/gomega/gcustom"
+	"github.com/onsi/gomega/types"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Finalizer is the finalizer kopy adds to sources and copies
+	Finalizer = "kopy.kot-labs.com/finalizer"
+	// OriginNamespaceLabel and OriginNameLabel identify the source of a copy
+	OriginNamespaceLabel = "kopy.kot-labs.com/origin.namespace"
+	OriginNameLabel      = "kopy.kot-labs.com/origin.name"
+)
+
+// Fetch returns a function that reads obj again from c every time it is called, for polling an object with
+// Eventually and the matchers of this package:
+//
+//	Eventually(testenv.Fetch(ctx, c, cp)).Should(testenv.BeSyncedFrom(src))
+func Fetch(ctx context.Context, c client.Client, obj client.Object) func() (client.Object, error) {
+	key := client.ObjectKeyFromObject(obj)
+	return func() (client.Object, error) {
+		return obj, c.Get(ctx, key, obj)
+	}
+}
+
+// BeSyncedFrom succeeds if the actual Secret or ConfigMap is a copy of src: it is labeled with the origin of src and
+// carries the same data
+func BeSyncedFrom(src client.Object) types.GomegaMatcher {
+	return gcustom.MakeMatcher(func(cp client.Object) (bool, error) {
+		if cp.GetLabels()[OriginNamespaceLabel] != src.GetNamespace() {
+			return false, nil
+		}
+		if name, ok := cp.GetLabels()[OriginNameLabel]; ok && name != src.GetName() {
+			return false, nil
+		}
+		srcData, err := data(src)
+		if err != nil {
+			return false, err
+		}
+		cpData, err := data(cp)
+		if err != nil {
+			return false, err
+		}
+		return maps.EqualFunc(srcData, cpData, slices.Equal), nil
+	}).WithTemplate("Expected\n{{.FormattedActual}}\n{{.To}} be a kopy copy of {{.Data}}", fmt.Sprintf("%s/%s", src.GetNamespace(), src.GetName()))
+}
+
+// HaveKopyFinalizer succeeds if the actual object carries the kopy finalizer
+func HaveKopyFinalizer() types.GomegaMatcher {
+	return gcustom.MakeMatcher(func(o client.Object) (bool, error) {
+		return slices.Contains(o.GetFinalizers(), Finalizer), nil
+	}).WithTemplate("Expected finalizers {{.Actual.GetFinalizers}}\n{{.To}} contain " + Finalizer)
+}
+
+// BeOrphaned succeeds if the actual object is a copy kopy let go of, e.g. after its source was deleted or the sync
+// annotation removed from it: it has neither the kopy finalizer nor the origin labels, and keeps its data
+func BeOrphaned() types.GomegaMatcher {
+	return gcustom.MakeMatcher(func(o client.Object) (bool, error) {
+		_, hasNamespace := o.GetLabels()[OriginNamespaceLabel]
+		_, hasName := o.GetLabels()[OriginNameLabel]
+		return !hasNamespace && !hasName && !slices.Contains(o.GetFinalizers(), Finalizer), nil
+	}).WithTemplate("Expected\n{{.FormattedActual}}\n{{.To}} be released by kopy, without the kopy finalizer and origin labels")
+}
+
+// data returns the data of a Secret or ConfigMap keyed by data key
+func data(o client.Object) (map[string][]byte, error) {
+	switch obj := o.(type) {
+	case *corev1.Secret:
+		return obj.Data, nil
+	case *corev1.ConfigMap:
+		d := maps.Clone(obj.BinaryData)
+		if d == nil {
+			d = map[string][]byte{}
+		}
+		for k, v := range obj.Data {
+			d[k] = []byte(v)
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("expected a Secret or ConfigMap, got %T", o)
+}
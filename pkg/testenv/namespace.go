@@ -0,0 +1,44 @@
+// Package testenv provides helpers and Gomega matchers for running kopy specs in parallel against envtest or a kind
+// cluster.
+package testenv
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onsi/ginkgo/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CreateNamespace creates a namespace named after base with a unique suffix so specs can run with ginkgo -p and
+// reruns don't collide with namespaces leaked by earlier runs. The namespace is deleted when the spec finishes,
+// even if it fails.
+func CreateNamespace(ctx context.Context, c client.Client, base string, labels map[string]string) (*corev1.Namespace, error) {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   UniqueName(base),
+			Labels: labels,
+		},
+	}
+	if err := c.Create(ctx, ns); err != nil {
+		return nil, err
+	}
+	ginkgo.DeferCleanup(func(ctx context.Context) error {
+		return client.IgnoreNotFound(c.Delete(ctx, ns))
+	})
+	return ns, nil
+}
+
+// UniqueName returns base followed by the number of the parallel ginkgo process and a random suffix,
+// truncating base to keep the result a valid namespace name
+func UniqueName(base string) string {
+	suffix := fmt.Sprintf("-%d-%s", ginkgo.GinkgoParallelProcess(), rand.String(5))
+	if max := validation.DNS1123LabelMaxLength - len(suffix); len(base) > max {
+		base = base[:max]
+	}
+	return base + suffix
+}
@@ -0,0 +1,51 @@
+// Package v1 defines version 1 of the kopy transform webhook protocol. kopy POSTs a Request as JSON to each
+// configured webhook before it writes a copy, and the webhook replies with a Response that either carries the
+// mutated copy or asks kopy to skip the target namespace. Webhooks can be written in any language; this package only
+// exists so Go implementations don't have to redeclare the types.
+package v1
+
+import (
+	"encoding/json"
+)
+
+// APIVersion identifies this version of the protocol in requests and responses
+const APIVersion = "transform.kopy.kot-labs.com/v1"
+
+// Operation is the write kopy is about to make
+type Operation string
+
+const (
+	// OperationCreate means the copy doesn't exist in the target namespace yet
+	OperationCreate Operation = "CREATE"
+	// OperationUpdate means an existing copy is being overwritten
+	OperationUpdate Operation = "UPDATE"
+)
+
+// Request is sent to a transform webhook for every copy kopy writes
+type Request struct {
+	APIVersion string `json:"apiVersion"`
+	// UID identifies the request and must be echoed in the response
+	UID string `json:"uid"`
+	// Operation is the write kopy is about to make
+	Operation Operation `json:"operation"`
+	// TargetNamespace is the namespace the copy is written to
+	TargetNamespace string `json:"targetNamespace"`
+	// Source is the source object as stored in the cluster
+	Source json.RawMessage `json:"source"`
+	// Object is the copy kopy is about to write, including the changes of earlier webhooks
+	Object json.RawMessage `json:"object"`
+}
+
+// Response is returned by a transform webhook
+type Response struct {
+	APIVersion string `json:"apiVersion"`
+	// UID is the UID of the request
+	UID string `json:"uid"`
+	// Skip tells kopy not to write the copy to the target namespace. An existing copy is left untouched.
+	Skip bool `json:"skip,omitempty"`
+	// Object is the mutated copy. It is ignored when Skip is set and the copy is written unchanged when empty. The
+	// name, namespace, kind and kopy labels of the copy can't be changed.
+	Object json.RawMessage `json:"object,omitempty"`
+	// Message is logged by kopy, e.g. to explain why a copy was skipped
+	Message string `json:"message,omitempty"`
+}